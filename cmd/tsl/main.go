@@ -6,6 +6,8 @@ import (
 	"os"
 	"path/filepath"
 
+	"github.com/mishankov/totalscript-lang/internal/ast"
+	"github.com/mishankov/totalscript-lang/internal/checker"
 	"github.com/mishankov/totalscript-lang/internal/interpreter"
 	"github.com/mishankov/totalscript-lang/internal/lexer"
 	"github.com/mishankov/totalscript-lang/internal/parser"
@@ -36,6 +38,38 @@ func main() {
 		os.Exit(0)
 	}
 
+	if arg == "openapi" {
+		if len(os.Args) < 3 {
+			printUsage()
+			os.Exit(1)
+		}
+		printOpenAPI(os.Args[2])
+		os.Exit(0)
+	}
+
+	if arg == "check" {
+		if len(os.Args) < 3 {
+			printUsage()
+			os.Exit(1)
+		}
+		if !checkFile(os.Args[2]) {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	// Type-check before running
+	if arg == "--check" {
+		if len(os.Args) < 3 {
+			printUsage()
+			os.Exit(1)
+		}
+		if !checkFile(os.Args[2]) {
+			os.Exit(1)
+		}
+		arg = os.Args[2]
+	}
+
 	// Run file
 	runFile(arg)
 }
@@ -44,12 +78,63 @@ func printUsage() {
 	fmt.Println("TotalScript - A scripting language with batteries included")
 	fmt.Println()
 	fmt.Println("Usage:")
-	fmt.Println("  tsl <file.tsl>    Run a TotalScript file")
-	fmt.Println("  tsl --version     Show version")
-	fmt.Println("  tsl --help        Show this help message")
+	fmt.Println("  tsl <file.tsl>            Run a TotalScript file")
+	fmt.Println("  tsl --check <file.tsl>    Type-check a TotalScript file, then run it")
+	fmt.Println("  tsl check <file.tsl>      Type-check a TotalScript file without running it")
+	fmt.Println("  tsl openapi <file.tsl>    Print the OpenAPI document of the file's HTTP server")
+	fmt.Println("  tsl --version             Show version")
+	fmt.Println("  tsl --help                Show this help message")
 }
 
 func runFile(filename string) {
+	absPath, program := parseFile(filename)
+
+	// Interpret
+	env := interpreter.NewEnvironment()
+	env.SetCurrentFile(absPath) // Set current file for module resolution
+	stdlib.RegisterBuiltins(env)
+	result := interpreter.Eval(program, env)
+
+	if result != nil && result.Type() == interpreter.ErrorObj {
+		fmt.Fprintf(os.Stderr, "%s\n", result.Inspect())
+		os.Exit(1)
+	}
+}
+
+// checkFile type-checks a file and prints the errors found. It reports
+// whether the file is free of type errors.
+func checkFile(filename string) bool {
+	absPath, program := parseFile(filename)
+
+	globals := interpreter.NewEnvironment()
+	stdlib.RegisterBuiltins(globals)
+
+	diagnostics := checker.Check(program, absPath, globals)
+	for _, d := range diagnostics {
+		fmt.Fprintf(os.Stderr, "%s\n", d.Error())
+	}
+	return len(diagnostics) == 0
+}
+
+// printOpenAPI evaluates a file without starting its HTTP server and prints
+// the OpenAPI document describing the server's routes.
+func printOpenAPI(filename string) {
+	absPath, program := parseFile(filename)
+
+	env := interpreter.NewEnvironment()
+	env.SetCurrentFile(absPath)
+	stdlib.RegisterBuiltins(env)
+
+	doc, err := interpreter.GenerateOpenAPI(program, env)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		os.Exit(1)
+	}
+	fmt.Println(string(doc))
+}
+
+// parseFile reads and parses a file, exiting on errors.
+func parseFile(filename string) (string, *ast.Program) {
 	// Get absolute path for the file
 	absPath, err := filepath.Abs(filename)
 	if err != nil {
@@ -76,16 +161,7 @@ func runFile(filename string) {
 		os.Exit(1)
 	}
 
-	// Interpret
-	env := interpreter.NewEnvironment()
-	env.SetCurrentFile(absPath) // Set current file for module resolution
-	stdlib.RegisterBuiltins(env)
-	result := interpreter.Eval(program, env)
-
-	if result != nil && result.Type() == interpreter.ErrorObj {
-		fmt.Fprintf(os.Stderr, "%s\n", result.Inspect())
-		os.Exit(1)
-	}
+	return absPath, program
 }
 
 func printParserErrors(errors []*parser.ParseError) {
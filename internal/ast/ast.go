@@ -3,6 +3,7 @@ package ast
 
 import (
 	"bytes"
+	"fmt"
 	"strings"
 
 	"github.com/mishankov/totalscript-lang/internal/token"
@@ -51,11 +52,15 @@ func (p *Program) String() string {
 
 // VarStatement represents a variable declaration.
 // var x: integer = 5
+//
+// A pattern instead of the name destructures the value:
+// var [first, ...rest] = items
 type VarStatement struct {
-	Token token.Token // the 'var' token
-	Name  *Identifier
-	Type  *TypeExpression
-	Value Expression
+	Token   token.Token // the 'var' token
+	Name    *Identifier
+	Pattern Pattern // destructuring pattern, if Name is nil
+	Type    *TypeExpression
+	Value   Expression
 }
 
 func (vs *VarStatement) statementNode()       {}
@@ -63,7 +68,11 @@ func (vs *VarStatement) TokenLiteral() string { return vs.Token.Literal }
 func (vs *VarStatement) String() string {
 	var out bytes.Buffer
 	out.WriteString(vs.TokenLiteral() + " ")
-	out.WriteString(vs.Name.String())
+	if vs.Pattern != nil {
+		out.WriteString(vs.Pattern.String())
+	} else {
+		out.WriteString(vs.Name.String())
+	}
 	if vs.Type != nil {
 		out.WriteString(": ")
 		out.WriteString(vs.Type.String())
@@ -77,11 +86,13 @@ func (vs *VarStatement) String() string {
 
 // ConstStatement represents a constant declaration.
 // const PI: float = 3.14
+// const {name, age} = user
 type ConstStatement struct {
-	Token token.Token // the 'const' token
-	Name  *Identifier
-	Type  *TypeExpression
-	Value Expression
+	Token   token.Token // the 'const' token
+	Name    *Identifier
+	Pattern Pattern // destructuring pattern, if Name is nil
+	Type    *TypeExpression
+	Value   Expression
 }
 
 func (cs *ConstStatement) statementNode()       {}
@@ -89,7 +100,11 @@ func (cs *ConstStatement) TokenLiteral() string { return cs.Token.Literal }
 func (cs *ConstStatement) String() string {
 	var out bytes.Buffer
 	out.WriteString(cs.TokenLiteral() + " ")
-	out.WriteString(cs.Name.String())
+	if cs.Pattern != nil {
+		out.WriteString(cs.Pattern.String())
+	} else {
+		out.WriteString(cs.Name.String())
+	}
 	if cs.Type != nil {
 		out.WriteString(": ")
 		out.WriteString(cs.Type.String())
@@ -158,6 +173,28 @@ func (es *ExpressionStatement) String() string {
 	return ""
 }
 
+// MultiAssignStatement represents an assignment of several values at once.
+// All values are evaluated before any target is assigned: a, b = b, a
+type MultiAssignStatement struct {
+	Token   token.Token // the '=' token
+	Targets []Expression
+	Values  []Expression
+}
+
+func (ms *MultiAssignStatement) statementNode()       {}
+func (ms *MultiAssignStatement) TokenLiteral() string { return ms.Token.Literal }
+func (ms *MultiAssignStatement) String() string {
+	targets := make([]string, 0, len(ms.Targets))
+	for _, t := range ms.Targets {
+		targets = append(targets, t.String())
+	}
+	values := make([]string, 0, len(ms.Values))
+	for _, v := range ms.Values {
+		values = append(values, v.String())
+	}
+	return strings.Join(targets, ", ") + " = " + strings.Join(values, ", ")
+}
+
 // BlockStatement represents a block of statements.
 type BlockStatement struct {
 	Token      token.Token // the '{' token
@@ -213,6 +250,31 @@ func (ws *WhileStatement) String() string {
 	return out.String()
 }
 
+// TryStatement represents a try/catch statement. Runtime errors raised in
+// Body are converted to Error instances and bound to ErrorName in Catch.
+// try { ... } catch e { ... }
+type TryStatement struct {
+	Token     token.Token // the 'try' token
+	Body      *BlockStatement
+	ErrorName *Identifier // optional
+	Catch     *BlockStatement
+}
+
+func (ts *TryStatement) statementNode()       {}
+func (ts *TryStatement) TokenLiteral() string { return ts.Token.Literal }
+func (ts *TryStatement) String() string {
+	var out bytes.Buffer
+	out.WriteString("try ")
+	out.WriteString(ts.Body.String())
+	out.WriteString(" catch ")
+	if ts.ErrorName != nil {
+		out.WriteString(ts.ErrorName.String())
+		out.WriteString(" ")
+	}
+	out.WriteString(ts.Catch.String())
+	return out.String()
+}
+
 // ForStatement represents a for loop (both for-in and C-style).
 type ForStatement struct {
 	Token token.Token // the 'for' token
@@ -220,6 +282,7 @@ type ForStatement struct {
 	// For-in style: for i in 0..10 { ... } or for index, value in array { ... }
 	Iterator     *Identifier // optional second identifier for index/key
 	Value        *Identifier
+	Pattern      Pattern // destructures the values instead of Value: for [k, v] in pairs
 	Iterable     Expression
 	IsRangeStyle bool
 
@@ -241,7 +304,11 @@ func (fs *ForStatement) String() string {
 			out.WriteString(fs.Iterator.String())
 			out.WriteString(", ")
 		}
-		out.WriteString(fs.Value.String())
+		if fs.Pattern != nil {
+			out.WriteString(fs.Pattern.String())
+		} else {
+			out.WriteString(fs.Value.String())
+		}
 		out.WriteString(" in ")
 		out.WriteString(fs.Iterable.String())
 	} else {
@@ -343,6 +410,31 @@ func (sl *StringLiteral) expressionNode()      {}
 func (sl *StringLiteral) TokenLiteral() string { return sl.Token.Literal }
 func (sl *StringLiteral) String() string       { return `"` + sl.Value + `"` }
 
+// InterpolatedString represents a string with embedded expressions,
+// e.g. "Hello ${user.name}!". Parts are string literals and expressions.
+type InterpolatedString struct {
+	Token token.Token // the TEMPLATE token
+	Parts []Expression
+}
+
+func (is *InterpolatedString) expressionNode()      {}
+func (is *InterpolatedString) TokenLiteral() string { return is.Token.Literal }
+func (is *InterpolatedString) String() string {
+	var out bytes.Buffer
+	out.WriteString(`"`)
+	for _, part := range is.Parts {
+		if str, ok := part.(*StringLiteral); ok {
+			out.WriteString(str.Value)
+			continue
+		}
+		out.WriteString("${")
+		out.WriteString(part.String())
+		out.WriteString("}")
+	}
+	out.WriteString(`"`)
+	return out.String()
+}
+
 // BooleanLiteral represents a boolean literal.
 type BooleanLiteral struct {
 	Token token.Token
@@ -440,6 +532,19 @@ func (ie *InfixExpression) String() string {
 	return out.String()
 }
 
+// PropagateExpression represents the postfix error propagation operator.
+// value? returns an Error from the enclosing function, otherwise the value.
+type PropagateExpression struct {
+	Token token.Token // the '?' token
+	Value Expression
+}
+
+func (pe *PropagateExpression) expressionNode()      {}
+func (pe *PropagateExpression) TokenLiteral() string { return pe.Token.Literal }
+func (pe *PropagateExpression) String() string {
+	return "(" + pe.Value.String() + "?)"
+}
+
 // IfExpression represents an if expression.
 type IfExpression struct {
 	Token       token.Token // the 'if' token
@@ -464,8 +569,10 @@ func (ie *IfExpression) String() string {
 }
 
 // FunctionLiteral represents a function literal.
+//
+// Lambdas are function literals too: x => x * 2, (a, b) => { ... }
 type FunctionLiteral struct {
-	Token      token.Token // the 'function' token
+	Token      token.Token // the 'function' or '=>' token
 	Parameters []*Parameter
 	ReturnType *TypeExpression
 	Body       *BlockStatement
@@ -479,6 +586,13 @@ func (fl *FunctionLiteral) String() string {
 	for _, p := range fl.Parameters {
 		params = append(params, p.String())
 	}
+	if fl.Token.Type == token.ARROW {
+		out.WriteString("(")
+		out.WriteString(strings.Join(params, ", "))
+		out.WriteString(") => ")
+		out.WriteString(fl.Body.String())
+		return out.String()
+	}
 	out.WriteString(fl.TokenLiteral())
 	out.WriteString("(")
 	out.WriteString(strings.Join(params, ", "))
@@ -492,49 +606,81 @@ func (fl *FunctionLiteral) String() string {
 	return out.String()
 }
 
-// Parameter represents a function parameter.
+// Parameter represents a function parameter: name: type = default. A
+// variadic parameter (...rest: integer) collects the remaining arguments into
+// an array; its type is the type of the elements.
 type Parameter struct {
-	Name *Identifier
-	Type *TypeExpression
+	Name     *Identifier
+	Type     *TypeExpression
+	Default  Expression // optional
+	Variadic bool
 }
 
 func (p *Parameter) String() string {
 	var out bytes.Buffer
+	if p.Variadic {
+		out.WriteString("...")
+	}
 	out.WriteString(p.Name.String())
 	if p.Type != nil {
 		out.WriteString(": ")
 		out.WriteString(p.Type.String())
 	}
+	if p.Default != nil {
+		out.WriteString(" = ")
+		out.WriteString(p.Default.String())
+	}
 	return out.String()
 }
 
 // CallExpression represents a function call.
 type CallExpression struct {
-	Token     token.Token // the '(' token
-	Function  Expression
-	Arguments []Expression
+	Token          token.Token // the '(' token
+	Function       Expression
+	Arguments      []Expression
+	NamedArguments []*NamedArgument // follow the positional arguments
+	Optional       bool             // f?.(x) calls f only if it is not null
 }
 
 func (ce *CallExpression) expressionNode()      {}
 func (ce *CallExpression) TokenLiteral() string { return ce.Token.Literal }
 func (ce *CallExpression) String() string {
 	var out bytes.Buffer
-	args := make([]string, 0, len(ce.Arguments))
+	args := make([]string, 0, len(ce.Arguments)+len(ce.NamedArguments))
 	for _, a := range ce.Arguments {
 		args = append(args, a.String())
 	}
+	for _, a := range ce.NamedArguments {
+		args = append(args, a.String())
+	}
 	out.WriteString(ce.Function.String())
+	if ce.Optional {
+		out.WriteString("?.")
+	}
 	out.WriteString("(")
 	out.WriteString(strings.Join(args, ", "))
 	out.WriteString(")")
 	return out.String()
 }
 
+// NamedArgument represents an argument passed by parameter or field name:
+// Point(x: 1, y: 2)
+type NamedArgument struct {
+	Token token.Token // the name token
+	Name  *Identifier
+	Value Expression
+}
+
+func (na *NamedArgument) String() string {
+	return na.Name.String() + ": " + na.Value.String()
+}
+
 // IndexExpression represents an index operation (array[0], map["key"]).
 type IndexExpression struct {
-	Token token.Token // the '[' token
-	Left  Expression
-	Index Expression
+	Token    token.Token // the '[' token
+	Left     Expression
+	Index    Expression
+	Optional bool // items?.[0] indexes items only if it is not null
 }
 
 func (ie *IndexExpression) expressionNode()      {}
@@ -543,17 +689,23 @@ func (ie *IndexExpression) String() string {
 	var out bytes.Buffer
 	out.WriteString("(")
 	out.WriteString(ie.Left.String())
+	if ie.Optional {
+		out.WriteString("?.")
+	}
 	out.WriteString("[")
 	out.WriteString(ie.Index.String())
 	out.WriteString("])")
 	return out.String()
 }
 
-// MemberExpression represents a member access (obj.property).
+// MemberExpression represents a member access (obj.property). Optional
+// access (obj?.property) short-circuits the rest of the chain to null when
+// obj is null.
 type MemberExpression struct {
-	Token  token.Token // the '.' token
-	Object Expression
-	Member *Identifier
+	Token    token.Token // the '.' or '?.' token
+	Object   Expression
+	Member   *Identifier
+	Optional bool
 }
 
 func (me *MemberExpression) expressionNode()      {}
@@ -561,6 +713,9 @@ func (me *MemberExpression) TokenLiteral() string { return me.Token.Literal }
 func (me *MemberExpression) String() string {
 	var out bytes.Buffer
 	out.WriteString(me.Object.String())
+	if me.Optional {
+		out.WriteString("?")
+	}
 	out.WriteString(".")
 	out.WriteString(me.Member.String())
 	return out.String()
@@ -578,13 +733,17 @@ func (re *RangeExpression) expressionNode()      {}
 func (re *RangeExpression) TokenLiteral() string { return re.Token.Literal }
 func (re *RangeExpression) String() string {
 	var out bytes.Buffer
-	out.WriteString(re.Start.String())
+	if re.Start != nil {
+		out.WriteString(re.Start.String())
+	}
 	if re.Inclusive {
 		out.WriteString("..=")
 	} else {
 		out.WriteString("..")
 	}
-	out.WriteString(re.End.String())
+	if re.End != nil {
+		out.WriteString(re.End.String())
+	}
 	return out.String()
 }
 
@@ -651,7 +810,9 @@ func (mm *ModelMethod) String() string {
 
 // ModelLiteral represents a model definition.
 type ModelLiteral struct {
-	Token        token.Token // the 'model' token
+	Token        token.Token       // the 'model' token
+	Extends      *TypeExpression   // Base model, if any
+	Implements   []*TypeExpression // Interfaces declared with implements
 	Fields       []*ModelField
 	Methods      []*ModelMethod
 	Constructors []*FunctionLiteral // Custom constructors
@@ -661,7 +822,22 @@ func (ml *ModelLiteral) expressionNode()      {}
 func (ml *ModelLiteral) TokenLiteral() string { return ml.Token.Literal }
 func (ml *ModelLiteral) String() string {
 	var out bytes.Buffer
-	out.WriteString("model {\n")
+	out.WriteString("model ")
+	if ml.Extends != nil {
+		out.WriteString("extends ")
+		out.WriteString(ml.Extends.String())
+		out.WriteString(" ")
+	}
+	if len(ml.Implements) > 0 {
+		names := make([]string, 0, len(ml.Implements))
+		for _, iface := range ml.Implements {
+			names = append(names, iface.String())
+		}
+		out.WriteString("implements ")
+		out.WriteString(strings.Join(names, ", "))
+		out.WriteString(" ")
+	}
+	out.WriteString("{\n")
 	for _, field := range ml.Fields {
 		out.WriteString("  ")
 		out.WriteString(field.String())
@@ -681,6 +857,57 @@ func (ml *ModelLiteral) String() string {
 	return out.String()
 }
 
+// InterfaceMethod represents a method signature in an interface definition.
+type InterfaceMethod struct {
+	Name       *Identifier
+	Parameters []*Parameter
+	ReturnType *TypeExpression
+}
+
+func (im *InterfaceMethod) String() string {
+	var out bytes.Buffer
+	params := make([]string, 0, len(im.Parameters))
+	for _, p := range im.Parameters {
+		params = append(params, p.String())
+	}
+	out.WriteString(im.Name.String())
+	out.WriteString(": function(")
+	out.WriteString(strings.Join(params, ", "))
+	out.WriteString(")")
+	if im.ReturnType != nil {
+		out.WriteString(": ")
+		out.WriteString(im.ReturnType.String())
+	}
+	return out.String()
+}
+
+// InterfaceLiteral represents an interface definition: the fields and
+// methods a model must have to conform to it.
+type InterfaceLiteral struct {
+	Token   token.Token // the 'interface' token
+	Fields  []*ModelField
+	Methods []*InterfaceMethod
+}
+
+func (il *InterfaceLiteral) expressionNode()      {}
+func (il *InterfaceLiteral) TokenLiteral() string { return il.Token.Literal }
+func (il *InterfaceLiteral) String() string {
+	var out bytes.Buffer
+	out.WriteString("interface {\n")
+	for _, field := range il.Fields {
+		out.WriteString("  ")
+		out.WriteString(field.String())
+		out.WriteString("\n")
+	}
+	for _, method := range il.Methods {
+		out.WriteString("  ")
+		out.WriteString(method.String())
+		out.WriteString("\n")
+	}
+	out.WriteString("}")
+	return out.String()
+}
+
 // EnumValue represents a value in an enum definition.
 type EnumValue struct {
 	Name  *Identifier
@@ -724,6 +951,16 @@ func (te *ThisExpression) expressionNode()      {}
 func (te *ThisExpression) TokenLiteral() string { return te.Token.Literal }
 func (te *ThisExpression) String() string       { return "this" }
 
+// SuperExpression represents the base of a model in its constructors and
+// methods: super(args) or super.method(args).
+type SuperExpression struct {
+	Token token.Token // the 'super' token
+}
+
+func (se *SuperExpression) expressionNode()      {}
+func (se *SuperExpression) TokenLiteral() string { return se.Token.Literal }
+func (se *SuperExpression) String() string       { return "super" }
+
 // DbFindExpression represents a database query: db.find(Model) { conditions }
 type DbFindExpression struct {
 	Token      token.Token       // The token after 'find' (usually '(')
@@ -813,3 +1050,171 @@ func (qm *QueryModifiers) String() string {
 	}
 	return strings.TrimSpace(out.String())
 }
+
+// MatchExpression represents a match expression. The first arm whose pattern
+// matches the value (and whose guard holds) gives the result.
+//
+//	match value {
+//	  case 0 { "zero" }
+//	  case n: integer if n > 0 { "positive" }
+//	  default { "other" }
+//	}
+type MatchExpression struct {
+	Token   token.Token // the 'match' token
+	Value   Expression
+	Arms    []*MatchArm
+	Default *BlockStatement
+}
+
+func (me *MatchExpression) expressionNode()      {}
+func (me *MatchExpression) TokenLiteral() string { return me.Token.Literal }
+func (me *MatchExpression) String() string {
+	var out bytes.Buffer
+	out.WriteString("match ")
+	out.WriteString(me.Value.String())
+	out.WriteString(" { ")
+	for _, arm := range me.Arms {
+		out.WriteString(arm.String())
+		out.WriteString(" ")
+	}
+	if me.Default != nil {
+		out.WriteString("default ")
+		out.WriteString(me.Default.String())
+		out.WriteString(" ")
+	}
+	out.WriteString("}")
+	return out.String()
+}
+
+// MatchArm represents a case of a match expression: alternative patterns, an
+// optional guard and the body.
+type MatchArm struct {
+	Token    token.Token // the 'case' token
+	Patterns []Pattern
+	Guard    Expression // optional
+	Body     *BlockStatement
+}
+
+func (ma *MatchArm) String() string {
+	var out bytes.Buffer
+	out.WriteString("case ")
+	patterns := make([]string, 0, len(ma.Patterns))
+	for _, p := range ma.Patterns {
+		patterns = append(patterns, p.String())
+	}
+	out.WriteString(strings.Join(patterns, ", "))
+	if ma.Guard != nil {
+		out.WriteString(" if ")
+		out.WriteString(ma.Guard.String())
+	}
+	out.WriteString(" ")
+	out.WriteString(ma.Body.String())
+	return out.String()
+}
+
+// Pattern represents a pattern that values are matched against.
+type Pattern interface {
+	Node
+	patternNode()
+}
+
+// WildcardPattern matches any value: _
+type WildcardPattern struct {
+	Token token.Token // the '_' token
+}
+
+func (wp *WildcardPattern) patternNode()         {}
+func (wp *WildcardPattern) TokenLiteral() string { return wp.Token.Literal }
+func (wp *WildcardPattern) String() string       { return "_" }
+
+// BindingPattern matches any value, or values of Type, and binds it to Name:
+// n, e: Error
+type BindingPattern struct {
+	Token token.Token // the identifier token
+	Name  *Identifier
+	Type  *TypeExpression // optional
+}
+
+func (bp *BindingPattern) patternNode()         {}
+func (bp *BindingPattern) TokenLiteral() string { return bp.Token.Literal }
+func (bp *BindingPattern) String() string {
+	if bp.Type != nil {
+		return bp.Name.String() + ": " + bp.Type.String()
+	}
+	return bp.Name.String()
+}
+
+// ValuePattern matches values equal to a literal or enum value, or integers
+// in a range: 0, "ok", Color.Red, 1..=9
+type ValuePattern struct {
+	Token token.Token // the first token of the value
+	Value Expression
+}
+
+func (vp *ValuePattern) patternNode()         {}
+func (vp *ValuePattern) TokenLiteral() string { return vp.Token.Literal }
+func (vp *ValuePattern) String() string       { return vp.Value.String() }
+
+// ArrayPattern matches arrays element by element. With a rest pattern, longer
+// arrays match too and the remaining elements are bound to Rest:
+// [first, second, ...rest]
+type ArrayPattern struct {
+	Token    token.Token // the '[' token
+	Elements []Pattern
+	HasRest  bool
+	Rest     *Identifier // nil for an unnamed rest: [first, ...]
+}
+
+func (ap *ArrayPattern) patternNode()         {}
+func (ap *ArrayPattern) TokenLiteral() string { return ap.Token.Literal }
+func (ap *ArrayPattern) String() string {
+	elements := make([]string, 0, len(ap.Elements)+1)
+	for _, e := range ap.Elements {
+		elements = append(elements, e.String())
+	}
+	if ap.HasRest {
+		rest := "..."
+		if ap.Rest != nil {
+			rest += ap.Rest.String()
+		}
+		elements = append(elements, rest)
+	}
+	return "[" + strings.Join(elements, ", ") + "]"
+}
+
+// MapPattern matches maps having the keys, whose values match the patterns.
+// A key without a pattern binds the value to a variable of the same name:
+// {"status": 200, body}
+type MapPattern struct {
+	Token  token.Token // the '{' token
+	Keys   []string
+	Values []Pattern
+}
+
+func (mp *MapPattern) patternNode()         {}
+func (mp *MapPattern) TokenLiteral() string { return mp.Token.Literal }
+func (mp *MapPattern) String() string {
+	pairs := make([]string, 0, len(mp.Keys))
+	for i, key := range mp.Keys {
+		pairs = append(pairs, fmt.Sprintf("%q: %s", key, mp.Values[i].String()))
+	}
+	return "{" + strings.Join(pairs, ", ") + "}"
+}
+
+// ModelPattern matches instances of a model whose fields, in declaration
+// order, match the patterns: Point(x, 0)
+type ModelPattern struct {
+	Token  token.Token // the model name token
+	Model  Expression  // identifier or module member (geo.Point)
+	Fields []Pattern
+}
+
+func (mp *ModelPattern) patternNode()         {}
+func (mp *ModelPattern) TokenLiteral() string { return mp.Token.Literal }
+func (mp *ModelPattern) String() string {
+	fields := make([]string, 0, len(mp.Fields))
+	for _, f := range mp.Fields {
+		fields = append(fields, f.String())
+	}
+	return mp.Model.String() + "(" + strings.Join(fields, ", ") + ")"
+}
@@ -0,0 +1,707 @@
+// Package checker implements a static type-checking pass for TotalScript.
+//
+// The checker infers expression types from literals, type annotations, model
+// and enum declarations and imported modules, and reports the type errors
+// the interpreter would only find while running the program: calls with
+// wrong arguments, mismatching assignments and return values, and access to
+// members that do not exist. Expressions whose types cannot be inferred are
+// never reported.
+package checker
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/mishankov/totalscript-lang/internal/ast"
+	"github.com/mishankov/totalscript-lang/internal/interpreter"
+	"github.com/mishankov/totalscript-lang/internal/lexer"
+	"github.com/mishankov/totalscript-lang/internal/parser"
+	"github.com/mishankov/totalscript-lang/internal/token"
+)
+
+// Diagnostic is a type error found by the checker.
+type Diagnostic struct {
+	File    string
+	Line    int
+	Column  int
+	Message string
+}
+
+// Error formats the diagnostic as file:line:column: message.
+func (d *Diagnostic) Error() string {
+	return fmt.Sprintf("%s:%d:%d: %s", d.File, d.Line, d.Column, d.Message)
+}
+
+// Checker holds the state of a type-checking pass.
+type Checker struct {
+	file        string
+	globals     *interpreter.Environment
+	diagnostics []*Diagnostic
+	quiet       int                // suppress diagnostics while > 0
+	returns     []*Type            // expected return types of enclosing functions
+	assigned    map[string]bool    // variables assigned after their declaration
+	literals    map[ast.Node]*Type // types of function, model and enum literals
+	models      map[*interpreter.Model]*modelInfo
+	modules     map[string]*Type // checked file modules by absolute path
+}
+
+// Check type-checks a parsed program. file is used for diagnostics and to
+// resolve imports of file modules; globals provides the built-in functions
+// and models, as registered by stdlib.RegisterBuiltins.
+func Check(program *ast.Program, file string, globals *interpreter.Environment) []*Diagnostic {
+	c := &Checker{
+		globals:  globals,
+		literals: make(map[ast.Node]*Type),
+		models:   make(map[*interpreter.Model]*modelInfo),
+		modules:  make(map[string]*Type),
+	}
+	c.checkFile(program, file)
+
+	// Diagnostics of the checked file come first, then those of its imports
+	sort.SliceStable(c.diagnostics, func(i, j int) bool {
+		a, b := c.diagnostics[i], c.diagnostics[j]
+		if a.File != b.File {
+			if a.File == file || b.File == file {
+				return a.File == file
+			}
+			return a.File < b.File
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+	return c.diagnostics
+}
+
+// checkFile checks the program of a file in a new top-level scope and
+// returns that scope.
+func (c *Checker) checkFile(program *ast.Program, file string) *scope {
+	prevFile, prevAssigned := c.file, c.assigned
+	c.file = file
+	c.assigned = make(map[string]bool)
+	collectAssigned(program, c.assigned)
+	defer func() { c.file, c.assigned = prevFile, prevAssigned }()
+
+	s := &scope{checker: c, vars: make(map[string]*variable), env: c.globals}
+	c.hoist(program.Statements, s)
+	c.checkStatements(program.Statements, s)
+	return s
+}
+
+// errorf reports a diagnostic at the position of tok.
+func (c *Checker) errorf(tok token.Token, format string, args ...any) {
+	if c.quiet > 0 {
+		return
+	}
+	c.diagnostics = append(c.diagnostics, &Diagnostic{
+		File:    c.file,
+		Line:    tok.Line,
+		Column:  tok.Column,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// resolveQuiet resolves a type annotation that was already checked where it
+// was written.
+func (c *Checker) resolveQuiet(expr *ast.TypeExpression, s *scope) *Type {
+	c.quiet++
+	defer func() { c.quiet-- }()
+	return c.resolveType(expr, s)
+}
+
+// variable is a variable known to the checker.
+type variable struct {
+	t        *Type
+	declared bool // has a type annotation that assignments must satisfy
+}
+
+// scope mirrors an interpreter environment.
+type scope struct {
+	checker  *Checker
+	parent   *scope
+	vars     map[string]*variable
+	narrowed map[string]*Type
+	env      *interpreter.Environment // runtime values behind the outermost scope
+	shared   bool                     // declarations go to the parent, as in if and while blocks
+}
+
+// child returns a scope enclosed by s, like NewEnclosedEnvironment.
+func (s *scope) child() *scope {
+	return &scope{checker: s.checker, parent: s, vars: make(map[string]*variable)}
+}
+
+// narrow returns a scope sharing declarations with s in which the variables
+// of n have narrower types.
+func (s *scope) narrow(n interpreter.Narrowing) *scope {
+	narrowed := &scope{checker: s.checker, parent: s, shared: true, narrowed: make(map[string]*Type, len(n))}
+	for name, typeExpr := range n {
+		current, _ := s.lookup(name)
+		narrowed.narrowed[name] = s.checker.narrowedType(current, typeExpr, s)
+	}
+	return narrowed
+}
+
+// declare adds a variable to the innermost scope holding declarations.
+func (s *scope) declare(name string, v *variable) {
+	for s.shared {
+		s = s.parent
+	}
+	s.vars[name] = v
+}
+
+// variable returns the declaration of a variable.
+func (s *scope) variable(name string) (*variable, bool) {
+	for ; s != nil; s = s.parent {
+		if v, ok := s.vars[name]; ok {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// lookup returns the current type of a name.
+func (s *scope) lookup(name string) (*Type, bool) {
+	for ; s != nil; s = s.parent {
+		if t, ok := s.narrowed[name]; ok {
+			return t, true
+		}
+		if v, ok := s.vars[name]; ok {
+			return v.t, true
+		}
+		if s.env != nil {
+			if obj, ok := s.env.Get(name); ok {
+				return s.checker.typeOfObject(obj, s.env), true
+			}
+		}
+	}
+	return nil, false
+}
+
+// typeOf implements interpreter.TypeLookup for type narrowing.
+func (s *scope) typeOf(name string) (*ast.TypeExpression, bool) {
+	t, ok := s.lookup(name)
+	if !ok || t.kind == unknownKind {
+		return nil, false
+	}
+	return t.typeExpression(), true
+}
+
+// narrowedType converts a narrowed type annotation back to a static type,
+// preferring the alternatives of the current type it names.
+func (c *Checker) narrowedType(current *Type, typeExpr *ast.TypeExpression, s *scope) *Type {
+	if current != nil {
+		names := typeExpr.Union
+		if len(names) == 0 {
+			names = []string{typeExpr.String()}
+			if typeExpr.Optional {
+				names = []string{(&ast.TypeExpression{Name: typeExpr.Name, Generic: typeExpr.Generic}).String()}
+			}
+		}
+		if typeExpr.Optional {
+			names = append(names, typeNull)
+		}
+
+		alternatives := []*Type{current}
+		if current.kind == unionKind {
+			alternatives = current.members
+		}
+		var members []*Type
+		for _, name := range names {
+			for _, alt := range alternatives {
+				if alt.String() == name || strings.HasSuffix(name, "."+alt.String()) {
+					members = append(members, alt)
+					break
+				}
+			}
+		}
+		if len(members) == len(names) {
+			return unionOf(members...)
+		}
+	}
+	return c.resolveQuiet(typeExpr, s)
+}
+
+// hoist declares the functions, models, enums and variables of a block before
+// checking it, so functions may refer to declarations that follow them.
+func (c *Checker) hoist(statements []ast.Statement, s *scope) {
+	for _, stmt := range statements {
+		switch stmt := stmt.(type) {
+		case *ast.VarStatement:
+			if stmt.Pattern != nil {
+				hoistPattern(stmt.Pattern, s)
+			} else if _, ok := s.vars[stmt.Name.Value]; !ok {
+				s.declare(stmt.Name.Value, &variable{t: c.hoistedType(stmt.Name.Value, stmt.Value, s)})
+			}
+		case *ast.ConstStatement:
+			if stmt.Pattern != nil {
+				hoistPattern(stmt.Pattern, s)
+			} else if _, ok := s.vars[stmt.Name.Value]; !ok {
+				s.declare(stmt.Name.Value, &variable{t: c.hoistedType(stmt.Name.Value, stmt.Value, s)})
+			}
+		case *ast.ImportStatement:
+			s.declare(stmt.ModuleName, &variable{t: c.importModule(stmt)})
+		}
+	}
+}
+
+// hoistedType returns the type of a declaration known before checking it.
+func (c *Checker) hoistedType(name string, value ast.Expression, s *scope) *Type {
+	switch value := value.(type) {
+	case *ast.FunctionLiteral:
+		return c.functionType(value, s)
+	case *ast.ModelLiteral:
+		t := c.modelType(value, s)
+		t.model.name = name
+		return t
+	case *ast.EnumLiteral:
+		t := c.enumType(value)
+		t.enum.name = name
+		return t
+	case *ast.InterfaceLiteral:
+		t := c.interfaceType(value, s)
+		t.iface.name = name
+		return t
+	}
+	return unknownType
+}
+
+// checkStatements checks statements in order, narrowing variable types after
+// guards like `if x is Error { return x }`.
+func (c *Checker) checkStatements(statements []ast.Statement, s *scope) {
+	for _, stmt := range statements {
+		c.checkStatement(stmt, s)
+		if narrowing := interpreter.GuardNarrowing(stmt, s.typeOf); narrowing != nil {
+			s = s.narrow(narrowing)
+		}
+	}
+}
+
+// checkBlock checks a block sharing declarations with s.
+func (c *Checker) checkBlock(block *ast.BlockStatement, s *scope) {
+	if block != nil {
+		c.checkStatements(block.Statements, s)
+	}
+}
+
+//nolint:gocyclo,cyclop
+func (c *Checker) checkStatement(stmt ast.Statement, s *scope) {
+	switch stmt := stmt.(type) {
+	case *ast.ExpressionStatement:
+		c.expr(stmt.Expression, s)
+
+	case *ast.VarStatement:
+		if stmt.Pattern != nil {
+			c.checkPattern(stmt.Pattern, c.expr(stmt.Value, s), s)
+			return
+		}
+		c.checkDeclaration(stmt.Name, stmt.Type, stmt.Value, s)
+
+	case *ast.ConstStatement:
+		if stmt.Pattern != nil {
+			c.checkPattern(stmt.Pattern, c.expr(stmt.Value, s), s)
+			return
+		}
+		c.checkDeclaration(stmt.Name, stmt.Type, stmt.Value, s)
+
+	case *ast.MultiAssignStatement:
+		c.checkMultiAssign(stmt, s)
+
+	case *ast.ImportStatement:
+		// Imports are loaded when the block is hoisted
+
+	case *ast.ReturnStatement:
+		valueType := nullType
+		if stmt.ReturnValue != nil {
+			valueType = c.expr(stmt.ReturnValue, s)
+		}
+		if len(c.returns) == 0 {
+			return
+		}
+		if expected := c.returns[len(c.returns)-1]; expected != nil && !assignable(valueType, expected) {
+			c.errorf(stmt.Token, "return type: %s", mismatch(expected, valueType, stmt.ReturnValue))
+		}
+
+	case *ast.BlockStatement:
+		c.checkBlock(stmt, s)
+
+	case *ast.WhileStatement:
+		c.expr(stmt.Condition, s)
+		whenTrue, _ := interpreter.NarrowCondition(stmt.Condition, s.typeOf)
+		c.checkBlock(stmt.Body, s.narrow(whenTrue))
+
+	case *ast.ForStatement:
+		c.checkFor(stmt, s)
+
+	case *ast.SwitchStatement:
+		c.expr(stmt.Value, s)
+		for _, clause := range stmt.Cases {
+			for _, value := range clause.Values {
+				c.expr(value, s)
+			}
+			c.checkBlock(clause.Body, s.narrow(nil))
+		}
+		c.checkBlock(stmt.Default, s.narrow(nil))
+
+	case *ast.TryStatement:
+		c.checkBlock(stmt.Body, s.narrow(nil))
+		catch := s.narrow(nil)
+		if stmt.ErrorName != nil {
+			catch = s.child()
+			catch.declare(stmt.ErrorName.Value, &variable{t: c.errorType()})
+		}
+		c.checkBlock(stmt.Catch, catch)
+	}
+}
+
+// checkDeclaration checks a var or const statement and declares its variable.
+func (c *Checker) checkDeclaration(name *ast.Identifier, typeExpr *ast.TypeExpression, value ast.Expression, s *scope) {
+	valueType := nullType
+	if value != nil {
+		valueType = c.expr(value, s)
+	}
+
+	switch {
+	case typeExpr != nil:
+		declared := c.resolveType(typeExpr, s)
+		if !assignable(valueType, declared) {
+			c.errorf(name.Token, "%s", mismatch(declared, valueType, value))
+		}
+		s.declare(name.Value, &variable{t: declared, declared: true})
+	case c.assigned[name.Value]:
+		// The inferred type does not survive later assignments
+		s.declare(name.Value, &variable{t: unknownType})
+	default:
+		switch valueType.kind {
+		case modelKind:
+			if valueType.model.name == "" {
+				valueType.model.name = name.Value
+			}
+		case enumKind:
+			if valueType.enum.name == "" {
+				valueType.enum.name = name.Value
+			}
+		case interfaceKind:
+			if valueType.iface.name == "" {
+				valueType.iface.name = name.Value
+			}
+		}
+		s.declare(name.Value, &variable{t: valueType})
+	}
+}
+
+// mismatch formats a type mismatch, adding what is known about a variable
+// holding a union, like the interpreter's narrowing hint.
+func mismatch(expected, got *Type, value ast.Expression) string {
+	msg := fmt.Sprintf("type mismatch: expected %s, got %s", expected, got)
+	if ident, ok := value.(*ast.Identifier); ok && got.kind == unionKind {
+		msg += fmt.Sprintf(" (%s is %s here; check it with `is` first)", ident.Value, got)
+	}
+	if expected.kind == implementerKind && got.kind == instanceKind {
+		msg += fmt.Sprintf(" (%s)", missingMember(got.model, expected.iface))
+	}
+	return msg
+}
+
+func (c *Checker) checkFor(stmt *ast.ForStatement, s *scope) {
+	forScope := s.child()
+
+	if !stmt.IsRangeStyle {
+		if stmt.Init != nil {
+			c.checkStatement(stmt.Init, forScope)
+		}
+		if stmt.Condition != nil {
+			c.expr(stmt.Condition, forScope)
+		}
+		c.checkBlock(stmt.Body, forScope.narrow(nil))
+		if stmt.Post != nil {
+			c.expr(stmt.Post, forScope)
+		}
+		return
+	}
+
+	iterable := c.expr(stmt.Iterable, s)
+	key, value := unknownType, unknownType
+	switch iterable.kind {
+	case arrayKind:
+		key, value = integerType, iterable.elem
+	case mapKind:
+		key, value = stringType, iterable.elem
+	case unknownKind, unionKind, functionKind:
+	default:
+		c.errorf(tokenOf(stmt.Iterable), "cannot iterate over %s", objectType(iterable))
+	}
+
+	if stmt.Iterator != nil {
+		forScope.declare(stmt.Iterator.Value, &variable{t: key})
+	}
+	if stmt.Pattern != nil {
+		c.checkPattern(stmt.Pattern, value, forScope)
+	} else {
+		forScope.declare(stmt.Value.Value, &variable{t: value})
+	}
+	c.checkBlock(stmt.Body, forScope)
+}
+
+// importModule loads the type of an imported module.
+func (c *Checker) importModule(stmt *ast.ImportStatement) *Type {
+	if !strings.HasPrefix(stmt.Path, "./") && !strings.HasPrefix(stmt.Path, "../") {
+		module, ok := interpreter.StdlibModule(stmt.Path)
+		if !ok {
+			c.errorf(stmt.Token, "unknown stdlib module: %s", stmt.Path)
+			return unknownType
+		}
+		return c.typeOfObject(module, module.Scope)
+	}
+
+	absPath := filepath.Join(filepath.Dir(c.file), stmt.Path)
+	if !strings.HasSuffix(absPath, ".tsl") {
+		absPath += ".tsl"
+	}
+	if t, ok := c.modules[absPath]; ok {
+		return t
+	}
+	// Guard against import cycles while the module is checked
+	c.modules[absPath] = unknownType
+
+	content, err := os.ReadFile(absPath) //nolint:gosec // Checking the imported module
+	if err != nil {
+		c.errorf(stmt.Token, "failed to read module file '%s': %s", absPath, err)
+		return unknownType
+	}
+	p := parser.New(lexer.New(string(content)))
+	program := p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		messages := make([]string, 0, len(p.Errors()))
+		for _, e := range p.Errors() {
+			messages = append(messages, e.Message)
+		}
+		c.errorf(stmt.Token, "parse errors in module '%s': %s", absPath, strings.Join(messages, "; "))
+		return unknownType
+	}
+
+	moduleScope := c.checkFile(program, absPath)
+	name := strings.TrimSuffix(filepath.Base(stmt.Path), ".tsl")
+	t := &Type{kind: moduleKind, module: &moduleInfo{
+		name: name,
+		members: func(member string) (*Type, bool) {
+			v, ok := moduleScope.vars[member]
+			if !ok {
+				return nil, false
+			}
+			return v.t, true
+		},
+	}}
+	c.modules[absPath] = t
+	return t
+}
+
+// typeOfObject returns the type of a runtime value of the global environment
+// or a standard library module; env resolves the type annotations it holds.
+//
+//nolint:cyclop
+func (c *Checker) typeOfObject(obj interpreter.Object, env *interpreter.Environment) *Type {
+	switch obj := obj.(type) {
+	case *interpreter.Integer:
+		return integerType
+	case *interpreter.Float:
+		return floatType
+	case *interpreter.String:
+		return stringType
+	case *interpreter.Boolean:
+		return booleanType
+	case *interpreter.Null:
+		return nullType
+	case *interpreter.Array:
+		return arrayOf(unknownType)
+	case *interpreter.Map:
+		return mapOf(unknownType, unknownType)
+	case *interpreter.Function:
+		return &Type{kind: functionKind, fn: &signature{
+			params: obj.Parameters,
+			scope:  &scope{checker: c, env: obj.Env},
+			result: obj.ReturnType,
+		}}
+	case *interpreter.Builtin, *interpreter.BoundMethod:
+		return functionType
+	case *interpreter.Model:
+		return &Type{kind: modelKind, model: c.runtimeModel(obj, env)}
+	case *interpreter.ModelInstance:
+		return instanceOf(c.runtimeModel(obj.Model, env))
+	case *interpreter.Enum:
+		info := &enumInfo{name: obj.Name}
+		for name := range obj.Values {
+			info.values = append(info.values, name)
+		}
+		return &Type{kind: enumKind, enum: info}
+	case *interpreter.Interface:
+		return &Type{kind: interfaceKind, iface: c.runtimeInterface(obj, env)}
+	case *interpreter.Module:
+		module := obj
+		return &Type{kind: moduleKind, module: &moduleInfo{
+			name: module.Name,
+			members: func(member string) (*Type, bool) {
+				value, ok := module.Scope.Get(member)
+				if !ok {
+					return nil, false
+				}
+				return c.typeOfObject(value, module.Scope), true
+			},
+		}}
+	}
+	return unknownType
+}
+
+// runtimeModel describes a built-in model. Its instances may have native
+// methods, so access to members it does not declare is not reported.
+func (c *Checker) runtimeModel(model *interpreter.Model, env *interpreter.Environment) *modelInfo {
+	if info, ok := c.models[model]; ok {
+		return info
+	}
+
+	info := &modelInfo{
+		name:       model.Name,
+		fieldNames: model.FieldNames,
+		fields:     model.Fields,
+		methods:    make(map[string]*signature, len(model.Methods)),
+		statics:    make(map[string]bool, len(model.StaticMethods)),
+		scope:      &scope{checker: c, env: env},
+		open:       true,
+		native:     model.NativeConstructor != nil,
+	}
+	c.models[model] = info
+	if model.Base != nil {
+		info.base = c.runtimeModel(model.Base, env)
+	}
+	for name, method := range model.Methods {
+		info.methods[name] = &signature{params: method.Parameters, scope: &scope{checker: c, env: method.Env}, result: method.ReturnType}
+	}
+	for _, constructor := range model.Constructors {
+		info.constructors = append(info.constructors, &signature{params: constructor.Parameters, scope: &scope{checker: c, env: constructor.Env}})
+	}
+	for name := range model.StaticMethods {
+		info.statics[name] = true
+	}
+	return info
+}
+
+// collectAssigned adds the names of variables assigned anywhere in node to
+// names. Their types may change after the declaration, so the checker only
+// relies on the type annotations of such variables.
+//
+//nolint:gocyclo,cyclop
+func collectAssigned(node ast.Node, names map[string]bool) {
+	switch node := node.(type) {
+	case *ast.Program:
+		for _, stmt := range node.Statements {
+			collectAssigned(stmt, names)
+		}
+	case *ast.BlockStatement:
+		if node == nil {
+			return
+		}
+		for _, stmt := range node.Statements {
+			collectAssigned(stmt, names)
+		}
+	case *ast.ExpressionStatement:
+		collectAssigned(node.Expression, names)
+	case *ast.VarStatement:
+		if node.Value != nil {
+			collectAssigned(node.Value, names)
+		}
+	case *ast.ConstStatement:
+		collectAssigned(node.Value, names)
+	case *ast.MultiAssignStatement:
+		for _, target := range node.Targets {
+			if ident, ok := target.(*ast.Identifier); ok {
+				names[ident.Value] = true
+			}
+			collectAssigned(target, names)
+		}
+		for _, value := range node.Values {
+			collectAssigned(value, names)
+		}
+	case *ast.ReturnStatement:
+		if node.ReturnValue != nil {
+			collectAssigned(node.ReturnValue, names)
+		}
+	case *ast.WhileStatement:
+		collectAssigned(node.Condition, names)
+		collectAssigned(node.Body, names)
+	case *ast.ForStatement:
+		for _, child := range []ast.Node{node.Iterable, node.Init, node.Condition, node.Post, node.Body} {
+			collectAssigned(child, names)
+		}
+	case *ast.SwitchStatement:
+		collectAssigned(node.Value, names)
+		for _, clause := range node.Cases {
+			collectAssigned(clause.Body, names)
+		}
+		collectAssigned(node.Default, names)
+	case *ast.TryStatement:
+		collectAssigned(node.Body, names)
+		collectAssigned(node.Catch, names)
+	case *ast.InfixExpression:
+		switch node.Operator {
+		case "=", "+=", "-=", "*=", "/=", "%=":
+			if ident, ok := node.Left.(*ast.Identifier); ok {
+				names[ident.Value] = true
+			}
+		}
+		collectAssigned(node.Left, names)
+		collectAssigned(node.Right, names)
+	case *ast.PrefixExpression:
+		collectAssigned(node.Right, names)
+	case *ast.PropagateExpression:
+		collectAssigned(node.Value, names)
+	case *ast.MatchExpression:
+		collectAssigned(node.Value, names)
+		for _, arm := range node.Arms {
+			collectAssigned(arm.Guard, names)
+			collectAssigned(arm.Body, names)
+		}
+		collectAssigned(node.Default, names)
+	case *ast.IfExpression:
+		collectAssigned(node.Condition, names)
+		collectAssigned(node.Consequence, names)
+		collectAssigned(node.Alternative, names)
+	case *ast.FunctionLiteral:
+		collectAssigned(node.Body, names)
+	case *ast.CallExpression:
+		collectAssigned(node.Function, names)
+		for _, arg := range node.Arguments {
+			collectAssigned(arg, names)
+		}
+		for _, arg := range node.NamedArguments {
+			collectAssigned(arg.Value, names)
+		}
+	case *ast.InterpolatedString:
+		for _, part := range node.Parts {
+			collectAssigned(part, names)
+		}
+	case *ast.ArrayLiteral:
+		for _, element := range node.Elements {
+			collectAssigned(element, names)
+		}
+	case *ast.MapLiteral:
+		for key, value := range node.Pairs {
+			collectAssigned(key, names)
+			collectAssigned(value, names)
+		}
+	case *ast.IndexExpression:
+		collectAssigned(node.Left, names)
+		collectAssigned(node.Index, names)
+	case *ast.MemberExpression:
+		collectAssigned(node.Object, names)
+	case *ast.ModelLiteral:
+		for _, constructor := range node.Constructors {
+			collectAssigned(constructor, names)
+		}
+		for _, method := range node.Methods {
+			collectAssigned(method.Function, names)
+		}
+	}
+}
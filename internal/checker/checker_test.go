@@ -0,0 +1,586 @@
+package checker
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/mishankov/totalscript-lang/internal/interpreter"
+	"github.com/mishankov/totalscript-lang/internal/lexer"
+	"github.com/mishankov/totalscript-lang/internal/parser"
+	"github.com/mishankov/totalscript-lang/internal/stdlib"
+)
+
+//nolint:gochecknoglobals
+var registerMethods sync.Once
+
+// testCheck checks a program stored in file and returns the diagnostics
+// formatted as line:column: message.
+func testCheck(t *testing.T, input, file string) []string {
+	t.Helper()
+	registerMethods.Do(stdlib.RegisterMethods)
+
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+
+	globals := interpreter.NewEnvironment()
+	stdlib.RegisterBuiltins(globals)
+
+	var messages []string
+	for _, d := range Check(program, file, globals) {
+		msg := strings.TrimPrefix(d.Error(), file+":")
+		messages = append(messages, msg)
+	}
+	return messages
+}
+
+func expectDiagnostics(t *testing.T, input string, got, expected []string) {
+	t.Helper()
+	if len(got) != len(expected) {
+		t.Fatalf("for input:\n%s\nexpected %d diagnostics %q, got %d: %q", input, len(expected), expected, len(got), got)
+	}
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Errorf("for input:\n%s\ndiagnostic %d: expected %q, got %q", input, i, expected[i], got[i])
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		input    string
+		expected []string
+	}{
+		{
+			"valid program",
+			`const add = function(a: integer, b: float): float { return a + b }
+var total: float = add(1, 2)
+var names = ["a", "b"]
+for i, name in names { println(i, name.upper()) }
+var scores = {"math": 95}
+scores["math"] = scores["math"] + 1`,
+			nil,
+		},
+		{
+			"argument types",
+			`const add = function(a: integer, b: integer): integer { return a + b }
+add(1, "2")`,
+			[]string{`2:8: parameter 'b': type mismatch: expected integer, got string`},
+		},
+		{
+			"argument count",
+			`const greet = function(name: string) { println(name) }
+greet()`,
+			[]string{`2:6: wrong number of arguments: expected 1, got 0`},
+		},
+		{
+			"default, named and variadic parameters",
+			`const f = function(a: integer, b: string = 1, ...rest: float) { return rest.upper() }
+f(1)
+f()
+f(1, "x", 2.0, "y")
+f(b: "x")
+f(1, c: 2)
+const g = (a, b = 2) => a + b
+g(1, 2, 3)`,
+			[]string{
+				`1:44: parameter 'b': type mismatch: expected string, got integer`,
+				`1:77: undefined method 'upper' for type ARRAY`,
+				`3:2: wrong number of arguments: expected at least 1, got 0`,
+				`4:16: parameter 'rest': type mismatch: expected float, got string`,
+				`5:2: missing argument for parameter 'a'`,
+				`6:2: unknown parameter 'c'`,
+				`8:2: wrong number of arguments: expected 1 to 2, got 3`,
+			},
+		},
+		{
+			"array method callbacks",
+			`var nums = [1, 2]
+nums.map(function(x, scale = 10) { return x * scale })
+nums.map((...xs) => xs)
+nums.filter((x: integer) => x > 1)
+nums.map((x: string) => x)
+nums.each((x, y) => x)
+nums.reduce(0, (acc, x: string) => acc)`,
+			[]string{
+				`5:22: parameter 'x': type mismatch: expected string, got integer`,
+				`6:18: wrong number of arguments: expected 2, got 1`,
+				`7:33: parameter 'x': type mismatch: expected string, got integer`,
+			},
+		},
+		{
+			"return types",
+			`const f = function(): integer { return "x" }
+const g = function(): string? { return null }`,
+			[]string{`1:33: return type: type mismatch: expected integer, got string`},
+		},
+		{
+			"declarations and assignments",
+			`var count: integer = "x"
+var ratio: float = 1
+ratio = "y"
+var items: array<string> = [1, 2]`,
+			[]string{
+				`1:5: type mismatch: expected integer, got string`,
+				`3:7: type mismatch: expected float, got string`,
+				`4:5: type mismatch: expected array<string>, got array<integer>`,
+			},
+		},
+		{
+			"operators",
+			`var a = 1 + "x"
+var b = 1.5 + true
+var c = "a" - "b"`,
+			[]string{
+				`1:11: type mismatch: INTEGER + STRING`,
+				`2:13: type mismatch in float operation`,
+				`3:13: unknown operator: STRING - STRING`,
+			},
+		},
+		{
+			"undefined names",
+			`println(missing)
+var x: Missing = null
+var y = 1 is Nothing`,
+			[]string{
+				`1:9: identifier not found: missing`,
+				`2:8: unknown type: Missing`,
+				`3:14: undefined type: Nothing`,
+			},
+		},
+		{
+			"methods of values",
+			`var s = "abc"
+s.upper()
+s.shout()
+var numbers = [1, 2]
+numbers.length()
+var n = 5
+n.abs()`,
+			[]string{
+				`3:3: undefined method 'shout' for type STRING`,
+				`7:3: undefined method 'abs' for type INTEGER`,
+			},
+		},
+		{
+			"interpolated strings",
+			`var name = "Alice"
+var greeting: string = "Hello ${name}"
+println("${greeting.upper()} ${nobody}")`,
+			[]string{`3:32: identifier not found: nobody`},
+		},
+		{
+			"untyped reassigned variables are not inferred",
+			`var value = 1
+value = "text"
+println(value.upper())`,
+			nil,
+		},
+		{
+			"functions declared later",
+			`const first = function(): integer { return second() }
+const second = function(): integer { return 2 }`,
+			nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			expectDiagnostics(t, tt.input, testCheck(t, tt.input, "main.tsl"), tt.expected)
+		})
+	}
+}
+
+func TestCheckModelsAndEnums(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		input    string
+		expected []string
+	}{
+		{
+			"default constructor",
+			`const Point = model {
+  x: float
+  y: float
+}
+var p = Point(1, 2)
+var q = Point(1, "2")
+var r = Point(1)`,
+			[]string{
+				`6:18: field 'y': type mismatch: expected float, got string`,
+				`7:14: wrong number of arguments for Point: expected 2, got 1`,
+			},
+		},
+		{
+			"custom constructor",
+			`const Point = model {
+  x: float
+  y: float
+
+  constructor = function(v: float) {
+    return Point(v, v)
+  }
+}
+var p = Point(1)
+var q = Point("1")`,
+			[]string{`10:15: constructor parameter 'v': type mismatch: expected float, got string`},
+		},
+		{
+			"fields and methods",
+			`const User = model {
+  name: string
+
+  greet = function(greeting: string): string {
+    return greeting + " " + this.name
+  }
+}
+var user = User("Alice")
+println(user.name.upper(), user.greet("Hi"), user.age)
+user.greet(1)
+user.name = 5
+user.email = "a@b.c"`,
+			[]string{
+				`9:51: model User has no field or method 'age'`,
+				`10:12: parameter 'greeting': type mismatch: expected string, got integer`,
+				`11:11: field 'name': type mismatch: expected string, got integer`,
+				`12:6: model User has no field 'email'`,
+			},
+		},
+		{
+			"typed instances",
+			`const A = model { x: integer }
+const B = model { x: integer }
+var a: A = B(1)
+const takesA = function(value: A) { println(value.x) }
+takesA(A(1))`,
+			[]string{`3:5: type mismatch: expected A, got B`},
+		},
+		{
+			"enums",
+			`const Color = enum {
+  Red = "red"
+  Green = "green"
+}
+var c: Color = Color.Red
+println(c.value, Color.values(), Color.fromValue("red"))
+println(Color.Blue, c.name)`,
+			[]string{
+				`7:15: enum Color has no member 'Blue'`,
+				`7:23: enum value only has 'value' member`,
+			},
+		},
+		{
+			"match patterns",
+			`const Point = model {
+  x: float
+  y: float
+}
+const Color = enum {
+  Red = "red"
+  Green = "green"
+  Blue = "blue"
+}
+const describe = function(value: Point | Color): string {
+  return match value {
+    case Point(x, 0.0) { x.upper() }
+    case Point(x) { "x" }
+    case c: Shade { "shade" }
+    case _ { "other" }
+  }
+}
+const name = function(c: Color): string {
+  return match c {
+    case Color.Red { "R" }
+    case Color.Green if c.value == "green" { "G" }
+  }
+}
+println(x)`,
+			[]string{
+				`12:28: undefined method 'upper' for type FLOAT`,
+				`13:10: wrong number of fields in pattern for Point: expected 2, got 1`,
+				`14:13: unknown type: Shade`,
+				`19:10: match is not exhaustive: Color.Blue, Color.Green not handled`,
+				`24:9: identifier not found: x`,
+			},
+		},
+		{
+			"destructuring",
+			`const Point = model {
+  x: float
+  y: float
+}
+var [first, ...rest] = [1, 2, 3]
+var label: string = first
+println(rest.upper())
+var Point(x, y) = Point(1, 2)
+var Point(z) = Point(1, 2)
+for i, Point(px, py) in [Point(1, 2)] { println(px.upper()) }
+var a = 1
+var b = 2
+a, b = b, a
+a, b = 1`,
+			[]string{
+				`6:5: type mismatch: expected string, got integer`,
+				`7:14: undefined method 'upper' for type ARRAY`,
+				`9:5: wrong number of fields in pattern for Point: expected 2, got 1`,
+				`10:52: undefined method 'upper' for type FLOAT`,
+				`14:6: wrong number of values in assignment: expected 2, got 1`,
+			},
+		},
+		{
+			"named fields",
+			`const Point = model {
+  x: integer
+  y: integer
+}
+var p = Point(y: 2, x: 1)
+Point(x: 1)
+Point(1, 2, y: 3)
+Point(z: 1)
+Point(x: "s", y: 2)`,
+			[]string{
+				`6:6: missing field 'y' for Point`,
+				`7:6: field 'y' given twice`,
+				`8:6: model Point has no field 'z'`,
+				`9:10: field 'x': type mismatch: expected integer, got string`,
+			},
+		},
+		{
+			"interfaces",
+			`const Shape = interface {
+  name: string
+  area: function(): float
+}
+const Square = model implements Shape {
+  name: string
+  side: float
+  area = function(): float { return this.side * this.side }
+}
+const Bad = model implements Shape { name: integer }
+const Point = model { x: float }
+var s: Shape = Square("a", 1)
+println(s.area().upper())
+var p: Shape = Point(1)
+var q: Point | string = "q"
+if q is Shape { println(q.name) }`,
+			[]string{
+				`10:30: model does not implement Shape: field 'name' has type integer, expected string`,
+				`13:18: undefined method 'upper' for type FLOAT`,
+				`14:5: type mismatch: expected Shape, got Point (missing field 'name')`,
+			},
+		},
+		{
+			"inheritance",
+			`const Entity = model {
+  owner: string
+  describe = function(): string { return this.owner }
+}
+const Post = model extends Entity {
+  title: string
+  owner: integer
+  f = function() { return super.describe().upper() + super.missing() }
+  constructor = function(title: string) {
+    var post = super(1)
+    post.title = title
+    return post
+  }
+}
+var entity: Entity = Post("Hi")
+var post: Post = Entity("ann")
+println(super(1))`,
+			[]string{
+				`7:3: field 'owner' is declared in Entity with type string`,
+				`8:60: model Entity has no field or method 'missing'`,
+				`10:22: field 'owner': type mismatch: expected string, got integer`,
+				`16:5: type mismatch: expected Post, got Entity`,
+				`17:9: 'super' can only be used inside a model extending another model`,
+			},
+		},
+		{
+			"built-in models",
+			`var err = Error("failed")
+println(err.message)
+var bad = Error()`,
+			[]string{`3:16: wrong number of arguments for Error: expected 1, got 0`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			expectDiagnostics(t, tt.input, testCheck(t, tt.input, "main.tsl"), tt.expected)
+		})
+	}
+}
+
+func TestCheckNarrowing(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		input    string
+		expected []string
+	}{
+		{
+			"unchecked union",
+			`const divide = function(a: float, b: float): float | Error {
+  if b == 0 { return Error("division by zero") }
+  return a / b
+}
+var result = divide(1, 2)
+println(result.message)`,
+			[]string{"6:16: undefined method 'message' for type FLOAT (result is float | Error here; check it with `is` first)"},
+		},
+		{
+			"narrowed by is",
+			`const divide = function(a: float, b: float): float | Error {
+  if b == 0 { return Error("division by zero") }
+  return a / b
+}
+var result = divide(1, 2)
+if result is Error {
+  println(result.message)
+} else {
+  var value: float = result
+}`,
+			nil,
+		},
+		{
+			"narrowed by guard",
+			`const half = function(input: integer | Error): float {
+  if input is Error {
+    return 0.0
+  }
+  return input / 2
+}`,
+			nil,
+		},
+		{
+			"optional values",
+			`const find = function(name: string): string? { return null }
+var name = find("x")
+println(name.upper())
+if name != null {
+  println(name.upper())
+}`,
+			[]string{"3:14: undefined method 'upper' for type NULL (name is string? here; check it with `is` first)"},
+		},
+		{
+			"union arguments",
+			`const parse = function(text: string): integer | Error { return 1 }
+const double = function(n: integer): integer { return n * 2 }
+var n = parse("1")
+double(n)`,
+			[]string{"4:8: parameter 'n': type mismatch: expected integer, got integer | Error (n is integer | Error here; check it with `is` first)"},
+		},
+		{
+			"null safety",
+			`const Address = model { city: string }
+const User = model {
+  name: string
+  address: Address?
+}
+const find = function(name: string): User? { return null }
+var user = find("Ann")
+var name: string = user?.name ?? "anonymous"
+var city: string = user?.address?.city ?? "unknown"
+var maybe: string = user?.name
+println(user?.address.city, user?.name.shout())`,
+			[]string{
+				`10:5: type mismatch: expected string, got string?`,
+				`11:23: undefined method 'city' for type NULL`,
+				`11:40: undefined method 'shout' for type STRING`,
+			},
+		},
+		{
+			"error propagation",
+			`const parse = function(text: string): integer | Error { return 1 }
+const double = function(text: string): integer | Error {
+  var n = parse(text)?
+  var m: integer = n
+  return n * 2
+}
+const broken = function(text: string): integer {
+  return parse(text)? + 1
+}`,
+			[]string{`8:21: return type: type mismatch: expected integer, got Error`},
+		},
+		{
+			"try and catch",
+			`try {
+  var n = 1 + 2
+} catch e {
+  println(e.message)
+  var code: integer = e
+}
+try { println("x") } catch { println(e) }`,
+			[]string{
+				`5:7: type mismatch: expected integer, got Error`,
+				`7:38: identifier not found: e`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			expectDiagnostics(t, tt.input, testCheck(t, tt.input, "main.tsl"), tt.expected)
+		})
+	}
+}
+
+func TestCheckImports(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	lib := `const Point = model {
+  x: float
+  y: float
+}
+
+const add = function(a: integer, b: integer): integer {
+  return a + b
+}
+
+var broken: integer = "x"
+`
+	if err := os.WriteFile(filepath.Join(dir, "lib.tsl"), []byte(lib), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	input := `import ./lib
+import math
+
+var p: lib.Point = lib.Point(1, 2)
+println(p.x, lib.add(1, 2), math.sqrt(4), math.PI)
+lib.add("1", 2)
+lib.subtract(1, 2)
+math.cube(2)
+var q: lib.Line = null`
+
+	main := filepath.Join(dir, "main.tsl")
+	expected := []string{
+		`6:9: parameter 'a': type mismatch: expected integer, got string`,
+		`7:5: module 'lib' has no member 'subtract'`,
+		`8:6: module 'math' has no member 'cube'`,
+		`9:8: type Line not found in module lib`,
+		// Diagnostics of imported modules name their file
+		filepath.Join(dir, "lib.tsl") + `:10:5: type mismatch: expected integer, got string`,
+	}
+	expectDiagnostics(t, input, testCheck(t, input, main), expected)
+}
+
+func TestDiagnosticError(t *testing.T) {
+	t.Parallel()
+	d := &Diagnostic{File: "app.tsl", Line: 3, Column: 7, Message: "identifier not found: x"}
+	if got, want := d.Error(), "app.tsl:3:7: identifier not found: x"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
@@ -0,0 +1,1018 @@
+package checker
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/mishankov/totalscript-lang/internal/ast"
+	"github.com/mishankov/totalscript-lang/internal/interpreter"
+	"github.com/mishankov/totalscript-lang/internal/token"
+)
+
+// expr checks an expression and returns its type.
+//
+//nolint:gocyclo,cyclop
+func (c *Checker) expr(e ast.Expression, s *scope) *Type {
+	switch e := e.(type) {
+	case *ast.IntegerLiteral:
+		return integerType
+	case *ast.FloatLiteral:
+		return floatType
+	case *ast.StringLiteral:
+		return stringType
+	case *ast.InterpolatedString:
+		for _, part := range e.Parts {
+			c.expr(part, s)
+		}
+		return stringType
+	case *ast.BooleanLiteral:
+		return booleanType
+	case *ast.NullLiteral:
+		return nullType
+
+	case *ast.Identifier:
+		t, ok := s.lookup(e.Value)
+		if !ok {
+			c.errorf(e.Token, "identifier not found: %s", e.Value)
+			return unknownType
+		}
+		return t
+
+	case *ast.ThisExpression:
+		t, ok := s.lookup("this")
+		if !ok {
+			c.errorf(e.Token, "'this' can only be used inside a model method")
+			return unknownType
+		}
+		return t
+
+	case *ast.SuperExpression:
+		if _, ok := c.superModel(e, s); ok {
+			c.errorf(e.Token, "'super' must be called or followed by a method name")
+		}
+		return unknownType
+
+	case *ast.ArrayLiteral:
+		if len(e.Elements) == 0 {
+			return arrayOf(unknownType)
+		}
+		elements := make([]*Type, 0, len(e.Elements))
+		for _, element := range e.Elements {
+			elements = append(elements, c.expr(element, s))
+		}
+		return arrayOf(unionOf(elements...))
+
+	case *ast.MapLiteral:
+		if len(e.Pairs) == 0 {
+			return mapOf(stringType, unknownType)
+		}
+		values := make([]*Type, 0, len(e.Pairs))
+		for key, value := range e.Pairs {
+			if keyType := c.expr(key, s); definite(keyType) && !keyType.is(typeString) {
+				c.errorf(tokenOf(key), "map key must be string, got %s", objectType(keyType))
+			}
+			values = append(values, c.expr(value, s))
+		}
+		return mapOf(stringType, unionOf(values...))
+
+	case *ast.PrefixExpression:
+		right := c.expr(e.Right, s)
+		if e.Operator == "!" {
+			return booleanType
+		}
+		if right.isNumeric() || !definite(right) {
+			return right
+		}
+		c.errorf(e.Token, "unknown operator: %s%s", e.Operator, objectType(right))
+		return unknownType
+
+	case *ast.InfixExpression:
+		switch e.Operator {
+		case "=", "+=", "-=", "*=", "/=", "%=":
+			return c.checkAssignment(e, s)
+		case "is":
+			return c.checkIs(e, s)
+		case "??":
+			left := c.expr(e.Left, s)
+			return unionOf(left.withoutNull(), c.expr(e.Right, s))
+		}
+		left := c.expr(e.Left, s)
+		right := c.expr(e.Right, s)
+		return c.binary(e.Token, e.Operator, left, right)
+
+	case *ast.PropagateExpression:
+		return c.checkPropagate(e, s)
+
+	case *ast.MatchExpression:
+		return c.checkMatch(e, s)
+
+	case *ast.IfExpression:
+		c.expr(e.Condition, s)
+		whenTrue, whenFalse := interpreter.NarrowCondition(e.Condition, s.typeOf)
+		c.checkBlock(e.Consequence, s.narrow(whenTrue))
+		if e.Alternative != nil {
+			c.checkBlock(e.Alternative, s.narrow(whenFalse))
+		}
+		return unknownType
+
+	case *ast.FunctionLiteral:
+		return c.checkFunction(e, s, nil)
+
+	case *ast.CallExpression, *ast.IndexExpression, *ast.MemberExpression:
+		t, skipped := c.chain(e, s)
+		if skipped {
+			return unionOf(t, nullType)
+		}
+		return t
+
+	case *ast.RangeExpression:
+		for _, bound := range []ast.Expression{e.Start, e.End} {
+			if bound == nil {
+				continue
+			}
+			if t := c.expr(bound, s); definite(t) && !t.is(typeInteger) {
+				what := "start"
+				if bound == e.End {
+					what = "end"
+				}
+				c.errorf(tokenOf(bound), "range %s must be integer", what)
+			}
+		}
+		return arrayOf(integerType)
+
+	case *ast.ModelLiteral:
+		return c.checkModel(e, s)
+
+	case *ast.EnumLiteral:
+		for _, value := range e.Values {
+			c.expr(value.Value, s)
+		}
+		return c.enumType(e)
+
+	case *ast.InterfaceLiteral:
+		return c.checkInterface(e, s)
+
+	case *ast.DbFindExpression:
+		c.expr(e.Model, s)
+		for _, cond := range e.Conditions {
+			// Fields refer to the queried model through this
+			c.expr(cond.Value, s)
+		}
+		if e.Modifiers != nil {
+			if e.Modifiers.Limit != nil {
+				c.expr(e.Modifiers.Limit, s)
+			}
+			if e.Modifiers.Offset != nil {
+				c.expr(e.Modifiers.Offset, s)
+			}
+		}
+		return unknownType
+	}
+
+	return unknownType
+}
+
+// binary returns the type of a binary operation, reporting operand types the
+// interpreter rejects.
+//
+//nolint:cyclop
+func (c *Checker) binary(tok token.Token, operator string, left, right *Type) *Type {
+	switch operator {
+	case "==", "!=", "<", ">", "<=", ">=", "&&", "||":
+		if !definite(left) || !definite(right) {
+			return booleanType
+		}
+	default:
+		if !definite(left) || !definite(right) {
+			return unknownType
+		}
+	}
+
+	switch {
+	case left.is(typeInteger) && right.is(typeInteger):
+		switch operator {
+		case "+", "-", "*", "//", "%", "**":
+			return integerType
+		case "/":
+			return floatType
+		case "<", ">", "<=", ">=", "==", "!=":
+			return booleanType
+		}
+	case left.is(typeFloat) || right.is(typeFloat):
+		if !left.isNumeric() || !right.isNumeric() {
+			c.errorf(tok, "type mismatch in float operation")
+			return unknownType
+		}
+		switch operator {
+		case "+", "-", "*", "/", "**":
+			return floatType
+		case "<", ">", "<=", ">=", "==", "!=":
+			return booleanType
+		}
+	case left.is(typeString) && right.is(typeString):
+		switch operator {
+		case "+":
+			return stringType
+		case "==", "!=":
+			return booleanType
+		}
+	case operator == "==" || operator == "!=" || operator == "&&" || operator == "||":
+		return booleanType
+	case objectType(left) != objectType(right):
+		c.errorf(tok, "type mismatch: %s %s %s", objectType(left), operator, objectType(right))
+		return unknownType
+	}
+
+	c.errorf(tok, "unknown operator: %s %s %s", objectType(left), operator, objectType(right))
+	return unknownType
+}
+
+// checkIs checks `x is T`, where T is a type name or a model or enum value.
+func (c *Checker) checkIs(e *ast.InfixExpression, s *scope) *Type {
+	c.expr(e.Left, s)
+
+	switch right := e.Right.(type) {
+	case *ast.NullLiteral, *ast.BooleanLiteral:
+		return booleanType
+	case *ast.Identifier:
+		switch right.Value {
+		case typeInteger, typeFloat, typeString, typeBoolean, typeNull, typeArray, typeMap, typeFunction:
+			return booleanType
+		}
+		t, ok := s.lookup(right.Value)
+		if !ok {
+			c.errorf(right.Token, "undefined type: %s", right.Value)
+			return booleanType
+		}
+		c.checkIsType(right.Token, t)
+	default:
+		c.checkIsType(tokenOf(e.Right), c.expr(e.Right, s))
+	}
+	return booleanType
+}
+
+func (c *Checker) checkIsType(tok token.Token, t *Type) {
+	switch t.kind {
+	case modelKind, enumKind, interfaceKind, unknownKind, unionKind:
+		return
+	}
+	c.errorf(tok, "'is' operator requires a type name or type value on the right side")
+}
+
+// checkAssignment checks an assignment to a variable, index or field.
+//
+//nolint:cyclop
+func (c *Checker) checkAssignment(e *ast.InfixExpression, s *scope) *Type {
+	value := c.expr(e.Right, s)
+	compound := e.Operator != "="
+	op := e.Operator[:len(e.Operator)-1]
+
+	switch left := e.Left.(type) {
+	case *ast.Identifier:
+		current, ok := s.lookup(left.Value)
+		if !ok && compound {
+			c.errorf(left.Token, "identifier not found: %s", left.Value)
+			return unknownType
+		}
+		if !ok {
+			// Assigning an undeclared variable declares it
+			s.declare(left.Value, &variable{t: unknownType})
+			return value
+		}
+		if compound {
+			value = c.binary(e.Token, op, current, value)
+		}
+		if v, ok := s.variable(left.Value); ok && v.declared && !assignable(value, v.t) {
+			c.errorf(e.Token, "%s", mismatch(v.t, value, e.Right))
+		}
+		// What was known about the variable's type no longer holds
+		for scope := s; scope != nil; scope = scope.parent {
+			delete(scope.narrowed, left.Value)
+			if _, ok := scope.vars[left.Value]; ok {
+				break
+			}
+		}
+
+	case *ast.IndexExpression:
+		current := c.expr(left, s)
+		if compound {
+			value = c.binary(e.Token, op, current, value)
+		}
+
+	case *ast.MemberExpression:
+		object := c.expr(left.Object, s)
+		name := left.Member.Value
+		switch {
+		case object.kind == instanceKind:
+			info := object.model
+			fieldType, exists := info.fields[name]
+			if !exists {
+				if !info.open {
+					c.errorf(left.Member.Token, "model %s has no field '%s'", info.name, name)
+				}
+				return value
+			}
+			expected := c.resolveQuiet(fieldType, info.scope)
+			if compound {
+				value = c.binary(e.Token, op, expected, value)
+			}
+			if fieldType != nil && !assignable(value, expected) {
+				c.errorf(e.Token, "field '%s': %s", name, mismatch(expected, value, e.Right))
+			}
+		case object.kind == implementerKind:
+			fieldType := object.iface.fields[name]
+			if fieldType == nil {
+				return value
+			}
+			expected := c.resolveQuiet(fieldType, object.iface.scope)
+			if compound {
+				value = c.binary(e.Token, op, expected, value)
+			}
+			if !assignable(value, expected) {
+				c.errorf(e.Token, "field '%s': %s", name, mismatch(expected, value, e.Right))
+			}
+		case definite(object):
+			c.errorf(left.Member.Token, "member assignment only supported for model instances, got %s", objectType(object))
+		}
+	}
+
+	return value
+}
+
+// checkPropagate checks value?, which returns Error values from the enclosing
+// function; the rest of the union is the result.
+func (c *Checker) checkPropagate(e *ast.PropagateExpression, s *scope) *Type {
+	value := c.expr(e.Value, s)
+	errorType := c.errorType()
+
+	if len(c.returns) > 0 {
+		if expected := c.returns[len(c.returns)-1]; expected != nil && !assignable(errorType, expected) {
+			c.errorf(e.Token, "return type: %s", mismatch(expected, errorType, nil))
+		}
+	}
+
+	return value.without(func(alt *Type) bool {
+		return alt.kind == instanceKind && sameModel(alt.model, errorType.model)
+	})
+}
+
+// errorType returns the type of instances of the built-in Error model.
+func (c *Checker) errorType() *Type {
+	return instanceOf(c.runtimeModel(interpreter.ErrorModel, c.globals))
+}
+
+// checkFunction checks the body of a function literal; this is the instance
+// type for model methods and constructors.
+func (c *Checker) checkFunction(lit *ast.FunctionLiteral, s *scope, this *Type) *Type {
+	body := s.child()
+	if this != nil {
+		body.declare("this", &variable{t: this})
+	}
+	for _, param := range lit.Parameters {
+		c.declareParameter(param, body)
+	}
+
+	var expected *Type
+	if lit.ReturnType != nil {
+		expected = c.resolveType(lit.ReturnType, s)
+	}
+	c.returns = append(c.returns, expected)
+	defer func() { c.returns = c.returns[:len(c.returns)-1] }()
+
+	c.hoist(lit.Body.Statements, body)
+	c.checkBlock(lit.Body, body)
+	return c.functionType(lit, s)
+}
+
+// declareParameter declares a function parameter in the body scope after
+// checking its default value, which may refer to earlier parameters.
+// Variadic parameters are arrays of the annotated type.
+func (c *Checker) declareParameter(param *ast.Parameter, body *scope) {
+	t := unknownType
+	if param.Type != nil {
+		t = c.resolveType(param.Type, body)
+	}
+	if param.Default != nil {
+		value := c.expr(param.Default, body)
+		if param.Type != nil && !assignable(value, t) {
+			c.errorf(tokenOf(param.Default), "parameter '%s': %s", param.Name.Value, mismatch(t, value, param.Default))
+		}
+	}
+
+	if param.Variadic {
+		body.declare(param.Name.Value, &variable{t: arrayOf(t), declared: param.Type != nil})
+		return
+	}
+	body.declare(param.Name.Value, &variable{t: t, declared: param.Type != nil})
+}
+
+// functionType returns the type of a function literal.
+func (c *Checker) functionType(lit *ast.FunctionLiteral, s *scope) *Type {
+	if t, ok := c.literals[lit]; ok {
+		return t
+	}
+	t := &Type{kind: functionKind, fn: &signature{params: lit.Parameters, scope: s, result: lit.ReturnType}}
+	c.literals[lit] = t
+	return t
+}
+
+// checkModel checks the base, field types, constructors and methods of a
+// model.
+func (c *Checker) checkModel(lit *ast.ModelLiteral, s *scope) *Type {
+	t := c.modelType(lit, s)
+	body := s
+	if lit.Extends != nil {
+		body = c.checkExtends(lit, t.model, s)
+	}
+	for _, field := range lit.Fields {
+		c.resolveType(field.Type, s)
+	}
+	for _, typeExpr := range lit.Implements {
+		c.checkImplements(t.model, typeExpr, s)
+	}
+	this := instanceOf(t.model)
+	for _, constructor := range lit.Constructors {
+		c.checkFunction(constructor, body, this)
+	}
+	for _, method := range lit.Methods {
+		c.checkFunction(method.Function, body, this)
+	}
+	return t
+}
+
+// modelType returns the type of a model literal.
+func (c *Checker) modelType(lit *ast.ModelLiteral, s *scope) *Type {
+	if t, ok := c.literals[lit]; ok {
+		return t
+	}
+	info := &modelInfo{
+		fieldNames: make([]string, 0, len(lit.Fields)),
+		fields:     make(map[string]*ast.TypeExpression, len(lit.Fields)),
+		methods:    make(map[string]*signature, len(lit.Methods)),
+		scope:      s,
+	}
+	if lit.Extends != nil {
+		inherit(info, c.resolveQuiet(lit.Extends, s))
+	}
+	for _, field := range lit.Fields {
+		if _, inherited := info.fields[field.Name.Value]; !inherited {
+			info.fieldNames = append(info.fieldNames, field.Name.Value)
+		}
+		info.fields[field.Name.Value] = field.Type
+	}
+	for _, constructor := range lit.Constructors {
+		info.constructors = append(info.constructors, &signature{params: constructor.Parameters, scope: s})
+	}
+	for _, method := range lit.Methods {
+		info.methods[method.Name.Value] = &signature{params: method.Function.Parameters, scope: s, result: method.Function.ReturnType}
+	}
+	t := &Type{kind: modelKind, model: info}
+	c.literals[lit] = t
+	return t
+}
+
+// enumType returns the type of an enum literal.
+func (c *Checker) enumType(lit *ast.EnumLiteral) *Type {
+	if t, ok := c.literals[lit]; ok {
+		return t
+	}
+	info := &enumInfo{}
+	for _, value := range lit.Values {
+		info.values = append(info.values, value.Name.Value)
+	}
+	t := &Type{kind: enumKind, enum: info}
+	c.literals[lit] = t
+	return t
+}
+
+// chain checks a call, index or member access like evalChain, reporting
+// whether an optional link (?.) may short-circuit it to null.
+func (c *Checker) chain(e ast.Expression, s *scope) (*Type, bool) {
+	switch e := e.(type) {
+	case *ast.CallExpression:
+		if _, ok := e.Function.(*ast.SuperExpression); ok {
+			return c.checkSuperCall(e, s), false
+		}
+		callee, skipped := c.chainTarget(e.Function, e.Optional, s)
+		return c.checkCall(e, callee, s), skipped
+	case *ast.IndexExpression:
+		left, skipped := c.chainTarget(e.Left, e.Optional, s)
+		return c.checkIndex(e, left, s), skipped
+	case *ast.MemberExpression:
+		if super, ok := e.Object.(*ast.SuperExpression); ok {
+			base, ok := c.superModel(super, s)
+			if !ok {
+				return unknownType, false
+			}
+			return c.checkMember(e, instanceOf(base)), false
+		}
+		object, skipped := c.chainTarget(e.Object, e.Optional, s)
+		return c.checkMember(e, object), skipped
+	}
+	return c.expr(e, s), false
+}
+
+// chainTarget checks the target of a chain link. Optional links only use
+// targets that are not null.
+func (c *Checker) chainTarget(e ast.Expression, optional bool, s *scope) (*Type, bool) {
+	t, skipped := c.chain(e, s)
+	if optional {
+		return t.withoutNull(), true
+	}
+	return t, skipped
+}
+
+// checkCall checks the arguments of a call of callee and returns its result type.
+func (c *Checker) checkCall(call *ast.CallExpression, callee *Type, s *scope) *Type {
+	args := make([]argument, 0, len(call.Arguments)+len(call.NamedArguments))
+	for _, arg := range call.Arguments {
+		args = append(args, argument{expr: arg, t: c.expr(arg, s)})
+	}
+	for _, arg := range call.NamedArguments {
+		args = append(args, argument{name: arg.Name.Value, expr: arg.Value, t: c.expr(arg.Value, s)})
+	}
+
+	switch callee.kind {
+	case functionKind:
+		if callee.fn == nil {
+			return unknownType
+		}
+		if callee.fn.callback != nil {
+			c.checkCallback(callee.fn, args)
+			return c.result(callee.fn)
+		}
+		bound, msg := bindArguments(callee.fn.params, args)
+		if msg != "" {
+			c.errorf(call.Token, "%s", msg)
+			return c.result(callee.fn)
+		}
+		c.checkArguments(callee.fn, bound, "parameter")
+		return c.result(callee.fn)
+
+	case modelKind:
+		return c.checkConstruction(call, callee.model, args)
+
+	case unknownKind, unionKind:
+		return unknownType
+	}
+
+	c.errorf(call.Token, "not a function: %s", objectType(callee))
+	return unknownType
+}
+
+// checkCallback checks the function given to a built-in method like a call
+// with the arguments the method passes to it.
+func (c *Checker) checkCallback(method *signature, args []argument) {
+	if len(args) == 0 {
+		return
+	}
+	fn := args[len(args)-1]
+	if fn.t.kind != functionKind || fn.t.fn == nil {
+		return
+	}
+
+	passed := make([]argument, len(method.callback))
+	for i, t := range method.callback {
+		passed[i] = argument{expr: fn.expr, t: t}
+	}
+	bound, msg := bindArguments(fn.t.fn.params, passed)
+	if msg != "" {
+		c.errorf(tokenOf(fn.expr), "%s", msg)
+		return
+	}
+	c.checkArguments(fn.t.fn, bound, "parameter")
+}
+
+// result returns the result type of a signature.
+func (c *Checker) result(sig *signature) *Type {
+	if sig.returns != nil {
+		return sig.returns
+	}
+	return c.resolveQuiet(sig.result, sig.scope)
+}
+
+// argument is a positional or named argument of a call.
+type argument struct {
+	name string // parameter or field name of a named argument
+	expr ast.Expression
+	t    *Type
+}
+
+// boundArgument is an argument given for a parameter.
+type boundArgument struct {
+	param *ast.Parameter
+	argument
+}
+
+// bindArguments matches the arguments of a call to parameters like the
+// interpreter does: positional arguments in order, the rest of them to a
+// variadic parameter, then named arguments. Parameters with defaults may be
+// left out. It returns the runtime error message if the arguments do not fit.
+//
+//nolint:cyclop
+func bindArguments(params []*ast.Parameter, args []argument) ([]boundArgument, string) {
+	positional := len(params)
+	if positional > 0 && params[positional-1].Variadic {
+		positional--
+	}
+
+	var bound []boundArgument
+	given := make(map[*ast.Parameter]bool)
+	count, named := 0, false
+	for _, arg := range args {
+		if arg.name != "" {
+			named = true
+			i := slices.IndexFunc(params, func(param *ast.Parameter) bool {
+				return param.Name.Value == arg.name && !param.Variadic
+			})
+			if i < 0 {
+				return nil, fmt.Sprintf("unknown parameter '%s'", arg.name)
+			}
+			if given[params[i]] {
+				return nil, fmt.Sprintf("parameter '%s' given twice", arg.name)
+			}
+			given[params[i]] = true
+			bound = append(bound, boundArgument{param: params[i], argument: arg})
+			continue
+		}
+
+		count++
+		switch {
+		case count <= positional:
+			given[params[count-1]] = true
+			bound = append(bound, boundArgument{param: params[count-1], argument: arg})
+		case positional < len(params):
+			bound = append(bound, boundArgument{param: params[positional], argument: arg})
+		}
+	}
+
+	if count > positional && positional == len(params) {
+		return nil, argumentCount(params, count)
+	}
+	for _, param := range params[:positional] {
+		if !given[param] && param.Default == nil {
+			if !named {
+				return nil, argumentCount(params, count)
+			}
+			return nil, fmt.Sprintf("missing argument for parameter '%s'", param.Name.Value)
+		}
+	}
+	return bound, ""
+}
+
+// argumentCount returns the message for a call with too few or too many
+// arguments, like the interpreter's.
+func argumentCount(params []*ast.Parameter, got int) string {
+	required := 0
+	for _, param := range params {
+		if param.Variadic {
+			return fmt.Sprintf("wrong number of arguments: expected at least %d, got %d", required, got)
+		}
+		if param.Default == nil {
+			required++
+		}
+	}
+	if required == len(params) {
+		return fmt.Sprintf("wrong number of arguments: expected %d, got %d", required, got)
+	}
+	return fmt.Sprintf("wrong number of arguments: expected %d to %d, got %d", required, len(params), got)
+}
+
+// checkArguments checks argument types against annotated parameters. The
+// type of a variadic parameter applies to each of its arguments.
+func (c *Checker) checkArguments(sig *signature, bound []boundArgument, what string) {
+	for _, arg := range bound {
+		if arg.param.Type == nil {
+			continue
+		}
+		expected := c.resolveQuiet(arg.param.Type, sig.scope)
+		if !assignable(arg.t, expected) {
+			c.errorf(tokenOf(arg.expr), "%s '%s': %s", what, arg.param.Name.Value, mismatch(expected, arg.t, arg.expr))
+		}
+	}
+}
+
+// checkConstruction checks a model constructor call: the first custom
+// constructor accepting the arguments, or the default one taking the fields
+// in order or by name.
+func (c *Checker) checkConstruction(call *ast.CallExpression, info *modelInfo, args []argument) *Type {
+	for _, constructor := range info.constructors {
+		if bound, msg := bindArguments(constructor.params, args); msg == "" {
+			c.checkArguments(constructor, bound, "constructor parameter")
+			return instanceOf(info)
+		}
+	}
+	if info.native {
+		return instanceOf(info)
+	}
+
+	fields, msg := bindFields(info, args)
+	if msg != "" {
+		c.errorf(call.Token, "%s", msg)
+		return instanceOf(info)
+	}
+	for i, name := range info.fieldNames {
+		fieldType := info.fields[name]
+		if fieldType == nil {
+			continue
+		}
+		expected := c.resolveQuiet(fieldType, info.scope)
+		if !assignable(fields[i].t, expected) {
+			c.errorf(tokenOf(fields[i].expr), "field '%s': %s", name, mismatch(expected, fields[i].t, fields[i].expr))
+		}
+	}
+	return instanceOf(info)
+}
+
+// bindFields orders the arguments of a default constructor call by field.
+func bindFields(info *modelInfo, args []argument) ([]argument, string) {
+	fields := make([]argument, len(info.fieldNames))
+	given := make([]bool, len(info.fieldNames))
+	count := 0
+	for _, arg := range args {
+		if arg.name == "" {
+			count++
+			if count <= len(fields) {
+				fields[count-1], given[count-1] = arg, true
+			}
+			continue
+		}
+		i := slices.Index(info.fieldNames, arg.name)
+		if i < 0 {
+			return nil, fmt.Sprintf("model %s has no field '%s'", info.name, arg.name)
+		}
+		if given[i] {
+			return nil, fmt.Sprintf("field '%s' given twice", arg.name)
+		}
+		fields[i], given[i] = arg, true
+	}
+
+	if count > len(fields) || (count < len(fields) && count == len(args)) {
+		return nil, fmt.Sprintf("wrong number of arguments for %s: expected %d, got %d", info.name, len(fields), count)
+	}
+	for i, ok := range given {
+		if !ok {
+			return nil, fmt.Sprintf("missing field '%s' for %s", info.fieldNames[i], info.name)
+		}
+	}
+	return fields, ""
+}
+
+// checkIndex checks an index or slice expression of left.
+func (c *Checker) checkIndex(e *ast.IndexExpression, left *Type, s *scope) *Type {
+	if rangeExpr, ok := e.Index.(*ast.RangeExpression); ok {
+		for _, bound := range []ast.Expression{rangeExpr.Start, rangeExpr.End} {
+			if bound != nil {
+				c.expr(bound, s)
+			}
+		}
+		if definite(left) && left.kind != arrayKind {
+			c.errorf(e.Token, "slice operation only supported for arrays, got %s", objectType(left))
+			return unknownType
+		}
+		return left
+	}
+
+	index := c.expr(e.Index, s)
+	switch {
+	case !definite(left):
+		return unknownType
+	case left.kind == arrayKind && (index.is(typeInteger) || !definite(index)):
+		return left.elem
+	case left.kind == mapKind && (index.is(typeString) || !definite(index)):
+		return left.elem
+	}
+	c.errorf(e.Token, "index operator not supported: %s", objectType(left))
+	return unknownType
+}
+
+// checkMember checks access to a field, method or member of object.
+func (c *Checker) checkMember(e *ast.MemberExpression, object *Type) *Type {
+	t, msg := c.memberType(object, e.Member.Value)
+	if msg != "" {
+		if ident, ok := e.Object.(*ast.Identifier); ok && object.kind == unionKind {
+			msg += fmt.Sprintf(" (%s is %s here; check it with `is` first)", ident.Value, object)
+		}
+		c.errorf(e.Member.Token, "%s", msg)
+	}
+	return t
+}
+
+// memberType returns the type of a member of a value of type object, or the
+// error accessing it would cause.
+//
+//nolint:cyclop
+func (c *Checker) memberType(object *Type, name string) (*Type, string) {
+	switch object.kind {
+	case unknownKind, functionKind:
+		return unknownType, ""
+
+	case unionKind:
+		members := make([]*Type, 0, len(object.members))
+		for _, member := range object.members {
+			t, msg := c.memberType(member, name)
+			if msg != "" {
+				return unknownType, msg
+			}
+			members = append(members, t)
+		}
+		return unionOf(members...), ""
+
+	case enumKind:
+		value := &Type{kind: enumValueKind, enum: object.enum}
+		switch name {
+		case "values":
+			return &Type{kind: functionKind, fn: &signature{returns: arrayOf(value)}}, ""
+		case "fromValue":
+			return &Type{kind: functionKind, fn: &signature{params: []*ast.Parameter{{Name: &ast.Identifier{Value: "value"}}}, returns: value}}, ""
+		}
+		for _, valueName := range object.enum.values {
+			if valueName == name {
+				return value, ""
+			}
+		}
+		return unknownType, fmt.Sprintf("enum %s has no member '%s'", object.enum.name, name)
+
+	case enumValueKind:
+		if name == "value" {
+			return unknownType, ""
+		}
+		return unknownType, "enum value only has 'value' member"
+
+	case moduleKind:
+		if t, ok := object.module.members(name); ok {
+			return t, ""
+		}
+		return unknownType, fmt.Sprintf("module '%s' has no member '%s'", object.module.name, name)
+
+	case instanceKind:
+		info := object.model
+		if fieldType, ok := info.fields[name]; ok {
+			return c.resolveQuiet(fieldType, info.scope), ""
+		}
+		if method, ok := info.methods[name]; ok {
+			return &Type{kind: functionKind, fn: method}, ""
+		}
+		if info.open {
+			return unknownType, ""
+		}
+		return unknownType, fmt.Sprintf("model %s has no field or method '%s'", info.name, name)
+
+	case implementerKind:
+		info := object.iface
+		if fieldType, ok := info.fields[name]; ok {
+			return c.resolveQuiet(fieldType, info.scope), ""
+		}
+		if method, ok := info.methods[name]; ok {
+			return &Type{kind: functionKind, fn: method}, ""
+		}
+		// The model of the instance may have more members
+		return unknownType, ""
+
+	case modelKind:
+		if object.model.statics[name] {
+			return functionType, ""
+		}
+		return unknownType, fmt.Sprintf("model %s has no static method '%s'", object.model.name, name)
+
+	case mapKind:
+		// Keys shadow the map methods
+		if interpreter.HasMethod(interpreter.MapObj, name) {
+			return functionType, ""
+		}
+		return object.elem, ""
+	}
+
+	if interpreter.HasMethod(objectType(object), name) {
+		if object.kind == arrayKind {
+			if callback, ok := arrayCallbacks(object.elem)[name]; ok {
+				return &Type{kind: functionKind, fn: &signature{returns: unknownType, callback: callback}}, ""
+			}
+		}
+		return functionType, ""
+	}
+	return unknownType, fmt.Sprintf("undefined method '%s' for type %s", name, objectType(object))
+}
+
+// arrayCallbacks returns the arguments array methods pass to their callback.
+// The accumulator of reduce may change type with each call.
+func arrayCallbacks(elem *Type) map[string][]*Type {
+	return map[string][]*Type{
+		"map":    {elem},
+		"filter": {elem},
+		"each":   {elem},
+		"reduce": {unknownType, elem},
+	}
+}
+
+// definite reports whether t is a single known type the interpreter would
+// reject in the wrong place. Functions are not definite: they may be
+// builtins or bound methods at runtime.
+func definite(t *Type) bool {
+	switch t.kind {
+	case unknownKind, unionKind, functionKind:
+		return false
+	}
+	return true
+}
+
+// objectType returns the runtime object type of values of t.
+func objectType(t *Type) interpreter.ObjectType {
+	switch t.kind {
+	case basicKind:
+		switch t.name {
+		case typeInteger:
+			return interpreter.IntegerObj
+		case typeFloat:
+			return interpreter.FloatObj
+		case typeString:
+			return interpreter.StringObj
+		case typeBoolean:
+			return interpreter.BooleanObj
+		}
+		return interpreter.NullObj
+	case arrayKind:
+		return interpreter.ArrayObj
+	case mapKind:
+		return interpreter.MapObj
+	case functionKind:
+		return interpreter.FunctionObj
+	case modelKind:
+		return interpreter.ModelObj
+	case instanceKind:
+		return interpreter.ModelInstanceObj
+	case enumKind:
+		return interpreter.EnumObj
+	case enumValueKind:
+		return interpreter.EnumValueObj
+	case moduleKind:
+		return interpreter.ModuleObj
+	case interfaceKind:
+		return interpreter.InterfaceObj
+	case implementerKind:
+		return interpreter.ModelInstanceObj
+	}
+	return ""
+}
+
+// tokenOf returns the token an expression starts with, for diagnostics.
+//
+//nolint:cyclop
+func tokenOf(e ast.Expression) token.Token {
+	switch e := e.(type) {
+	case *ast.Identifier:
+		return e.Token
+	case *ast.IntegerLiteral:
+		return e.Token
+	case *ast.FloatLiteral:
+		return e.Token
+	case *ast.StringLiteral:
+		return e.Token
+	case *ast.InterpolatedString:
+		return e.Token
+	case *ast.BooleanLiteral:
+		return e.Token
+	case *ast.NullLiteral:
+		return e.Token
+	case *ast.ThisExpression:
+		return e.Token
+	case *ast.ArrayLiteral:
+		return e.Token
+	case *ast.MapLiteral:
+		return e.Token
+	case *ast.PrefixExpression:
+		return e.Token
+	case *ast.InfixExpression:
+		return tokenOf(e.Left)
+	case *ast.PropagateExpression:
+		return tokenOf(e.Value)
+	case *ast.IfExpression:
+		return e.Token
+	case *ast.MatchExpression:
+		return e.Token
+	case *ast.FunctionLiteral:
+		return e.Token
+	case *ast.CallExpression:
+		return tokenOf(e.Function)
+	case *ast.IndexExpression:
+		return tokenOf(e.Left)
+	case *ast.MemberExpression:
+		return tokenOf(e.Object)
+	case *ast.RangeExpression:
+		if e.Start != nil {
+			return tokenOf(e.Start)
+		}
+		return e.Token
+	case *ast.ModelLiteral:
+		return e.Token
+	case *ast.EnumLiteral:
+		return e.Token
+	case *ast.InterfaceLiteral:
+		return e.Token
+	case *ast.SuperExpression:
+		return e.Token
+	case *ast.DbFindExpression:
+		return e.Token
+	}
+	return token.Token{}
+}
@@ -0,0 +1,75 @@
+package checker
+
+import (
+	"maps"
+
+	"github.com/mishankov/totalscript-lang/internal/ast"
+)
+
+// inherit copies the fields and methods of the base model into info, like
+// the interpreter's inheritModel. A base that is not known may have any
+// member.
+func inherit(info *modelInfo, base *Type) {
+	if base.kind != instanceKind {
+		info.open = true
+		return
+	}
+	info.base = base.model
+	info.fieldNames = append(info.fieldNames, base.model.fieldNames...)
+	maps.Copy(info.fields, base.model.fields)
+	maps.Copy(info.methods, base.model.methods)
+}
+
+// checkExtends checks the base of a model and fields redeclaring inherited
+// ones. It returns the scope of the constructors and methods, in which super
+// is the base model.
+func (c *Checker) checkExtends(lit *ast.ModelLiteral, info *modelInfo, s *scope) *scope {
+	body := s.child()
+	base := c.resolveType(lit.Extends, s)
+	if base.kind != instanceKind {
+		if definite(base) {
+			c.errorf(lit.Extends.Token, "'%s' is not a model", lit.Extends.String())
+		}
+		body.declare("super", &variable{t: unknownType})
+		return body
+	}
+
+	for _, field := range lit.Fields {
+		if baseType, ok := base.model.fields[field.Name.Value]; ok && !sameAnnotation(baseType, field.Type) {
+			c.errorf(field.Name.Token, "field '%s' is declared in %s with type %s", field.Name.Value, base.model.name, baseType)
+		}
+	}
+	body.declare("super", &variable{t: &Type{kind: modelKind, model: info.base}})
+	return body
+}
+
+// superModel returns the base model super refers to, reporting super outside
+// of derived models. It is false if the base is not known.
+func (c *Checker) superModel(e *ast.SuperExpression, s *scope) (*modelInfo, bool) {
+	t, ok := s.lookup("super")
+	if !ok {
+		c.errorf(e.Token, "'super' can only be used inside a model extending another model")
+		return nil, false
+	}
+	if t.kind != modelKind {
+		return nil, false
+	}
+	return t.model, true
+}
+
+// checkSuperCall checks super(args) like a call of a base constructor. The
+// result is an instance of the derived model.
+func (c *Checker) checkSuperCall(call *ast.CallExpression, s *scope) *Type {
+	super, _ := call.Function.(*ast.SuperExpression)
+	base, ok := c.superModel(super, s)
+	if !ok {
+		c.checkCall(call, unknownType, s)
+		return unknownType
+	}
+
+	c.checkCall(call, &Type{kind: modelKind, model: base}, s)
+	if this, ok := s.lookup("this"); ok {
+		return this
+	}
+	return unknownType
+}
@@ -0,0 +1,141 @@
+package checker
+
+import (
+	"fmt"
+
+	"github.com/mishankov/totalscript-lang/internal/ast"
+	"github.com/mishankov/totalscript-lang/internal/interpreter"
+)
+
+// checkInterface checks the member types of an interface literal.
+func (c *Checker) checkInterface(lit *ast.InterfaceLiteral, s *scope) *Type {
+	t := c.interfaceType(lit, s)
+	for _, field := range lit.Fields {
+		c.resolveType(field.Type, s)
+	}
+	for _, method := range lit.Methods {
+		for _, param := range method.Parameters {
+			c.resolveType(param.Type, s)
+		}
+		c.resolveType(method.ReturnType, s)
+	}
+	return t
+}
+
+// interfaceType returns the type of an interface literal.
+func (c *Checker) interfaceType(lit *ast.InterfaceLiteral, s *scope) *Type {
+	if t, ok := c.literals[lit]; ok {
+		return t
+	}
+	info := &interfaceInfo{
+		fieldNames: make([]string, 0, len(lit.Fields)),
+		fields:     make(map[string]*ast.TypeExpression, len(lit.Fields)),
+		methods:    make(map[string]*signature, len(lit.Methods)),
+		scope:      s,
+	}
+	for _, field := range lit.Fields {
+		info.fieldNames = append(info.fieldNames, field.Name.Value)
+		info.fields[field.Name.Value] = field.Type
+	}
+	for _, method := range lit.Methods {
+		info.order = append(info.order, method.Name.Value)
+		info.methods[method.Name.Value] = &signature{params: method.Parameters, scope: s, result: method.ReturnType}
+	}
+	t := &Type{kind: interfaceKind, iface: info}
+	c.literals[lit] = t
+	return t
+}
+
+// runtimeInterface describes an interface of the global environment or a
+// module.
+func (c *Checker) runtimeInterface(iface *interpreter.Interface, env *interpreter.Environment) *interfaceInfo {
+	info := &interfaceInfo{
+		name:       iface.Name,
+		fieldNames: iface.FieldNames,
+		fields:     iface.Fields,
+		methods:    make(map[string]*signature, len(iface.Methods)),
+		scope:      &scope{checker: c, env: env},
+	}
+	for _, method := range iface.Methods {
+		info.order = append(info.order, method.Name.Value)
+		info.methods[method.Name.Value] = &signature{params: method.Parameters, scope: info.scope, result: method.ReturnType}
+	}
+	return info
+}
+
+// checkImplements checks an interface declared by a model with implements,
+// like evalModelLiteral does.
+func (c *Checker) checkImplements(model *modelInfo, typeExpr *ast.TypeExpression, s *scope) {
+	t := c.resolveType(typeExpr, s)
+	switch t.kind {
+	case unknownKind:
+		return
+	case implementerKind:
+		if missing := missingMember(model, t.iface); missing != "" {
+			c.errorf(typeExpr.Token, "model does not implement %s: %s", typeExpr.String(), missing)
+		}
+	default:
+		c.errorf(typeExpr.Token, "'%s' is not an interface", typeExpr.String())
+	}
+}
+
+// missingMember describes the first member of an interface a model lacks,
+// or returns "" if the model conforms, like the interpreter's check. Built-in
+// models may have native methods, so their methods are not checked.
+func missingMember(model *modelInfo, iface *interfaceInfo) string {
+	for _, name := range iface.fieldNames {
+		fieldType, ok := model.fields[name]
+		if !ok {
+			return fmt.Sprintf("missing field '%s'", name)
+		}
+		if expected := iface.fields[name]; !sameAnnotation(fieldType, expected) {
+			return fmt.Sprintf("field '%s' has type %s, expected %s", name, fieldType, expected)
+		}
+	}
+
+	for _, name := range iface.order {
+		method := iface.methods[name]
+		fn, ok := model.methods[name]
+		if !ok {
+			if model.open {
+				continue
+			}
+			return fmt.Sprintf("missing method '%s'", name)
+		}
+		if !acceptsArguments(fn.params, len(method.params)) {
+			return fmt.Sprintf("method '%s' does not take %d arguments", name, len(method.params))
+		}
+		for i, param := range method.params {
+			if i < len(fn.params) && !fn.params[i].Variadic && !sameAnnotation(fn.params[i].Type, param.Type) {
+				return fmt.Sprintf("method '%s' parameter '%s' has type %s, expected %s",
+					name, fn.params[i].Name.Value, fn.params[i].Type, param.Type)
+			}
+		}
+		if !sameAnnotation(fn.result, method.result) {
+			return fmt.Sprintf("method '%s' returns %s, expected %s", name, fn.result, method.result)
+		}
+	}
+
+	return ""
+}
+
+// sameAnnotation reports whether two type annotations agree. A missing
+// annotation agrees with any type.
+func sameAnnotation(a, b *ast.TypeExpression) bool {
+	return a == nil || b == nil || a.String() == b.String()
+}
+
+// acceptsArguments reports whether a function with params can be called
+// with count positional arguments.
+func acceptsArguments(params []*ast.Parameter, count int) bool {
+	required := 0
+	for _, param := range params {
+		if param.Variadic {
+			return count >= required
+		}
+		if param.Default == nil {
+			required++
+		}
+	}
+	return count >= required && count <= len(params)
+}
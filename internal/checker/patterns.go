@@ -0,0 +1,202 @@
+package checker
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/mishankov/totalscript-lang/internal/ast"
+)
+
+// checkMatch checks the arms of a match expression like evalMatchExpression.
+// The bindings of an arm are visible in its guard and body only.
+func (c *Checker) checkMatch(e *ast.MatchExpression, s *scope) *Type {
+	value := c.expr(e.Value, s)
+	for _, arm := range e.Arms {
+		armScope := s.child()
+		for _, pattern := range arm.Patterns {
+			c.checkPattern(pattern, value, armScope)
+		}
+		if arm.Guard != nil {
+			c.expr(arm.Guard, armScope)
+		}
+		c.checkBlock(arm.Body, armScope)
+	}
+
+	if e.Default != nil {
+		c.checkBlock(e.Default, s.narrow(nil))
+	} else if value.kind == enumValueKind {
+		c.checkExhaustive(e, value, s)
+	}
+	return unknownType
+}
+
+// checkPattern checks a pattern matched against values of type value and
+// declares its bindings in s.
+//
+//nolint:cyclop
+func (c *Checker) checkPattern(pattern ast.Pattern, value *Type, s *scope) {
+	switch pattern := pattern.(type) {
+	case *ast.BindingPattern:
+		t := value
+		if pattern.Type != nil {
+			t = c.resolveType(pattern.Type, s)
+		} else if c.assigned[pattern.Name.Value] {
+			// The inferred type does not survive later assignments
+			t = unknownType
+		}
+		if pattern.Name.Value != "_" {
+			s.declare(pattern.Name.Value, &variable{t: t})
+		}
+
+	case *ast.ValuePattern:
+		if rangeExpr, ok := pattern.Value.(*ast.RangeExpression); ok {
+			// Range patterns also match floats, so bounds are not checked as integers
+			for _, bound := range []ast.Expression{rangeExpr.Start, rangeExpr.End} {
+				if bound != nil {
+					c.expr(bound, s)
+				}
+			}
+			return
+		}
+		c.expr(pattern.Value, s)
+
+	case *ast.ArrayPattern:
+		elem := unknownType
+		if value.kind == arrayKind {
+			elem = value.elem
+		}
+		for _, element := range pattern.Elements {
+			c.checkPattern(element, elem, s)
+		}
+		if pattern.Rest != nil {
+			rest := arrayOf(elem)
+			if c.assigned[pattern.Rest.Value] {
+				rest = unknownType
+			}
+			s.declare(pattern.Rest.Value, &variable{t: rest})
+		}
+
+	case *ast.MapPattern:
+		elem := unknownType
+		if value.kind == mapKind {
+			elem = value.elem
+		}
+		for _, p := range pattern.Values {
+			c.checkPattern(p, elem, s)
+		}
+
+	case *ast.ModelPattern:
+		c.checkModelPattern(pattern, s)
+	}
+}
+
+// checkModelPattern checks the model and field patterns of a model pattern.
+func (c *Checker) checkModelPattern(pattern *ast.ModelPattern, s *scope) {
+	model := c.expr(pattern.Model, s)
+	if model.kind != modelKind {
+		if definite(model) {
+			c.errorf(pattern.Token, "'%s' is not a model", pattern.Model.String())
+		}
+		for _, field := range pattern.Fields {
+			c.checkPattern(field, unknownType, s)
+		}
+		return
+	}
+
+	info := model.model
+	if len(pattern.Fields) != len(info.fieldNames) {
+		c.errorf(pattern.Token, "wrong number of fields in pattern for %s: expected %d, got %d",
+			info.name, len(info.fieldNames), len(pattern.Fields))
+		return
+	}
+	for i, name := range info.fieldNames {
+		c.checkPattern(pattern.Fields[i], c.resolveQuiet(info.fields[name], info.scope), s)
+	}
+}
+
+// checkExhaustive reports the values of an enum a match without default does
+// not handle, like the interpreter does when the match runs.
+func (c *Checker) checkExhaustive(e *ast.MatchExpression, value *Type, s *scope) {
+	handled := make(map[string]bool)
+	for _, arm := range e.Arms {
+		if arm.Guard != nil {
+			continue
+		}
+		for _, pattern := range arm.Patterns {
+			switch pattern := pattern.(type) {
+			case *ast.WildcardPattern:
+				return
+			case *ast.BindingPattern:
+				if pattern.Type == nil || assignable(value, c.resolveQuiet(pattern.Type, s)) {
+					return
+				}
+			case *ast.ValuePattern:
+				member, ok := pattern.Value.(*ast.MemberExpression)
+				if !ok {
+					continue
+				}
+				if enum := c.exprQuiet(member.Object, s); enum.kind == enumKind && enum.enum.name == value.enum.name {
+					handled[member.Member.Value] = true
+				}
+			}
+		}
+	}
+
+	var missing []string
+	for _, name := range value.enum.values {
+		if !handled[name] {
+			missing = append(missing, value.enum.name+"."+name)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		c.errorf(e.Token, "match is not exhaustive: %s not handled", strings.Join(missing, ", "))
+	}
+}
+
+// hoistPattern declares the variables of a destructuring declaration before
+// checking the block, like hoist does for plain declarations.
+func hoistPattern(pattern ast.Pattern, s *scope) {
+	switch pattern := pattern.(type) {
+	case *ast.BindingPattern:
+		if _, ok := s.vars[pattern.Name.Value]; !ok && pattern.Name.Value != "_" {
+			s.declare(pattern.Name.Value, &variable{t: unknownType})
+		}
+	case *ast.ArrayPattern:
+		for _, element := range pattern.Elements {
+			hoistPattern(element, s)
+		}
+		if pattern.Rest != nil {
+			hoistPattern(&ast.BindingPattern{Name: pattern.Rest}, s)
+		}
+	case *ast.MapPattern:
+		for _, value := range pattern.Values {
+			hoistPattern(value, s)
+		}
+	case *ast.ModelPattern:
+		for _, field := range pattern.Fields {
+			hoistPattern(field, s)
+		}
+	}
+}
+
+// checkMultiAssign checks each target of a multiple assignment like a single
+// assignment of its value.
+func (c *Checker) checkMultiAssign(stmt *ast.MultiAssignStatement, s *scope) {
+	if len(stmt.Values) != len(stmt.Targets) {
+		c.errorf(stmt.Token, "wrong number of values in assignment: expected %d, got %d", len(stmt.Targets), len(stmt.Values))
+	}
+	for i, target := range stmt.Targets {
+		if i >= len(stmt.Values) {
+			break
+		}
+		c.checkAssignment(&ast.InfixExpression{Token: stmt.Token, Left: target, Operator: "=", Right: stmt.Values[i]}, s)
+	}
+}
+
+// exprQuiet returns the type of an expression that was already checked.
+func (c *Checker) exprQuiet(e ast.Expression, s *scope) *Type {
+	c.quiet++
+	defer func() { c.quiet-- }()
+	return c.expr(e, s)
+}
@@ -0,0 +1,454 @@
+package checker
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/mishankov/totalscript-lang/internal/ast"
+)
+
+// Type names of the built-in types.
+const (
+	typeInteger  = "integer"
+	typeFloat    = "float"
+	typeString   = "string"
+	typeBoolean  = "boolean"
+	typeNull     = "null"
+	typeArray    = "array"
+	typeMap      = "map"
+	typeFunction = "function"
+)
+
+// kind classifies static types.
+type kind int
+
+const (
+	unknownKind     kind = iota // not inferred; compatible with everything
+	basicKind                   // integer, float, string, boolean, null
+	arrayKind                   // array<elem>
+	mapKind                     // map<key, elem>
+	functionKind                // function with a signature, if known
+	modelKind                   // a model itself, callable as constructor
+	instanceKind                // an instance of a model
+	enumKind                    // an enum itself
+	enumValueKind               // a value of an enum
+	moduleKind                  // an imported module
+	interfaceKind               // an interface itself
+	implementerKind             // an instance of a model conforming to an interface
+	unionKind                   // one of several types
+)
+
+// Type is the static type of an expression.
+type Type struct {
+	kind    kind
+	name    string  // basic type name
+	elem    *Type   // array and map element type
+	key     *Type   // map key type
+	members []*Type // union alternatives
+	fn      *signature
+	model   *modelInfo
+	enum    *enumInfo
+	module  *moduleInfo
+	iface   *interfaceInfo
+}
+
+// signature describes the parameters and result of a function.
+type signature struct {
+	params  []*ast.Parameter
+	scope   *scope // scope resolving the type annotations
+	result  *ast.TypeExpression
+	returns *Type // result of built-in members, instead of result
+	// callback lists the arguments a built-in method passes to the function
+	// given as its last argument, like the elements for array.map.
+	callback []*Type
+}
+
+// modelInfo describes the fields, methods and constructors of a model.
+type modelInfo struct {
+	name         string
+	base         *modelInfo // model this one extends, if any
+	fieldNames   []string
+	fields       map[string]*ast.TypeExpression
+	methods      map[string]*signature
+	constructors []*signature
+	statics      map[string]bool
+	scope        *scope // scope resolving field types
+	open         bool   // built-in model whose instances may have native methods
+	native       bool   // built-in model with a native constructor
+}
+
+// interfaceInfo describes the fields and methods of an interface.
+type interfaceInfo struct {
+	name       string
+	fieldNames []string
+	fields     map[string]*ast.TypeExpression
+	methods    map[string]*signature
+	order      []string // method names in declaration order
+	scope      *scope   // scope resolving member types
+}
+
+// enumInfo describes the values of an enum.
+type enumInfo struct {
+	name   string
+	values []string
+}
+
+// moduleInfo describes the members of an imported module.
+type moduleInfo struct {
+	name    string
+	members func(name string) (*Type, bool)
+}
+
+//nolint:gochecknoglobals
+var (
+	unknownType  = &Type{kind: unknownKind}
+	integerType  = &Type{kind: basicKind, name: typeInteger}
+	floatType    = &Type{kind: basicKind, name: typeFloat}
+	stringType   = &Type{kind: basicKind, name: typeString}
+	booleanType  = &Type{kind: basicKind, name: typeBoolean}
+	nullType     = &Type{kind: basicKind, name: typeNull}
+	functionType = &Type{kind: functionKind}
+)
+
+func arrayOf(elem *Type) *Type { return &Type{kind: arrayKind, elem: elem} }
+
+func mapOf(key, elem *Type) *Type { return &Type{kind: mapKind, key: key, elem: elem} }
+
+func instanceOf(model *modelInfo) *Type { return &Type{kind: instanceKind, model: model} }
+
+// String formats a type like the interpreter's type mismatch errors.
+func (t *Type) String() string {
+	switch t.kind {
+	case basicKind:
+		return t.name
+	case arrayKind:
+		if t.elem.kind == unknownKind {
+			return typeArray
+		}
+		return "array<" + t.elem.String() + ">"
+	case mapKind:
+		if t.elem.kind == unknownKind {
+			return typeMap
+		}
+		return "map<" + t.key.String() + ", " + t.elem.String() + ">"
+	case functionKind:
+		return typeFunction
+	case modelKind:
+		return "model " + t.model.name
+	case instanceKind:
+		return t.model.name
+	case enumKind:
+		return "enum " + t.enum.name
+	case enumValueKind:
+		return t.enum.name
+	case moduleKind:
+		return "module " + t.module.name
+	case interfaceKind:
+		return "interface " + t.iface.name
+	case implementerKind:
+		return t.iface.name
+	case unionKind:
+		return t.typeExpression().String()
+	}
+	return "unknown"
+}
+
+// typeExpression converts a type back to an annotation, for type narrowing.
+func (t *Type) typeExpression() *ast.TypeExpression {
+	if t.kind != unionKind {
+		if t.kind == arrayKind || t.kind == mapKind {
+			if t.elem.kind == unknownKind {
+				return &ast.TypeExpression{Name: t.String()}
+			}
+			expr := &ast.TypeExpression{Name: typeArray, Generic: []string{t.elem.String()}}
+			if t.kind == mapKind {
+				expr.Name = typeMap
+				expr.Generic = []string{t.key.String(), t.elem.String()}
+			}
+			return expr
+		}
+		return &ast.TypeExpression{Name: t.String()}
+	}
+
+	expr := &ast.TypeExpression{}
+	for _, member := range t.members {
+		if member.kind == basicKind && member.name == typeNull {
+			expr.Optional = true
+			continue
+		}
+		expr.Union = append(expr.Union, member.String())
+	}
+	if len(expr.Union) == 1 {
+		expr.Name = expr.Union[0]
+		expr.Union = nil
+	}
+	return expr
+}
+
+// unionOf joins types into a union, dropping duplicates. A union with an
+// unknown member is unknown.
+func unionOf(types ...*Type) *Type {
+	var members []*Type
+	seen := make(map[string]bool)
+	for _, t := range types {
+		alternatives := []*Type{t}
+		if t.kind == unionKind {
+			alternatives = t.members
+		}
+		for _, alt := range alternatives {
+			if alt.kind == unknownKind {
+				return unknownType
+			}
+			if key := alt.String(); !seen[key] {
+				seen[key] = true
+				members = append(members, alt)
+			}
+		}
+	}
+	if len(members) == 1 {
+		return members[0]
+	}
+	sort.SliceStable(members, func(i, j int) bool {
+		// Keep null last, as in `string?`
+		return members[j].kind == basicKind && members[j].name == typeNull && members[i].name != typeNull
+	})
+	return &Type{kind: unionKind, members: members}
+}
+
+// without returns t without the alternatives drop matches. The result is
+// unknown if no alternative is left.
+func (t *Type) without(drop func(*Type) bool) *Type {
+	alternatives := []*Type{t}
+	if t.kind == unionKind {
+		alternatives = t.members
+	}
+	var rest []*Type
+	for _, alt := range alternatives {
+		if !drop(alt) {
+			rest = append(rest, alt)
+		}
+	}
+	if len(rest) == 0 {
+		return unknownType
+	}
+	return unionOf(rest...)
+}
+
+// withoutNull returns t without null, the type of a value checked not to be null.
+func (t *Type) withoutNull() *Type {
+	return t.without(func(alt *Type) bool { return alt.is(typeNull) })
+}
+
+// isNumeric reports whether t is integer or float.
+func (t *Type) isNumeric() bool {
+	return t.kind == basicKind && (t.name == typeInteger || t.name == typeFloat)
+}
+
+// is reports whether t is the basic type name.
+func (t *Type) is(name string) bool {
+	return t.kind == basicKind && t.name == name
+}
+
+// assignable reports whether values of type from can be used where type to is
+// expected. Unknown types are assignable both ways, so only definite
+// mismatches are reported.
+//
+//nolint:gocognit,cyclop
+func assignable(from, to *Type) bool {
+	if from.kind == unknownKind || to.kind == unknownKind {
+		return true
+	}
+
+	if from.kind == unionKind {
+		for _, member := range from.members {
+			if !assignable(member, to) {
+				return false
+			}
+		}
+		return true
+	}
+	if to.kind == unionKind {
+		for _, member := range to.members {
+			if assignable(from, member) {
+				return true
+			}
+		}
+		return false
+	}
+
+	switch to.kind {
+	case basicKind:
+		if from.kind != basicKind {
+			return false
+		}
+		// Integers are converted to float
+		return from.name == to.name || (from.name == typeInteger && to.name == typeFloat)
+	case arrayKind:
+		return from.kind == arrayKind && assignable(from.elem, to.elem)
+	case mapKind:
+		return from.kind == mapKind && assignable(from.elem, to.elem)
+	case functionKind:
+		return from.kind == functionKind
+	case instanceKind:
+		return from.kind == instanceKind && derivesFrom(from.model, to.model)
+	case implementerKind:
+		if from.kind == instanceKind {
+			return missingMember(from.model, to.iface) == ""
+		}
+		return from.kind == implementerKind && (from.iface == to.iface || from.iface.name == to.iface.name)
+	case enumValueKind:
+		return from.kind == enumValueKind && from.enum.name == to.enum.name
+	case modelKind, enumKind, moduleKind, interfaceKind:
+		return from.kind == to.kind
+	}
+	return true
+}
+
+// sameModel compares models by identity, or by name for models loaded twice.
+func sameModel(a, b *modelInfo) bool {
+	return a == b || (a.name != "" && a.name == b.name)
+}
+
+// derivesFrom reports whether model is base or extends it, directly or not.
+func derivesFrom(model, base *modelInfo) bool {
+	for ; model != nil; model = model.base {
+		if sameModel(model, base) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveType converts a type annotation to a static type, reporting unknown
+// type names.
+func (c *Checker) resolveType(expr *ast.TypeExpression, s *scope) *Type {
+	if expr == nil {
+		return unknownType
+	}
+
+	var t *Type
+	if len(expr.Union) > 0 {
+		members := make([]*Type, 0, len(expr.Union))
+		for _, name := range expr.Union {
+			members = append(members, c.resolveTypeName(name, nil, expr, s))
+		}
+		t = unionOf(members...)
+	} else {
+		t = c.resolveTypeName(expr.Name, expr.Generic, expr, s)
+	}
+
+	if expr.Optional {
+		t = unionOf(t, nullType)
+	}
+	return t
+}
+
+// resolveTypeName resolves a single type name with its generic arguments.
+func (c *Checker) resolveTypeName(name string, generic []string, expr *ast.TypeExpression, s *scope) *Type {
+	switch name {
+	case typeInteger:
+		return integerType
+	case typeFloat:
+		return floatType
+	case typeString:
+		return stringType
+	case typeBoolean:
+		return booleanType
+	case typeNull:
+		return nullType
+	case typeFunction:
+		return functionType
+	case typeArray:
+		if len(generic) == 1 {
+			return arrayOf(c.resolveGeneric(generic[0], expr, s))
+		}
+		return arrayOf(unknownType)
+	case typeMap:
+		if len(generic) == 2 {
+			return mapOf(c.resolveGeneric(generic[0], expr, s), c.resolveGeneric(generic[1], expr, s))
+		}
+		return mapOf(unknownType, unknownType)
+	}
+
+	var t *Type
+	moduleName, member, qualified := strings.Cut(name, ".")
+	if qualified {
+		module, ok := s.lookup(moduleName)
+		if !ok || module.kind != moduleKind {
+			c.errorf(expr.Token, "unknown module: %s", moduleName)
+			return unknownType
+		}
+		if t, ok = module.module.members(member); !ok {
+			c.errorf(expr.Token, "type %s not found in module %s", member, moduleName)
+			return unknownType
+		}
+	} else {
+		var ok bool
+		if t, ok = s.lookup(name); !ok {
+			c.errorf(expr.Token, "unknown type: %s", name)
+			return unknownType
+		}
+	}
+
+	switch t.kind {
+	case modelKind:
+		return instanceOf(t.model)
+	case enumKind:
+		return &Type{kind: enumValueKind, enum: t.enum}
+	case interfaceKind:
+		return &Type{kind: implementerKind, iface: t.iface}
+	case unknownKind:
+		return unknownType
+	case functionKind:
+		if qualified {
+			// Native constructors double as types (http.Response)
+			return unknownType
+		}
+	}
+	c.errorf(expr.Token, "'%s' is not a valid type", name)
+	return unknownType
+}
+
+// resolveGeneric resolves a generic argument, which may be a union written
+// as "integer | string".
+func (c *Checker) resolveGeneric(arg string, expr *ast.TypeExpression, s *scope) *Type {
+	parts := strings.Split(arg, "|")
+	members := make([]*Type, 0, len(parts))
+	for _, part := range parts {
+		part = strings.TrimSpace(part)
+		optional := strings.HasSuffix(part, "?")
+		part = strings.TrimSuffix(part, "?")
+
+		name, inner, generic := strings.Cut(part, "<")
+		var args []string
+		if generic {
+			args = splitGenericArgs(strings.TrimSuffix(inner, ">"))
+		}
+		t := c.resolveTypeName(name, args, expr, s)
+		if optional {
+			t = unionOf(t, nullType)
+		}
+		members = append(members, t)
+	}
+	return unionOf(members...)
+}
+
+// splitGenericArgs splits "string, array<integer>" at top-level commas.
+func splitGenericArgs(s string) []string {
+	var args []string
+	depth, start := 0, 0
+	for i, r := range s {
+		switch r {
+		case '<':
+			depth++
+		case '>':
+			depth--
+		case ',':
+			if depth == 0 {
+				args = append(args, strings.TrimSpace(s[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	return append(args, strings.TrimSpace(s[start:]))
+}
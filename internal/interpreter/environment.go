@@ -55,6 +55,13 @@ func (e *Environment) Set(name string, val Object) Object {
 	return val
 }
 
+// Define creates a variable in the current scope, shadowing variables of
+// the same name in outer scopes.
+func (e *Environment) Define(name string, val Object) Object {
+	e.store[name] = val
+	return val
+}
+
 // GetType retrieves the type annotation for a variable.
 func (e *Environment) GetType(name string) (*ast.TypeExpression, bool) {
 	typeExpr, ok := e.types[name]
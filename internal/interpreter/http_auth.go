@@ -0,0 +1,554 @@
+package interpreter
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Defaults of the authentication middleware.
+const (
+	defaultAuthRealm    = "Restricted"
+	defaultAPIKeyHeader = "X-API-Key"
+	jwtAlgHS256         = "HS256"
+	jwtAlgRS256         = "RS256"
+)
+
+// authConfig holds the options shared by all authentication middleware.
+type authConfig struct {
+	scheme    string // WWW-Authenticate scheme
+	realm     string
+	authorize Object // function(user) returning false to answer 403
+}
+
+// createAuthNamespace creates the http.auth namespace. Each entry is a factory
+// returning native middleware that authenticates requests and sets req.user:
+//
+//	server.use(http.auth.basic(function(username, password) { ... }))
+//	server.use(http.auth.jwt({"secret": os.env("JWT_SECRET")}))
+func createAuthNamespace() *Map {
+	namespace := &Map{Pairs: make(map[string]Object)}
+	namespace.Pairs["basic"] = createBasicAuthMiddleware()
+	namespace.Pairs["apiKey"] = createAPIKeyAuthMiddleware()
+	namespace.Pairs["jwt"] = createJWTAuthMiddleware()
+	return namespace
+}
+
+// authOptions validates the options shared by all authentication middleware,
+// realm and authorize, and rejects keys that are not allowed.
+func authOptions(name, scheme string, options Object, allowed ...string) (*Map, *authConfig, *Error) {
+	config := &authConfig{scheme: scheme, realm: defaultAuthRealm}
+	if options == nil {
+		return &Map{Pairs: make(map[string]Object)}, config, nil
+	}
+
+	opts, err := middlewareOptions(name, []Object{options}, append(allowed, "realm", "authorize")...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if value, ok := opts.Pairs["realm"]; ok {
+		str, ok := value.(*String)
+		if !ok {
+			return nil, nil, newError("%s() option 'realm' must be string, got %s", name, value.Type())
+		}
+		config.realm = str.Value
+	}
+	if value, ok := opts.Pairs["authorize"]; ok {
+		switch value.(type) {
+		case *Function, *Builtin:
+		default:
+			return nil, nil, newError("%s() option 'authorize' must be function, got %s", name, value.Type())
+		}
+		config.authorize = value
+	}
+	return opts, config, nil
+}
+
+// authCallback validates the (callback, options?) arguments of basic() and apiKey().
+func authCallback(name string, args []Object) (Object, Object, *Error) {
+	if len(args) < 1 || len(args) > 2 {
+		return nil, nil, newError("%s() takes 1-2 arguments (callback, options?), got %d", name, len(args))
+	}
+	switch args[0].(type) {
+	case *Function, *Builtin:
+	default:
+		return nil, nil, newError("%s() callback must be function, got %s", name, args[0].Type())
+	}
+	if len(args) == 2 {
+		return args[0], args[1], nil
+	}
+	return args[0], nil, nil
+}
+
+// authenticated sets req.user and passes the request on, or answers 403 when
+// the authorize option rejects the user.
+func (c *authConfig) authenticated(request *ModelInstance, next Object, user Object) Object {
+	request.Fields["user"] = user
+
+	if c.authorize != nil {
+		allowed := callTSFunction(c.authorize, user)
+		if IsError(allowed) {
+			return allowed
+		}
+		if !IsTruthy(allowed) {
+			return c.forbidden("insufficient permissions")
+		}
+	}
+	return callTSFunction(next, request)
+}
+
+// unauthorized answers 401 with a WWW-Authenticate challenge.
+func (c *authConfig) unauthorized(message, challengeError string) Object {
+	return c.errorResponse(http.StatusUnauthorized, message, challengeError)
+}
+
+// forbidden answers 403 for authenticated users that are not allowed in.
+func (c *authConfig) forbidden(message string) Object {
+	challengeError := ""
+	if c.scheme == "Bearer" {
+		challengeError = "insufficient_scope"
+	}
+	return c.errorResponse(http.StatusForbidden, message, challengeError)
+}
+
+// errorResponse creates a JSON error response with a WWW-Authenticate challenge.
+func (c *authConfig) errorResponse(status int64, message, challengeError string) Object {
+	challenge := fmt.Sprintf("%s realm=%q", c.scheme, c.realm)
+	if c.scheme == "Basic" {
+		challenge += `, charset="UTF-8"`
+	}
+	if challengeError != "" {
+		challenge += fmt.Sprintf(", error=%q, error_description=%q", challengeError, message)
+	}
+
+	body := &Map{Pairs: make(map[string]Object)}
+	body.Pairs["error"] = &String{Value: message}
+	headers := &Map{Pairs: make(map[string]Object)}
+	headers.Pairs["WWW-Authenticate"] = &String{Value: challenge}
+	return newResponse(status, body, headers)
+}
+
+// createBasicAuthMiddleware creates http.auth.basic(callback, options?). The
+// callback receives the username and password and returns the user, true to
+// use the username as the user, or null/false to reject the credentials.
+func createBasicAuthMiddleware() *Builtin {
+	return &Builtin{
+		Name: "basic",
+		Fn: func(args ...Object) Object {
+			callback, options, err := authCallback("basic", args)
+			if err != nil {
+				return err
+			}
+			_, config, err := authOptions("basic", "Basic", options)
+			if err != nil {
+				return err
+			}
+
+			return nativeMiddleware("basic", func(request *ModelInstance, next Object) Object {
+				encoded, found := cutAuthScheme(requestHeader(request, "Authorization"), "Basic")
+				if !found {
+					return config.unauthorized("authentication required", "")
+				}
+				decoded, decodeErr := base64.StdEncoding.DecodeString(encoded)
+				if decodeErr != nil {
+					return config.unauthorized("invalid credentials", "")
+				}
+				username, password, found := strings.Cut(string(decoded), ":")
+				if !found {
+					return config.unauthorized("invalid credentials", "")
+				}
+
+				user := callTSFunction(callback, &String{Value: username}, &String{Value: password})
+				if IsError(user) {
+					return user
+				}
+				if !IsTruthy(user) {
+					return config.unauthorized("invalid credentials", "")
+				}
+				if b, ok := user.(*Boolean); ok && b.Value {
+					user = &String{Value: username}
+				}
+				return config.authenticated(request, next, user)
+			})
+		},
+	}
+}
+
+// createAPIKeyAuthMiddleware creates http.auth.apiKey(callback, options?). The
+// key is read from a header (X-API-Key by default) or a query parameter, and
+// the callback returns the user it belongs to, or null/false.
+//
+//nolint:gocognit
+func createAPIKeyAuthMiddleware() *Builtin {
+	return &Builtin{
+		Name: "apiKey",
+		Fn: func(args ...Object) Object {
+			callback, options, err := authCallback("apiKey", args)
+			if err != nil {
+				return err
+			}
+			opts, config, err := authOptions("apiKey", "ApiKey", options, "header", "query")
+			if err != nil {
+				return err
+			}
+
+			header := defaultAPIKeyHeader
+			query := ""
+			for _, key := range []string{"header", "query"} {
+				value, ok := opts.Pairs[key]
+				if !ok {
+					continue
+				}
+				str, ok := value.(*String)
+				if !ok {
+					return newError("apiKey() option '%s' must be string, got %s", key, value.Type())
+				}
+				if key == "header" {
+					header = str.Value
+				} else {
+					query = str.Value
+				}
+			}
+
+			return nativeMiddleware("apiKey", func(request *ModelInstance, next Object) Object {
+				key := ""
+				if header != "" {
+					key = requestHeader(request, header)
+				}
+				if key == "" && query != "" {
+					if queryMap, ok := request.Fields["query"].(*Map); ok {
+						key = mapToValues(queryMap).Get(query)
+					}
+				}
+				if key == "" {
+					return config.unauthorized("API key required", "")
+				}
+
+				user := callTSFunction(callback, &String{Value: key})
+				if IsError(user) {
+					return user
+				}
+				if !IsTruthy(user) {
+					return config.unauthorized("invalid API key", "")
+				}
+				if b, ok := user.(*Boolean); ok && b.Value {
+					user = &String{Value: key}
+				}
+				return config.authenticated(request, next, user)
+			})
+		},
+	}
+}
+
+// jwtConfig holds the verification options of http.auth.jwt().
+type jwtConfig struct {
+	secret     []byte
+	publicKey  *rsa.PublicKey
+	algorithms []string
+	issuer     string
+	audience   string
+	leeway     time.Duration
+	claims     *Map // claims that must be present with these values
+	now        func() time.Time
+}
+
+// createJWTAuthMiddleware creates http.auth.jwt(options), which verifies Bearer
+// tokens signed with HS256 (secret) or RS256 (publicKey) and sets req.user to
+// their claims.
+//
+// Options:
+//   - secret (string): non-empty HMAC key for HS256 tokens
+//   - publicKey (string): PEM-encoded RSA public key or certificate, or a file containing one, for RS256 tokens
+//   - algorithms (array): accepted algorithms, defaults to those of the configured keys
+//   - issuer, audience (string): required iss and aud claims
+//   - leeway (integer): seconds of clock skew allowed for exp and nbf
+//   - claims (map): claims that must have these values; array claims must contain them
+//
+//nolint:gocognit,funlen
+func createJWTAuthMiddleware() *Builtin {
+	return &Builtin{
+		Name: "jwt",
+		Fn: func(args ...Object) Object {
+			if len(args) != 1 {
+				return newError("jwt() takes 1 argument (options), got %d", len(args))
+			}
+			opts, config, err := authOptions("jwt", "Bearer", args[0],
+				"secret", "publicKey", "algorithms", "issuer", "audience", "leeway", "claims")
+			if err != nil {
+				return err
+			}
+
+			jwt := &jwtConfig{now: time.Now}
+			for key, value := range opts.Pairs {
+				switch key {
+				case "secret", "publicKey", "issuer", "audience":
+					str, ok := value.(*String)
+					if !ok {
+						return newError("jwt() option '%s' must be string, got %s", key, value.Type())
+					}
+					switch key {
+					case "secret":
+						// An empty key would accept any HS256 token signed with it
+						if str.Value == "" {
+							return newError("jwt() option 'secret' must be a non-empty string")
+						}
+						jwt.secret = []byte(str.Value)
+					case "publicKey":
+						publicKey, keyErr := parseRSAPublicKey(str.Value)
+						if keyErr != nil {
+							return newError("jwt() invalid publicKey: %s", keyErr.Error())
+						}
+						jwt.publicKey = publicKey
+					case "issuer":
+						jwt.issuer = str.Value
+					default:
+						jwt.audience = str.Value
+					}
+				case "algorithms":
+					algorithms, listErr := stringList("jwt", key, value)
+					if listErr != nil {
+						return listErr
+					}
+					for _, alg := range algorithms {
+						if alg != jwtAlgHS256 && alg != jwtAlgRS256 {
+							return newError("jwt() unsupported algorithm '%s'", alg)
+						}
+					}
+					jwt.algorithms = algorithms
+				case "leeway":
+					n, ok := value.(*Integer)
+					if !ok || n.Value < 0 {
+						return newError("jwt() option 'leeway' must be a non-negative integer")
+					}
+					jwt.leeway = time.Duration(n.Value) * time.Second
+				case "claims":
+					claims, ok := value.(*Map)
+					if !ok {
+						return newError("jwt() option 'claims' must be map, got %s", value.Type())
+					}
+					jwt.claims = claims
+				}
+			}
+
+			if jwt.secret == nil && jwt.publicKey == nil {
+				return newError("jwt() requires option 'secret' or 'publicKey'")
+			}
+			if jwt.algorithms == nil {
+				if jwt.secret != nil {
+					jwt.algorithms = append(jwt.algorithms, jwtAlgHS256)
+				}
+				if jwt.publicKey != nil {
+					jwt.algorithms = append(jwt.algorithms, jwtAlgRS256)
+				}
+			}
+
+			return nativeMiddleware("jwt", func(request *ModelInstance, next Object) Object {
+				token, found := cutAuthScheme(requestHeader(request, "Authorization"), "Bearer")
+				if !found {
+					return config.unauthorized("authentication required", "")
+				}
+
+				claims, verifyErr := jwt.verify(token)
+				if verifyErr != nil {
+					return config.unauthorized(verifyErr.Error(), "invalid_token")
+				}
+				if claimErr := jwt.checkClaims(claims); claimErr != nil {
+					return config.forbidden(claimErr.Error())
+				}
+				return config.authenticated(request, next, claims)
+			})
+		},
+	}
+}
+
+// verify checks the signature and time claims of a token and returns its claims.
+func (c *jwtConfig) verify(token string) (*Map, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := decodeJWTPart(parts[0], &header); err != nil {
+		return nil, errors.New("malformed token header")
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.New("malformed token signature")
+	}
+
+	if !c.accepts(header.Alg) {
+		return nil, fmt.Errorf("algorithm %q not accepted", header.Alg)
+	}
+	signed := []byte(parts[0] + "." + parts[1])
+	switch header.Alg {
+	case jwtAlgHS256:
+		mac := hmac.New(sha256.New, c.secret)
+		mac.Write(signed)
+		if !hmac.Equal(signature, mac.Sum(nil)) {
+			return nil, errors.New("invalid signature")
+		}
+	case jwtAlgRS256:
+		digest := sha256.Sum256(signed)
+		if rsa.VerifyPKCS1v15(c.publicKey, crypto.SHA256, digest[:], signature) != nil {
+			return nil, errors.New("invalid signature")
+		}
+	}
+
+	var payload map[string]interface{}
+	if err := decodeJWTPart(parts[1], &payload); err != nil {
+		return nil, errors.New("malformed token payload")
+	}
+	claims, ok := convertJSONToObject(payload).(*Map)
+	if !ok {
+		return nil, errors.New("malformed token payload")
+	}
+
+	now := c.now()
+	if exp, ok := numericClaim(claims, "exp"); ok && now.After(exp.Add(c.leeway)) {
+		return nil, errors.New("token expired")
+	}
+	if nbf, ok := numericClaim(claims, "nbf"); ok && now.Add(c.leeway).Before(nbf) {
+		return nil, errors.New("token not valid yet")
+	}
+	if c.issuer != "" && valueToString(claims.Pairs["iss"]) != c.issuer {
+		return nil, errors.New("invalid issuer")
+	}
+	if c.audience != "" && !claimContains(claims.Pairs["aud"], &String{Value: c.audience}) {
+		return nil, errors.New("invalid audience")
+	}
+
+	return claims, nil
+}
+
+// accepts reports whether tokens signed with alg are accepted. Algorithms
+// without a configured key are never accepted.
+func (c *jwtConfig) accepts(alg string) bool {
+	for _, a := range c.algorithms {
+		if a == alg {
+			return (alg == jwtAlgHS256 && c.secret != nil) || (alg == jwtAlgRS256 && c.publicKey != nil)
+		}
+	}
+	return false
+}
+
+// checkClaims checks the claims required by the claims option.
+func (c *jwtConfig) checkClaims(claims *Map) error {
+	if c.claims == nil {
+		return nil
+	}
+	for name, expected := range c.claims.Pairs {
+		if !claimContains(claims.Pairs[name], expected) {
+			return fmt.Errorf("claim %q does not match", name)
+		}
+	}
+	return nil
+}
+
+// claimContains reports whether a claim equals expected or, for array claims
+// like roles or aud, contains it.
+func claimContains(claim, expected Object) bool {
+	if claim == nil {
+		return false
+	}
+	if arr, ok := claim.(*Array); ok {
+		for _, elem := range arr.Elements {
+			if objectsEqual(elem, expected) {
+				return true
+			}
+		}
+		return false
+	}
+	return objectsEqual(claim, expected)
+}
+
+// numericClaim returns a NumericDate claim (seconds since the epoch) as a time.
+func numericClaim(claims *Map, name string) (time.Time, bool) {
+	switch v := claims.Pairs[name].(type) {
+	case *Integer:
+		return time.Unix(v.Value, 0), true
+	case *Float:
+		sec, frac := math.Modf(v.Value)
+		return time.Unix(int64(sec), int64(frac*1e9)), true
+	}
+	return time.Time{}, false
+}
+
+// decodeJWTPart decodes a base64url-encoded JSON part of a token.
+func decodeJWTPart(part string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// parseRSAPublicKey parses a PEM-encoded RSA public key or certificate, given
+// directly or as the path of a file containing it.
+func parseRSAPublicKey(value string) (*rsa.PublicKey, error) {
+	data := []byte(value)
+	if !strings.Contains(value, "-----BEGIN") {
+		var err error
+		if data, err = os.ReadFile(value); err != nil {
+			return nil, err
+		}
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+
+	var key interface{}
+	switch block.Type {
+	case "CERTIFICATE":
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		key = cert.PublicKey
+	case "RSA PUBLIC KEY":
+		rsaKey, err := x509.ParsePKCS1PublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		key = rsaKey
+	default:
+		pkixKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		key = pkixKey
+	}
+
+	rsaKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("not an RSA public key")
+	}
+	return rsaKey, nil
+}
+
+// cutAuthScheme returns the credentials of an Authorization header using scheme.
+// Scheme names are case-insensitive.
+func cutAuthScheme(header, scheme string) (string, bool) {
+	if len(header) <= len(scheme) || !strings.EqualFold(header[:len(scheme)], scheme) || header[len(scheme)] != ' ' {
+		return "", false
+	}
+	credentials := strings.TrimSpace(header[len(scheme)+1:])
+	return credentials, credentials != ""
+}
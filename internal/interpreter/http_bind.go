@@ -0,0 +1,275 @@
+package interpreter
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mishankov/totalscript-lang/internal/ast"
+)
+
+// createBindMethod creates the req.bind(Model) method.
+// Type names in model fields are resolved in env, the scope of the route handler.
+func createBindMethod(request *ModelInstance, env *Environment) *Builtin {
+	return &Builtin{
+		Name: "bind",
+		Fn: func(args ...Object) Object {
+			if len(args) != 1 {
+				return newError("bind() takes 1 argument (model), got %d", len(args))
+			}
+
+			model, ok := args[0].(*Model)
+			if !ok {
+				return newError("bind() argument must be model, got %s", args[0].Type())
+			}
+
+			return bindRequest(request, model, env)
+		},
+	}
+}
+
+// bindingHandler wraps a route handler registered with the {"body": Model} option.
+// The bound instance is available to the handler as req.data.
+func bindingHandler(handler Object, model *Model, env *Environment) *Builtin {
+	return &Builtin{
+		Name: "bind",
+		Fn: func(args ...Object) Object {
+			if len(args) != 1 {
+				return newError("handler requires 1 argument (request)")
+			}
+
+			request, ok := isRequest(args[0])
+			if !ok {
+				return newError("request must be http.Request, got %s", args[0].Type())
+			}
+
+			bound := bindRequest(request, model, env)
+			if IsError(bound) {
+				return bound
+			}
+			request.Fields["data"] = bound
+
+			return callTSFunction(handler, request)
+		},
+	}
+}
+
+// handlerEnv returns the scope a route handler was defined in.
+func handlerEnv(handler Object) *Environment {
+	if fn, ok := handler.(*Function); ok {
+		return fn.Env
+	}
+	return NewEnvironment()
+}
+
+// bindRequest decodes the body of a request (JSON, url-encoded or multipart
+// form) into an instance of model, validating every field.
+// Problems are reported in Error.Fields.
+func bindRequest(request *ModelInstance, model *Model, env *Environment) Object {
+	var data *Map
+	var err *Error
+	if contentType := requestContentType(request); isMultipart(contentType) {
+		data, err = decodeBindMultipart(request, model)
+	} else {
+		data, err = decodeBindBody(contentType, valueToString(request.Fields["body"]), model)
+	}
+	if err != nil {
+		return err
+	}
+
+	problems := make(map[string]string)
+	instance := bindMap(data, model, env, "", problems)
+	if len(problems) > 0 {
+		return validationError(problems)
+	}
+
+	return instance
+}
+
+// requestContentType returns the Content-Type header of a request.
+func requestContentType(request *ModelInstance) string {
+	return requestHeader(request, "Content-Type")
+}
+
+// decodeBindBody decodes a request body to a map according to its content type.
+// Form values are converted to the types of the model fields they are bound to.
+func decodeBindBody(contentType, body string, model *Model) (*Map, *Error) {
+	if mediaType, _, _ := mime.ParseMediaType(contentType); mediaType == contentTypeForm {
+		values, err := url.ParseQuery(body)
+		if err != nil {
+			return nil, validationError(map[string]string{"body": "invalid form data: " + err.Error()})
+		}
+		return formValuesToMap(values, model), nil
+	}
+
+	var raw interface{}
+	if err := json.Unmarshal([]byte(body), &raw); err != nil {
+		return nil, validationError(map[string]string{"body": "invalid JSON: " + err.Error()})
+	}
+
+	data, ok := convertJSONToObject(raw).(*Map)
+	if !ok {
+		return nil, validationError(map[string]string{"body": "expected JSON object"})
+	}
+
+	return data, nil
+}
+
+// decodeBindMultipart decodes the fields of a multipart request to a map.
+// The body is parsed by req.multipart(), so binding and the handler share the result.
+func decodeBindMultipart(request *ModelInstance, model *Model) (*Map, *Error) {
+	parsed := request.Methods["multipart"].Fn()
+	if err, ok := parsed.(*Error); ok {
+		return nil, validationError(map[string]string{"body": err.Message})
+	}
+
+	values := make(url.Values)
+	if result, ok := parsed.(*Map); ok {
+		if fields, ok := result.Pairs["fields"].(*Map); ok {
+			values = mapToValues(fields)
+		}
+	}
+	return formValuesToMap(values, model), nil
+}
+
+// formValuesToMap converts form values for the fields of model. Fields typed as
+// arrays receive every value; other fields receive the first one.
+func formValuesToMap(values map[string][]string, model *Model) *Map {
+	data := &Map{Pairs: make(map[string]Object)}
+
+	for _, name := range model.FieldNames {
+		vals, ok := values[name]
+		if !ok || len(vals) == 0 {
+			continue
+		}
+
+		typeExpr := model.Fields[name]
+		if typeExpr != nil && typeExpr.Name == typeNameArray {
+			var elementType *ast.TypeExpression
+			if len(typeExpr.Generic) > 0 {
+				elementType = &ast.TypeExpression{Name: typeExpr.Generic[0]}
+			}
+			elements := make([]Object, len(vals))
+			for i, v := range vals {
+				elements[i] = convertFormValue(v, elementType)
+			}
+			data.Pairs[name] = &Array{Elements: elements}
+			continue
+		}
+
+		data.Pairs[name] = convertFormValue(vals[0], typeExpr)
+	}
+
+	return data
+}
+
+// convertFormValue converts a form string to the scalar type named by typeExpr.
+// Values that do not parse stay strings so validation reports the mismatch.
+func convertFormValue(value string, typeExpr *ast.TypeExpression) Object {
+	if typeExpr == nil {
+		return &String{Value: value}
+	}
+
+	switch typeExpr.Name {
+	case typeNameInteger:
+		if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return &Integer{Value: n}
+		}
+	case typeNameFloat:
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return &Float{Value: f}
+		}
+	case typeNameBoolean:
+		if b, err := strconv.ParseBool(value); err == nil {
+			return nativeBoolToBooleanObject(b)
+		}
+	}
+
+	return &String{Value: value}
+}
+
+// bindMap creates an instance of model from data. Problems are recorded in
+// problems keyed by field path (prefix + field name).
+func bindMap(data *Map, model *Model, env *Environment, prefix string, problems map[string]string) *ModelInstance {
+	instance := &ModelInstance{Model: model, Fields: make(map[string]Object)}
+
+	for _, name := range model.FieldNames {
+		typeExpr := model.Fields[name]
+		path := prefix + name
+
+		value, ok := data.Pairs[name]
+		if !ok || value == NULL {
+			if typeExpr != nil && !typeExpr.Optional {
+				problems[path] = "missing required field"
+				continue
+			}
+			instance.Fields[name] = NULL
+			continue
+		}
+
+		// Nested objects are bound to model-typed fields recursively
+		if nested := fieldModel(typeExpr, env); nested != nil {
+			if m, ok := value.(*Map); ok {
+				instance.Fields[name] = bindMap(m, nested, env, path+".", problems)
+				continue
+			}
+		}
+
+		if err := validateType(value, typeExpr, env); err != nil {
+			if errObj, ok := err.(*Error); ok {
+				problems[path] = errObj.Message
+			}
+			continue
+		}
+		instance.Fields[name] = coerceValue(value, typeExpr)
+	}
+
+	return instance
+}
+
+// fieldModel returns the model a field is typed as, if any.
+func fieldModel(typeExpr *ast.TypeExpression, env *Environment) *Model {
+	if typeExpr == nil || len(typeExpr.Union) > 0 || len(typeExpr.Generic) > 0 {
+		return nil
+	}
+	obj, ok := env.Get(typeExpr.Name)
+	if !ok {
+		return nil
+	}
+	model, _ := obj.(*Model)
+	return model
+}
+
+// validationError creates a binding error listing the offending fields.
+func validationError(problems map[string]string) *Error {
+	paths := make([]string, 0, len(problems))
+	for path := range problems {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	details := make([]string, len(paths))
+	for i, path := range paths {
+		details[i] = path + ": " + problems[path]
+	}
+
+	return &Error{Message: "invalid request body: " + strings.Join(details, "; "), Fields: problems}
+}
+
+// writeValidationError responds with 400 and a JSON description of a binding error:
+//
+//	{"error": "invalid request body", "fields": {"age": "type mismatch: ..."}}
+func writeValidationError(w http.ResponseWriter, err *Error) {
+	payload, _ := json.Marshal(map[string]interface{}{
+		"error":  "invalid request body",
+		"fields": err.Fields,
+	})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	//nolint:errcheck,gosec
+	w.Write(payload)
+}
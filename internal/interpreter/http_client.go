@@ -0,0 +1,748 @@
+package interpreter
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Defaults for http.Client() options.
+const (
+	defaultClientTimeout = 30 * time.Second
+	defaultClientBackoff = 100 * time.Millisecond
+)
+
+// httpClient is a reusable HTTP client created by http.Client(options?).
+// The default http.client object is an httpClient with default options.
+type httpClient struct {
+	baseURL string
+	headers http.Header // sent with every request
+	retries int
+	backoff time.Duration // delay before the first retry, doubled for each further retry
+	client  *http.Client  // shared so connections are reused between requests
+
+	// retryNonIdempotent also retries POST and PATCH requests
+	retryNonIdempotent bool
+
+	tlsOptions *Map // client TLS options, merged under per-request ones
+	mu         sync.Mutex
+	transports map[string]*http.Transport // per-request TLS transports, keyed by tlsOptionsKey
+}
+
+// Request body kinds, named after the option that sets them.
+const (
+	clientBodyRaw       = "body"
+	clientBodyJSON      = "json"
+	clientBodyForm      = "form"
+	clientBodyMultipart = "multipart"
+)
+
+// clientRequest is a single request built from client method arguments.
+type clientRequest struct {
+	method   string
+	url      string
+	headers  http.Header
+	query    url.Values
+	body     Object // nil when the request has no body
+	bodyKind string // one of the clientBody* constants
+	download string // file path the response body is written to
+	tls      *tls.Config
+	tlsKey   string         // identifies the TLS options that built tls
+	timeout  *time.Duration // overrides the client timeout when set
+}
+
+// setBody sets the request body, rejecting a second body option.
+func (req *clientRequest) setBody(kind string, value Object) *Error {
+	if req.body != nil {
+		return newError("request body given more than once ('%s' and '%s')", req.bodyKind, kind)
+	}
+	req.body = value
+	req.bodyKind = kind
+	return nil
+}
+
+// newClient creates a client from http.Client() options:
+//
+//	{"baseUrl": "https://api.example.com", "timeoutMs": 5000, "headers": {...},
+//	 "retries": 3, "backoff": 200, "retryNonIdempotent": false, "followRedirects": false}
+//
+// Only idempotent requests are retried unless retryNonIdempotent is true.
+// TLS options (ca, cert, key, insecureSkipVerify) apply to every request.
+//
+//nolint:funlen,gocognit
+func newClient(options *Map) (*httpClient, *Error) {
+	c := &httpClient{headers: make(http.Header), backoff: defaultClientBackoff}
+	timeout := defaultClientTimeout
+	followRedirects := true
+	tlsOptions := &Map{Pairs: make(map[string]Object)}
+
+	if options != nil {
+		for key, value := range options.Pairs {
+			switch key {
+			case "baseUrl":
+				str, ok := value.(*String)
+				if !ok {
+					return nil, newError("Client() option 'baseUrl' must be string, got %s", value.Type())
+				}
+				c.baseURL = str.Value
+			case "timeoutMs", "retries", "backoff":
+				n, ok := value.(*Integer)
+				if !ok || n.Value < 0 {
+					return nil, newError("Client() option '%s' must be a non-negative integer", key)
+				}
+				switch key {
+				case "timeoutMs":
+					timeout = time.Duration(n.Value) * time.Millisecond
+				case "retries":
+					c.retries = int(n.Value)
+				default:
+					c.backoff = time.Duration(n.Value) * time.Millisecond
+				}
+			case "headers":
+				headers, ok := value.(*Map)
+				if !ok {
+					return nil, newError("Client() option 'headers' must be map, got %s", value.Type())
+				}
+				if err := setResponseHeaders(c.headers, headers); err != nil {
+					return nil, err
+				}
+			case "followRedirects":
+				b, ok := value.(*Boolean)
+				if !ok {
+					return nil, newError("Client() option 'followRedirects' must be boolean, got %s", value.Type())
+				}
+				followRedirects = b.Value
+			case "retryNonIdempotent":
+				b, ok := value.(*Boolean)
+				if !ok {
+					return nil, newError("Client() option 'retryNonIdempotent' must be boolean, got %s", value.Type())
+				}
+				c.retryNonIdempotent = b.Value
+			default:
+				if !isClientTLSOption(key) {
+					return nil, newError("Client() unknown option '%s'", key)
+				}
+				tlsOptions.Pairs[key] = value
+			}
+		}
+	}
+
+	tlsConfig, err := clientTLSConfig(tlsOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	c.tlsOptions = tlsOptions
+	c.client = &http.Client{Timeout: timeout, Transport: newClientTransport(tlsConfig)}
+	if !followRedirects {
+		c.client.CheckRedirect = func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		}
+	}
+
+	return c, nil
+}
+
+// newClientTransport creates a transport based on http.DefaultTransport.
+func newClientTransport(tlsConfig *tls.Config) *http.Transport {
+	transport, _ := http.DefaultTransport.(*http.Transport)
+	transport = transport.Clone()
+	if tlsConfig != nil {
+		transport.TLSClientConfig = tlsConfig
+	}
+	return transport
+}
+
+// createClientConstructor creates the http.Client(options?) constructor.
+func createClientConstructor() *Builtin {
+	return &Builtin{
+		Name: "Client",
+		Fn: func(args ...Object) Object {
+			if len(args) > 1 {
+				return newError("Client() takes 0-1 arguments (options), got %d", len(args))
+			}
+
+			var options *Map
+			if len(args) == 1 {
+				var ok bool
+				options, ok = args[0].(*Map)
+				if !ok {
+					return newError("Client() options must be map, got %s", args[0].Type())
+				}
+			}
+
+			c, err := newClient(options)
+			if err != nil {
+				return err
+			}
+
+			return createClientObject(c)
+		},
+	}
+}
+
+// createDefaultClientObject creates the http.client object.
+func createDefaultClientObject() *Map {
+	c, _ := newClient(nil)
+	client := createClientObject(c)
+	client.Pairs["websocket"] = createClientWebSocketMethod()
+	return client
+}
+
+// createClientObject creates the TotalScript object for a client.
+func createClientObject(c *httpClient) *Map {
+	client := &Map{Pairs: make(map[string]Object)}
+
+	client.Pairs["get"] = createClientMethod(c, "GET")
+	client.Pairs["post"] = createClientMethod(c, "POST")
+	client.Pairs["put"] = createClientMethod(c, "PUT")
+	client.Pairs["patch"] = createClientMethod(c, "PATCH")
+	client.Pairs["delete"] = createClientMethod(c, "DELETE")
+	client.Pairs["request"] = createClientRequestMethod(c)
+
+	return client
+}
+
+// createClientMethod creates an HTTP client method for the given HTTP verb.
+// GET/DELETE: (url, headers?, options?)
+// POST/PUT/PATCH: (url, body, headers?, options?)
+func createClientMethod(c *httpClient, method string) *Builtin {
+	return &Builtin{
+		Name: strings.ToLower(method),
+		Fn: func(args ...Object) Object {
+			// GET/DELETE have no body argument
+			minArgs := 2
+			if method == "GET" || method == "DELETE" {
+				minArgs = 1
+			}
+			if len(args) < minArgs || len(args) > minArgs+2 {
+				return newError("%s() takes %d-%d arguments, got %d", method, minArgs, minArgs+2, len(args))
+			}
+
+			urlObj, ok := args[0].(*String)
+			if !ok {
+				return newError("%s() url must be string, got %s", method, args[0].Type())
+			}
+
+			req := &clientRequest{method: method, url: urlObj.Value, headers: make(http.Header)}
+			if minArgs == 2 && args[1] != NULL {
+				req.body = args[1]
+				req.bodyKind = clientBodyRaw
+			}
+
+			if len(args) > minArgs {
+				headers, ok := args[minArgs].(*Map)
+				if !ok {
+					return newError("%s() headers must be map, got %s", method, args[minArgs].Type())
+				}
+				if err := setResponseHeaders(req.headers, headers); err != nil {
+					return err
+				}
+			}
+
+			if len(args) > minArgs+1 {
+				options, ok := args[minArgs+1].(*Map)
+				if !ok {
+					return newError("%s() options must be map, got %s", method, args[minArgs+1].Type())
+				}
+				if err := req.applyOptions(options, c.tlsOptions); err != nil {
+					return err
+				}
+			}
+
+			return c.do(req)
+		},
+	}
+}
+
+// createClientRequestMethod creates the request(method, url, options?) method.
+func createClientRequestMethod(c *httpClient) *Builtin {
+	return &Builtin{
+		Name: "request",
+		Fn: func(args ...Object) Object {
+			if len(args) < 2 || len(args) > 3 {
+				return newError("request() takes 2-3 arguments (method, url, options?), got %d", len(args))
+			}
+
+			method, ok := args[0].(*String)
+			if !ok {
+				return newError("request() method must be string, got %s", args[0].Type())
+			}
+
+			urlObj, ok := args[1].(*String)
+			if !ok {
+				return newError("request() url must be string, got %s", args[1].Type())
+			}
+
+			req := &clientRequest{method: strings.ToUpper(method.Value), url: urlObj.Value, headers: make(http.Header)}
+
+			if len(args) == 3 {
+				options, ok := args[2].(*Map)
+				if !ok {
+					return newError("request() options must be map, got %s", args[2].Type())
+				}
+				if err := req.applyOptions(options, c.tlsOptions); err != nil {
+					return err
+				}
+			}
+
+			return c.do(req)
+		},
+	}
+}
+
+// applyOptions applies per-request options: headers, query, body, json, form,
+// multipart, download, timeoutMs and TLS options. TLS options are merged over
+// clientTLS, so a request can add a certificate to the client's CA.
+//
+//nolint:gocognit
+func (req *clientRequest) applyOptions(options, clientTLS *Map) *Error {
+	tlsOptions := &Map{Pairs: make(map[string]Object)}
+	requestTLS := false
+
+	for key, value := range options.Pairs {
+		switch key {
+		case "headers":
+			headers, ok := value.(*Map)
+			if !ok {
+				return newError("request option 'headers' must be map, got %s", value.Type())
+			}
+			if err := setResponseHeaders(req.headers, headers); err != nil {
+				return err
+			}
+		case "query":
+			query, ok := value.(*Map)
+			if !ok {
+				return newError("request option 'query' must be map, got %s", value.Type())
+			}
+			req.query = mapToValues(query)
+		case clientBodyRaw, clientBodyJSON, clientBodyForm, clientBodyMultipart:
+			if err := req.setBody(key, value); err != nil {
+				return err
+			}
+		case "download":
+			path, ok := value.(*String)
+			if !ok {
+				return newError("request option 'download' must be string, got %s", value.Type())
+			}
+			req.download = path.Value
+		case "timeoutMs":
+			n, ok := value.(*Integer)
+			if !ok || n.Value < 0 {
+				return newError("request option 'timeoutMs' must be a non-negative integer")
+			}
+			timeout := time.Duration(n.Value) * time.Millisecond
+			req.timeout = &timeout
+		default:
+			if !isClientTLSOption(key) {
+				return newError("unknown request option '%s'", key)
+			}
+			tlsOptions.Pairs[key] = value
+			requestTLS = true
+		}
+	}
+	if !requestTLS {
+		return nil
+	}
+
+	if clientTLS != nil {
+		// A request certificate replaces the client's cert and key as a pair
+		_, requestCert := tlsOptions.Pairs["cert"]
+		_, requestKey := tlsOptions.Pairs["key"]
+		for key, value := range clientTLS.Pairs {
+			if _, ok := tlsOptions.Pairs[key]; ok {
+				continue
+			}
+			if (key == "cert" || key == "key") && (requestCert || requestKey) {
+				continue
+			}
+			tlsOptions.Pairs[key] = value
+		}
+	}
+
+	tlsConfig, err := clientTLSConfig(tlsOptions)
+	if err != nil {
+		return err
+	}
+	req.tls = tlsConfig
+	req.tlsKey = tlsOptionsKey(tlsOptions)
+
+	return nil
+}
+
+// resolveURL prefixes relative URLs with the client's base URL.
+func (c *httpClient) resolveURL(url string) string {
+	if c.baseURL == "" || strings.Contains(url, "://") {
+		return url
+	}
+	return strings.TrimRight(c.baseURL, "/") + "/" + strings.TrimLeft(url, "/")
+}
+
+// httpClientFor returns the client to use for req, honouring per-request overrides.
+func (c *httpClient) httpClientFor(req *clientRequest) *http.Client {
+	if req.tls == nil && req.timeout == nil {
+		return c.client
+	}
+
+	client := *c.client
+	if req.tls != nil {
+		client.Transport = c.transportFor(req)
+	}
+	if req.timeout != nil {
+		client.Timeout = *req.timeout
+	}
+	return &client
+}
+
+// transportFor returns the transport for req's TLS options, creating it on
+// first use so requests with the same options share connections.
+func (c *httpClient) transportFor(req *clientRequest) *http.Transport {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if transport, ok := c.transports[req.tlsKey]; ok {
+		return transport
+	}
+	if c.transports == nil {
+		c.transports = make(map[string]*http.Transport)
+	}
+	transport := newClientTransport(req.tls)
+	c.transports[req.tlsKey] = transport
+	return transport
+}
+
+// canRetry reports whether requests with method may be retried.
+// Requests with idempotent methods are always retryable.
+func (c *httpClient) canRetry(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return c.retryNonIdempotent
+}
+
+// shouldRetry reports whether a failed attempt may be retried.
+// Network errors and 429/502/503/504 responses are retried.
+func shouldRetry(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// do performs req, retrying as configured, and returns the response object or an Error.
+//
+//nolint:funlen
+func (c *httpClient) do(req *clientRequest) Object {
+	retries := c.retries
+	if !c.canRetry(req.method) {
+		retries = 0
+	}
+
+	// Encode the body once so it can be replayed on retries. Multipart
+	// uploads sent only once are streamed from disk instead.
+	var bodyBytes []byte
+	var bodyStream io.ReadCloser
+	var contentType string
+	var bodyErr *Error
+	if req.bodyKind == clientBodyMultipart && retries == 0 {
+		bodyStream, contentType, bodyErr = req.streamMultipartBody()
+	} else {
+		bodyBytes, contentType, bodyErr = req.encodeBody()
+	}
+	if bodyErr != nil {
+		return bodyErr
+	}
+
+	requestURL, urlErr := req.buildURL(c.resolveURL(req.url))
+	if urlErr != nil {
+		return urlErr
+	}
+
+	client := c.httpClientFor(req)
+
+	var resp *http.Response
+	for attempt := 0; ; attempt++ {
+		var reqBody io.Reader
+		switch {
+		case bodyStream != nil:
+			reqBody = bodyStream
+		case req.body != nil:
+			reqBody = bytes.NewReader(bodyBytes)
+		}
+
+		//nolint:noctx
+		httpReq, err := http.NewRequest(req.method, requestURL, reqBody)
+		if err != nil {
+			if bodyStream != nil {
+				//nolint:errcheck
+				bodyStream.Close()
+			}
+			return &Error{Message: "failed to create request: " + err.Error()}
+		}
+
+		// Client headers first, request headers replace them
+		for key, values := range c.headers {
+			httpReq.Header[key] = append([]string(nil), values...)
+		}
+		for key, values := range req.headers {
+			httpReq.Header[key] = append([]string(nil), values...)
+		}
+		if contentType != "" && httpReq.Header.Get("Content-Type") == "" {
+			httpReq.Header.Set("Content-Type", contentType)
+		}
+
+		resp, err = client.Do(httpReq)
+		if attempt >= retries || !shouldRetry(resp, err) {
+			if err != nil {
+				return &Error{Message: err.Error()}
+			}
+			break
+		}
+
+		if resp != nil {
+			//nolint:errcheck
+			io.Copy(io.Discard, resp.Body)
+			//nolint:errcheck
+			resp.Body.Close()
+		}
+		time.Sleep(c.backoff << attempt)
+	}
+	//nolint:errcheck
+	defer resp.Body.Close()
+
+	if req.download != "" {
+		return downloadResponse(resp, req.download)
+	}
+
+	// Read response body
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return &Error{Message: "failed to read response: " + err.Error()}
+	}
+
+	return createClientResponse(resp, respBody)
+}
+
+// encodeBody encodes the request body and returns it with its content type.
+// A string given as "body" is sent as-is; any other body is sent as JSON.
+func (req *clientRequest) encodeBody() ([]byte, string, *Error) {
+	switch req.bodyKind {
+	case clientBodyRaw, clientBodyJSON:
+		if str, ok := req.body.(*String); ok && req.bodyKind == clientBodyRaw {
+			return []byte(str.Value), "", nil
+		}
+		// Convert object to JSON
+		jsonBytes, err := json.Marshal(convertObjectToGo(req.body))
+		if err != nil {
+			return nil, "", &Error{Message: "failed to marshal body: " + err.Error()}
+		}
+		return jsonBytes, "application/json", nil
+	case clientBodyForm:
+		form, ok := req.body.(*Map)
+		if !ok {
+			return nil, "", newError("request option 'form' must be map, got %s", req.body.Type())
+		}
+		return []byte(mapToValues(form).Encode()), contentTypeForm, nil
+	case clientBodyMultipart:
+		fields, files, err := req.multipartValues()
+		if err != nil {
+			return nil, "", err
+		}
+		var buf bytes.Buffer
+		writer := multipart.NewWriter(&buf)
+		if err := writeMultipartBody(writer, fields, files); err != nil {
+			return nil, "", err
+		}
+		return buf.Bytes(), writer.FormDataContentType(), nil
+	}
+	return nil, "", nil
+}
+
+// streamMultipartBody returns a reader producing the multipart body while it
+// is sent, so uploaded files are not held in memory. Errors reading the files
+// fail the request.
+func (req *clientRequest) streamMultipartBody() (io.ReadCloser, string, *Error) {
+	fields, files, err := req.multipartValues()
+	if err != nil {
+		return nil, "", err
+	}
+
+	// The transport closes the reader when it stops sending, which ends the
+	// writer goroutine
+	pr, pw := io.Pipe()
+	writer := multipart.NewWriter(pw)
+	go func() {
+		if err := writeMultipartBody(writer, fields, files); err != nil {
+			pw.CloseWithError(errors.New(err.Message))
+			return
+		}
+		//nolint:errcheck
+		pw.Close()
+	}()
+
+	return pr, writer.FormDataContentType(), nil
+}
+
+// multipartValues returns the fields and file paths of a multipart option of
+// the form
+//
+//	{"fields": {"title": "report"}, "files": {"upload": "./report.csv"}}
+//
+// where each file value is a path or an array of paths read from disk.
+func (req *clientRequest) multipartValues() (url.Values, url.Values, *Error) {
+	data, ok := req.body.(*Map)
+	if !ok {
+		return nil, nil, newError("request option 'multipart' must be map, got %s", req.body.Type())
+	}
+
+	for key, value := range data.Pairs {
+		switch key {
+		case "fields", "files":
+			if _, ok := value.(*Map); !ok {
+				return nil, nil, newError("multipart '%s' must be map, got %s", key, value.Type())
+			}
+		default:
+			return nil, nil, newError("multipart option has unknown key '%s' (expected fields or files)", key)
+		}
+	}
+
+	var fields, files url.Values
+	if m, ok := data.Pairs["fields"].(*Map); ok {
+		fields = mapToValues(m)
+	}
+	if m, ok := data.Pairs["files"].(*Map); ok {
+		files = mapToValues(m)
+	}
+	return fields, files, nil
+}
+
+// writeMultipartBody writes fields and the files at the given paths to writer
+// and closes it.
+func writeMultipartBody(writer *multipart.Writer, fields, files url.Values) *Error {
+	for _, name := range sortedKeys(fields) {
+		for _, v := range fields[name] {
+			if err := writer.WriteField(name, v); err != nil {
+				return &Error{Message: "failed to encode multipart field: " + err.Error()}
+			}
+		}
+	}
+
+	for _, name := range sortedKeys(files) {
+		for _, path := range files[name] {
+			if err := writeMultipartFile(writer, name, path); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return &Error{Message: "failed to encode multipart body: " + err.Error()}
+	}
+	return nil
+}
+
+// writeMultipartFile adds the file at path to writer under the given field name.
+func writeMultipartFile(writer *multipart.Writer, field, path string) *Error {
+	file, err := os.Open(path)
+	if err != nil {
+		return &Error{Message: "failed to open file: " + err.Error()}
+	}
+	//nolint:errcheck
+	defer file.Close()
+
+	part, err := writer.CreateFormFile(field, filepath.Base(path))
+	if err != nil {
+		return &Error{Message: "failed to encode multipart file: " + err.Error()}
+	}
+	if _, err := io.Copy(part, file); err != nil {
+		return &Error{Message: "failed to read file: " + err.Error()}
+	}
+	return nil
+}
+
+// buildURL adds the query option to rawURL.
+func (req *clientRequest) buildURL(rawURL string) (string, *Error) {
+	if len(req.query) == 0 {
+		return rawURL, nil
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", &Error{Message: "failed to create request: " + err.Error()}
+	}
+
+	query := u.Query()
+	for key, values := range req.query {
+		for _, v := range values {
+			query.Add(key, v)
+		}
+	}
+	u.RawQuery = query.Encode()
+
+	return u.String(), nil
+}
+
+// downloadResponse writes the response body to path. The returned response
+// object has an empty body and the file location in "path".
+func downloadResponse(resp *http.Response, path string) Object {
+	file, err := os.Create(path) //nolint:gosec // path is chosen by the script
+	if err != nil {
+		return &Error{Message: "failed to create download file: " + err.Error()}
+	}
+
+	size, err := io.Copy(file, resp.Body)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return &Error{Message: "failed to download response: " + err.Error()}
+	}
+
+	response := createClientResponse(resp, nil)
+	response.Pairs["path"] = &String{Value: path}
+	response.Pairs["size"] = &Integer{Value: size}
+	return response
+}
+
+// createClientResponse creates the response object returned by client methods.
+func createClientResponse(resp *http.Response, bodyBytes []byte) *Map {
+	response := &Map{Pairs: make(map[string]Object)}
+	response.Pairs["status"] = &Integer{Value: int64(resp.StatusCode)}
+	response.Pairs["body"] = &String{Value: string(bodyBytes)}
+	response.Pairs["headers"] = valuesToMap(resp.Header)
+	response.Pairs["ok"] = &Boolean{Value: resp.StatusCode >= 200 && resp.StatusCode < 300}
+
+	// Add json() method
+	response.Pairs["json"] = &Builtin{
+		Name: "json",
+		Fn: func(methodArgs ...Object) Object {
+			if len(methodArgs) != 0 {
+				return newError("json() takes no arguments")
+			}
+
+			var data interface{}
+			if err := json.Unmarshal(bodyBytes, &data); err != nil {
+				return &Error{Message: "invalid JSON: " + err.Error()}
+			}
+
+			return convertJSONToObject(data)
+		},
+	}
+
+	return response
+}
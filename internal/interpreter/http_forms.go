@@ -0,0 +1,230 @@
+package interpreter
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+)
+
+// Content types understood by req.form() and req.multipart().
+const (
+	contentTypeForm      = "application/x-www-form-urlencoded"
+	contentTypeMultipart = "multipart/form-data"
+)
+
+// unbufferedBody is the req.body of multipart requests. Their body is not read
+// up front but parsed from the stream by req.multipart().
+//
+//nolint:gochecknoglobals
+var unbufferedBody = &String{Value: ""}
+
+// isMultipart reports whether contentType is multipart/form-data.
+func isMultipart(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && mediaType == contentTypeMultipart
+}
+
+// requestScope tracks resources that live for the duration of a single request.
+type requestScope struct {
+	tempFiles    []string
+	bodyTooLarge bool // set when reading the body hit the server's maxBodySize
+}
+
+// release removes temporary files created while handling the request.
+func (s *requestScope) release() {
+	for _, path := range s.tempFiles {
+		_ = os.Remove(path)
+	}
+	s.tempFiles = nil
+}
+
+// valuesToMap converts url.Values to a TotalScript map<string, array<string>>.
+func valuesToMap(values map[string][]string) *Map {
+	result := &Map{Pairs: make(map[string]Object)}
+	for key, vals := range values {
+		elements := make([]Object, len(vals))
+		for i, v := range vals {
+			elements[i] = &String{Value: v}
+		}
+		result.Pairs[key] = &Array{Elements: elements}
+	}
+	return result
+}
+
+// mapToValues converts a TotalScript map to url.Values. Array values add one
+// entry per element; other values are converted to strings.
+func mapToValues(m *Map) url.Values {
+	values := make(url.Values)
+	for key, value := range m.Pairs {
+		if arr, ok := value.(*Array); ok {
+			for _, elem := range arr.Elements {
+				values.Add(key, valueToString(elem))
+			}
+			continue
+		}
+		values.Add(key, valueToString(value))
+	}
+	return values
+}
+
+// valueToString returns the value of a string, or the printed form of other values.
+func valueToString(obj Object) string {
+	if str, ok := obj.(*String); ok {
+		return str.Value
+	}
+	return obj.Inspect()
+}
+
+// sortedKeys returns the keys of values in sorted order.
+func sortedKeys(values url.Values) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// createFormMethod creates the req.form() method.
+// form() parses an application/x-www-form-urlencoded body into map<string, array<string>>.
+func createFormMethod(contentType string, body []byte) *Builtin {
+	return &Builtin{
+		Name: "form",
+		Fn: func(args ...Object) Object {
+			if len(args) != 0 {
+				return newError("form() takes no arguments")
+			}
+
+			mediaType, _, err := mime.ParseMediaType(contentType)
+			if err != nil || mediaType != contentTypeForm {
+				return &Error{Message: "request is not " + contentTypeForm + ", got " + contentType}
+			}
+
+			values, err := url.ParseQuery(string(body))
+			if err != nil {
+				return &Error{Message: "invalid form data: " + err.Error()}
+			}
+
+			return valuesToMap(values)
+		},
+	}
+}
+
+// createMultipartMethod creates the req.multipart() method.
+// multipart() parses a multipart/form-data body and returns:
+//
+//	{"fields": map<string, array<string>>, "files": array<map>}
+//
+// Each file map has name, filename, contentType and size. Files up to memoryLimit
+// bytes carry their data in "content"; larger files are written to a temporary
+// file whose location is given in "path" and which is removed after the response.
+// The form is parsed from body as it is read, without buffering it first.
+//
+//nolint:funlen
+func createMultipartMethod(contentType string, body io.Reader, memoryLimit int64, scope *requestScope) *Builtin {
+	var cached Object
+
+	return &Builtin{
+		Name: "multipart",
+		Fn: func(args ...Object) Object {
+			if len(args) != 0 {
+				return newError("multipart() takes no arguments")
+			}
+
+			// Parsing is done once; repeated calls share the result and temp files
+			if cached != nil {
+				return cached
+			}
+
+			mediaType, params, err := mime.ParseMediaType(contentType)
+			if err != nil || mediaType != contentTypeMultipart {
+				return &Error{Message: "request is not " + contentTypeMultipart + ", got " + contentType}
+			}
+
+			reader := multipart.NewReader(body, params["boundary"])
+			form, err := reader.ReadForm(memoryLimit)
+			if err != nil {
+				var maxBytesErr *http.MaxBytesError
+				if errors.As(err, &maxBytesErr) {
+					scope.bodyTooLarge = true
+					return newError("request body too large (limit %d bytes)", maxBytesErr.Limit)
+				}
+				return &Error{Message: "invalid multipart data: " + err.Error()}
+			}
+			//nolint:errcheck
+			defer form.RemoveAll()
+
+			// Sort field names so files come out in a stable order
+			fileFields := make([]string, 0, len(form.File))
+			for field := range form.File {
+				fileFields = append(fileFields, field)
+			}
+			sort.Strings(fileFields)
+
+			files := &Array{Elements: []Object{}}
+			for _, field := range fileFields {
+				for _, header := range form.File[field] {
+					file, err := multipartFileToMap(field, header, memoryLimit, scope)
+					if err != nil {
+						return err
+					}
+					files.Elements = append(files.Elements, file)
+				}
+			}
+
+			result := &Map{Pairs: make(map[string]Object)}
+			result.Pairs["fields"] = valuesToMap(form.Value)
+			result.Pairs["files"] = files
+			cached = result
+
+			return result
+		},
+	}
+}
+
+// multipartFileToMap converts an uploaded file to a TotalScript map.
+func multipartFileToMap(field string, header *multipart.FileHeader, memoryLimit int64, scope *requestScope) (*Map, *Error) {
+	file := &Map{Pairs: make(map[string]Object)}
+	file.Pairs["name"] = &String{Value: field}
+	file.Pairs["filename"] = &String{Value: header.Filename}
+	file.Pairs["contentType"] = &String{Value: header.Header.Get("Content-Type")}
+	file.Pairs["size"] = &Integer{Value: header.Size}
+
+	src, err := header.Open()
+	if err != nil {
+		return nil, &Error{Message: "failed to open uploaded file: " + err.Error()}
+	}
+	//nolint:errcheck
+	defer src.Close()
+
+	if header.Size <= memoryLimit {
+		content, err := io.ReadAll(src)
+		if err != nil {
+			return nil, &Error{Message: "failed to read uploaded file: " + err.Error()}
+		}
+		file.Pairs["content"] = &String{Value: string(content)}
+		return file, nil
+	}
+
+	dst, err := os.CreateTemp("", "tsl-upload-*")
+	if err != nil {
+		return nil, &Error{Message: "failed to create temp file: " + err.Error()}
+	}
+	scope.tempFiles = append(scope.tempFiles, dst.Name())
+
+	if _, err := io.Copy(dst, src); err != nil {
+		_ = dst.Close()
+		return nil, &Error{Message: "failed to store uploaded file: " + err.Error()}
+	}
+	if err := dst.Close(); err != nil {
+		return nil, &Error{Message: "failed to store uploaded file: " + err.Error()}
+	}
+
+	file.Pairs["path"] = &String{Value: dst.Name()}
+	return file, nil
+}
@@ -0,0 +1,696 @@
+package interpreter
+
+import (
+	"bytes"
+	"compress/gzip"
+	"container/list"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Defaults of the built-in middleware.
+const (
+	defaultRequestIDHeader = "X-Request-ID"
+	defaultGzipMinSize     = 1024
+	maxRequestIDLength     = 128
+	maxRateLimitBuckets    = 10000
+)
+
+// createMiddlewareNamespace creates the http.middleware namespace. Each entry is
+// a factory returning native middleware for server.use():
+//
+//	server.use(http.middleware.logging())
+//	server.use(http.middleware.cors({"origins": ["https://example.com"]}))
+func createMiddlewareNamespace() *Map {
+	namespace := &Map{Pairs: make(map[string]Object)}
+	namespace.Pairs["logging"] = createLoggingMiddleware()
+	namespace.Pairs["cors"] = createCORSMiddleware()
+	namespace.Pairs["recovery"] = createRecoveryMiddleware()
+	namespace.Pairs["rateLimit"] = createRateLimitMiddleware()
+	namespace.Pairs["gzip"] = createGzipMiddleware()
+	namespace.Pairs["requestId"] = createRequestIDMiddleware()
+	return namespace
+}
+
+// nativeMiddleware wraps fn as middleware taking (req, next).
+func nativeMiddleware(name string, fn func(request *ModelInstance, next Object) Object) *Builtin {
+	return &Builtin{
+		Name: name,
+		Fn: func(args ...Object) Object {
+			if len(args) != 2 {
+				return newError("%s middleware requires 2 arguments (req, next)", name)
+			}
+			request, ok := isRequest(args[0])
+			if !ok {
+				return newError("%s middleware: request must be http.Request, got %s", name, args[0].Type())
+			}
+			return fn(request, args[1])
+		},
+	}
+}
+
+// middlewareOptions returns the optional options map of a middleware factory,
+// rejecting keys that are not allowed.
+func middlewareOptions(name string, args []Object, allowed ...string) (*Map, *Error) {
+	if len(args) > 1 {
+		return nil, newError("%s() takes 0-1 arguments (options), got %d", name, len(args))
+	}
+	if len(args) == 0 {
+		return &Map{Pairs: make(map[string]Object)}, nil
+	}
+
+	options, ok := args[0].(*Map)
+	if !ok {
+		return nil, newError("%s() options must be map, got %s", name, args[0].Type())
+	}
+	for key := range options.Pairs {
+		known := false
+		for _, a := range allowed {
+			if key == a {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return nil, newError("%s() unknown option '%s'", name, key)
+		}
+	}
+	return options, nil
+}
+
+// createLoggingMiddleware creates http.middleware.logging(options?), which logs
+// one line per request: "GET /users 200 1.204ms". The "logger" option receives
+// each line instead of the standard logger.
+func createLoggingMiddleware() *Builtin {
+	return &Builtin{
+		Name: "logging",
+		Fn: func(args ...Object) Object {
+			options, err := middlewareOptions("logging", args, "logger")
+			if err != nil {
+				return err
+			}
+
+			logger, hasLogger := options.Pairs["logger"]
+			if hasLogger {
+				switch logger.(type) {
+				case *Function, *Builtin:
+				default:
+					return newError("logging() option 'logger' must be function, got %s", logger.Type())
+				}
+			}
+
+			return nativeMiddleware("logging", func(request *ModelInstance, next Object) Object {
+				start := time.Now()
+				result := callTSFunction(next, request)
+				elapsed := time.Since(start)
+
+				line := fmt.Sprintf("%s %s %d %s", valueToString(request.Fields["method"]),
+					valueToString(request.Fields["path"]), resultStatus(result), elapsed.Round(time.Microsecond))
+				if id := requestHeader(request, defaultRequestIDHeader); id != "" {
+					line += " id=" + id
+				}
+
+				if hasLogger {
+					if logErr := callTSFunction(logger, &String{Value: line}); IsError(logErr) {
+						return logErr
+					}
+				} else {
+					log.Println(line)
+				}
+
+				return result
+			})
+		},
+	}
+}
+
+// resultStatus returns the status code a middleware chain result is answered with.
+func resultStatus(result Object) int64 {
+	if err, ok := result.(*Error); ok {
+		if err.Fields != nil {
+			return http.StatusBadRequest
+		}
+		return http.StatusInternalServerError
+	}
+	if response, ok := isResponse(result); ok {
+		if status, ok := response.Fields["status"].(*Integer); ok {
+			return status.Value
+		}
+	}
+	return http.StatusInternalServerError
+}
+
+// corsConfig holds the options of http.middleware.cors().
+type corsConfig struct {
+	origins     []string
+	methods     []string
+	headers     []string // allowed request headers; empty echoes the preflight request
+	credentials bool
+	maxAge      int64
+}
+
+// createCORSMiddleware creates http.middleware.cors(options?).
+//
+// Options:
+//   - origins (array<string>): allowed origins, defaults to ["*"]
+//   - methods (array<string>): allowed methods, defaults to all route methods
+//   - headers (array<string>): allowed request headers, defaults to the requested ones
+//   - credentials (boolean): allow cookies and authorization headers; requires
+//     an explicit origins list
+//   - maxAge (integer): seconds browsers may cache preflight responses
+//
+//nolint:gocognit
+func createCORSMiddleware() *Builtin {
+	return &Builtin{
+		Name: "cors",
+		Fn: func(args ...Object) Object {
+			options, err := middlewareOptions("cors", args, "origins", "methods", "headers", "credentials", "maxAge")
+			if err != nil {
+				return err
+			}
+
+			config := &corsConfig{
+				origins: []string{"*"},
+				methods: []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+			}
+			for key, value := range options.Pairs {
+				switch key {
+				case "origins", "methods", "headers":
+					list, listErr := stringList("cors", key, value)
+					if listErr != nil {
+						return listErr
+					}
+					switch key {
+					case "origins":
+						config.origins = list
+					case "methods":
+						config.methods = list
+					default:
+						config.headers = list
+					}
+				case "credentials":
+					b, ok := value.(*Boolean)
+					if !ok {
+						return newError("cors() option 'credentials' must be boolean, got %s", value.Type())
+					}
+					config.credentials = b.Value
+				case "maxAge":
+					n, ok := value.(*Integer)
+					if !ok || n.Value < 0 {
+						return newError("cors() option 'maxAge' must be a non-negative integer")
+					}
+					config.maxAge = n.Value
+				}
+			}
+			if config.credentials && slices.Contains(config.origins, "*") {
+				return newError("cors() option 'credentials' requires an explicit 'origins' list without \"*\"")
+			}
+
+			return nativeMiddleware("cors", func(request *ModelInstance, next Object) Object {
+				return runCORSMiddleware(config, request, next)
+			})
+		},
+	}
+}
+
+// runCORSMiddleware answers preflight requests and adds CORS headers to
+// responses for allowed origins.
+func runCORSMiddleware(config *corsConfig, request *ModelInstance, next Object) Object {
+	origin := requestHeader(request, "Origin")
+	if origin == "" {
+		return callTSFunction(next, request)
+	}
+
+	allowOrigin := config.allowOrigin(origin)
+	if allowOrigin == "" {
+		// Not allowed: answer without CORS headers so the browser blocks the response
+		return callTSFunction(next, request)
+	}
+
+	headers := &Map{Pairs: make(map[string]Object)}
+	headers.Pairs["Access-Control-Allow-Origin"] = &String{Value: allowOrigin}
+	if allowOrigin != "*" {
+		headers.Pairs["Vary"] = &String{Value: "Origin"}
+	}
+	if config.credentials {
+		headers.Pairs["Access-Control-Allow-Credentials"] = &String{Value: stringTrue}
+	}
+
+	// Preflight request
+	method := valueToString(request.Fields["method"])
+	requestedMethod := requestHeader(request, "Access-Control-Request-Method")
+	if method == http.MethodOptions && requestedMethod != "" {
+		headers.Pairs["Access-Control-Allow-Methods"] = &String{Value: strings.Join(config.methods, ", ")}
+		allowHeaders := strings.Join(config.headers, ", ")
+		if len(config.headers) == 0 {
+			allowHeaders = requestHeader(request, "Access-Control-Request-Headers")
+		}
+		if allowHeaders != "" {
+			headers.Pairs["Access-Control-Allow-Headers"] = &String{Value: allowHeaders}
+		}
+		if config.maxAge > 0 {
+			headers.Pairs["Access-Control-Max-Age"] = &String{Value: strconv.FormatInt(config.maxAge, 10)}
+		}
+		return newResponse(http.StatusNoContent, &String{Value: ""}, headers)
+	}
+
+	result := callTSFunction(next, request)
+	for name, value := range headers.Pairs {
+		if name == "Vary" {
+			appendResponseHeader(result, name, value)
+		} else {
+			setResponseHeader(result, name, value)
+		}
+	}
+	return result
+}
+
+// allowOrigin returns the Access-Control-Allow-Origin value for origin, or ""
+// when the origin is not allowed.
+func (c *corsConfig) allowOrigin(origin string) string {
+	for _, allowed := range c.origins {
+		if allowed == "*" {
+			return "*"
+		}
+		if strings.EqualFold(allowed, origin) {
+			return origin
+		}
+	}
+	return ""
+}
+
+// createRecoveryMiddleware creates http.middleware.recovery(options?). Panics and
+// errors returned by later middleware or the handler are logged and answered
+// with a JSON 500 response: {"error": "internal server error"}. With the
+// "expose" option the error message is sent instead. Binding errors are passed
+// through so they are still answered with 400.
+func createRecoveryMiddleware() *Builtin {
+	return &Builtin{
+		Name: "recovery",
+		Fn: func(args ...Object) Object {
+			options, err := middlewareOptions("recovery", args, "expose")
+			if err != nil {
+				return err
+			}
+
+			expose := false
+			if value, ok := options.Pairs["expose"]; ok {
+				b, ok := value.(*Boolean)
+				if !ok {
+					return newError("recovery() option 'expose' must be boolean, got %s", value.Type())
+				}
+				expose = b.Value
+			}
+
+			return nativeMiddleware("recovery", func(request *ModelInstance, next Object) (result Object) {
+				defer func() {
+					if r := recover(); r != nil {
+						result = recoveredResponse(fmt.Sprintf("panic: %v", r), expose)
+					}
+				}()
+
+				result = callTSFunction(next, request)
+				if errObj, ok := result.(*Error); ok && errObj.Fields == nil {
+					return recoveredResponse(errObj.Message, expose)
+				}
+				return result
+			})
+		},
+	}
+}
+
+// recoveredResponse logs message and creates the 500 response of the recovery middleware.
+func recoveredResponse(message string, expose bool) *ModelInstance {
+	log.Printf("recovered: %s", message)
+
+	body := &Map{Pairs: make(map[string]Object)}
+	body.Pairs["error"] = &String{Value: "internal server error"}
+	if expose {
+		body.Pairs["error"] = &String{Value: message}
+	}
+	return newResponse(http.StatusInternalServerError, body, &Map{Pairs: make(map[string]Object)})
+}
+
+// rateLimiter is a set of token buckets, one per client key. At most
+// maxRateLimitBuckets are kept; the least recently used one is evicted first.
+type rateLimiter struct {
+	mu      sync.Mutex
+	rate    float64 // tokens added per second
+	burst   float64 // bucket capacity
+	buckets map[string]*list.Element
+	recent  list.List // *tokenBucket values, most recently used first
+	now     func() time.Time
+}
+
+// tokenBucket holds the tokens left for one client.
+type tokenBucket struct {
+	key    string
+	tokens float64
+	last   time.Time
+}
+
+// allow takes a token from the bucket of key. If none is left it returns false
+// and the time until the next token is available.
+func (l *rateLimiter) allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	var bucket *tokenBucket
+	if elem, ok := l.buckets[key]; ok {
+		l.recent.MoveToFront(elem)
+		bucket, _ = elem.Value.(*tokenBucket)
+	} else {
+		if len(l.buckets) >= maxRateLimitBuckets {
+			oldest := l.recent.Back()
+			l.recent.Remove(oldest)
+			if evicted, ok := oldest.Value.(*tokenBucket); ok {
+				delete(l.buckets, evicted.key)
+			}
+		}
+		bucket = &tokenBucket{key: key, tokens: l.burst, last: now}
+		l.buckets[key] = l.recent.PushFront(bucket)
+	}
+
+	bucket.tokens = math.Min(l.burst, bucket.tokens+now.Sub(bucket.last).Seconds()*l.rate)
+	bucket.last = now
+
+	if bucket.tokens < 1 {
+		wait := time.Duration((1 - bucket.tokens) / l.rate * float64(time.Second))
+		return false, wait
+	}
+	bucket.tokens--
+	return true, 0
+}
+
+// createRateLimitMiddleware creates http.middleware.rateLimit(options), a token
+// bucket limiter answering 429 with a Retry-After header.
+//
+// Options:
+//   - rate (number, required): requests per second allowed on average
+//   - burst (integer): requests allowed at once, defaults to rate rounded up
+//   - header (string): key clients by this request header instead of by IP
+func createRateLimitMiddleware() *Builtin {
+	return &Builtin{
+		Name: "rateLimit",
+		Fn: func(args ...Object) Object {
+			if len(args) != 1 {
+				return newError("rateLimit() takes 1 argument (options), got %d", len(args))
+			}
+			options, err := middlewareOptions("rateLimit", args, "rate", "burst", "header")
+			if err != nil {
+				return err
+			}
+
+			var rate float64
+			switch v := options.Pairs["rate"].(type) {
+			case *Integer:
+				rate = float64(v.Value)
+			case *Float:
+				rate = v.Value
+			}
+			if rate <= 0 {
+				return newError("rateLimit() requires a positive number 'rate' option")
+			}
+
+			burst := math.Max(1, math.Ceil(rate))
+			if value, ok := options.Pairs["burst"]; ok {
+				n, ok := value.(*Integer)
+				if !ok || n.Value < 1 {
+					return newError("rateLimit() option 'burst' must be a positive integer")
+				}
+				burst = float64(n.Value)
+			}
+
+			keyHeader := ""
+			if value, ok := options.Pairs["header"]; ok {
+				str, ok := value.(*String)
+				if !ok {
+					return newError("rateLimit() option 'header' must be string, got %s", value.Type())
+				}
+				keyHeader = str.Value
+			}
+
+			limiter := &rateLimiter{
+				rate:    rate,
+				burst:   burst,
+				buckets: make(map[string]*list.Element),
+				now:     time.Now,
+			}
+
+			return nativeMiddleware("rateLimit", func(request *ModelInstance, next Object) Object {
+				key := valueToString(request.Fields["ip"])
+				if keyHeader != "" {
+					if value := requestHeader(request, keyHeader); value != "" {
+						key = "header:" + value
+					}
+				}
+
+				allowed, wait := limiter.allow(key)
+				if !allowed {
+					retryAfter := int64(math.Ceil(wait.Seconds()))
+					headers := &Map{Pairs: make(map[string]Object)}
+					headers.Pairs["Retry-After"] = &String{Value: strconv.FormatInt(retryAfter, 10)}
+					return newResponse(http.StatusTooManyRequests, &String{Value: "429 Too Many Requests"}, headers)
+				}
+
+				return callTSFunction(next, request)
+			})
+		},
+	}
+}
+
+// createGzipMiddleware creates http.middleware.gzip(options?), which compresses
+// response bodies for clients accepting gzip. Streaming responses, static
+// files and proxied responses are sent unchanged.
+//
+// Options:
+//   - minSize (integer): smallest body in bytes worth compressing, defaults to 1024
+//   - level (integer): compression level from 1 (fastest) to 9 (smallest)
+//
+//nolint:gocognit
+func createGzipMiddleware() *Builtin {
+	return &Builtin{
+		Name: "gzip",
+		Fn: func(args ...Object) Object {
+			options, err := middlewareOptions("gzip", args, "minSize", "level")
+			if err != nil {
+				return err
+			}
+
+			minSize := int64(defaultGzipMinSize)
+			level := gzip.DefaultCompression
+			if value, ok := options.Pairs["minSize"]; ok {
+				n, ok := value.(*Integer)
+				if !ok || n.Value < 0 {
+					return newError("gzip() option 'minSize' must be a non-negative integer")
+				}
+				minSize = n.Value
+			}
+			if value, ok := options.Pairs["level"]; ok {
+				n, ok := value.(*Integer)
+				if !ok || n.Value < gzip.BestSpeed || n.Value > gzip.BestCompression {
+					return newError("gzip() option 'level' must be an integer from 1 to 9")
+				}
+				level = int(n.Value)
+			}
+
+			return nativeMiddleware("gzip", func(request *ModelInstance, next Object) Object {
+				result := callTSFunction(next, request)
+				if !acceptsGzip(request) {
+					return result
+				}
+				response, ok := isResponse(result)
+				if !ok {
+					return result
+				}
+				if errObj := compressResponse(response, minSize, level); errObj != nil {
+					return errObj
+				}
+				return response
+			})
+		},
+	}
+}
+
+// acceptsGzip reports whether the Accept-Encoding header of request allows gzip.
+func acceptsGzip(request *ModelInstance) bool {
+	for _, part := range strings.Split(requestHeader(request, "Accept-Encoding"), ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if !strings.EqualFold(strings.TrimSpace(coding), "gzip") {
+			continue
+		}
+		if q, found := strings.CutPrefix(strings.TrimSpace(params), "q="); found {
+			if weight, err := strconv.ParseFloat(q, 64); err == nil && weight == 0 {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// compressResponse replaces the body of response with its gzip encoding.
+// Bodies that are small, already encoded or streamed are left as they are.
+func compressResponse(response *ModelInstance, minSize int64, level int) *Error {
+	body := response.Fields["body"]
+	switch body.(type) {
+	case *StreamResponse, *WebSocketUpgrade, *StaticFile, *ProxyResponse:
+		return nil
+	}
+
+	headers, ok := response.Fields["headers"].(*Map)
+	if !ok {
+		headers = &Map{Pairs: make(map[string]Object)}
+		response.Fields["headers"] = headers
+	}
+	if responseHeader(headers, "Content-Encoding") != nil {
+		return nil
+	}
+
+	raw, contentType, err := encodeResponseBody(body)
+	if err != nil {
+		return &Error{Message: "error marshaling response: " + err.Error()}
+	}
+	if len(raw) == 0 || int64(len(raw)) < minSize {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	writer, err := gzip.NewWriterLevel(&buf, level)
+	if err != nil {
+		return &Error{Message: "gzip: " + err.Error()}
+	}
+	if _, err := writer.Write(raw); err != nil {
+		return &Error{Message: "gzip: " + err.Error()}
+	}
+	if err := writer.Close(); err != nil {
+		return &Error{Message: "gzip: " + err.Error()}
+	}
+
+	// The compressed body would be sniffed as gzip, so keep the original type
+	if responseHeader(headers, "Content-Type") == nil {
+		if contentType == "" {
+			contentType = http.DetectContentType(raw)
+		}
+		headers.Pairs["Content-Type"] = &String{Value: contentType}
+	}
+	headers.Pairs["Content-Encoding"] = &String{Value: "gzip"}
+	appendResponseHeader(response, "Vary", &String{Value: "Accept-Encoding"})
+
+	response.Fields["body"] = &String{Value: buf.String()}
+	return nil
+}
+
+// createRequestIDMiddleware creates http.middleware.requestId(options?). It keeps
+// the request ID sent by the client or generates one, makes it available as
+// req.headers["X-Request-Id"] and echoes it in the response. The "header"
+// option changes the header name.
+func createRequestIDMiddleware() *Builtin {
+	return &Builtin{
+		Name: "requestId",
+		Fn: func(args ...Object) Object {
+			options, err := middlewareOptions("requestId", args, "header")
+			if err != nil {
+				return err
+			}
+
+			header := defaultRequestIDHeader
+			if value, ok := options.Pairs["header"]; ok {
+				str, ok := value.(*String)
+				if !ok || str.Value == "" {
+					return newError("requestId() option 'header' must be a non-empty string")
+				}
+				header = str.Value
+			}
+
+			return nativeMiddleware("requestId", func(request *ModelInstance, next Object) Object {
+				id := requestHeader(request, header)
+				if id == "" || len(id) > maxRequestIDLength {
+					id = uuid.New().String()
+				}
+
+				if headers, ok := request.Fields["headers"].(*Map); ok {
+					headers.Pairs[http.CanonicalHeaderKey(header)] = &Array{Elements: []Object{&String{Value: id}}}
+				}
+
+				result := callTSFunction(next, request)
+				setResponseHeader(result, header, &String{Value: id})
+				return result
+			})
+		},
+	}
+}
+
+// requestHeader returns the first value of a request header.
+func requestHeader(request *ModelInstance, name string) string {
+	headers, ok := request.Fields["headers"].(*Map)
+	if !ok {
+		return ""
+	}
+	switch v := headers.Pairs[http.CanonicalHeaderKey(name)].(type) {
+	case *Array:
+		if len(v.Elements) > 0 {
+			return valueToString(v.Elements[0])
+		}
+	case *String:
+		return v.Value
+	}
+	return ""
+}
+
+// responseHeader looks up a response header by case-insensitive name.
+func responseHeader(headers *Map, name string) Object {
+	for key, value := range headers.Pairs {
+		if strings.EqualFold(key, name) {
+			return value
+		}
+	}
+	return nil
+}
+
+// setResponseHeader replaces a header of a Response result.
+func setResponseHeader(result Object, name string, value Object) {
+	response, ok := isResponse(result)
+	if !ok {
+		return
+	}
+
+	headers, ok := response.Fields["headers"].(*Map)
+	if !ok {
+		headers = &Map{Pairs: make(map[string]Object)}
+		response.Fields["headers"] = headers
+	}
+	for key := range headers.Pairs {
+		if strings.EqualFold(key, name) {
+			delete(headers.Pairs, key)
+		}
+	}
+	headers.Pairs[name] = value
+}
+
+// stringList converts an array of strings passed as a middleware option.
+func stringList(name, key string, value Object) ([]string, *Error) {
+	arr, ok := value.(*Array)
+	if !ok {
+		return nil, newError("%s() option '%s' must be array of strings, got %s", name, key, value.Type())
+	}
+	list := make([]string, len(arr.Elements))
+	for i, elem := range arr.Elements {
+		str, ok := elem.(*String)
+		if !ok {
+			return nil, newError("%s() option '%s' must be array of strings, got %s element", name, key, elem.Type())
+		}
+		list[i] = str.Value
+	}
+	return list, nil
+}
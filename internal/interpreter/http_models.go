@@ -0,0 +1,191 @@
+package interpreter
+
+import (
+	"encoding/json"
+
+	"github.com/mishankov/totalscript-lang/internal/ast"
+)
+
+// The http.Request and http.Response models are shared by every http module
+// instance, so values created by one server pass type checks everywhere.
+//
+//nolint:gochecknoglobals
+var (
+	httpRequestModel  = newRequestModel()
+	httpResponseModel = newResponseModel()
+)
+
+// newBuiltinModel creates a model from field names and their type expressions.
+func newBuiltinModel(name string, fieldNames []string, fields map[string]*ast.TypeExpression) *Model {
+	return &Model{
+		Name:         name,
+		FieldNames:   fieldNames,
+		Fields:       fields,
+		Annotations:  make(map[string][]string),
+		Methods:      make(map[string]*Function),
+		Constructors: []*Function{},
+	}
+}
+
+// newRequestModel creates the http.Request model. Request instances also have
+// the native methods json(), form(), multipart() and bind(Model).
+func newRequestModel() *Model {
+	return newBuiltinModel("Request",
+		[]string{"method", "path", "params", "query", "headers", "cookies", "ip", "body", "session", "data", "user"},
+		map[string]*ast.TypeExpression{
+			"method":  {Name: typeNameString},
+			"path":    {Name: typeNameString},
+			"params":  {Name: typeNameMap, Generic: []string{typeNameString, typeNameString}},
+			"query":   {Name: typeNameMap, Generic: []string{typeNameString, typeNameArray}},
+			"headers": {Name: typeNameMap, Generic: []string{typeNameString, typeNameArray}},
+			"cookies": {Name: typeNameMap, Generic: []string{typeNameString, typeNameString}},
+			"ip":      {Name: typeNameString},
+			"body":    {Name: typeNameString},
+			"session": {Name: typeNameMap, Optional: true}, // set by http.session()
+			"data":    nil,                                 // set by the {"body": Model} route option
+			"user":    nil,                                 // set by http.auth middleware
+		})
+}
+
+// newResponseModel creates the http.Response model. Its constructor accepts
+// Response(status), Response(status, body) and Response(status, body, headers).
+// Response instances have a native json() method.
+func newResponseModel() *Model {
+	model := newBuiltinModel("Response",
+		[]string{"status", "body", "headers", "ok"},
+		map[string]*ast.TypeExpression{
+			"status":  {Name: typeNameInteger},
+			"body":    nil,
+			"headers": {Name: typeNameMap},
+			"ok":      {Name: typeNameBoolean},
+		})
+	model.NativeConstructor = func(args ...Object) Object {
+		return responseConstructor(model, args...)
+	}
+	model.StaticMethods = map[string]*Builtin{
+		"html": createResponseHTMLMethod(model),
+	}
+	return model
+}
+
+// createResponseHTMLMethod creates http.Response.html(status, template, data?),
+// which renders a template file with the template module's configuration.
+func createResponseHTMLMethod(model *Model) *Builtin {
+	return &Builtin{
+		Name: "html",
+		Fn: func(args ...Object) Object {
+			if len(args) < 2 || len(args) > 3 {
+				return newError("html() takes 2-3 arguments (status, template, data?), got %d", len(args))
+			}
+
+			status, ok := args[0].(*Integer)
+			if !ok {
+				return newError("html() status must be integer, got %s", args[0].Type())
+			}
+
+			name, data, errObj := templateArgs("html", args[1:])
+			if errObj != nil {
+				return errObj
+			}
+
+			out, err := defaultTemplates.render(name, data)
+			if err != nil {
+				return &Error{Message: "template error: " + err.Error()}
+			}
+
+			headers := &Map{Pairs: make(map[string]Object)}
+			headers.Pairs["Content-Type"] = &String{Value: "text/html; charset=utf-8"}
+			return createResponseInstance(model, status.Value, &String{Value: out}, headers)
+		},
+	}
+}
+
+// responseConstructor implements http.Response(status, body?, headers?).
+func responseConstructor(model *Model, args ...Object) Object {
+	if len(args) < 1 || len(args) > 3 {
+		return newError("Response() takes 1-3 arguments, got %d", len(args))
+	}
+
+	// Get status (required)
+	status, ok := args[0].(*Integer)
+	if !ok {
+		return newError("Response() status must be integer, got %s", args[0].Type())
+	}
+
+	// Get body (optional)
+	var body Object = &String{Value: ""}
+	if len(args) >= 2 {
+		body = args[1]
+	}
+
+	// Get headers (optional)
+	headers := &Map{Pairs: make(map[string]Object)}
+	if len(args) >= 3 {
+		headers, ok = args[2].(*Map)
+		if !ok {
+			return newError("Response() headers must be map, got %s", args[2].Type())
+		}
+	}
+
+	return createResponseInstance(model, status.Value, body, headers)
+}
+
+// newResponse creates an http.Response instance.
+func newResponse(status int64, body Object, headers *Map) *ModelInstance {
+	return createResponseInstance(httpResponseModel, status, body, headers)
+}
+
+// createResponseInstance creates an instance of the Response model.
+func createResponseInstance(model *Model, status int64, body Object, headers *Map) *ModelInstance {
+	response := &ModelInstance{
+		Model:   model,
+		Fields:  make(map[string]Object),
+		Methods: make(map[string]*Builtin),
+	}
+	response.Fields["status"] = &Integer{Value: status}
+	response.Fields["body"] = body
+	response.Fields["headers"] = headers
+	response.Fields["ok"] = nativeBoolToBooleanObject(status >= 200 && status < 300)
+
+	// Add json() method to parse body as JSON
+	response.Methods["json"] = &Builtin{
+		Name: "json",
+		Fn: func(args ...Object) Object {
+			if len(args) != 0 {
+				return newError("json() takes no arguments")
+			}
+
+			bodyStr, ok := response.Fields["body"].(*String)
+			if !ok {
+				return newError("cannot parse non-string body as JSON")
+			}
+
+			var data interface{}
+			if err := json.Unmarshal([]byte(bodyStr.Value), &data); err != nil {
+				return &Error{Message: "invalid JSON: " + err.Error()}
+			}
+
+			return convertJSONToObject(data)
+		},
+	}
+
+	return response
+}
+
+// isRequest reports whether obj is an http.Request instance.
+func isRequest(obj Object) (*ModelInstance, bool) {
+	instance, ok := obj.(*ModelInstance)
+	if !ok || instance.Model != httpRequestModel {
+		return nil, false
+	}
+	return instance, true
+}
+
+// isResponse reports whether obj is an http.Response instance.
+func isResponse(obj Object) (*ModelInstance, bool) {
+	instance, ok := obj.(*ModelInstance)
+	if !ok || instance.Model != httpResponseModel {
+		return nil, false
+	}
+	return instance, true
+}
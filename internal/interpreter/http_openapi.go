@@ -0,0 +1,499 @@
+package interpreter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/mishankov/totalscript-lang/internal/ast"
+)
+
+// Defaults for the info section of generated OpenAPI documents.
+const (
+	defaultOpenAPITitle   = "TotalScript API"
+	defaultOpenAPIVersion = "1.0.0"
+)
+
+// openAPIConfig holds the options of server.openapi().
+type openAPIConfig struct {
+	title   string
+	version string
+	path    string // route serving the document; empty when not served
+}
+
+// serverStartHook replaces the listening part of server.start() while
+// GenerateOpenAPI evaluates a program.
+//
+//nolint:gochecknoglobals // set only for the duration of GenerateOpenAPI
+var serverStartHook func(state *httpServerState) Object
+
+// GenerateOpenAPI evaluates program without starting any server and returns
+// the OpenAPI document of the first server whose start() method was called.
+func GenerateOpenAPI(program *ast.Program, env *Environment) ([]byte, error) {
+	var started *httpServerState
+	serverStartHook = func(state *httpServerState) Object {
+		if started == nil {
+			started = state
+		}
+		return NULL
+	}
+	defer func() { serverStartHook = nil }()
+
+	if result := Eval(program, env); result != nil && result.Type() == ErrorObj {
+		return nil, errors.New(result.Inspect())
+	}
+	if started == nil {
+		return nil, errors.New("no server was started (call server.start(port))")
+	}
+
+	return json.MarshalIndent(buildOpenAPIDocument(started), "", "  ")
+}
+
+// createOpenAPIMethod creates the server.openapi(options?) method. It returns
+// the OpenAPI document of the server as a map; the "path" option also serves
+// it as JSON:
+//
+//	server.openapi({"title": "Users API", "version": "2.0.0", "path": "/openapi.json"})
+func createOpenAPIMethod(state *httpServerState) *Builtin {
+	return &Builtin{
+		Name: "openapi",
+		Fn: func(args ...Object) Object {
+			if len(args) > 1 {
+				return newError("openapi() takes 0-1 arguments (options), got %d", len(args))
+			}
+
+			config := &openAPIConfig{title: defaultOpenAPITitle, version: defaultOpenAPIVersion}
+			if len(args) == 1 {
+				options, ok := args[0].(*Map)
+				if !ok {
+					return newError("openapi() options must be map, got %s", args[0].Type())
+				}
+				for key, value := range options.Pairs {
+					str, ok := value.(*String)
+					if !ok {
+						return newError("openapi() option '%s' must be string, got %s", key, value.Type())
+					}
+					switch key {
+					case "title":
+						config.title = str.Value
+					case "version":
+						config.version = str.Value
+					case "path":
+						config.path = str.Value
+					default:
+						return newError("openapi() unknown option '%s'", key)
+					}
+				}
+			}
+			state.openapi = config
+
+			if config.path != "" {
+				if state.routes["GET"] == nil {
+					state.routes["GET"] = make(map[string]Object)
+				}
+				// The document is built per request so later routes are included
+				state.routes["GET"][config.path] = &Builtin{
+					Name: "openapi",
+					Fn: func(_ ...Object) Object {
+						return newResponse(200, openAPIObject(state), &Map{Pairs: make(map[string]Object)})
+					},
+				}
+			}
+
+			return openAPIObject(state)
+		},
+	}
+}
+
+// openAPIObject returns the OpenAPI document of a server as a TotalScript map.
+func openAPIObject(state *httpServerState) Object {
+	data, err := json.Marshal(buildOpenAPIDocument(state))
+	if err != nil {
+		return &Error{Message: "failed to build OpenAPI document: " + err.Error()}
+	}
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return &Error{Message: "failed to build OpenAPI document: " + err.Error()}
+	}
+	return convertJSONToObject(doc)
+}
+
+// openAPIGenerator collects the component schemas of models referenced by routes.
+type openAPIGenerator struct {
+	schemas map[string]interface{}
+	names   map[*Model]string // schema name of each model; models may share a name
+
+	// validationRef references the schema of 400 binding errors. Its name is
+	// chosen once all models are registered so it cannot replace one of them.
+	validationRef map[string]interface{}
+}
+
+// buildOpenAPIDocument describes the routes of a server as an OpenAPI 3 document.
+// WebSocket routes, static paths and the route serving the document are left out.
+func buildOpenAPIDocument(state *httpServerState) map[string]interface{} {
+	config := state.openapi
+	if config == nil {
+		config = &openAPIConfig{title: defaultOpenAPITitle, version: defaultOpenAPIVersion}
+	}
+
+	gen := &openAPIGenerator{schemas: make(map[string]interface{}), names: make(map[*Model]string)}
+	paths := make(map[string]interface{})
+
+	// Visit routes in order so numbered schema names are stable
+	for _, method := range slices.Sorted(maps.Keys(state.routes)) {
+		if method == websocketMethod {
+			continue
+		}
+		routes := state.routes[method]
+		for _, pattern := range slices.Sorted(maps.Keys(routes)) {
+			handler := routes[pattern]
+			// Wildcards match several segments, which path templates cannot express
+			if method == "GET" && pattern == config.path || isWildcardPattern(pattern) {
+				continue
+			}
+
+			path, params := openAPIPath(pattern)
+			item, ok := paths[path].(map[string]interface{})
+			if !ok {
+				item = make(map[string]interface{})
+				paths[path] = item
+			}
+			item[strings.ToLower(method)] = gen.operation(handler, params, state.routeBodies[method][pattern])
+		}
+	}
+
+	doc := map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":   config.title,
+			"version": config.version,
+		},
+		"paths": paths,
+	}
+	gen.registerValidationError()
+	if len(gen.schemas) > 0 {
+		doc["components"] = map[string]interface{}{"schemas": gen.schemas}
+	}
+
+	return doc
+}
+
+// openAPIPath converts a route pattern to an OpenAPI path template
+// ("/users/:id" becomes "/users/{id}") and returns its parameter names.
+func openAPIPath(pattern string) (string, []string) {
+	segments := strings.Split(pattern, "/")
+	var params []string
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") {
+			name := segment[1:]
+			params = append(params, name)
+			segments[i] = "{" + name + "}"
+		}
+	}
+	return strings.Join(segments, "/"), params
+}
+
+// operation describes a single route. The request body schema comes from the
+// {"body": Model} route option and the response schema from the handler's
+// return type. Type names are resolved in the scope of the handler.
+func (g *openAPIGenerator) operation(handler Object, params []string, bodyModel *Model) map[string]interface{} {
+	op := make(map[string]interface{})
+
+	if len(params) > 0 {
+		parameters := make([]interface{}, len(params))
+		for i, name := range params {
+			parameters[i] = map[string]interface{}{
+				"name":     name,
+				"in":       "path",
+				"required": true,
+				"schema":   map[string]interface{}{"type": "string"},
+			}
+		}
+		op["parameters"] = parameters
+	}
+
+	responses := g.responses(handler)
+
+	if bodyModel != nil {
+		schema := g.modelSchema(bodyModel, handlerEnv(handler))
+		op["requestBody"] = map[string]interface{}{
+			"required": true,
+			"content": map[string]interface{}{
+				"application/json":   map[string]interface{}{"schema": schema},
+				contentTypeForm:      map[string]interface{}{"schema": schema},
+				contentTypeMultipart: map[string]interface{}{"schema": schema},
+			},
+		}
+		responses["400"] = map[string]interface{}{
+			"description": "Invalid request body",
+			"content": map[string]interface{}{
+				"application/json": map[string]interface{}{"schema": g.validationErrorSchema()},
+			},
+		}
+	}
+
+	op["responses"] = responses
+	return op
+}
+
+// responses describes the responses of a handler. A declared return type
+// other than http.Response is the schema of 200 responses; Error members
+// are answered with 500.
+func (g *openAPIGenerator) responses(handler Object) map[string]interface{} {
+	success := map[string]interface{}{"description": "Successful response"}
+	responses := map[string]interface{}{"200": success}
+
+	fn, ok := handler.(*Function)
+	if !ok || !returnsValue(fn) {
+		return responses
+	}
+
+	returnType := fn.ReturnType
+	names := returnType.Union
+	if len(names) == 0 {
+		names = []string{returnType.Name}
+	}
+	var values []string
+	for _, name := range names {
+		model := resolveModel(name, fn.Env)
+		switch {
+		case model == httpResponseModel:
+		case model != nil && model.derivesFrom(ErrorModel):
+			responses["500"] = map[string]interface{}{"description": "Error"}
+		default:
+			values = append(values, name)
+		}
+	}
+
+	var schema map[string]interface{}
+	switch {
+	case len(values) == 0:
+		return responses
+	case len(returnType.Union) == 0:
+		schema = g.typeSchema(returnType, fn.Env)
+	case len(values) == 1:
+		schema = g.typeNameSchema(values[0], fn.Env)
+	default:
+		schema = g.unionSchema(values, fn.Env)
+	}
+
+	// Strings are sent as they are, other values as JSON
+	contentType := "application/json"
+	if len(values) == 1 && values[0] == typeNameString {
+		contentType = "text/plain"
+	}
+	success["content"] = map[string]interface{}{contentType: map[string]interface{}{"schema": schema}}
+	return responses
+}
+
+// resolveModel returns the model a type name refers to in env, if any.
+func resolveModel(name string, env *Environment) *Model {
+	obj, err := lookupType(name, env)
+	if err != nil {
+		return nil
+	}
+	model, _ := obj.(*Model)
+	return model
+}
+
+// returnsValue reports whether a route handler declares a return type other
+// than http.Response, which then describes its successful responses.
+func returnsValue(handler Object) bool {
+	fn, ok := handler.(*Function)
+	if !ok || fn.ReturnType == nil {
+		return false
+	}
+	returnType := fn.ReturnType
+	return len(returnType.Union) > 0 || resolveModel(returnType.Name, fn.Env) != httpResponseModel
+}
+
+// schemaName returns the component schema name of a model. Models with the
+// same name, from different modules, get numbered names.
+func (g *openAPIGenerator) schemaName(model *Model) string {
+	if name, ok := g.names[model]; ok {
+		return name
+	}
+	name := model.Name
+	for i := 2; g.schemas[name] != nil; i++ {
+		name = fmt.Sprintf("%s%d", model.Name, i)
+	}
+	g.names[model] = name
+	return name
+}
+
+// modelSchema registers model as a component schema and returns a reference to it.
+func (g *openAPIGenerator) modelSchema(model *Model, env *Environment) map[string]interface{} {
+	_, registered := g.names[model]
+	name := g.schemaName(model)
+	ref := map[string]interface{}{"$ref": "#/components/schemas/" + name}
+	if registered {
+		return ref
+	}
+
+	properties := make(map[string]interface{})
+	schema := map[string]interface{}{"type": "object", "properties": properties}
+	// Register before descending so recursive models terminate
+	g.schemas[name] = schema
+
+	var required []interface{}
+	for _, name := range model.FieldNames {
+		typeExpr := model.Fields[name]
+		properties[name] = g.typeSchema(typeExpr, env)
+		if typeExpr != nil && !typeExpr.Optional {
+			required = append(required, name)
+		}
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+
+	return ref
+}
+
+// typeSchema converts a type annotation to a JSON schema.
+func (g *openAPIGenerator) typeSchema(typeExpr *ast.TypeExpression, env *Environment) map[string]interface{} {
+	if typeExpr == nil {
+		return map[string]interface{}{}
+	}
+
+	var schema map[string]interface{}
+	switch {
+	case len(typeExpr.Union) > 0:
+		schema = g.unionSchema(typeExpr.Union, env)
+	case typeExpr.Name == typeNameArray:
+		items := map[string]interface{}{}
+		if len(typeExpr.Generic) > 0 {
+			items = g.typeNameSchema(typeExpr.Generic[0], env)
+		}
+		schema = map[string]interface{}{"type": "array", "items": items}
+	case typeExpr.Name == typeNameMap:
+		schema = map[string]interface{}{"type": "object"}
+		if len(typeExpr.Generic) > 1 {
+			schema["additionalProperties"] = g.typeNameSchema(typeExpr.Generic[1], env)
+		}
+	default:
+		schema = g.typeNameSchema(typeExpr.Name, env)
+	}
+
+	if typeExpr.Optional {
+		if _, isRef := schema["$ref"]; isRef {
+			// Siblings of $ref are ignored in OpenAPI 3.0
+			schema = map[string]interface{}{"allOf": []interface{}{schema}}
+		}
+		schema["nullable"] = true
+	}
+
+	return schema
+}
+
+// typeNameSchema converts a single type name, which may be a union written
+// inside a generic ("integer | string"), to a JSON schema.
+func (g *openAPIGenerator) typeNameSchema(name string, env *Environment) map[string]interface{} {
+	if strings.Contains(name, "|") {
+		parts := strings.Split(name, "|")
+		for i := range parts {
+			parts[i] = strings.TrimSpace(parts[i])
+		}
+		return g.unionSchema(parts, env)
+	}
+
+	switch name {
+	case typeNameString:
+		return map[string]interface{}{"type": "string"}
+	case typeNameInteger:
+		return map[string]interface{}{"type": "integer", "format": "int64"}
+	case typeNameFloat:
+		return map[string]interface{}{"type": "number", "format": "double"}
+	case typeNameBoolean:
+		return map[string]interface{}{"type": "boolean"}
+	case typeNameArray:
+		return map[string]interface{}{"type": "array", "items": map[string]interface{}{}}
+	case typeNameMap, typeNameJSON:
+		return map[string]interface{}{"type": "object"}
+	case typeNameNull:
+		return map[string]interface{}{"nullable": true}
+	}
+
+	obj, err := lookupType(name, env)
+	if err != nil {
+		return map[string]interface{}{}
+	}
+	switch t := obj.(type) {
+	case *Model:
+		return g.modelSchema(t, env)
+	case *Enum:
+		return enumSchema(t)
+	}
+	return map[string]interface{}{}
+}
+
+// unionSchema converts union members to a oneOf schema; a null member makes
+// the schema nullable instead.
+func (g *openAPIGenerator) unionSchema(names []string, env *Environment) map[string]interface{} {
+	var oneOf []interface{}
+	nullable := false
+	for _, name := range names {
+		if name == typeNameNull {
+			nullable = true
+			continue
+		}
+		oneOf = append(oneOf, g.typeNameSchema(name, env))
+	}
+
+	schema := map[string]interface{}{"oneOf": oneOf}
+	if nullable {
+		schema["nullable"] = true
+	}
+	return schema
+}
+
+// enumSchema lists the underlying values of an enum.
+func enumSchema(enum *Enum) map[string]interface{} {
+	names := make([]string, 0, len(enum.Values))
+	for name := range enum.Values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	values := make([]interface{}, len(names))
+	for i, name := range names {
+		values[i] = convertObjectToGo(enum.Values[name])
+	}
+	return map[string]interface{}{"enum": values}
+}
+
+// validationErrorSchema returns a reference to the schema of 400 responses to
+// failed bindings. The reference is completed by registerValidationError.
+func (g *openAPIGenerator) validationErrorSchema() map[string]interface{} {
+	if g.validationRef == nil {
+		g.validationRef = make(map[string]interface{})
+	}
+	return g.validationRef
+}
+
+// registerValidationError registers the binding error schema, if referenced,
+// under the first name no model uses: ValidationError, ValidationError2, ...
+func (g *openAPIGenerator) registerValidationError() {
+	if g.validationRef == nil {
+		return
+	}
+	name := "ValidationError"
+	for i := 2; g.schemas[name] != nil; i++ {
+		name = fmt.Sprintf("ValidationError%d", i)
+	}
+	g.validationRef["$ref"] = "#/components/schemas/" + name
+	g.schemas[name] = map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"error": map[string]interface{}{"type": "string"},
+			"fields": map[string]interface{}{
+				"type":                 "object",
+				"additionalProperties": map[string]interface{}{"type": "string"},
+			},
+		},
+	}
+}
@@ -0,0 +1,316 @@
+package interpreter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"net/textproto"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// proxyRequestKey is the context key under which the request to forward is
+// passed to the reverse proxy.
+type proxyRequestKey struct{}
+
+// proxyConfig holds the options of http.proxy().
+type proxyConfig struct {
+	target       *url.URL
+	stripPrefix  string
+	headers      *Map   // headers set on every forwarded request
+	rewrite      Object // function(req) changing the request before it is forwarded
+	response     Object // function(res) changing the status and headers of the upstream response
+	preserveHost bool
+}
+
+// createProxyFunction creates http.proxy(targetUrl, options?). The result is
+// native code usable as a route handler (req) forwarding every request to the
+// target, or as middleware (req, next) forwarding the requests under
+// stripPrefix and passing the others to next:
+//
+//	server.get("/api/*", http.proxy("http://localhost:9000", {"stripPrefix": "/api"}))
+//	server.use(http.proxy("http://localhost:9000", {"stripPrefix": "/api"}))
+//
+// Options:
+//   - stripPrefix (string): remove this prefix from the request path
+//   - headers (map): headers set on forwarded requests
+//   - rewrite (function(req)): change req.path, req.query, req.headers or req.body before forwarding
+//   - response (function(res)): change res.status and res.headers of the upstream response
+//   - preserveHost (boolean): keep the Host header of the incoming request
+//   - ca, cert, key, insecureSkipVerify: TLS settings for HTTPS targets
+//
+//nolint:gocognit,funlen
+func createProxyFunction() *Builtin {
+	return &Builtin{
+		Name: "proxy",
+		Fn: func(args ...Object) Object {
+			if len(args) < 1 || len(args) > 2 {
+				return newError("proxy() takes 1-2 arguments (targetUrl, options?), got %d", len(args))
+			}
+
+			targetStr, ok := args[0].(*String)
+			if !ok {
+				return newError("proxy() targetUrl must be string, got %s", args[0].Type())
+			}
+			target, err := url.Parse(targetStr.Value)
+			if err != nil || target.Scheme == "" || target.Host == "" {
+				return newError("proxy() invalid target URL: %s", targetStr.Value)
+			}
+
+			config := &proxyConfig{target: target}
+			tlsOptions := &Map{Pairs: make(map[string]Object)}
+
+			if len(args) == 2 {
+				options, ok := args[1].(*Map)
+				if !ok {
+					return newError("proxy() options must be map, got %s", args[1].Type())
+				}
+				for key, value := range options.Pairs {
+					switch key {
+					case "stripPrefix":
+						str, ok := value.(*String)
+						if !ok {
+							return newError("proxy() option 'stripPrefix' must be string, got %s", value.Type())
+						}
+						config.stripPrefix = strings.TrimSuffix(str.Value, "/")
+					case "headers":
+						headers, ok := value.(*Map)
+						if !ok {
+							return newError("proxy() option 'headers' must be map, got %s", value.Type())
+						}
+						config.headers = headers
+					case "rewrite", "response":
+						switch value.(type) {
+						case *Function, *Builtin:
+						default:
+							return newError("proxy() option '%s' must be function, got %s", key, value.Type())
+						}
+						if key == "rewrite" {
+							config.rewrite = value
+						} else {
+							config.response = value
+						}
+					case "preserveHost":
+						b, ok := value.(*Boolean)
+						if !ok {
+							return newError("proxy() option 'preserveHost' must be boolean, got %s", value.Type())
+						}
+						config.preserveHost = b.Value
+					default:
+						if !isClientTLSOption(key) {
+							return newError("proxy() unknown option '%s'", key)
+						}
+						tlsOptions.Pairs[key] = value
+					}
+				}
+			}
+
+			tlsConfig, tlsErr := clientTLSConfig(tlsOptions)
+			if tlsErr != nil {
+				return tlsErr
+			}
+			proxy := newReverseProxy(config)
+			proxy.Transport = newClientTransport(tlsConfig)
+
+			return &Builtin{
+				Name: "proxy",
+				Fn: func(args ...Object) Object {
+					// Called as a route handler (req) or as middleware (req, next)
+					if len(args) != 1 && len(args) != 2 {
+						return newError("proxy handler requires 1-2 arguments (req, next?), got %d", len(args))
+					}
+					request, ok := isRequest(args[0])
+					if !ok {
+						return newError("proxy: request must be http.Request, got %s", args[0].Type())
+					}
+					if len(args) == 2 && config.stripPrefix != "" {
+						if _, found := config.strip(valueToString(request.Fields["path"])); !found {
+							return callTSFunction(args[1], request)
+						}
+					}
+					return forwardRequest(config, proxy, request)
+				},
+			}
+		},
+	}
+}
+
+// forwardRequest applies the request hooks and returns the response telling
+// the server to forward the request.
+func forwardRequest(config *proxyConfig, proxy http.Handler, request *ModelInstance) Object {
+	if config.stripPrefix != "" {
+		if path, found := config.strip(valueToString(request.Fields["path"])); found {
+			request.Fields["path"] = &String{Value: path}
+		}
+	}
+
+	if config.headers != nil {
+		headers, ok := request.Fields["headers"].(*Map)
+		if !ok {
+			headers = &Map{Pairs: make(map[string]Object)}
+			request.Fields["headers"] = headers
+		}
+		for name, value := range config.headers.Pairs {
+			if _, isArray := value.(*Array); !isArray {
+				value = &Array{Elements: []Object{value}}
+			}
+			headers.Pairs[http.CanonicalHeaderKey(name)] = value
+		}
+	}
+
+	if config.rewrite != nil {
+		if result := callTSFunction(config.rewrite, request); IsError(result) {
+			return result
+		}
+	}
+
+	return newResponse(http.StatusOK, &ProxyResponse{Proxy: proxy, Request: request}, &Map{Pairs: make(map[string]Object)})
+}
+
+// strip removes stripPrefix from path. It reports false when path is not
+// under the prefix: "/api" strips "/api" and "/api/users" but not "/apis".
+func (c *proxyConfig) strip(path string) (string, bool) {
+	rest, found := strings.CutPrefix(path, c.stripPrefix)
+	if !found || (rest != "" && !strings.HasPrefix(rest, "/")) {
+		return path, false
+	}
+	return "/" + strings.TrimPrefix(rest, "/"), true
+}
+
+// newReverseProxy creates the reverse proxy forwarding requests to the target.
+// The outgoing request is built from the http.Request instance, so changes made
+// by hooks and middleware are forwarded.
+func newReverseProxy(config *proxyConfig) *httputil.ReverseProxy {
+	proxy := &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			pr.SetURL(config.target)
+			pr.SetXForwarded()
+			if config.preserveHost {
+				pr.Out.Host = pr.In.Host
+			}
+
+			request, ok := pr.In.Context().Value(proxyRequestKey{}).(*ModelInstance)
+			if !ok {
+				return
+			}
+			applyProxyRequest(pr.Out, config.target, request)
+		},
+		ErrorHandler: func(w http.ResponseWriter, _ *http.Request, err error) {
+			w.WriteHeader(http.StatusBadGateway)
+			//nolint:errcheck,gosec
+			w.Write([]byte("502 Bad Gateway: " + err.Error()))
+		},
+	}
+
+	if config.response != nil {
+		proxy.ModifyResponse = func(resp *http.Response) error {
+			return applyProxyResponseHook(config.response, resp)
+		}
+	}
+
+	return proxy
+}
+
+// applyProxyRequest sets the method, path, query, headers and body of the
+// outgoing request from an http.Request instance.
+func applyProxyRequest(out *http.Request, target *url.URL, request *ModelInstance) {
+	out.Method = valueToString(request.Fields["method"])
+
+	path := valueToString(request.Fields["path"])
+	out.URL.Path = strings.TrimSuffix(target.Path, "/") + "/" + strings.TrimPrefix(path, "/")
+	out.URL.RawPath = ""
+
+	query := target.Query()
+	if queryMap, ok := request.Fields["query"].(*Map); ok {
+		for key, values := range mapToValues(queryMap) {
+			query[key] = append(query[key], values...)
+		}
+	}
+	out.URL.RawQuery = query.Encode()
+
+	if headersMap, ok := request.Fields["headers"].(*Map); ok {
+		header := make(http.Header)
+		//nolint:errcheck // request headers hold no cookie maps
+		setResponseHeaders(header, headersMap)
+		// Forwarding headers were set by SetXForwarded
+		for _, name := range []string{"X-Forwarded-For", "X-Forwarded-Host", "X-Forwarded-Proto"} {
+			if values := out.Header.Values(name); len(values) > 0 {
+				header[name] = values
+			}
+		}
+		// Hop-by-hop headers were removed from the incoming request already,
+		// including those named in its Connection header (RFC 9110 §7.6.1)
+		for _, value := range header.Values("Connection") {
+			for name := range strings.SplitSeq(value, ",") {
+				if name = textproto.TrimString(name); name != "" {
+					header.Del(name)
+				}
+			}
+		}
+		for _, name := range []string{"Connection", "Keep-Alive", "Proxy-Connection", "Te", "Trailer", "Transfer-Encoding", "Upgrade"} {
+			header.Del(name)
+		}
+		out.Header = header
+	}
+
+	// Unbuffered multipart bodies are streamed to the upstream as received
+	if request.Fields["body"] == unbufferedBody {
+		return
+	}
+
+	body := valueToString(request.Fields["body"])
+	out.Header.Del("Content-Length")
+	out.ContentLength = int64(len(body))
+	out.Body = http.NoBody
+	if body != "" {
+		out.Body = io.NopCloser(strings.NewReader(body))
+	}
+}
+
+// applyProxyResponseHook calls the response hook with the status and headers of
+// an upstream response and applies its changes. The body is streamed and not
+// available to the hook.
+func applyProxyResponseHook(hook Object, resp *http.Response) error {
+	response := newResponse(int64(resp.StatusCode), &String{Value: ""}, valuesToMap(resp.Header))
+
+	if result := callTSFunction(hook, response); IsError(result) {
+		errObj, _ := result.(*Error)
+		return errors.New(errObj.Message)
+	}
+
+	status, ok := response.Fields["status"].(*Integer)
+	if !ok {
+		return fmt.Errorf("response status must be integer, got %s", response.Fields["status"].Type())
+	}
+	resp.StatusCode = int(status.Value)
+	resp.Status = strconv.Itoa(resp.StatusCode) + " " + http.StatusText(resp.StatusCode)
+
+	if headersMap, ok := response.Fields["headers"].(*Map); ok {
+		header := make(http.Header)
+		if err := setResponseHeaders(header, headersMap); err != nil {
+			return errors.New(err.Message)
+		}
+		resp.Header = header
+	}
+	return nil
+}
+
+// writeProxyResponse forwards a request upstream and streams the response.
+// Headers set on the response by middleware are sent as well.
+func writeProxyResponse(w http.ResponseWriter, r *http.Request, response *ModelInstance, forward *ProxyResponse) {
+	if headersMap, ok := response.Fields["headers"].(*Map); ok {
+		if err := setResponseHeaders(w.Header(), headersMap); err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			//nolint:errcheck,gosec
+			w.Write([]byte(err.Message))
+			return
+		}
+	}
+
+	ctx := context.WithValue(r.Context(), proxyRequestKey{}, forward.Request)
+	forward.Proxy.ServeHTTP(w, r.WithContext(ctx))
+}
@@ -0,0 +1,491 @@
+package interpreter
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Session store kinds supported by http.session().
+const (
+	sessionStoreCookie = "cookie"
+	sessionStoreDB     = "db"
+)
+
+// maxSessionCookieSize is the largest Set-Cookie value browsers are required
+// to store (RFC 6265, section 6.1).
+const maxSessionCookieSize = 4096
+
+// sessionModelType is the model_type used for session rows in the db module's table.
+const sessionModelType = "__http_session__"
+
+// createCookieConstructor creates the http.Cookie(name, value, options?) function.
+// The returned map can be used as a value of the "Set-Cookie" response header.
+//
+//nolint:gocognit,gocyclo,funlen
+func createCookieConstructor() *Builtin {
+	return &Builtin{
+		Name: "Cookie",
+		Fn: func(args ...Object) Object {
+			if len(args) < 2 || len(args) > 3 {
+				return newError("Cookie() takes 2-3 arguments, got %d", len(args))
+			}
+
+			name, ok := args[0].(*String)
+			if !ok {
+				return newError("Cookie() name must be string, got %s", args[0].Type())
+			}
+
+			value, ok := args[1].(*String)
+			if !ok {
+				return newError("Cookie() value must be string, got %s", args[1].Type())
+			}
+
+			cookie := &Map{Pairs: make(map[string]Object)}
+			cookie.Pairs["name"] = name
+			cookie.Pairs["value"] = value
+			cookie.Pairs["path"] = &String{Value: "/"}
+			cookie.Pairs["httpOnly"] = FALSE
+			cookie.Pairs["secure"] = FALSE
+
+			if len(args) == 3 {
+				options, ok := args[2].(*Map)
+				if !ok {
+					return newError("Cookie() options must be map, got %s", args[2].Type())
+				}
+
+				for key, opt := range options.Pairs {
+					switch key {
+					case "path", "domain":
+						if _, ok := opt.(*String); !ok {
+							return newError("Cookie() option '%s' must be string, got %s", key, opt.Type())
+						}
+					case "maxAge", "expires":
+						if _, ok := opt.(*Integer); !ok {
+							return newError("Cookie() option '%s' must be integer, got %s", key, opt.Type())
+						}
+					case "httpOnly", "secure":
+						if _, ok := opt.(*Boolean); !ok {
+							return newError("Cookie() option '%s' must be boolean, got %s", key, opt.Type())
+						}
+					case "sameSite":
+						str, ok := opt.(*String)
+						if !ok {
+							return newError("Cookie() option 'sameSite' must be string, got %s", opt.Type())
+						}
+						if _, err := parseSameSite(str.Value); err != nil {
+							return err
+						}
+					default:
+						return newError("Cookie() unknown option '%s'", key)
+					}
+					cookie.Pairs[key] = opt
+				}
+			}
+
+			return cookie
+		},
+	}
+}
+
+// parseSameSite converts a SameSite attribute name to its net/http value.
+func parseSameSite(value string) (http.SameSite, *Error) {
+	switch strings.ToLower(value) {
+	case "":
+		return http.SameSiteDefaultMode, nil
+	case "lax":
+		return http.SameSiteLaxMode, nil
+	case "strict":
+		return http.SameSiteStrictMode, nil
+	case "none":
+		return http.SameSiteNoneMode, nil
+	default:
+		return http.SameSiteDefaultMode, newError("invalid sameSite value: %s (expected Lax, Strict or None)", value)
+	}
+}
+
+// cookieFromMap converts a cookie map created by http.Cookie() to a net/http cookie.
+func cookieFromMap(m *Map) (*http.Cookie, *Error) {
+	cookie := &http.Cookie{}
+
+	name, ok := m.Pairs["name"].(*String)
+	if !ok {
+		return nil, newError("cookie must have a string 'name'")
+	}
+	cookie.Name = name.Value
+
+	if value, ok := m.Pairs["value"].(*String); ok {
+		cookie.Value = value.Value
+	}
+	if path, ok := m.Pairs["path"].(*String); ok {
+		cookie.Path = path.Value
+	}
+	if domain, ok := m.Pairs["domain"].(*String); ok {
+		cookie.Domain = domain.Value
+	}
+	if maxAge, ok := m.Pairs["maxAge"].(*Integer); ok {
+		cookie.MaxAge = int(maxAge.Value)
+		if cookie.MaxAge == 0 {
+			// MaxAge 0 means "delete now" in TotalScript, -1 in net/http
+			cookie.MaxAge = -1
+		}
+	}
+	if expires, ok := m.Pairs["expires"].(*Integer); ok {
+		cookie.Expires = time.UnixMilli(expires.Value).UTC()
+	}
+	if httpOnly, ok := m.Pairs["httpOnly"].(*Boolean); ok {
+		cookie.HttpOnly = httpOnly.Value
+	}
+	if secure, ok := m.Pairs["secure"].(*Boolean); ok {
+		cookie.Secure = secure.Value
+	}
+	if sameSite, ok := m.Pairs["sameSite"].(*String); ok {
+		mode, err := parseSameSite(sameSite.Value)
+		if err != nil {
+			return nil, err
+		}
+		cookie.SameSite = mode
+	}
+
+	return cookie, nil
+}
+
+// createCookiesMap converts request cookies to a map<string, string>.
+func createCookiesMap(r *http.Request) *Map {
+	cookies := &Map{Pairs: make(map[string]Object)}
+	for _, c := range r.Cookies() {
+		// First cookie with a given name wins, matching r.Cookie()
+		if _, exists := cookies.Pairs[c.Name]; !exists {
+			cookies.Pairs[c.Name] = &String{Value: c.Value}
+		}
+	}
+	return cookies
+}
+
+// sessionConfig holds the options of a session middleware instance.
+type sessionConfig struct {
+	secret   []byte
+	name     string
+	store    string
+	maxAge   int64
+	secure   bool
+	sameSite string
+}
+
+// createSessionMiddleware creates the http.session(options) function.
+// It returns native middleware that exposes req.session as a mutable map.
+//
+// Options:
+//   - secret (string, required): key used to sign the session cookie
+//   - cookie (string): cookie name, defaults to "tsl_session"
+//   - store (string): "cookie" (default) keeps data in the cookie, "db" keeps it in the db module
+//   - maxAge (integer): session lifetime in seconds after its last change, defaults to 86400
+//   - secure (boolean), sameSite (string): cookie attributes
+//
+//nolint:gocognit,funlen
+func createSessionMiddleware() *Builtin {
+	return &Builtin{
+		Name: "session",
+		Fn: func(args ...Object) Object {
+			if len(args) != 1 {
+				return newError("session() takes 1 argument (options), got %d", len(args))
+			}
+
+			options, ok := args[0].(*Map)
+			if !ok {
+				return newError("session() options must be map, got %s", args[0].Type())
+			}
+
+			config := &sessionConfig{
+				name:     "tsl_session",
+				store:    sessionStoreCookie,
+				maxAge:   86400,
+				sameSite: "Lax",
+			}
+
+			secret, ok := options.Pairs["secret"].(*String)
+			if !ok || secret.Value == "" {
+				return newError("session() requires a non-empty string 'secret' option")
+			}
+			config.secret = []byte(secret.Value)
+
+			if name, ok := options.Pairs["cookie"].(*String); ok {
+				config.name = name.Value
+			}
+			if store, ok := options.Pairs["store"].(*String); ok {
+				if store.Value != sessionStoreCookie && store.Value != sessionStoreDB {
+					return newError("session() store must be \"cookie\" or \"db\", got %q", store.Value)
+				}
+				config.store = store.Value
+			}
+			if maxAge, ok := options.Pairs["maxAge"].(*Integer); ok {
+				if maxAge.Value <= 0 {
+					return newError("session() option 'maxAge' must be a positive integer")
+				}
+				config.maxAge = maxAge.Value
+			}
+			if secure, ok := options.Pairs["secure"].(*Boolean); ok {
+				config.secure = secure.Value
+			}
+			if sameSite, ok := options.Pairs["sameSite"].(*String); ok {
+				if _, err := parseSameSite(sameSite.Value); err != nil {
+					return err
+				}
+				config.sameSite = sameSite.Value
+			}
+
+			var dbState *DBState
+			if config.store == sessionStoreDB {
+				dbModule, ok := loadStdlibModule("db").(*Module)
+				if !ok {
+					return newError("session() could not load db module")
+				}
+				wrapper, ok := dbModule.Scope.store["__db_state__"].(*DBStateWrapper)
+				if !ok {
+					return newError("session() could not access database state")
+				}
+				dbState = wrapper.State
+			}
+
+			return &Builtin{
+				Name: "sessionMiddleware",
+				Fn: func(mwArgs ...Object) Object {
+					if len(mwArgs) != 2 {
+						return newError("session middleware requires 2 arguments (req, next)")
+					}
+					return runSessionMiddleware(config, dbState, mwArgs[0], mwArgs[1])
+				},
+			}
+		},
+	}
+}
+
+// runSessionMiddleware loads the session, calls the rest of the chain and
+// persists the session if it changed.
+func runSessionMiddleware(config *sessionConfig, dbState *DBState, req Object, next Object) Object {
+	request, ok := isRequest(req)
+	if !ok {
+		return newError("session middleware: request must be http.Request, got %s", req.Type())
+	}
+
+	sessionID := ""
+	var data map[string]interface{}
+	now := time.Now()
+
+	if cookies, ok := request.Fields["cookies"].(*Map); ok {
+		if raw, ok := cookies.Pairs[config.name].(*String); ok {
+			if payload, valid := verifySigned(config.secret, raw.Value, now); valid {
+				if config.store == sessionStoreDB {
+					sessionID = payload
+					data = loadDBSession(dbState, sessionID, now)
+				} else {
+					_ = json.Unmarshal([]byte(payload), &data)
+				}
+			}
+		}
+	}
+
+	session, ok := convertJSONToObject(data).(*Map)
+	if !ok {
+		session = &Map{Pairs: make(map[string]Object)}
+	}
+	before := encodeSession(session)
+	request.Fields["session"] = session
+
+	// Errors and non-Response results are answered with 400 or 500 without
+	// the session cookie, so the session of a failed request is not saved
+	result := callTSFunction(next, request)
+	if _, ok := isResponse(result); !ok {
+		return result
+	}
+
+	after := encodeSession(session)
+	if after == before {
+		return result
+	}
+
+	expires := now.Add(time.Duration(config.maxAge) * time.Second)
+	cookie := &http.Cookie{
+		Name:     config.name,
+		Path:     "/",
+		MaxAge:   int(config.maxAge),
+		HttpOnly: true,
+		Secure:   config.secure,
+	}
+	cookie.SameSite, _ = parseSameSite(config.sameSite)
+
+	if config.store == sessionStoreDB {
+		if sessionID == "" {
+			sessionID = newSessionID()
+		}
+		cookie.Value = signValue(config.secret, sessionID, expires)
+	} else {
+		cookie.Value = signValue(config.secret, after, expires)
+	}
+
+	// Browsers drop larger cookies, which would silently lose the session
+	header := cookie.String()
+	if len(header) > maxSessionCookieSize {
+		return newError("session cookie is %d bytes (limit %d); store less data in the session or use the \"db\" store",
+			len(header), maxSessionCookieSize)
+	}
+
+	if config.store == sessionStoreDB {
+		if err := saveDBSession(dbState, sessionID, after, expires); err != nil {
+			return err
+		}
+	}
+
+	appendResponseHeader(result, "Set-Cookie", &String{Value: header})
+	return result
+}
+
+// encodeSession serializes a session map to JSON.
+func encodeSession(session *Map) string {
+	jsonBytes, err := json.Marshal(convertObjectToGo(session))
+	if err != nil {
+		return ""
+	}
+	return string(jsonBytes)
+}
+
+// signValue returns "value.exp.signature": the base64url encoded value, its
+// expiry as Unix seconds and the HMAC-SHA256 signature of both.
+func signValue(secret []byte, value string, expires time.Time) string {
+	payload := base64.RawURLEncoding.EncodeToString([]byte(value)) + "." +
+		strconv.FormatInt(expires.Unix(), 10)
+	return payload + "." + base64.RawURLEncoding.EncodeToString(signPayload(secret, payload))
+}
+
+// verifySigned checks a value produced by signValue and returns the original
+// value. Values that expired before now are rejected.
+func verifySigned(secret []byte, signed string, now time.Time) (string, bool) {
+	payload, encodedSig, found := cutLast(signed, ".")
+	if !found {
+		return "", false
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(encodedSig)
+	if err != nil || !hmac.Equal(sig, signPayload(secret, payload)) {
+		return "", false
+	}
+
+	encodedValue, exp, found := strings.Cut(payload, ".")
+	if !found {
+		return "", false
+	}
+	expires, err := strconv.ParseInt(exp, 10, 64)
+	if err != nil || now.Unix() >= expires {
+		return "", false
+	}
+	value, err := base64.RawURLEncoding.DecodeString(encodedValue)
+	if err != nil {
+		return "", false
+	}
+	return string(value), true
+}
+
+// signPayload returns the HMAC-SHA256 signature of payload.
+func signPayload(secret []byte, payload string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
+
+// cutLast slices s around the last instance of sep.
+func cutLast(s, sep string) (string, string, bool) {
+	if i := strings.LastIndex(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
+
+// newSessionID generates a random session identifier.
+func newSessionID() string {
+	b := make([]byte, 32)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// loadDBSession reads session data stored by saveDBSession.
+// Sessions that expired before now are treated as missing.
+func loadDBSession(state *DBState, sessionID string, now time.Time) map[string]interface{} {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	if err := state.ensureOpen(); err != nil {
+		return nil
+	}
+
+	var value string
+	row := state.execer().QueryRow(`
+		SELECT d.field_value FROM data d
+		JOIN data e ON e.entity_id = d.entity_id AND e.model_type = d.model_type AND e.field_name = ?
+		WHERE d.entity_id = ? AND d.model_type = ? AND d.field_name = ? AND CAST(e.field_value AS INTEGER) > ?
+	`, "expires", sessionID, sessionModelType, "data", now.Unix())
+	if err := row.Scan(&value); err != nil {
+		return nil
+	}
+
+	var data map[string]interface{}
+	if err := json.Unmarshal([]byte(value), &data); err != nil {
+		return nil
+	}
+	return data
+}
+
+// saveDBSession stores session data and its expiry as rows in the db module's
+// table. Sessions that have already expired are deleted on each save.
+func saveDBSession(state *DBState, sessionID, data string, expires time.Time) *Error {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	if err := state.ensureOpen(); err != nil {
+		return &Error{Message: err.Error()}
+	}
+
+	_, _ = state.execer().Exec(`
+		DELETE FROM data WHERE model_type = ? AND (entity_id = ? OR entity_id IN (
+			SELECT entity_id FROM data
+			WHERE model_type = ? AND field_name = ? AND CAST(field_value AS INTEGER) <= ?
+		))
+	`, sessionModelType, sessionID, sessionModelType, "expires", time.Now().Unix())
+	_, err := state.execer().Exec(`
+		INSERT INTO data (entity_id, model_type, field_name, field_value, field_type)
+		VALUES (?, ?, ?, ?, ?), (?, ?, ?, ?, ?)
+	`, sessionID, sessionModelType, "data", data, typeNameJSON,
+		sessionID, sessionModelType, "expires", strconv.FormatInt(expires.Unix(), 10), typeNameInteger)
+	if err != nil {
+		return &Error{Message: err.Error()}
+	}
+	return nil
+}
+
+// appendResponseHeader adds a header value to a response returned by a handler.
+func appendResponseHeader(result Object, name string, value Object) {
+	response, ok := isResponse(result)
+	if !ok {
+		return
+	}
+
+	headers, ok := response.Fields["headers"].(*Map)
+	if !ok {
+		headers = &Map{Pairs: make(map[string]Object)}
+		response.Fields["headers"] = headers
+	}
+
+	switch existing := headers.Pairs[name].(type) {
+	case *Array:
+		existing.Elements = append(existing.Elements, value)
+	case nil:
+		headers.Pairs[name] = &Array{Elements: []Object{value}}
+	default:
+		headers.Pairs[name] = &Array{Elements: []Object{existing, value}}
+	}
+}
@@ -0,0 +1,267 @@
+package interpreter
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// staticMount serves the files of a directory under a route prefix.
+type staticMount struct {
+	prefix       string
+	fsys         fs.FS
+	listing      bool   // list directories without index.html
+	spa          bool   // serve index.html for unknown paths without extension
+	etag         bool   // send ETag headers
+	cacheControl string // Cache-Control header value; empty sends none
+}
+
+// createStaticMethod creates the static(routePath, filesystemPath, options?)
+// method for serving static files.
+//
+// Options:
+//   - listing (boolean): list directories without index.html, defaults to false
+//   - spa (boolean): serve index.html for unknown paths, for single-page apps
+//   - etag (boolean): send ETag headers, defaults to true
+//   - maxAge (integer): seconds clients may cache files (Cache-Control: public, max-age=N)
+//   - cacheControl (string): Cache-Control header value, overrides maxAge
+//
+//nolint:gocognit
+func createStaticMethod(state *httpServerState) *Builtin {
+	return &Builtin{
+		Name: "static",
+		Fn: func(args ...Object) Object {
+			if len(args) < 2 || len(args) > 3 {
+				return newError("static() takes 2-3 arguments (routePath, filesystemPath, options?), got %d", len(args))
+			}
+
+			routePath, ok := args[0].(*String)
+			if !ok {
+				return newError("static() routePath must be string, got %s", args[0].Type())
+			}
+
+			fsPath, ok := args[1].(*String)
+			if !ok {
+				return newError("static() filesystemPath must be string, got %s", args[1].Type())
+			}
+
+			mount := &staticMount{
+				prefix: "/" + strings.Trim(routePath.Value, "/"),
+				fsys:   os.DirFS(fsPath.Value),
+				etag:   true,
+			}
+
+			if len(args) == 3 {
+				options, ok := args[2].(*Map)
+				if !ok {
+					return newError("static() options must be map, got %s", args[2].Type())
+				}
+				for key, value := range options.Pairs {
+					switch key {
+					case "listing", "spa", "etag":
+						b, ok := value.(*Boolean)
+						if !ok {
+							return newError("static() option '%s' must be boolean, got %s", key, value.Type())
+						}
+						switch key {
+						case "listing":
+							mount.listing = b.Value
+						case "spa":
+							mount.spa = b.Value
+						default:
+							mount.etag = b.Value
+						}
+					case "maxAge":
+						n, ok := value.(*Integer)
+						if !ok || n.Value < 0 {
+							return newError("static() option 'maxAge' must be a non-negative integer")
+						}
+						if _, set := options.Pairs["cacheControl"]; !set {
+							mount.cacheControl = "public, max-age=" + strconv.FormatInt(n.Value, 10)
+						}
+					case "cacheControl":
+						str, ok := value.(*String)
+						if !ok {
+							return newError("static() option 'cacheControl' must be string, got %s", value.Type())
+						}
+						mount.cacheControl = str.Value
+					default:
+						return newError("static() unknown option '%s'", key)
+					}
+				}
+			}
+
+			// Keep mounts with longer prefixes first so they take precedence
+			state.staticMounts = append(state.staticMounts, mount)
+			sort.SliceStable(state.staticMounts, func(i, j int) bool {
+				return len(state.staticMounts[i].prefix) > len(state.staticMounts[j].prefix)
+			})
+			return NULL
+		},
+	}
+}
+
+// staticHandler returns the final handler serving urlPath from the static
+// mounts of a server, or nil if no mount has a file for it.
+func staticHandler(state *httpServerState, method, urlPath string) Object {
+	if method != http.MethodGet && method != http.MethodHead {
+		return nil
+	}
+
+	for _, mount := range state.staticMounts {
+		if response := mount.serve(urlPath); response != nil {
+			return &Builtin{
+				Name: "static",
+				Fn: func(_ ...Object) Object {
+					return response
+				},
+			}
+		}
+	}
+	return nil
+}
+
+// serve creates the response for urlPath, or returns nil if the mount has no
+// file for it.
+func (m *staticMount) serve(urlPath string) *ModelInstance {
+	rel, ok := m.relativePath(urlPath)
+	if !ok {
+		return nil
+	}
+
+	info, err := fs.Stat(m.fsys, rel)
+	if err == nil && info.IsDir() {
+		index := path.Join(rel, "index.html")
+		if indexInfo, err := fs.Stat(m.fsys, index); err == nil && !indexInfo.IsDir() {
+			return m.fileResponse(index, indexInfo)
+		}
+		if m.listing {
+			return m.listingResponse(urlPath, rel)
+		}
+		return nil
+	}
+	if err == nil {
+		return m.fileResponse(rel, info)
+	}
+
+	// Single-page apps route paths like /users/42 on the client; missing
+	// assets (paths with an extension) still get 404
+	if m.spa && path.Ext(rel) == "" {
+		if indexInfo, err := fs.Stat(m.fsys, "index.html"); err == nil && !indexInfo.IsDir() {
+			return m.fileResponse("index.html", indexInfo)
+		}
+	}
+	return nil
+}
+
+// relativePath returns the file path of urlPath within the mount.
+func (m *staticMount) relativePath(urlPath string) (string, bool) {
+	rest := urlPath
+	if m.prefix != "/" {
+		var found bool
+		rest, found = strings.CutPrefix(urlPath, m.prefix)
+		if !found || (rest != "" && !strings.HasPrefix(rest, "/")) {
+			return "", false
+		}
+	}
+
+	rel := strings.TrimPrefix(path.Clean("/"+rest), "/")
+	if rel == "" {
+		rel = "."
+	}
+	if !fs.ValidPath(rel) {
+		return "", false
+	}
+	return rel, true
+}
+
+// fileResponse creates the response for a file, with caching headers.
+func (m *staticMount) fileResponse(name string, info fs.FileInfo) *ModelInstance {
+	headers := &Map{Pairs: make(map[string]Object)}
+	if m.cacheControl != "" {
+		headers.Pairs["Cache-Control"] = &String{Value: m.cacheControl}
+	}
+	if m.etag {
+		headers.Pairs["ETag"] = &String{Value: fileETag(info)}
+	}
+
+	body := &StaticFile{FS: m.fsys, Name: name, ModTime: info.ModTime()}
+	return newResponse(http.StatusOK, body, headers)
+}
+
+// fileETag identifies a version of a file by its modification time and size.
+func fileETag(info fs.FileInfo) string {
+	return fmt.Sprintf(`W/"%x-%x"`, info.ModTime().UnixNano(), info.Size())
+}
+
+// listingResponse creates an HTML listing of a directory.
+func (m *staticMount) listingResponse(urlPath, rel string) *ModelInstance {
+	entries, err := fs.ReadDir(m.fsys, rel)
+	if err != nil {
+		return nil
+	}
+
+	var buf strings.Builder
+	buf.WriteString("<!doctype html>\n<pre>\n")
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() {
+			name += "/"
+		}
+		href := path.Join("/", urlPath, name)
+		if entry.IsDir() {
+			href += "/"
+		}
+		fmt.Fprintf(&buf, "<a href=\"%s\">%s</a>\n", html.EscapeString(href), html.EscapeString(name))
+	}
+	buf.WriteString("</pre>\n")
+
+	headers := &Map{Pairs: make(map[string]Object)}
+	headers.Pairs["Content-Type"] = &String{Value: "text/html; charset=utf-8"}
+	return newResponse(http.StatusOK, &String{Value: buf.String()}, headers)
+}
+
+// writeStaticFile writes a file response, answering conditional and range
+// requests. Headers set by the handler and middleware are sent as well.
+func writeStaticFile(w http.ResponseWriter, r *http.Request, response *ModelInstance, file *StaticFile) {
+	if headersMap, ok := response.Fields["headers"].(*Map); ok {
+		if err := setResponseHeaders(w.Header(), headersMap); err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			//nolint:errcheck,gosec
+			w.Write([]byte(err.Message))
+			return
+		}
+	}
+
+	f, err := file.FS.Open(file.Name)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		//nolint:errcheck,gosec
+		w.Write([]byte("404 Not Found"))
+		return
+	}
+	//nolint:errcheck
+	defer f.Close()
+
+	content, ok := f.(io.ReadSeeker)
+	if !ok {
+		data, err := io.ReadAll(f)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			//nolint:errcheck,gosec
+			w.Write([]byte("failed to read file: " + err.Error()))
+			return
+		}
+		content = bytes.NewReader(data)
+	}
+
+	http.ServeContent(w, r, file.Name, file.ModTime, content)
+}
@@ -0,0 +1,276 @@
+package interpreter
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// createStreamConstructor creates the http.stream(status, writerFn, headers?) function.
+// writerFn receives a writer object with write(chunk) and flush() methods.
+func createStreamConstructor() *Builtin {
+	return &Builtin{
+		Name: "stream",
+		Fn: func(args ...Object) Object {
+			if len(args) < 2 || len(args) > 3 {
+				return newError("stream() takes 2-3 arguments (status, writer, headers?), got %d", len(args))
+			}
+
+			status, ok := args[0].(*Integer)
+			if !ok {
+				return newError("stream() status must be integer, got %s", args[0].Type())
+			}
+
+			if _, ok := args[1].(*Function); !ok {
+				return newError("stream() writer must be function, got %s", args[1].Type())
+			}
+
+			headers := &Map{Pairs: make(map[string]Object)}
+			if len(args) == 3 {
+				headers, ok = args[2].(*Map)
+				if !ok {
+					return newError("stream() headers must be map, got %s", args[2].Type())
+				}
+			}
+
+			return newResponse(status.Value, &StreamResponse{Writer: args[1]}, headers)
+		},
+	}
+}
+
+// createSSEConstructor creates the http.sse(fn) function.
+// fn receives a send(event) function that returns false once the client disconnects.
+func createSSEConstructor() *Builtin {
+	return &Builtin{
+		Name: "sse",
+		Fn: func(args ...Object) Object {
+			if len(args) != 1 {
+				return newError("sse() takes 1 argument (function), got %d", len(args))
+			}
+
+			if _, ok := args[0].(*Function); !ok {
+				return newError("sse() argument must be function, got %s", args[0].Type())
+			}
+
+			headers := &Map{Pairs: make(map[string]Object)}
+			headers.Pairs["Content-Type"] = &String{Value: "text/event-stream"}
+			headers.Pairs["Cache-Control"] = &String{Value: "no-cache"}
+
+			return newResponse(http.StatusOK, &StreamResponse{Writer: args[0], SSE: true}, headers)
+		},
+	}
+}
+
+// streamWriter writes a streaming response, delaying the status line until the
+// first chunk so that errors raised before any output can still become a 500.
+type streamWriter struct {
+	w             http.ResponseWriter
+	r             *http.Request
+	response      *ModelInstance // the http.Response whose body is being streamed
+	headerWritten bool
+	closed        bool
+}
+
+// writeHeader sends the status line and headers if not sent yet.
+func (sw *streamWriter) writeHeader() {
+	if sw.headerWritten {
+		return
+	}
+	sw.headerWritten = true
+	if headers, ok := sw.response.Fields["headers"].(*Map); ok {
+		if err := setResponseHeaders(sw.w.Header(), headers); err != nil {
+			sw.w.WriteHeader(http.StatusInternalServerError)
+			//nolint:errcheck,gosec
+			sw.w.Write([]byte(err.Message))
+			sw.closed = true
+			return
+		}
+	}
+	status := int64(http.StatusOK)
+	if statusInt, ok := sw.response.Fields["status"].(*Integer); ok {
+		status = statusInt.Value
+	}
+	sw.w.WriteHeader(int(status))
+}
+
+// write sends a chunk and reports whether the client is still connected.
+func (sw *streamWriter) write(data []byte) bool {
+	if sw.disconnected() {
+		return false
+	}
+	sw.writeHeader()
+	if sw.closed {
+		return false
+	}
+	if _, err := sw.w.Write(data); err != nil {
+		sw.closed = true
+		return false
+	}
+	return true
+}
+
+// flush pushes buffered data to the client.
+func (sw *streamWriter) flush() bool {
+	if sw.disconnected() {
+		return false
+	}
+	sw.writeHeader()
+	if flusher, ok := sw.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	return !sw.closed
+}
+
+// disconnected reports whether the client has gone away.
+func (sw *streamWriter) disconnected() bool {
+	if sw.closed {
+		return true
+	}
+	if sw.r.Context().Err() != nil {
+		sw.closed = true
+	}
+	return sw.closed
+}
+
+// writeStreamResponse runs the writer function of a streaming response.
+func writeStreamResponse(w http.ResponseWriter, r *http.Request, response *ModelInstance, stream *StreamResponse) {
+	sw := &streamWriter{w: w, r: r, response: response}
+
+	var arg Object
+	if stream.SSE {
+		// Send headers right away so the client knows the stream is open
+		sw.flush()
+		arg = &Builtin{
+			Name: "send",
+			Fn: func(args ...Object) Object {
+				if len(args) != 1 {
+					return newError("send() takes 1 argument (event), got %d", len(args))
+				}
+				event, err := formatSSEEvent(args[0])
+				if err != nil {
+					return err
+				}
+				return nativeBoolToBooleanObject(sw.write([]byte(event)) && sw.flush())
+			},
+		}
+	} else {
+		arg = createStreamWriterObject(sw)
+	}
+
+	result := callTSFunction(stream.Writer, arg)
+
+	if err, ok := result.(*Error); ok && !sw.headerWritten {
+		w.WriteHeader(http.StatusInternalServerError)
+		//nolint:errcheck,gosec
+		w.Write([]byte(err.Message))
+		return
+	}
+
+	sw.writeHeader()
+}
+
+// createStreamWriterObject creates the writer object passed to http.stream() functions.
+func createStreamWriterObject(sw *streamWriter) *Map {
+	writer := &Map{Pairs: make(map[string]Object)}
+
+	writer.Pairs["write"] = &Builtin{
+		Name: "write",
+		Fn: func(args ...Object) Object {
+			if len(args) != 1 {
+				return newError("write() takes 1 argument (chunk), got %d", len(args))
+			}
+			chunk, err := encodeChunk(args[0])
+			if err != nil {
+				return err
+			}
+			return nativeBoolToBooleanObject(sw.write(chunk))
+		},
+	}
+
+	writer.Pairs["flush"] = &Builtin{
+		Name: "flush",
+		Fn: func(args ...Object) Object {
+			if len(args) != 0 {
+				return newError("flush() takes no arguments")
+			}
+			return nativeBoolToBooleanObject(sw.flush())
+		},
+	}
+
+	return writer
+}
+
+// encodeChunk converts a value to bytes: strings as-is, other values as JSON.
+func encodeChunk(obj Object) ([]byte, *Error) {
+	if str, ok := obj.(*String); ok {
+		return []byte(str.Value), nil
+	}
+	jsonBytes, err := json.Marshal(convertObjectToGo(obj))
+	if err != nil {
+		return nil, newError("failed to encode chunk: %s", err.Error())
+	}
+	return jsonBytes, nil
+}
+
+// sseLineBreaks normalizes the line endings accepted by SSE parsers to "\n".
+var sseLineBreaks = strings.NewReplacer("\r\n", "\n", "\r", "\n") //nolint:gochecknoglobals
+
+// formatSSEEvent formats a value as a Server-Sent Event.
+// A string is sent as data; a map may contain event, id, retry and data keys,
+// where non-string data is encoded as JSON.
+func formatSSEEvent(obj Object) (string, *Error) {
+	var out strings.Builder
+	data := obj
+
+	if event, ok := obj.(*Map); ok {
+		data = event.Pairs["data"]
+		for _, field := range []string{"event", "id", "retry"} {
+			if value, ok := event.Pairs[field]; ok {
+				line, err := formatSSEField(field, value)
+				if err != nil {
+					return "", err
+				}
+				fmt.Fprintf(&out, "%s: %s\n", field, line)
+			}
+		}
+	}
+
+	if data != nil && data != NULL {
+		payload, err := encodeChunk(data)
+		if err != nil {
+			return "", err
+		}
+		for _, line := range strings.Split(sseLineBreaks.Replace(string(payload)), "\n") {
+			fmt.Fprintf(&out, "data: %s\n", line)
+		}
+	}
+
+	out.WriteString("\n")
+	return out.String(), nil
+}
+
+// formatSSEField validates the value of an event, id or retry field.
+// Values must fit on a single line so they cannot inject further fields.
+func formatSSEField(field string, value Object) (string, *Error) {
+	switch field {
+	case "retry":
+		if n, ok := value.(*Integer); ok && n.Value >= 0 {
+			return n.Inspect(), nil
+		}
+		return "", newError("send() event field 'retry' must be a non-negative integer")
+	case "id":
+		if n, ok := value.(*Integer); ok {
+			return n.Inspect(), nil
+		}
+	}
+
+	str, ok := value.(*String)
+	if !ok {
+		return "", newError("send() event field '%s' must be string, got %s", field, value.Type())
+	}
+	if strings.ContainsAny(str.Value, "\r\n") {
+		return "", newError("send() event field '%s' must not contain line breaks", field)
+	}
+	return str.Value, nil
+}
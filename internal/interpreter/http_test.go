@@ -0,0 +1,1871 @@
+package interpreter
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"container/list"
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"log"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mishankov/totalscript-lang/internal/ast"
+	"github.com/mishankov/totalscript-lang/internal/lexer"
+	"github.com/mishankov/totalscript-lang/internal/parser"
+)
+
+// testHTTPServer evaluates input with `http` and `server` predefined and
+// returns the handler that server.start() would serve.
+func testHTTPServer(t *testing.T, input string) http.Handler {
+	t.Helper()
+	return testHTTPServerWithState(t, newHTTPServerState(), input)
+}
+
+// testHTTPServerWithState is like testHTTPServer but uses the given server state.
+func testHTTPServerWithState(t *testing.T, state *httpServerState, input string) http.Handler {
+	t.Helper()
+
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+
+	env := NewEnvironment()
+	env.Set("Error", ErrorModel)
+	env.Set("http", createHTTPModule())
+	env.Set("server", createServerInstance(state))
+
+	if result := Eval(program, env); IsError(result) {
+		t.Fatalf("unexpected error: %s", result.Inspect())
+	}
+
+	return newServerMux(state)
+}
+
+// doRequest performs a request against handler and returns the recorded response.
+func doRequest(handler http.Handler, req *http.Request) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestHTTPRequestCookies(t *testing.T) {
+	t.Parallel()
+	handler := testHTTPServer(t, `
+	server.get("/", function(req) {
+		return http.Response(200, req.cookies["theme"])
+	})
+	`)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(&http.Cookie{Name: "theme", Value: "dark"})
+	rec := doRequest(handler, req)
+
+	if rec.Body.String() != "dark" {
+		t.Errorf("wrong body. expected=%q, got=%q", "dark", rec.Body.String())
+	}
+}
+
+func TestHTTPSetCookie(t *testing.T) {
+	t.Parallel()
+	handler := testHTTPServer(t, `
+	server.get("/", function(req) {
+		var c = http.Cookie("sid", "abc", {"httpOnly": true, "sameSite": "Strict", "maxAge": 60})
+		return http.Response(200, "ok", {"Set-Cookie": [c]})
+	})
+	`)
+
+	rec := doRequest(handler, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	setCookie := rec.Header().Get("Set-Cookie")
+	for _, part := range []string{"sid=abc", "Path=/", "Max-Age=60", "HttpOnly", "SameSite=Strict"} {
+		if !strings.Contains(setCookie, part) {
+			t.Errorf("Set-Cookie %q does not contain %q", setCookie, part)
+		}
+	}
+}
+
+func TestHTTPCookieSession(t *testing.T) {
+	t.Parallel()
+	handler := testHTTPServer(t, `
+	server.use(http.session({"secret": "test-secret"}))
+	server.get("/count", function(req) {
+		var count = req.session["count"]
+		if count == null {
+			count = 0
+		}
+		req.session["count"] = count + 1
+		return http.Response(200, req.session)
+	})
+	`)
+
+	rec := doRequest(handler, httptest.NewRequest(http.MethodGet, "/count", nil))
+	if rec.Body.String() != `{"count":1}` {
+		t.Fatalf("wrong first body: %q", rec.Body.String())
+	}
+
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != "tsl_session" {
+		t.Fatalf("expected tsl_session cookie, got %v", cookies)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/count", nil)
+	req.AddCookie(cookies[0])
+	rec = doRequest(handler, req)
+	if rec.Body.String() != `{"count":2}` {
+		t.Errorf("wrong second body: %q", rec.Body.String())
+	}
+
+	// A tampered cookie starts a fresh session
+	req = httptest.NewRequest(http.MethodGet, "/count", nil)
+	req.AddCookie(&http.Cookie{Name: "tsl_session", Value: cookies[0].Value + "x"})
+	rec = doRequest(handler, req)
+	if rec.Body.String() != `{"count":1}` {
+		t.Errorf("tampered cookie was accepted: %q", rec.Body.String())
+	}
+}
+
+func TestHTTPSessionResults(t *testing.T) {
+	t.Parallel()
+	handler := testHTTPServer(t, `
+	server.use(http.session({"secret": "test-secret"}))
+	server.use(function(req, next) {
+		req.session["seen"] = true
+		return next(req)
+	})
+	server.get("/text", function(req) {
+		return "not a response"
+	})
+	server.get("/large", function(req) {
+		var data = ""
+		for i in 0..500 {
+			data = data + "0123456789"
+		}
+		req.session["data"] = data
+		return http.Response(200, "ok")
+	})
+	server.websocket("/ws", function(conn) { conn.close() })
+	`)
+
+	// Results other than a Response are answered with 500 and keep no session
+	rec := doRequest(handler, httptest.NewRequest(http.MethodGet, "/text", nil))
+	if rec.Code != http.StatusInternalServerError || rec.Header().Get("Set-Cookie") != "" {
+		t.Errorf("expected 500 without a session cookie, got %d %v", rec.Code, rec.Header())
+	}
+
+	rec = doRequest(handler, httptest.NewRequest(http.MethodGet, "/large", nil))
+	if rec.Code != http.StatusInternalServerError || !strings.Contains(rec.Body.String(), "session cookie is") {
+		t.Errorf("expected error for an oversized session cookie, got %d %s", rec.Code, rec.Body.String())
+	}
+
+	// The WebSocket handshake carries the session cookie
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+	conn, err := net.Dial("tcp", strings.TrimPrefix(ts.URL, "http://"))
+	if err != nil {
+		t.Fatalf("dial error: %v", err)
+	}
+	defer conn.Close()
+
+	req := httptest.NewRequest(http.MethodGet, ts.URL+"/ws", nil)
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	if err := req.Write(conn); err != nil {
+		t.Fatalf("handshake write error: %v", err)
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(conn), req)
+	if err != nil {
+		t.Fatalf("handshake read error: %v", err)
+	}
+	cookies := resp.Cookies()
+	if resp.StatusCode != http.StatusSwitchingProtocols || len(cookies) != 1 || cookies[0].Name != "tsl_session" {
+		t.Errorf("expected 101 with a session cookie, got %d %v", resp.StatusCode, resp.Header)
+	}
+}
+
+func TestSessionSignedExpiry(t *testing.T) {
+	t.Parallel()
+	secret := []byte("test-secret")
+	now := time.Now()
+	signed := signValue(secret, `{"user":"ann"}`, now.Add(time.Minute))
+
+	if value, ok := verifySigned(secret, signed, now); !ok || value != `{"user":"ann"}` {
+		t.Errorf("expected valid session value, got %q (%t)", value, ok)
+	}
+	if _, ok := verifySigned(secret, signed, now.Add(2*time.Minute)); ok {
+		t.Error("expired session value was accepted")
+	}
+
+	// Extending the expiry invalidates the signature
+	parts := strings.Split(signed, ".")
+	extended := parts[0] + "." + strconv.FormatInt(now.Add(time.Hour).Unix(), 10) + "." + parts[2]
+	if _, ok := verifySigned(secret, extended, now.Add(2*time.Minute)); ok {
+		t.Error("session value with a forged expiry was accepted")
+	}
+}
+
+func TestDBSessionExpiry(t *testing.T) {
+	t.Parallel()
+	state := &DBState{path: filepath.Join(t.TempDir(), "sessions.db")}
+	now := time.Now()
+
+	if err := saveDBSession(state, "old", `{"n":1}`, now.Add(-time.Second)); err != nil {
+		t.Fatalf("saveDBSession() error: %s", err.Message)
+	}
+	if data := loadDBSession(state, "old", now); data != nil {
+		t.Errorf("expired session was loaded: %v", data)
+	}
+
+	// Rows of other models that share the session id are kept
+	_, _ = state.db.Exec(`INSERT INTO data (entity_id, model_type, field_name, field_value, field_type)
+		VALUES ('current', 'User', 'name', 'Ann', 'string')`)
+
+	for range 2 {
+		if err := saveDBSession(state, "current", `{"n":2}`, now.Add(time.Minute)); err != nil {
+			t.Fatalf("saveDBSession() error: %s", err.Message)
+		}
+	}
+	if data := loadDBSession(state, "current", now); data["n"] != float64(2) {
+		t.Errorf("expected current session, got %v", data)
+	}
+
+	var rows, users int
+	_ = state.db.QueryRow("SELECT COUNT(*) FROM data WHERE model_type = ?", sessionModelType).Scan(&rows)
+	_ = state.db.QueryRow("SELECT COUNT(*) FROM data WHERE model_type = 'User'").Scan(&users)
+	if rows != 2 || users != 1 {
+		t.Errorf("expected 2 session rows and 1 user row, got %d and %d", rows, users)
+	}
+}
+
+func TestHTTPRequestForm(t *testing.T) {
+	t.Parallel()
+	handler := testHTTPServer(t, `
+	server.post("/", function(req) {
+		var form = req.form()
+		return http.Response(200, form["name"][0] + ":" + form["tag"][1])
+	})
+	`)
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("name=Ann+Lee&tag=a&tag=b"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := doRequest(handler, req)
+
+	if rec.Body.String() != "Ann Lee:b" {
+		t.Errorf("wrong body. expected=%q, got=%q", "Ann Lee:b", rec.Body.String())
+	}
+}
+
+func TestHTTPRequestMultipart(t *testing.T) {
+	t.Parallel()
+	state := newHTTPServerState()
+	state.multipartMemory = 8
+	handler := testHTTPServerWithState(t, state, `
+	server.post("/upload", function(req) {
+		var data = req.multipart()
+		var small = data["files"][0]
+		var large = data["files"][1]
+		return http.Response(200, {
+			"title": data["fields"]["title"][0],
+			"small": [small["filename"], small["contentType"], small["size"], small["content"]],
+			"large": [large["filename"], large["size"], large["content"] == null, large["path"] != null]
+		})
+	})
+	`)
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	_ = writer.WriteField("title", "report")
+	part, _ := writer.CreateFormFile("a", "small.csv")
+	_, _ = part.Write([]byte("x,y"))
+	part, _ = writer.CreateFormFile("b", "large.csv")
+	_, _ = part.Write([]byte("1,2,3,4,5,6"))
+	_ = writer.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/upload", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	rec := doRequest(handler, req)
+
+	expected := `{"large":["large.csv",11,true,true],"small":["small.csv","application/octet-stream",3,"x,y"],"title":"report"}`
+	if rec.Body.String() != expected {
+		t.Errorf("wrong body.\nexpected=%s\ngot=%s", expected, rec.Body.String())
+	}
+}
+
+func TestHTTPRequestMultipartStreaming(t *testing.T) {
+	t.Parallel()
+	state := newHTTPServerState()
+	state.maxBodySize = 1024
+	handler := testHTTPServerWithState(t, state, `
+	server.post("/upload", function(req) {
+		var data = req.multipart()
+		return http.Response(200, [req.body, data["fields"]["title"][0]])
+	})
+	`)
+
+	newRequest := func(title string) *http.Request {
+		var body bytes.Buffer
+		writer := multipart.NewWriter(&body)
+		_ = writer.WriteField("title", title)
+		_ = writer.Close()
+		req := httptest.NewRequest(http.MethodPost, "/upload", &body)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		return req
+	}
+
+	// The body is parsed by multipart() instead of being read into req.body
+	rec := doRequest(handler, newRequest("report"))
+	if rec.Code != http.StatusOK || rec.Body.String() != `["","report"]` {
+		t.Errorf("wrong body: %d %s", rec.Code, rec.Body.String())
+	}
+
+	rec = doRequest(handler, newRequest(strings.Repeat("x", 2048)))
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("wrong status. expected=%d, got=%d %s", http.StatusRequestEntityTooLarge, rec.Code, rec.Body.String())
+	}
+}
+
+func TestHTTPMaxBodySize(t *testing.T) {
+	t.Parallel()
+	state := newHTTPServerState()
+	state.maxBodySize = 4
+	handler := testHTTPServerWithState(t, state, `
+	server.post("/", function(req) {
+		return http.Response(200, req.body)
+	})
+	`)
+
+	rec := doRequest(handler, httptest.NewRequest(http.MethodPost, "/", strings.NewReader("too large")))
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("wrong status. expected=%d, got=%d", http.StatusRequestEntityTooLarge, rec.Code)
+	}
+
+	rec = doRequest(handler, httptest.NewRequest(http.MethodPost, "/", strings.NewReader("ok")))
+	if rec.Code != http.StatusOK || rec.Body.String() != "ok" {
+		t.Errorf("small body rejected: %d %q", rec.Code, rec.Body.String())
+	}
+}
+
+func TestHTTPStreamResponse(t *testing.T) {
+	t.Parallel()
+	handler := testHTTPServer(t, `
+	server.get("/report", function(req) {
+		return http.stream(200, function(res) {
+			for i in 1..=3 {
+				res.write("line " + req.query["prefix"][0] + "\n")
+				res.flush()
+			}
+			res.write({"done": true})
+		}, {"Content-Type": "text/plain"})
+	})
+	`)
+
+	rec := doRequest(handler, httptest.NewRequest(http.MethodGet, "/report?prefix=x", nil))
+
+	expected := "line x\nline x\nline x\n{\"done\":true}"
+	if rec.Body.String() != expected {
+		t.Errorf("wrong body. expected=%q, got=%q", expected, rec.Body.String())
+	}
+	if !rec.Flushed {
+		t.Error("expected response to be flushed")
+	}
+	if rec.Header().Get("Content-Type") != "text/plain" {
+		t.Errorf("wrong Content-Type: %q", rec.Header().Get("Content-Type"))
+	}
+}
+
+func TestHTTPServerSentEvents(t *testing.T) {
+	t.Parallel()
+	handler := testHTTPServer(t, `
+	server.get("/events", function(req) {
+		return http.sse(function(send) {
+			send("hello")
+			send({"event": "update", "id": 2, "data": {"value": 42}})
+		})
+	})
+	`)
+
+	rec := doRequest(handler, httptest.NewRequest(http.MethodGet, "/events", nil))
+
+	expected := "data: hello\n\nevent: update\nid: 2\ndata: {\"value\":42}\n\n"
+	if rec.Body.String() != expected {
+		t.Errorf("wrong body. expected=%q, got=%q", expected, rec.Body.String())
+	}
+	if rec.Header().Get("Content-Type") != "text/event-stream" {
+		t.Errorf("wrong Content-Type: %q", rec.Header().Get("Content-Type"))
+	}
+	// Connection is hop-by-hop and managed by the server (and invalid in HTTP/2)
+	if rec.Header().Get("Cache-Control") != "no-cache" || rec.Header().Get("Connection") != "" {
+		t.Errorf("wrong SSE headers: %v", rec.Header())
+	}
+}
+
+func TestSSEEventValidation(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		event    map[string]Object
+		expected string
+	}{
+		{map[string]Object{"event": &String{Value: "a\ndata: forged"}}, "send() event field 'event' must not contain line breaks"},
+		{map[string]Object{"id": &String{Value: "1\rretry: 0"}}, "send() event field 'id' must not contain line breaks"},
+		{map[string]Object{"event": &Integer{Value: 1}}, "send() event field 'event' must be string, got INTEGER"},
+		{map[string]Object{"retry": &String{Value: "1000"}}, "send() event field 'retry' must be a non-negative integer"},
+		{map[string]Object{"retry": &Integer{Value: -1}}, "send() event field 'retry' must be a non-negative integer"},
+	}
+
+	for _, tt := range tests {
+		_, err := formatSSEEvent(&Map{Pairs: tt.event})
+		if err == nil || err.Message != tt.expected {
+			t.Errorf("expected error %q, got %v", tt.expected, err)
+		}
+	}
+
+	event, err := formatSSEEvent(&Map{Pairs: map[string]Object{
+		"id":    &String{Value: "a1"},
+		"retry": &Integer{Value: 500},
+		"data":  &String{Value: "one\rtwo\r\nthree"},
+	}})
+	if expected := "id: a1\nretry: 500\ndata: one\ndata: two\ndata: three\n\n"; err != nil || event != expected {
+		t.Errorf("wrong event. expected=%q, got=%q (%v)", expected, event, err)
+	}
+}
+
+func TestHTTPServerSentEventsDisconnect(t *testing.T) {
+	t.Parallel()
+	handler := testHTTPServer(t, `
+	server.get("/events", function(req) {
+		return http.sse(function(send) {
+			var sent = 0
+			while send("tick") {
+				sent += 1
+			}
+			return sent
+		})
+	})
+	`)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	req := httptest.NewRequest(http.MethodGet, "/events", nil).WithContext(ctx)
+	rec := doRequest(handler, req)
+
+	if rec.Body.String() != "" {
+		t.Errorf("expected no events after disconnect, got %q", rec.Body.String())
+	}
+}
+
+// testHTTPClient evaluates input with `http` predefined and returns the result.
+func testHTTPClient(t *testing.T, input string) Object {
+	t.Helper()
+
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+
+	env := NewEnvironment()
+	env.Set("http", createHTTPModule())
+
+	return Eval(program, env)
+}
+
+func TestHTTPStartOptionsValidation(t *testing.T) {
+	t.Parallel()
+	start := createStartMethod(newHTTPServerState())
+
+	tests := []struct {
+		options  map[string]Object
+		expected string
+	}{
+		{map[string]Object{"bogus": TRUE}, "start() unknown option 'bogus'"},
+		{map[string]Object{"cert": &String{Value: "cert.pem"}}, "start() options 'cert' and 'key' must be given together"},
+		{map[string]Object{"host": &Integer{Value: 1}}, "start() option 'host' must be string, got INTEGER"},
+		{
+			map[string]Object{"selfSigned": TRUE, "cert": &String{Value: "c.pem"}, "key": &String{Value: "k.pem"}},
+			"start() option 'selfSigned' cannot be combined with 'cert' and 'key'",
+		},
+	}
+
+	for _, tt := range tests {
+		result := start.Fn(&Integer{Value: 0}, &Map{Pairs: tt.options})
+		errObj, ok := result.(*Error)
+		if !ok || errObj.Message != tt.expected {
+			t.Errorf("expected error %q, got %s", tt.expected, result.Inspect())
+		}
+	}
+}
+
+func TestHTTPClientTLS(t *testing.T) {
+	t.Parallel()
+	certPEM, keyPEM, err := generateSelfSignedCert([]string{"127.0.0.1"})
+	if err != nil {
+		t.Fatalf("failed to generate certificate: %v", err)
+	}
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		t.Fatalf("failed to load certificate: %v", err)
+	}
+
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("secure"))
+	}))
+	ts.TLS = &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+	ts.Config.ErrorLog = log.New(io.Discard, "", 0)
+	ts.StartTLS()
+	defer ts.Close()
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(caFile, certPEM, 0o600); err != nil {
+		t.Fatalf("failed to write CA bundle: %v", err)
+	}
+
+	// Unknown certificate authority is rejected by default
+	result := testHTTPClient(t, `http.client.get("`+ts.URL+`")`)
+	if _, ok := result.(*Error); !ok {
+		t.Errorf("expected certificate error, got %s", result.Inspect())
+	}
+
+	for _, options := range []string{`{"insecureSkipVerify": true}`, `{"ca": "` + caFile + `"}`} {
+		result = testHTTPClient(t, `
+		var res = http.client.get("`+ts.URL+`", {}, `+options+`)
+		res["body"]
+		`)
+		if str, ok := result.(*String); !ok || str.Value != "secure" {
+			t.Errorf("options %s: expected body %q, got %s", options, "secure", result.Inspect())
+		}
+	}
+}
+
+func TestHTTPClientTLSMergesRequestOptions(t *testing.T) {
+	t.Parallel()
+	serverPEM, serverKeyPEM, err := generateSelfSignedCert([]string{"127.0.0.1"})
+	if err != nil {
+		t.Fatalf("failed to generate certificate: %v", err)
+	}
+	clientPEM, clientKeyPEM, err := generateSelfSignedCert([]string{"client"})
+	if err != nil {
+		t.Fatalf("failed to generate certificate: %v", err)
+	}
+	serverCert, err := tls.X509KeyPair(serverPEM, serverKeyPEM)
+	if err != nil {
+		t.Fatalf("failed to load certificate: %v", err)
+	}
+
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(strconv.Itoa(len(r.TLS.PeerCertificates))))
+	}))
+	ts.TLS = &tls.Config{
+		Certificates: []tls.Certificate{serverCert},
+		ClientAuth:   tls.RequireAnyClientCert,
+		MinVersion:   tls.VersionTLS12,
+	}
+	ts.Config.ErrorLog = log.New(io.Discard, "", 0)
+	ts.StartTLS()
+	defer ts.Close()
+
+	dir := t.TempDir()
+	files := map[string][]byte{"ca.pem": serverPEM, "client.pem": clientPEM, "client-key.pem": clientKeyPEM}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0o600); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+
+	client := `var api = http.Client({"ca": "` + filepath.Join(dir, "ca.pem") + `"})
+	`
+
+	// The server requires a client certificate
+	result := testHTTPClient(t, client+`api.get("`+ts.URL+`")`)
+	if _, ok := result.(*Error); !ok {
+		t.Errorf("expected handshake error without a client certificate, got %s", result.Inspect())
+	}
+
+	// The request certificate is used together with the client CA
+	result = testHTTPClient(t, client+`
+	var res = api.get("`+ts.URL+`", {}, {"cert": "`+filepath.Join(dir, "client.pem")+`", "key": "`+filepath.Join(dir, "client-key.pem")+`"})
+	res["body"]
+	`)
+	if str, ok := result.(*String); !ok || str.Value != "1" {
+		t.Errorf("expected body %q, got %s", "1", result.Inspect())
+	}
+}
+
+func TestHTTPClientOptions(t *testing.T) {
+	t.Parallel()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		_, _ = w.Write([]byte(r.Method + " " + r.URL.Path + " " + r.Header.Get("X-App") + " " +
+			r.Header.Get("X-Trace") + " " + string(body)))
+	}))
+	defer ts.Close()
+
+	result := testHTTPClient(t, `
+	var api = http.Client({"baseUrl": "`+ts.URL+`/v1/", "headers": {"X-App": "tsl", "X-Trace": "default"}})
+	var first = api.get("/users", {"X-Trace": "call"})["body"]
+	var second = api.request("put", "users/1", {"body": "data"})["body"]
+	var messages = [first, second]
+	messages
+	`)
+
+	expected := []string{"GET /v1/users tsl call ", "PUT /v1/users/1 tsl default data"}
+	arr, ok := result.(*Array)
+	if !ok {
+		t.Fatalf("expected array, got %s", result.Inspect())
+	}
+	for i, want := range expected {
+		if str, ok := arr.Elements[i].(*String); !ok || str.Value != want {
+			t.Errorf("wrong body %d. expected=%q, got=%s", i, want, arr.Elements[i].Inspect())
+		}
+	}
+}
+
+func TestHTTPClientRetries(t *testing.T) {
+	t.Parallel()
+	var attempts atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer ts.Close()
+
+	result := testHTTPClient(t, `
+	var api = http.Client({"retries": 2, "backoff": 1})
+	api.get("`+ts.URL+`")["status"]
+	`)
+
+	testIntegerObject(t, result, http.StatusOK)
+	if attempts.Load() != 3 {
+		t.Errorf("expected 3 attempts, got %d", attempts.Load())
+	}
+}
+
+func TestHTTPClientRetriesNonIdempotent(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		options  string
+		attempts int32
+	}{
+		{`{"retries": 2, "backoff": 1}`, 1},
+		{`{"retries": 2, "backoff": 1, "retryNonIdempotent": true}`, 3},
+	}
+
+	for _, tt := range tests {
+		var attempts atomic.Int32
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			attempts.Add(1)
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+
+		testHTTPClient(t, `http.Client(`+tt.options+`).post("`+ts.URL+`", "payload")`)
+		ts.Close()
+
+		if attempts.Load() != tt.attempts {
+			t.Errorf("%s: expected %d attempts, got %d", tt.options, tt.attempts, attempts.Load())
+		}
+	}
+}
+
+func TestHTTPClientTLSTransportReuse(t *testing.T) {
+	t.Parallel()
+	c, err := newClient(nil)
+	if err != nil {
+		t.Fatalf("newClient() error: %s", err.Message)
+	}
+
+	newRequest := func(insecure bool) *clientRequest {
+		req := &clientRequest{method: "GET"}
+		options := &Map{Pairs: map[string]Object{"insecureSkipVerify": nativeBoolToBooleanObject(insecure)}}
+		if err := req.applyOptions(options, c.tlsOptions); err != nil {
+			t.Fatalf("applyOptions() error: %s", err.Message)
+		}
+		return req
+	}
+
+	first := c.httpClientFor(newRequest(true)).Transport
+	if second := c.httpClientFor(newRequest(true)).Transport; second != first {
+		t.Error("expected requests with the same TLS options to share a transport")
+	}
+	if other := c.httpClientFor(newRequest(false)).Transport; other == first {
+		t.Error("expected requests with different TLS options to use different transports")
+	}
+}
+
+func TestHTTPClientRedirectsAndTimeout(t *testing.T) {
+	t.Parallel()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/old":
+			http.Redirect(w, r, "/new", http.StatusFound)
+		case "/slow":
+			time.Sleep(200 * time.Millisecond)
+		default:
+			_, _ = w.Write([]byte("new"))
+		}
+	}))
+	defer ts.Close()
+
+	result := testHTTPClient(t, `http.Client({"followRedirects": false}).get("`+ts.URL+`/old")["status"]`)
+	testIntegerObject(t, result, http.StatusFound)
+
+	result = testHTTPClient(t, `http.client.get("`+ts.URL+`/old")["body"]`)
+	if str, ok := result.(*String); !ok || str.Value != "new" {
+		t.Errorf("expected redirect to be followed, got %s", result.Inspect())
+	}
+
+	result = testHTTPClient(t, `http.Client({"timeoutMs": 20}).get("`+ts.URL+`/slow")`)
+	if _, ok := result.(*Error); !ok {
+		t.Errorf("expected timeout error, got %s", result.Inspect())
+	}
+}
+
+func TestHTTPClientRequestBodies(t *testing.T) {
+	t.Parallel()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentType := r.Header.Get("Content-Type")
+		if strings.HasPrefix(contentType, "multipart/form-data") {
+			file, header, err := r.FormFile("upload")
+			if err != nil {
+				t.Errorf("missing upload: %v", err)
+				return
+			}
+			content, _ := io.ReadAll(file)
+			_, _ = w.Write([]byte(r.FormValue("title") + " " + header.Filename + " " + string(content)))
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		_, _ = w.Write([]byte(r.URL.RawQuery + " | " + contentType + " | " + string(body)))
+	}))
+	defer ts.Close()
+
+	uploadPath := filepath.Join(t.TempDir(), "report.csv")
+	if err := os.WriteFile(uploadPath, []byte("a,b"), 0o600); err != nil {
+		t.Fatalf("failed to write upload file: %v", err)
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{
+			`http.client.request("POST", "` + ts.URL + `", {"json": {"a": 1}, "query": {"q": "x y", "tag": ["b", "c"]}})`,
+			`q=x+y&tag=b&tag=c | application/json | {"a":1}`,
+		},
+		{
+			`http.client.post("` + ts.URL + `", {"a": 1})`,
+			` | application/json | {"a":1}`,
+		},
+		{
+			`http.client.post("` + ts.URL + `", "raw", {"Content-Type": "text/plain"})`,
+			` | text/plain | raw`,
+		},
+		{
+			`http.client.request("POST", "` + ts.URL + `", {"form": {"name": "Ann Lee", "n": 2}})`,
+			` | application/x-www-form-urlencoded | n=2&name=Ann+Lee`,
+		},
+		{
+			`http.client.request("POST", "` + ts.URL + `", {"multipart": {"fields": {"title": "Q1"}, "files": {"upload": "` + uploadPath + `"}}})`,
+			`Q1 report.csv a,b`,
+		},
+	}
+
+	for _, tt := range tests {
+		result := testHTTPClient(t, `var res = `+tt.input+`
+		res["body"]`)
+		if str, ok := result.(*String); !ok || str.Value != tt.expected {
+			t.Errorf("%s\nexpected=%q, got=%s", tt.input, tt.expected, result.Inspect())
+		}
+	}
+
+	result := testHTTPClient(t, `http.client.request("POST", "`+ts.URL+`", {"json": 1, "form": {}})`)
+	if _, ok := result.(*Error); !ok {
+		t.Errorf("expected error for two body options, got %s", result.Inspect())
+	}
+}
+
+func TestHTTPClientMultipartStreaming(t *testing.T) {
+	t.Parallel()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		file, _, err := r.FormFile("upload")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		content, _ := io.ReadAll(file)
+		_, _ = w.Write([]byte(strconv.FormatInt(r.ContentLength, 10) + " " + string(content)))
+	}))
+	defer ts.Close()
+
+	uploadPath := filepath.Join(t.TempDir(), "report.csv")
+	if err := os.WriteFile(uploadPath, []byte("a,b"), 0o600); err != nil {
+		t.Fatalf("failed to write upload file: %v", err)
+	}
+	upload := `{"multipart": {"files": {"upload": "` + uploadPath + `"}}}`
+
+	// Uploads sent once are streamed without a length; retried ones are buffered
+	result := testHTTPClient(t, `http.client.request("POST", "`+ts.URL+`", `+upload+`)["body"]`)
+	if str, ok := result.(*String); !ok || str.Value != "-1 a,b" {
+		t.Errorf("expected streamed upload, got %s", result.Inspect())
+	}
+	result = testHTTPClient(t, `http.Client({"retries": 1}).request("PUT", "`+ts.URL+`", `+upload+`)["body"]`)
+	if str, ok := result.(*String); !ok || strings.HasPrefix(str.Value, "-1") || !strings.HasSuffix(str.Value, " a,b") {
+		t.Errorf("expected buffered upload, got %s", result.Inspect())
+	}
+
+	missing := `{"multipart": {"files": {"upload": "` + filepath.Join(t.TempDir(), "missing.csv") + `"}}}`
+	result = testHTTPClient(t, `http.client.request("POST", "`+ts.URL+`", `+missing+`)`)
+	if errObj, ok := result.(*Error); !ok || !strings.Contains(errObj.Message, "failed to open file") {
+		t.Errorf("expected open error, got %s", result.Inspect())
+	}
+}
+
+func TestHTTPClientDownload(t *testing.T) {
+	t.Parallel()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("large payload"))
+	}))
+	defer ts.Close()
+
+	path := filepath.Join(t.TempDir(), "out.bin")
+	result := testHTTPClient(t, `
+	var res = http.client.get("`+ts.URL+`", {}, {"download": "`+path+`"})
+	res["size"]
+	`)
+	testIntegerObject(t, result, int64(len("large payload")))
+
+	content, err := os.ReadFile(path)
+	if err != nil || string(content) != "large payload" {
+		t.Errorf("wrong downloaded content %q (%v)", content, err)
+	}
+}
+
+func TestHTTPRequestBind(t *testing.T) {
+	t.Parallel()
+	handler := testHTTPServer(t, `
+	const Address = model {
+		city: string
+	}
+	const User = model {
+		name: string
+		age: integer
+		score: float
+		nickname: string?
+		address: Address?
+	}
+	server.post("/users", function(req) {
+		var user = req.bind(User)
+		return http.Response(200, [user.name, user.age, user.score, user.nickname, user.address.city])
+	})
+	server.post("/signup", function(req) {
+		return http.Response(201, [req.data.name, req.data.age, req.data.score])
+	}, {"body": User})
+	`)
+
+	req := httptest.NewRequest(http.MethodPost, "/users",
+		strings.NewReader(`{"name": "Ann", "age": 30, "score": 5, "address": {"city": "Oslo"}}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec := doRequest(handler, req)
+	if rec.Code != http.StatusOK || rec.Body.String() != `["Ann",30,5,null,"Oslo"]` {
+		t.Errorf("wrong JSON bind response: %d %s", rec.Code, rec.Body.String())
+	}
+
+	// Form values are converted to the field types
+	req = httptest.NewRequest(http.MethodPost, "/signup", strings.NewReader("name=Bob&age=41&score=1.5"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec = doRequest(handler, req)
+	if rec.Code != http.StatusCreated || rec.Body.String() != `["Bob",41,1.5]` {
+		t.Errorf("wrong form bind response: %d %s", rec.Code, rec.Body.String())
+	}
+
+	// Multipart forms are bound from the parsed stream
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	_ = writer.WriteField("name", "Cy")
+	_ = writer.WriteField("age", "7")
+	_ = writer.WriteField("score", "2")
+	_ = writer.Close()
+	req = httptest.NewRequest(http.MethodPost, "/signup", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	rec = doRequest(handler, req)
+	if rec.Code != http.StatusCreated || rec.Body.String() != `["Cy",7,2]` {
+		t.Errorf("wrong multipart bind response: %d %s", rec.Code, rec.Body.String())
+	}
+
+	// Invalid fields produce a 400 listing every problem
+	req = httptest.NewRequest(http.MethodPost, "/signup",
+		strings.NewReader(`{"name": 1, "score": 2.5, "address": {"city": true}}`))
+	rec = doRequest(handler, req)
+	expected := `{"error":"invalid request body","fields":{` +
+		`"address.city":"type mismatch: expected string, got boolean",` +
+		`"age":"missing required field",` +
+		`"name":"type mismatch: expected string, got integer"}}`
+	if rec.Code != http.StatusBadRequest || rec.Body.String() != expected {
+		t.Errorf("wrong validation response: %d\nexpected=%s\ngot=%s", rec.Code, expected, rec.Body.String())
+	}
+}
+
+func TestHTTPRequestResponseModels(t *testing.T) {
+	t.Parallel()
+	handler := testHTTPServer(t, `
+	server.use(function(req: http.Request, next: function): http.Response {
+		var res = next(req)
+		if (res is http.Response) {
+			res.headers["X-Is-Response"] = ["yes"]
+		}
+		return res
+	})
+	server.get("/check", function(req: http.Request): http.Response {
+		return http.Response(200, [req is http.Request, req is http.Response, req.method])
+	})
+	server.get("/bad", function(req: http.Request): http.Response {
+		return "not a response"
+	})
+	`)
+
+	rec := doRequest(handler, httptest.NewRequest(http.MethodGet, "/check", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != `[true,false,"GET"]` {
+		t.Errorf("wrong body: %d %s", rec.Code, rec.Body.String())
+	}
+	if rec.Header().Get("X-Is-Response") != "yes" {
+		t.Errorf("wrong X-Is-Response header: %q", rec.Header().Get("X-Is-Response"))
+	}
+
+	// Annotated return types of handlers are validated
+	rec = doRequest(handler, httptest.NewRequest(http.MethodGet, "/bad", nil))
+	expected := "return value: type mismatch: expected http.Response, got string"
+	if rec.Code != http.StatusInternalServerError || rec.Body.String() != expected {
+		t.Errorf("wrong error response: %d %q", rec.Code, rec.Body.String())
+	}
+
+	result := testHTTPClient(t, `
+	var res = http.Response(201, "created")
+	res
+	`)
+	if result.Inspect() != `Response(status: 201, body: created, headers: {}, ok: true)` {
+		t.Errorf("wrong Inspect: %s", result.Inspect())
+	}
+}
+
+func TestHTTPTryCatch(t *testing.T) {
+	t.Parallel()
+	handler := testHTTPServer(t, `
+	server.post("/items", function(req: http.Request): http.Response {
+		try {
+			var data = req.json()
+			return http.Response(201, {"name": data["name"] + "!"})
+		} catch e {
+			return http.Response(400, {"error": e.message})
+		}
+	})
+	`)
+
+	req := httptest.NewRequest(http.MethodPost, "/items", strings.NewReader(`{"name": "pen"}`))
+	rec := doRequest(handler, req)
+	if rec.Code != http.StatusCreated || rec.Body.String() != `{"name":"pen!"}` {
+		t.Errorf("wrong response: %d %s", rec.Code, rec.Body.String())
+	}
+
+	// Runtime errors in the handler are caught instead of answering 500
+	for _, body := range []string{`not json`, `{"name": 1}`} {
+		rec = doRequest(handler, httptest.NewRequest(http.MethodPost, "/items", strings.NewReader(body)))
+		if rec.Code != http.StatusBadRequest || !strings.HasPrefix(rec.Body.String(), `{"error":`) {
+			t.Errorf("wrong response for %s: %d %s", body, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+func TestHTTPOpenAPI(t *testing.T) {
+	t.Parallel()
+	handler := testHTTPServer(t, `
+	const Address = model {
+		city: string
+	}
+	const User = model {
+		name: string
+		age: integer
+		address: Address?
+	}
+	const ValidationError = model {
+		field: string
+	}
+	server.openapi({"title": "Users API", "path": "/openapi.json"})
+	server.get("/users/:id", function(req: http.Request): http.Response {
+		return http.Response(200, req.params["id"])
+	})
+	server.post("/users", function(req: http.Request): http.Response {
+		return http.Response(201, req.data)
+	}, {"body": User})
+	server.put("/users/:id", function(req: http.Request): User | Error {
+		return req.data
+	}, {"body": User})
+	server.post("/checks", function(req: http.Request): ValidationError {
+		return ValidationError("name")
+	})
+	server.get("/files/*", function(req: http.Request): http.Response {
+		return http.Response(200, req.path)
+	})
+	server.get("/health", function(req: http.Request): string {
+		return "ok"
+	})
+	server.get("/users", function(req: http.Request): array<User> {
+		return [User("Bob", 40, null)]
+	})
+	server.websocket("/ws", function(conn) {})
+	`)
+
+	rec := doRequest(handler, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("wrong status: %d %s", rec.Code, rec.Body.String())
+	}
+
+	var doc struct {
+		OpenAPI string `json:"openapi"`
+		Info    struct {
+			Title string `json:"title"`
+		} `json:"info"`
+		Paths      map[string]map[string]json.RawMessage `json:"paths"`
+		Components struct {
+			Schemas map[string]json.RawMessage `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+
+	if doc.OpenAPI != "3.0.3" || doc.Info.Title != "Users API" {
+		t.Errorf("wrong header: %s %s", doc.OpenAPI, doc.Info.Title)
+	}
+	// Routes that are not HTTP operations and wildcard routes are left out
+	if len(doc.Paths) != 4 || doc.Paths["/ws"] != nil || doc.Paths["/openapi.json"] != nil || doc.Paths["/files/*"] != nil {
+		t.Errorf("wrong paths: %v", doc.Paths)
+	}
+
+	expectedGet := `{"parameters":[{"in":"path","name":"id","required":true,"schema":{"type":"string"}}],` +
+		`"responses":{"200":{"description":"Successful response"}}}`
+	if string(doc.Paths["/users/{id}"]["get"]) != expectedGet {
+		t.Errorf("wrong get operation: %s", doc.Paths["/users/{id}"]["get"])
+	}
+
+	if !strings.Contains(string(doc.Paths["/users"]["post"]), `"application/json":{"schema":{"$ref":"#/components/schemas/User"}}`) {
+		t.Errorf("post operation has no request body schema: %s", doc.Paths["/users"]["post"])
+	}
+
+	expectedUser := `{"properties":{"address":{"allOf":[{"$ref":"#/components/schemas/Address"}],"nullable":true},` +
+		`"age":{"format":"int64","type":"integer"},"name":{"type":"string"}},"required":["name","age"],"type":"object"}`
+	if string(doc.Components.Schemas["User"]) != expectedUser {
+		t.Errorf("wrong User schema: %s", doc.Components.Schemas["User"])
+	}
+	if doc.Components.Schemas["Address"] == nil || doc.Components.Schemas["ValidationError2"] == nil {
+		t.Errorf("missing component schemas: %v", doc.Components.Schemas)
+	}
+	// The binding error schema does not replace a model with the same name
+	if string(doc.Components.Schemas["ValidationError"]) != `{"properties":{"field":{"type":"string"}},"required":["field"],"type":"object"}` {
+		t.Errorf("wrong ValidationError schema: %s", doc.Components.Schemas["ValidationError"])
+	}
+	if post := string(doc.Paths["/users"]["post"]); !strings.Contains(post, `"$ref":"#/components/schemas/ValidationError2"`) {
+		t.Errorf("post operation does not reference the binding error schema: %s", post)
+	}
+
+	// Response schemas come from the handler's return type
+	put := string(doc.Paths["/users/{id}"]["put"])
+	for _, want := range []string{
+		`"requestBody":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/User"}}`,
+		`"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/User"}}},"description":"Successful response"}`,
+		`"500":{"description":"Error"}`,
+	} {
+		if !strings.Contains(put, want) {
+			t.Errorf("put operation has no %s: %s", want, put)
+		}
+	}
+	if health := string(doc.Paths["/health"]["get"]); !strings.Contains(health, `"text/plain":{"schema":{"type":"string"}}`) {
+		t.Errorf("wrong health operation: %s", health)
+	}
+
+	if list := string(doc.Paths["/users"]["get"]); !strings.Contains(list, `{"items":{"$ref":"#/components/schemas/User"},"type":"array"}`) {
+		t.Errorf("wrong list operation: %s", list)
+	}
+}
+
+func TestHTTPOpenAPISchemaNames(t *testing.T) {
+	t.Parallel()
+	// Models of different modules may have the same name
+	users := &Model{Name: "User", FieldNames: []string{"name"}, Fields: map[string]*ast.TypeExpression{"name": nil}}
+	accounts := &Model{Name: "User", FieldNames: []string{"id"}, Fields: map[string]*ast.TypeExpression{"id": nil}}
+
+	gen := &openAPIGenerator{schemas: make(map[string]interface{}), names: make(map[*Model]string)}
+	env := NewEnvironment()
+	refs := []interface{}{
+		gen.modelSchema(users, env)["$ref"],
+		gen.modelSchema(accounts, env)["$ref"],
+		gen.modelSchema(users, env)["$ref"],
+	}
+	expected := []interface{}{"#/components/schemas/User", "#/components/schemas/User2", "#/components/schemas/User"}
+	for i := range expected {
+		if refs[i] != expected[i] {
+			t.Errorf("wrong reference %d. expected=%v, got=%v", i, expected[i], refs[i])
+		}
+	}
+	if len(gen.schemas) != 2 {
+		t.Errorf("expected 2 schemas, got %v", gen.schemas)
+	}
+}
+
+func TestHTTPMiddlewareLoggingAndRequestID(t *testing.T) {
+	t.Parallel()
+	handler := testHTTPServer(t, `
+	var logged = {}
+	server.use(http.middleware.requestId())
+	server.use(http.middleware.logging({"logger": function(line) {
+		logged["line"] = line
+	}}))
+	server.get("/hello", function(req) {
+		return http.Response(200, req.headers["X-Request-Id"][0])
+	})
+	server.get("/logs", function(req) {
+		return http.Response(200, logged["line"])
+	})
+	`)
+
+	req := httptest.NewRequest(http.MethodGet, "/hello", nil)
+	req.Header.Set("X-Request-ID", "abc-123")
+	rec := doRequest(handler, req)
+	if rec.Body.String() != "abc-123" || rec.Header().Get("X-Request-ID") != "abc-123" {
+		t.Errorf("request ID not passed through: body=%q header=%q", rec.Body.String(), rec.Header().Get("X-Request-ID"))
+	}
+
+	rec = doRequest(handler, httptest.NewRequest(http.MethodGet, "/logs", nil))
+	line := rec.Body.String()
+	if !strings.HasPrefix(line, "GET /hello 200 ") || !strings.HasSuffix(line, " id=abc-123") {
+		t.Errorf("wrong log line: %q", line)
+	}
+
+	// A request ID is generated when the client sends none
+	rec = doRequest(handler, httptest.NewRequest(http.MethodGet, "/hello", nil))
+	if len(rec.Body.String()) != 36 || rec.Header().Get("X-Request-ID") != rec.Body.String() {
+		t.Errorf("wrong generated request ID: body=%q header=%q", rec.Body.String(), rec.Header().Get("X-Request-ID"))
+	}
+}
+
+func TestHTTPMiddlewareCORS(t *testing.T) {
+	t.Parallel()
+	handler := testHTTPServer(t, `
+	server.use(http.middleware.cors({
+		"origins": ["https://app.example.com"],
+		"methods": ["GET", "POST"],
+		"credentials": true,
+		"maxAge": 600
+	}))
+	server.post("/items", function(req) {
+		return http.Response(201, "created")
+	})
+	`)
+
+	// Preflight requests are answered even though no OPTIONS route exists
+	req := httptest.NewRequest(http.MethodOptions, "/items", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	req.Header.Set("Access-Control-Request-Method", "POST")
+	req.Header.Set("Access-Control-Request-Headers", "Content-Type")
+	rec := doRequest(handler, req)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("wrong preflight status: %d", rec.Code)
+	}
+	expected := map[string]string{
+		"Access-Control-Allow-Origin":      "https://app.example.com",
+		"Access-Control-Allow-Methods":     "GET, POST",
+		"Access-Control-Allow-Headers":     "Content-Type",
+		"Access-Control-Allow-Credentials": "true",
+		"Access-Control-Max-Age":           "600",
+		"Vary":                             "Origin",
+	}
+	for name, value := range expected {
+		if rec.Header().Get(name) != value {
+			t.Errorf("wrong preflight header %s: %q", name, rec.Header().Get(name))
+		}
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/items", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	rec = doRequest(handler, req)
+	if rec.Code != http.StatusCreated || rec.Header().Get("Access-Control-Allow-Origin") != "https://app.example.com" {
+		t.Errorf("wrong response: %d %v", rec.Code, rec.Header())
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/items", nil)
+	req.Header.Set("Origin", "https://evil.example.com")
+	rec = doRequest(handler, req)
+	if rec.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Errorf("disallowed origin got CORS header: %v", rec.Header())
+	}
+}
+
+func TestHTTPMiddlewareCORSCredentialsWildcard(t *testing.T) {
+	t.Parallel()
+	cors := createCORSMiddleware()
+	expected := `cors() option 'credentials' requires an explicit 'origins' list without "*"`
+
+	for _, origins := range []Object{nil, &Array{Elements: []Object{&String{Value: "*"}}}} {
+		options := &Map{Pairs: map[string]Object{"credentials": TRUE}}
+		if origins != nil {
+			options.Pairs["origins"] = origins
+		}
+		result := cors.Fn(options)
+		if errObj, ok := result.(*Error); !ok || errObj.Message != expected {
+			t.Errorf("expected error %q, got %s", expected, result.Inspect())
+		}
+	}
+}
+
+func TestHTTPMiddlewareRecovery(t *testing.T) {
+	t.Parallel()
+	handler := testHTTPServer(t, `
+	const Item = model {
+		name: string
+	}
+	server.use(http.middleware.recovery())
+	server.get("/fail", function(req) {
+		return undefinedFunction()
+	})
+	server.post("/items", function(req) {
+		return http.Response(201, req.data)
+	}, {"body": Item})
+	`)
+
+	rec := doRequest(handler, httptest.NewRequest(http.MethodGet, "/fail", nil))
+	if rec.Code != http.StatusInternalServerError || rec.Body.String() != `{"error":"internal server error"}` ||
+		rec.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("wrong recovered response: %d %s %v", rec.Code, rec.Body.String(), rec.Header())
+	}
+
+	// Binding errors are still answered with 400
+	rec = doRequest(handler, httptest.NewRequest(http.MethodPost, "/items", strings.NewReader(`{}`)))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("wrong binding error status: %d", rec.Code)
+	}
+
+	// Panics in native code are recovered too
+	middleware := createRecoveryMiddleware().Fn(&Map{Pairs: map[string]Object{"expose": TRUE}})
+	request := &ModelInstance{Model: httpRequestModel, Fields: make(map[string]Object)}
+	panicking := &Builtin{Fn: func(_ ...Object) Object { panic("boom") }}
+	result := callTSFunction(middleware, request, panicking)
+	response, ok := isResponse(result)
+	if !ok {
+		t.Fatalf("expected response, got %s", result.Inspect())
+	}
+	if response.Fields["status"].Inspect() != "500" || response.Fields["body"].Inspect() != "{error: panic: boom}" {
+		t.Errorf("wrong recovered panic: %s", response.Inspect())
+	}
+}
+
+func TestHTTPMiddlewareRateLimit(t *testing.T) {
+	t.Parallel()
+	handler := testHTTPServer(t, `
+	server.use(http.middleware.rateLimit({"rate": 0.5, "burst": 2, "header": "X-Api-Key"}))
+	server.get("/", function(req) {
+		return http.Response(200, "ok")
+	})
+	`)
+
+	for i := range 2 {
+		rec := doRequest(handler, httptest.NewRequest(http.MethodGet, "/", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("request %d: wrong status %d", i, rec.Code)
+		}
+	}
+
+	rec := doRequest(handler, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "2" {
+		t.Errorf("expected 429 with Retry-After 2, got %d %q", rec.Code, rec.Header().Get("Retry-After"))
+	}
+
+	// Clients with an API key have their own bucket
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Api-Key", "key-1")
+	if rec := doRequest(handler, req); rec.Code != http.StatusOK {
+		t.Errorf("keyed client was limited: %d", rec.Code)
+	}
+}
+
+func TestRateLimiterBucketCap(t *testing.T) {
+	t.Parallel()
+	now := time.Now()
+	limiter := &rateLimiter{rate: 1, burst: 1, buckets: make(map[string]*list.Element), now: func() time.Time { return now }}
+
+	for i := range maxRateLimitBuckets {
+		limiter.allow(strconv.Itoa(i))
+	}
+	// Using key 0 again makes key 1 the least recently used bucket
+	if ok, _ := limiter.allow("0"); ok {
+		t.Error("key 0 was not limited")
+	}
+	limiter.allow("new")
+
+	if len(limiter.buckets) != maxRateLimitBuckets || limiter.recent.Len() != maxRateLimitBuckets {
+		t.Errorf("expected %d buckets, got %d", maxRateLimitBuckets, len(limiter.buckets))
+	}
+	if _, ok := limiter.buckets["1"]; ok {
+		t.Error("least recently used bucket was not evicted")
+	}
+	if _, ok := limiter.buckets["0"]; !ok {
+		t.Error("recently used bucket was evicted")
+	}
+}
+
+func TestHTTPMiddlewareGzip(t *testing.T) {
+	t.Parallel()
+	handler := testHTTPServer(t, `
+	server.use(http.middleware.gzip({"minSize": 10}))
+	server.get("/data", function(req) {
+		return http.Response(200, {"message": "hello hello hello hello"})
+	})
+	server.get("/small", function(req) {
+		return http.Response(200, "hi")
+	})
+	`)
+
+	req := httptest.NewRequest(http.MethodGet, "/data", nil)
+	req.Header.Set("Accept-Encoding", "br, gzip")
+	rec := doRequest(handler, req)
+	if rec.Header().Get("Content-Encoding") != "gzip" || rec.Header().Get("Content-Type") != "application/json" ||
+		rec.Header().Get("Vary") != "Accept-Encoding" {
+		t.Fatalf("wrong headers: %v", rec.Header())
+	}
+	reader, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatalf("body is not gzip: %v", err)
+	}
+	body, err := io.ReadAll(reader)
+	if err != nil || string(body) != `{"message":"hello hello hello hello"}` {
+		t.Errorf("wrong decompressed body: %q %v", body, err)
+	}
+
+	// Small bodies and clients without gzip support get plain responses
+	req = httptest.NewRequest(http.MethodGet, "/small", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	if rec := doRequest(handler, req); rec.Header().Get("Content-Encoding") != "" || rec.Body.String() != "hi" {
+		t.Errorf("small body was compressed: %v", rec.Header())
+	}
+	req = httptest.NewRequest(http.MethodGet, "/data", nil)
+	req.Header.Set("Accept-Encoding", "gzip;q=0")
+	if rec := doRequest(handler, req); rec.Header().Get("Content-Encoding") != "" {
+		t.Errorf("body compressed for client refusing gzip: %v", rec.Header())
+	}
+}
+
+func TestHTTPStaticFiles(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	files := map[string]string{
+		"index.html":    "<h1>app</h1>",
+		"app.js":        "console.log(1)",
+		"docs/a.txt":    "a",
+		"docs/b/c.txt":  "c",
+		"private/x.txt": "x",
+		"img/logo.svg":  "<svg/>",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	root := filepath.ToSlash(dir)
+	handler := testHTTPServer(t, `
+	server.use(function(req, next) {
+		var res = next(req)
+		res.headers["X-Middleware"] = ["yes"]
+		return res
+	})
+	server.get("/api/ping", function(req) {
+		return http.Response(200, "pong")
+	})
+	server.static("/", "`+root+`", {"spa": true, "maxAge": 60})
+	server.static("/docs", "`+root+`/docs", {"listing": true})
+	server.static("/assets", "`+root+`/img", {"cacheControl": "no-cache"})
+	`)
+
+	rec := doRequest(handler, httptest.NewRequest(http.MethodGet, "/app.js", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "console.log(1)" {
+		t.Fatalf("wrong static response: %d %s", rec.Code, rec.Body.String())
+	}
+	if rec.Header().Get("Cache-Control") != "public, max-age=60" || rec.Header().Get("X-Middleware") != "yes" {
+		t.Errorf("wrong static headers: %v", rec.Header())
+	}
+	etag := rec.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("missing ETag")
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/app.js", nil)
+	req.Header.Set("If-None-Match", etag)
+	if rec := doRequest(handler, req); rec.Code != http.StatusNotModified {
+		t.Errorf("expected 304 for matching ETag, got %d", rec.Code)
+	}
+
+	// Routes take precedence over the root mount
+	if rec := doRequest(handler, httptest.NewRequest(http.MethodGet, "/api/ping", nil)); rec.Body.String() != "pong" {
+		t.Errorf("route shadowed by static mount: %s", rec.Body.String())
+	}
+
+	// SPA fallback serves index.html for client-side routes, not for missing assets
+	if rec := doRequest(handler, httptest.NewRequest(http.MethodGet, "/users/42", nil)); rec.Body.String() != "<h1>app</h1>" {
+		t.Errorf("wrong SPA fallback: %d %s", rec.Code, rec.Body.String())
+	}
+	if rec := doRequest(handler, httptest.NewRequest(http.MethodGet, "/missing.js", nil)); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for missing asset, got %d", rec.Code)
+	}
+
+	// Directories are listed only where enabled
+	rec = doRequest(handler, httptest.NewRequest(http.MethodGet, "/docs/", nil))
+	if !strings.Contains(rec.Body.String(), `<a href="/docs/a.txt">a.txt</a>`) ||
+		!strings.Contains(rec.Body.String(), `<a href="/docs/b/">b/</a>`) {
+		t.Errorf("wrong listing: %s", rec.Body.String())
+	}
+	if rec := doRequest(handler, httptest.NewRequest(http.MethodGet, "/private", nil)); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for directory without listing, got %d", rec.Code)
+	}
+
+	rec = doRequest(handler, httptest.NewRequest(http.MethodGet, "/assets/logo.svg", nil))
+	if rec.Body.String() != "<svg/>" || rec.Header().Get("Cache-Control") != "no-cache" ||
+		rec.Header().Get("Content-Type") != "image/svg+xml" || rec.Header().Get("ETag") == "" {
+		t.Errorf("wrong asset response: %d %s %v", rec.Code, rec.Body.String(), rec.Header())
+	}
+}
+
+func TestHTTPProxy(t *testing.T) {
+	t.Parallel()
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("X-Upstream", "yes")
+		w.Header().Set("Content-Type", "application/json")
+		//nolint:errcheck
+		json.NewEncoder(w).Encode(map[string]string{
+			"method":    r.Method,
+			"path":      r.URL.Path,
+			"query":     r.URL.RawQuery,
+			"token":     r.Header.Get("X-Token"),
+			"tenant":    r.Header.Get("X-Tenant"),
+			"session":   r.Header.Get("X-Session"),
+			"forwarded": r.Header.Get("X-Forwarded-For"),
+			"body":      string(body),
+		})
+	}))
+	defer upstream.Close()
+
+	dead := httptest.NewServer(http.NotFoundHandler())
+	deadURL := dead.URL
+	dead.Close()
+
+	handler := testHTTPServer(t, `
+	server.get("/api/*", http.proxy("`+upstream.URL+`/v1", {
+		"stripPrefix": "/api",
+		"headers": {"X-Token": "secret"}
+	}))
+	server.post("/rewrite/:tenant", http.proxy("`+upstream.URL+`", {
+		"rewrite": function(req: http.Request) {
+			req.path = "/tenants/" + req.params["tenant"]
+			req.headers["X-Tenant"] = [req.params["tenant"]]
+			req.body = req.body + "!"
+		},
+		"response": function(res: http.Response) {
+			res.status = 202
+			res.headers["X-Proxied"] = ["true"]
+		}
+	}))
+	server.get("/dead", http.proxy("`+deadURL+`"))
+	server.post("/upload", http.proxy("`+upstream.URL+`"))
+	server.get("/api/status", function(req) {
+		return http.Response(200, "local")
+	})
+	server.use(function(req: http.Request, next: function): http.Response {
+		var res = next(req)
+		res.headers["X-Middleware"] = "ran"
+		return res
+	})
+	`)
+
+	decode := func(rec *httptest.ResponseRecorder) map[string]string {
+		t.Helper()
+		var got map[string]string
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Fatalf("invalid upstream response %q: %v", rec.Body.String(), err)
+		}
+		return got
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/users/1?page=2", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	req.Header.Set("Connection", "X-Session")
+	req.Header.Set("X-Session", "hop")
+	rec := doRequest(handler, req)
+	got := decode(rec)
+	if rec.Code != http.StatusOK || got["path"] != "/v1/users/1" || got["query"] != "page=2" {
+		t.Errorf("wrong forwarded request: %d %v", rec.Code, got)
+	}
+	if got["method"] != http.MethodGet || got["token"] != "secret" || got["forwarded"] != "10.0.0.1" || got["session"] != "" {
+		t.Errorf("wrong forwarded headers: %v", got)
+	}
+	if rec.Header().Get("X-Upstream") != "yes" || rec.Header().Get("X-Middleware") != "ran" {
+		t.Errorf("wrong response headers: %v", rec.Header())
+	}
+
+	rec = doRequest(handler, httptest.NewRequest(http.MethodPost, "/rewrite/acme", strings.NewReader("data")))
+	got = decode(rec)
+	if got["path"] != "/tenants/acme" || got["tenant"] != "acme" || got["body"] != "data!" || got["method"] != http.MethodPost {
+		t.Errorf("rewrite hook not applied: %v", got)
+	}
+	if rec.Code != http.StatusAccepted || rec.Header().Get("X-Proxied") != "true" {
+		t.Errorf("response hook not applied: %d %v", rec.Code, rec.Header())
+	}
+
+	// Multipart bodies are not buffered and are streamed to the upstream
+	var upload bytes.Buffer
+	writer := multipart.NewWriter(&upload)
+	_ = writer.WriteField("title", "report")
+	_ = writer.Close()
+	req = httptest.NewRequest(http.MethodPost, "/upload", bytes.NewReader(upload.Bytes()))
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	rec = doRequest(handler, req)
+	if got = decode(rec); got["body"] != upload.String() {
+		t.Errorf("multipart body not forwarded: %v", got)
+	}
+
+	rec = doRequest(handler, httptest.NewRequest(http.MethodGet, "/api/status", nil))
+	if rec.Body.String() != "local" {
+		t.Errorf("exact route should win over wildcard: %s", rec.Body.String())
+	}
+
+	rec = doRequest(handler, httptest.NewRequest(http.MethodGet, "/dead", nil))
+	if rec.Code != http.StatusBadGateway {
+		t.Errorf("expected 502 for unreachable upstream, got %d", rec.Code)
+	}
+}
+
+func TestHTTPProxyMiddleware(t *testing.T) {
+	t.Parallel()
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		//nolint:errcheck
+		w.Write([]byte("upstream " + r.URL.Path))
+	}))
+	defer upstream.Close()
+
+	handler := testHTTPServer(t, `
+	var forward = http.proxy("`+upstream.URL+`")
+	server.use(function(req: http.Request, next: function): http.Response {
+		if req.path == "/health" {
+			return next(req)
+		}
+		return forward(req, next)
+	})
+	server.get("/health", function(req) {
+		return http.Response(200, "ok")
+	})
+	`)
+
+	rec := doRequest(handler, httptest.NewRequest(http.MethodGet, "/health", nil))
+	if rec.Body.String() != "ok" {
+		t.Errorf("wrong local response: %s", rec.Body.String())
+	}
+	rec = doRequest(handler, httptest.NewRequest(http.MethodGet, "/anything/else", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "upstream /anything/else" {
+		t.Errorf("wrong proxied response: %d %s", rec.Code, rec.Body.String())
+	}
+
+	// Used as middleware directly, requests outside stripPrefix reach the routes
+	handler = testHTTPServer(t, `
+	server.use(http.proxy("`+upstream.URL+`", {"stripPrefix": "/api"}))
+	server.get("/apis", function(req) {
+		return http.Response(200, "local")
+	})
+	`)
+
+	rec = doRequest(handler, httptest.NewRequest(http.MethodGet, "/api/users", nil))
+	if rec.Body.String() != "upstream /users" {
+		t.Errorf("wrong proxied response: %d %s", rec.Code, rec.Body.String())
+	}
+	rec = doRequest(handler, httptest.NewRequest(http.MethodGet, "/apis", nil))
+	if rec.Body.String() != "local" {
+		t.Errorf("expected request outside the prefix to reach the route, got %d %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestMatchPatternWildcard(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		pattern, path string
+		matched       bool
+		rest          string
+	}{
+		{"/api/*", "/api/users/1", true, "users/1"},
+		{"/api/*", "/api", true, ""},
+		{"/api/*", "/apis/x", false, ""},
+		{"/users/:id/*", "/users/7/posts", true, "posts"},
+		{"/*", "/any/path", true, "any/path"},
+	}
+	for _, tt := range tests {
+		matched, params := matchPattern(tt.pattern, tt.path)
+		if matched != tt.matched || (matched && params["*"] != tt.rest) {
+			t.Errorf("matchPattern(%q, %q) = %v %v, want %v %q", tt.pattern, tt.path, matched, params, tt.matched, tt.rest)
+		}
+	}
+}
+
+// signTestJWT creates a token with claims, signed with an HMAC secret (HS256)
+// or an RSA private key (RS256).
+func signTestJWT(t *testing.T, alg string, key interface{}, claims map[string]interface{}) string {
+	t.Helper()
+	header, _ := json.Marshal(map[string]string{"alg": alg, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+
+	var signature []byte
+	switch k := key.(type) {
+	case []byte:
+		mac := hmac.New(sha256.New, k)
+		mac.Write([]byte(signed))
+		signature = mac.Sum(nil)
+	case *rsa.PrivateKey:
+		digest := sha256.Sum256([]byte(signed))
+		var err error
+		if signature, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func authRequest(path, authorization string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	return req
+}
+
+func TestHTTPAuthBasic(t *testing.T) {
+	t.Parallel()
+	handler := testHTTPServer(t, `
+	server.use(http.auth.basic(function(username: string, password: string) {
+		if username == "alice" && password == "secret" {
+			return {"name": username, "admin": false}
+		}
+		if username == "bob" && password == "pw" {
+			return true
+		}
+		return null
+	}, {"realm": "admin area", "authorize": function(user) { return user != "bob" }}))
+	server.get("/me", function(req: http.Request): http.Response {
+		return http.Response(200, req.user["name"])
+	})
+	`)
+
+	basic := func(credentials string) string {
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(credentials))
+	}
+
+	rec := doRequest(handler, authRequest("/me", basic("alice:secret")))
+	if rec.Code != http.StatusOK || rec.Body.String() != "alice" {
+		t.Errorf("wrong authenticated response: %d %s", rec.Code, rec.Body.String())
+	}
+
+	for _, authorization := range []string{"", basic("alice:wrong"), "Basic !!!", "Bearer token"} {
+		rec = doRequest(handler, authRequest("/me", authorization))
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("%q: expected 401, got %d", authorization, rec.Code)
+		}
+		if got := rec.Header().Get("WWW-Authenticate"); got != `Basic realm="admin area", charset="UTF-8"` {
+			t.Errorf("%q: wrong challenge: %q", authorization, got)
+		}
+	}
+
+	rec = doRequest(handler, authRequest("/me", basic("bob:pw")))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("expected 403 for rejected user, got %d", rec.Code)
+	}
+}
+
+func TestHTTPAuthAPIKey(t *testing.T) {
+	t.Parallel()
+	handler := testHTTPServer(t, `
+	server.use(http.auth.apiKey(function(key: string) {
+		if key == "k1" {
+			return "service-a"
+		}
+		return false
+	}, {"query": "api_key"}))
+	server.get("/", function(req: http.Request): http.Response {
+		return http.Response(200, req.user)
+	})
+	`)
+
+	req := authRequest("/", "")
+	req.Header.Set("X-Api-Key", "k1")
+	rec := doRequest(handler, req)
+	if rec.Code != http.StatusOK || rec.Body.String() != "service-a" {
+		t.Errorf("wrong header key response: %d %s", rec.Code, rec.Body.String())
+	}
+
+	rec = doRequest(handler, authRequest("/?api_key=k1", ""))
+	if rec.Code != http.StatusOK || rec.Body.String() != "service-a" {
+		t.Errorf("wrong query key response: %d %s", rec.Code, rec.Body.String())
+	}
+
+	rec = doRequest(handler, authRequest("/?api_key=nope", ""))
+	if rec.Code != http.StatusUnauthorized || !strings.Contains(rec.Body.String(), "invalid API key") {
+		t.Errorf("expected 401 for unknown key, got %d %s", rec.Code, rec.Body.String())
+	}
+	if rec.Header().Get("WWW-Authenticate") == "" {
+		t.Error("missing WWW-Authenticate header")
+	}
+}
+
+func TestHTTPAuthJWT(t *testing.T) {
+	t.Parallel()
+	secret := []byte("s3cr3t")
+	handler := testHTTPServer(t, `
+	server.use(http.auth.jwt({
+		"secret": "s3cr3t",
+		"issuer": "auth.example.com",
+		"audience": "api",
+		"leeway": 5,
+		"claims": {"role": "admin"}
+	}))
+	server.get("/", function(req: http.Request): http.Response {
+		return http.Response(200, req.user["sub"])
+	})
+	`)
+
+	now := time.Now().Unix()
+	valid := map[string]interface{}{
+		"sub": "user-1", "iss": "auth.example.com", "aud": []string{"api", "web"},
+		"role": []string{"user", "admin"}, "exp": now + 60, "nbf": now - 60,
+	}
+	with := func(key string, value interface{}) map[string]interface{} {
+		claims := make(map[string]interface{}, len(valid))
+		for k, v := range valid {
+			claims[k] = v
+		}
+		claims[key] = value
+		return claims
+	}
+
+	rec := doRequest(handler, authRequest("/", "Bearer "+signTestJWT(t, "HS256", secret, valid)))
+	if rec.Code != http.StatusOK || rec.Body.String() != "user-1" {
+		t.Errorf("wrong authenticated response: %d %s", rec.Code, rec.Body.String())
+	}
+
+	unauthorized := map[string]string{
+		"expired":       signTestJWT(t, "HS256", secret, with("exp", now-60)),
+		"not yet valid": signTestJWT(t, "HS256", secret, with("nbf", now+60)),
+		"wrong issuer":  signTestJWT(t, "HS256", secret, with("iss", "evil")),
+		"wrong aud":     signTestJWT(t, "HS256", secret, with("aud", "other")),
+		"wrong secret":  signTestJWT(t, "HS256", []byte("other"), valid),
+		"alg none":      strings.Join(strings.Split(signTestJWT(t, "none", nil, valid), ".")[:2], ".") + ".",
+		"malformed":     "not-a-token",
+	}
+	for name, token := range unauthorized {
+		rec = doRequest(handler, authRequest("/", "Bearer "+token))
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("%s: expected 401, got %d", name, rec.Code)
+		}
+		if got := rec.Header().Get("WWW-Authenticate"); !strings.HasPrefix(got, `Bearer realm="Restricted", error="invalid_token"`) {
+			t.Errorf("%s: wrong challenge: %q", name, got)
+		}
+	}
+
+	// Within the leeway
+	rec = doRequest(handler, authRequest("/", "Bearer "+signTestJWT(t, "HS256", secret, with("exp", now-2))))
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected leeway to accept token, got %d", rec.Code)
+	}
+
+	rec = doRequest(handler, authRequest("/", "Bearer "+signTestJWT(t, "HS256", secret, with("role", "user"))))
+	if rec.Code != http.StatusForbidden || !strings.Contains(rec.Header().Get("WWW-Authenticate"), `error="insufficient_scope"`) {
+		t.Errorf("expected 403 for missing claim, got %d %q", rec.Code, rec.Header().Get("WWW-Authenticate"))
+	}
+
+	rec = doRequest(handler, authRequest("/", ""))
+	if rec.Code != http.StatusUnauthorized || rec.Header().Get("WWW-Authenticate") != `Bearer realm="Restricted"` {
+		t.Errorf("wrong response without token: %d %q", rec.Code, rec.Header().Get("WWW-Authenticate"))
+	}
+}
+
+func TestHTTPAuthJWTEmptySecret(t *testing.T) {
+	t.Parallel()
+	jwt := createJWTAuthMiddleware()
+
+	// An empty key would let anyone sign accepted tokens
+	result := jwt.Fn(&Map{Pairs: map[string]Object{"secret": &String{Value: ""}}})
+	errObj, ok := result.(*Error)
+	if !ok || errObj.Message != "jwt() option 'secret' must be a non-empty string" {
+		t.Errorf("expected empty secret to be rejected, got %s", result.Inspect())
+	}
+}
+
+func TestHTTPAuthJWTRS256(t *testing.T) {
+	t.Parallel()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyFile := filepath.Join(t.TempDir(), "public.pem")
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	handler := testHTTPServer(t, `
+	server.use(http.auth.jwt({"publicKey": "`+filepath.ToSlash(keyFile)+`"}))
+	server.get("/", function(req: http.Request): http.Response {
+		return http.Response(200, req.user["sub"])
+	})
+	`)
+
+	claims := map[string]interface{}{"sub": "user-2"}
+	rec := doRequest(handler, authRequest("/", "Bearer "+signTestJWT(t, "RS256", key, claims)))
+	if rec.Code != http.StatusOK || rec.Body.String() != "user-2" {
+		t.Errorf("wrong RS256 response: %d %s", rec.Code, rec.Body.String())
+	}
+
+	// A token signed with HS256 must not be accepted with the public key as secret
+	rec = doRequest(handler, authRequest("/", "Bearer "+signTestJWT(t, "HS256", der, claims)))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 for algorithm confusion, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,228 @@
+package interpreter
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"maps"
+	"math/big"
+	"net"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// startConfig holds the options accepted by server.start(port, options?).
+type startConfig struct {
+	host       string // bind address; empty means all interfaces
+	certFile   string
+	keyFile    string
+	selfSigned bool
+}
+
+// parseStartOptions validates the server.start() options map:
+//
+//	{"host": "127.0.0.1", "cert": "cert.pem", "key": "key.pem", "selfSigned": true}
+func parseStartOptions(options *Map) (*startConfig, *Error) {
+	config := &startConfig{}
+
+	for key, value := range options.Pairs {
+		switch key {
+		case "host", "cert", "key":
+			str, ok := value.(*String)
+			if !ok {
+				return nil, newError("start() option '%s' must be string, got %s", key, value.Type())
+			}
+			switch key {
+			case "host":
+				config.host = str.Value
+			case "cert":
+				config.certFile = str.Value
+			default:
+				config.keyFile = str.Value
+			}
+		case "selfSigned":
+			b, ok := value.(*Boolean)
+			if !ok {
+				return nil, newError("start() option 'selfSigned' must be boolean, got %s", value.Type())
+			}
+			config.selfSigned = b.Value
+		default:
+			return nil, newError("start() unknown option '%s'", key)
+		}
+	}
+
+	if (config.certFile == "") != (config.keyFile == "") {
+		return nil, newError("start() options 'cert' and 'key' must be given together")
+	}
+	if config.selfSigned && config.certFile != "" {
+		return nil, newError("start() option 'selfSigned' cannot be combined with 'cert' and 'key'")
+	}
+
+	return config, nil
+}
+
+// useTLS reports whether the server should be started with HTTPS.
+func (c *startConfig) useTLS() bool {
+	return c.certFile != "" || c.selfSigned
+}
+
+// tlsConfig loads the server certificate, generating one for selfSigned.
+func (c *startConfig) tlsConfig() (*tls.Config, error) {
+	var cert tls.Certificate
+	var err error
+
+	if c.selfSigned {
+		hosts := []string{"localhost", "127.0.0.1", "::1"}
+		if c.host != "" {
+			hosts = append(hosts, c.host)
+		}
+		var certPEM, keyPEM []byte
+		certPEM, keyPEM, err = generateSelfSignedCert(hosts)
+		if err == nil {
+			cert, err = tls.X509KeyPair(certPEM, keyPEM)
+		}
+	} else {
+		cert, err = tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}, nil
+}
+
+// generateSelfSignedCert creates a PEM encoded certificate and private key for
+// local development, valid for the given host names and IP addresses.
+func generateSelfSignedCert(hosts []string) ([]byte, []byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{Organization: []string{"TotalScript development"}},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(365 * 24 * time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	return certPEM, keyPEM, nil
+}
+
+// clientTLSConfig builds the TLS configuration for client options:
+//
+//	{"ca": "ca.pem", "cert": "client.pem", "key": "client-key.pem", "insecureSkipVerify": true}
+//
+// It returns nil when options contain no TLS settings.
+func clientTLSConfig(options *Map) (*tls.Config, *Error) {
+	if options == nil {
+		return nil, nil
+	}
+
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	configured := false
+	var certFile, keyFile string
+
+	for key, value := range options.Pairs {
+		switch key {
+		case "ca", "cert", "key":
+			str, ok := value.(*String)
+			if !ok {
+				return nil, newError("client option '%s' must be string, got %s", key, value.Type())
+			}
+			switch key {
+			case "ca":
+				pemBytes, err := os.ReadFile(str.Value)
+				if err != nil {
+					return nil, &Error{Message: "failed to read CA bundle: " + err.Error()}
+				}
+				pool := x509.NewCertPool()
+				if !pool.AppendCertsFromPEM(pemBytes) {
+					return nil, &Error{Message: "no certificates found in CA bundle " + str.Value}
+				}
+				config.RootCAs = pool
+			case "cert":
+				certFile = str.Value
+			default:
+				keyFile = str.Value
+			}
+		case "insecureSkipVerify":
+			b, ok := value.(*Boolean)
+			if !ok {
+				return nil, newError("client option 'insecureSkipVerify' must be boolean, got %s", value.Type())
+			}
+			config.InsecureSkipVerify = b.Value //nolint:gosec // explicitly requested for local testing
+		default:
+			return nil, newError("unknown client option '%s'", key)
+		}
+		configured = true
+	}
+
+	if (certFile == "") != (keyFile == "") {
+		return nil, newError("client options 'cert' and 'key' must be given together")
+	}
+	if certFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, &Error{Message: "failed to load client certificate: " + err.Error()}
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	if !configured {
+		return nil, nil
+	}
+	return config, nil
+}
+
+// isClientTLSOption reports whether key is handled by clientTLSConfig.
+func isClientTLSOption(key string) bool {
+	switch key {
+	case "ca", "cert", "key", "insecureSkipVerify":
+		return true
+	}
+	return false
+}
+
+// tlsOptionsKey returns a key identifying the TLS options in options.
+func tlsOptionsKey(options *Map) string {
+	var key strings.Builder
+	for _, name := range slices.Sorted(maps.Keys(options.Pairs)) {
+		key.WriteString(name + "=" + options.Pairs[name].Inspect() + "\n")
+	}
+	return key.String()
+}
@@ -0,0 +1,703 @@
+package interpreter
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha1" //nolint:gosec // SHA-1 is mandated by the WebSocket handshake (RFC 6455)
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// websocketMethod is the pseudo HTTP method under which WebSocket routes are stored.
+const websocketMethod = "WEBSOCKET"
+
+// websocketGUID is appended to the client key to compute Sec-WebSocket-Accept.
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// WebSocket frame opcodes.
+const (
+	wsOpContinuation = 0x0
+	wsOpText         = 0x1
+	wsOpBinary       = 0x2
+	wsOpClose        = 0x8
+	wsOpPing         = 0x9
+	wsOpPong         = 0xA
+)
+
+// wsMaxControlPayload limits the payload of close, ping and pong frames.
+const wsMaxControlPayload = 125
+
+// defaultWebSocketMessageSize limits incoming messages on client connections.
+const defaultWebSocketMessageSize = 16 << 20 // 16 MiB
+
+// defaultWebSocketReadTimeout closes server connections that receive nothing for this long.
+const defaultWebSocketReadTimeout = 60 * time.Second
+
+// WebSocket close codes (RFC 6455, section 7.4.1).
+const (
+	wsCloseNormal   = 1000
+	wsCloseProtocol = 1002
+	wsCloseTooLarge = 1009
+)
+
+var (
+	errWebSocketClosed   = errors.New("websocket closed")
+	errWebSocketTooLarge = errors.New("websocket message too large")
+	errWebSocketProtocol = errors.New("websocket protocol error")
+)
+
+// wsConn is a WebSocket connection (server or client side).
+type wsConn struct {
+	conn        net.Conn
+	reader      *bufio.Reader
+	client      bool          // client connections mask outgoing frames
+	maxSize     int64         // maximum incoming message size
+	readTimeout time.Duration // maximum wait for each incoming frame; 0 waits forever
+	writeMu     sync.Mutex
+	closed      bool
+	closeMux    sync.Mutex
+}
+
+// websocketConfig holds the options of a WebSocket route.
+type websocketConfig struct {
+	origins     []string      // allowed Origin values besides the server's own; "*" allows any
+	readTimeout time.Duration // see wsConn.readTimeout
+}
+
+// newWebSocketConfig creates a route configuration from server.websocket() options:
+//
+//	{"origins": ["https://app.example.com"], "readTimeoutMs": 30000}
+func newWebSocketConfig(options *Map) (*websocketConfig, *Error) {
+	config := &websocketConfig{readTimeout: defaultWebSocketReadTimeout}
+	if options == nil {
+		return config, nil
+	}
+
+	for key, value := range options.Pairs {
+		switch key {
+		case "origins":
+			origins, ok := value.(*Array)
+			if !ok {
+				return nil, newError("websocket() option 'origins' must be array, got %s", value.Type())
+			}
+			for _, element := range origins.Elements {
+				origin, ok := element.(*String)
+				if !ok {
+					return nil, newError("websocket() option 'origins' must contain strings, got %s", element.Type())
+				}
+				config.origins = append(config.origins, strings.TrimSuffix(origin.Value, "/"))
+			}
+		case "readTimeoutMs":
+			n, ok := value.(*Integer)
+			if !ok || n.Value < 0 {
+				return nil, newError("websocket() option 'readTimeoutMs' must be a non-negative integer")
+			}
+			config.readTimeout = time.Duration(n.Value) * time.Millisecond
+		default:
+			return nil, newError("websocket() unknown option '%s'", key)
+		}
+	}
+	return config, nil
+}
+
+// allowsOrigin reports whether a handshake from origin may be accepted for host.
+// Requests without an Origin header do not come from browsers and are allowed.
+func (config *websocketConfig) allowsOrigin(origin, host string) bool {
+	if origin == "" {
+		return true
+	}
+	for _, allowed := range config.origins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, host)
+}
+
+// createWebSocketMethod creates the server.websocket(path, handler, options?) method.
+func createWebSocketMethod(state *httpServerState) *Builtin {
+	return &Builtin{
+		Name: "websocket",
+		Fn: func(args ...Object) Object {
+			if len(args) < 2 || len(args) > 3 {
+				return newError("websocket() takes 2-3 arguments (path, handler, options?), got %d", len(args))
+			}
+
+			path, ok := args[0].(*String)
+			if !ok {
+				return newError("websocket() path must be string, got %s", args[0].Type())
+			}
+
+			handler, ok := args[1].(*Function)
+			if !ok {
+				return newError("websocket() handler must be function, got %s", args[1].Type())
+			}
+
+			var options *Map
+			if len(args) == 3 {
+				options, ok = args[2].(*Map)
+				if !ok {
+					return newError("websocket() options must be map, got %s", args[2].Type())
+				}
+			}
+			config, err := newWebSocketConfig(options)
+			if err != nil {
+				return err
+			}
+
+			if state.routes[websocketMethod] == nil {
+				state.routes[websocketMethod] = make(map[string]Object)
+			}
+			state.routes[websocketMethod][path.Value] = handler
+			state.websockets[path.Value] = config
+
+			return NULL
+		},
+	}
+}
+
+// isWebSocketUpgrade reports whether r asks to switch to the WebSocket protocol.
+func isWebSocketUpgrade(r *http.Request) bool {
+	return r.Method == http.MethodGet &&
+		strings.EqualFold(r.Header.Get("Upgrade"), "websocket") &&
+		headerContainsToken(r.Header, "Connection", "upgrade")
+}
+
+// headerContainsToken checks a comma-separated header for a token (case-insensitive).
+func headerContainsToken(header http.Header, name, token string) bool {
+	for _, value := range header.Values(name) {
+		for _, part := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(part), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// websocketRouteHandler returns the final handler of the middleware chain for a
+// WebSocket route. It defers the actual upgrade to the server.
+func websocketRouteHandler(handler Object, config *websocketConfig) *Builtin {
+	return &Builtin{
+		Name: "websocketUpgrade",
+		Fn: func(args ...Object) Object {
+			if len(args) != 1 {
+				return newError("websocket handler requires 1 argument (request)")
+			}
+			upgrade := &WebSocketUpgrade{Handler: handler, Request: args[0], config: config}
+			return newResponse(http.StatusSwitchingProtocols, upgrade, &Map{Pairs: make(map[string]Object)})
+		},
+	}
+}
+
+// websocketAccept computes the Sec-WebSocket-Accept value for a client key.
+func websocketAccept(key string) string {
+	//nolint:gosec
+	h := sha1.New()
+	h.Write([]byte(key + websocketGUID))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// serveWebSocket completes the handshake and runs the TotalScript handler.
+// Headers set on the upgrade response by middleware (e.g. Set-Cookie) are
+// sent with the handshake.
+//
+//nolint:funlen
+func serveWebSocket(w http.ResponseWriter, r *http.Request, response *ModelInstance, upgrade *WebSocketUpgrade, maxSize int64) {
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if key == "" || r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.WriteHeader(http.StatusBadRequest)
+		//nolint:errcheck,gosec
+		w.Write([]byte("invalid websocket handshake"))
+		return
+	}
+	if !upgrade.config.allowsOrigin(r.Header.Get("Origin"), r.Host) {
+		w.WriteHeader(http.StatusForbidden)
+		//nolint:errcheck,gosec
+		w.Write([]byte("websocket origin not allowed"))
+		return
+	}
+
+	header := make(http.Header)
+	if headersMap, ok := response.Fields["headers"].(*Map); ok {
+		if err := setResponseHeaders(header, headersMap); err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			//nolint:errcheck,gosec
+			w.Write([]byte(err.Message))
+			return
+		}
+	}
+	for _, name := range []string{"Upgrade", "Connection", "Sec-WebSocket-Accept", "Content-Length"} {
+		header.Del(name)
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		//nolint:errcheck,gosec
+		w.Write([]byte("websocket not supported"))
+		return
+	}
+
+	netConn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return
+	}
+
+	handshake := "HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + websocketAccept(key) + "\r\n"
+	if _, err := rw.WriteString(handshake); err != nil {
+		_ = netConn.Close()
+		return
+	}
+	if err := header.Write(rw); err != nil {
+		_ = netConn.Close()
+		return
+	}
+	if _, err := rw.WriteString("\r\n"); err != nil {
+		_ = netConn.Close()
+		return
+	}
+	if err := rw.Flush(); err != nil {
+		_ = netConn.Close()
+		return
+	}
+
+	conn := &wsConn{conn: netConn, reader: rw.Reader, maxSize: maxSize, readTimeout: upgrade.config.readTimeout}
+	//nolint:errcheck
+	defer conn.close(wsCloseNormal, "")
+
+	callTSFunction(upgrade.Handler, createWebSocketObject(conn, upgrade.Request))
+}
+
+// dialWebSocket opens a client WebSocket connection to rawURL (ws:// or wss://).
+//
+//nolint:funlen
+func dialWebSocket(rawURL string, headers http.Header, tlsConfig *tls.Config) (*wsConn, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	host := u.Host
+	var netConn net.Conn
+	dialer := &net.Dialer{Timeout: 30 * time.Second}
+
+	switch u.Scheme {
+	case "ws":
+		if u.Port() == "" {
+			host += ":80"
+		}
+		netConn, err = dialer.DialContext(context.Background(), "tcp", host)
+	case "wss":
+		if u.Port() == "" {
+			host += ":443"
+		}
+		if tlsConfig == nil {
+			tlsConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+		}
+		tlsConfig = tlsConfig.Clone()
+		tlsConfig.ServerName = u.Hostname()
+		tlsDialer := &tls.Dialer{NetDialer: dialer, Config: tlsConfig}
+		netConn, err = tlsDialer.DialContext(context.Background(), "tcp", host)
+	default:
+		return nil, fmt.Errorf("%w: unsupported scheme %q (expected ws or wss)", errWebSocketProtocol, u.Scheme)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	keyBytes := make([]byte, 16)
+	_, _ = rand.Read(keyBytes)
+	key := base64.StdEncoding.EncodeToString(keyBytes)
+
+	req := &http.Request{
+		Method:     http.MethodGet,
+		URL:        &url.URL{Path: u.Path, RawQuery: u.RawQuery},
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     make(http.Header),
+		Host:       u.Host,
+	}
+	if req.URL.Path == "" {
+		req.URL.Path = "/"
+	}
+	for name, values := range headers {
+		for _, v := range values {
+			req.Header.Add(name, v)
+		}
+	}
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Sec-WebSocket-Key", key)
+	req.Header.Set("Sec-WebSocket-Version", "13")
+
+	if err := req.Write(netConn); err != nil {
+		_ = netConn.Close()
+		return nil, err
+	}
+
+	reader := bufio.NewReader(netConn)
+	resp, err := http.ReadResponse(reader, req)
+	if err != nil {
+		_ = netConn.Close()
+		return nil, err
+	}
+	_ = resp.Body.Close()
+
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		_ = netConn.Close()
+		return nil, fmt.Errorf("%w: handshake failed with status %d", errWebSocketProtocol, resp.StatusCode)
+	}
+	if resp.Header.Get("Sec-WebSocket-Accept") != websocketAccept(key) {
+		_ = netConn.Close()
+		return nil, fmt.Errorf("%w: invalid Sec-WebSocket-Accept", errWebSocketProtocol)
+	}
+
+	return &wsConn{conn: netConn, reader: reader, client: true, maxSize: defaultWebSocketMessageSize}, nil
+}
+
+// writeFrame writes a single final frame.
+func (c *wsConn) writeFrame(opcode byte, payload []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	header := []byte{0x80 | opcode, 0}
+	length := len(payload)
+	switch {
+	case length < 126:
+		header[1] = byte(length)
+	case length <= 0xFFFF:
+		header[1] = 126
+		header = binary.BigEndian.AppendUint16(header, uint16(length))
+	default:
+		header[1] = 127
+		header = binary.BigEndian.AppendUint64(header, uint64(length))
+	}
+
+	if c.client {
+		header[1] |= 0x80
+		mask := make([]byte, 4)
+		_, _ = rand.Read(mask)
+		header = append(header, mask...)
+		masked := make([]byte, length)
+		for i := range payload {
+			masked[i] = payload[i] ^ mask[i%4]
+		}
+		payload = masked
+	}
+
+	if _, err := c.conn.Write(header); err != nil {
+		return err
+	}
+	_, err := c.conn.Write(payload)
+	return err
+}
+
+// readFrame reads a single frame and returns its FIN flag, opcode and payload.
+// Frames from clients must be masked and frames from servers must not be.
+func (c *wsConn) readFrame() (bool, byte, []byte, error) {
+	if c.readTimeout > 0 {
+		if err := c.conn.SetReadDeadline(time.Now().Add(c.readTimeout)); err != nil {
+			return false, 0, nil, err
+		}
+	}
+
+	var head [2]byte
+	if _, err := io.ReadFull(c.reader, head[:]); err != nil {
+		return false, 0, nil, err
+	}
+
+	fin := head[0]&0x80 != 0
+	opcode := head[0] & 0x0F
+	masked := head[1]&0x80 != 0
+	length := int64(head[1] & 0x7F)
+	if masked == c.client {
+		return false, 0, nil, errWebSocketProtocol
+	}
+	// Control frames (close, ping, pong) cannot be fragmented and carry at
+	// most 125 bytes, so the 16 and 64-bit lengths are not allowed
+	if opcode&0x08 != 0 && (!fin || length > wsMaxControlPayload) {
+		return false, 0, nil, errWebSocketProtocol
+	}
+
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.reader, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = int64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(c.reader, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = int64(binary.BigEndian.Uint64(ext[:])) //nolint:gosec
+	}
+
+	if length < 0 || length > c.maxSize {
+		return false, 0, nil, errWebSocketTooLarge
+	}
+
+	var mask [4]byte
+	if masked {
+		if _, err := io.ReadFull(c.reader, mask[:]); err != nil {
+			return false, 0, nil, err
+		}
+	}
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(c.reader, payload); err != nil {
+		return false, 0, nil, err
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+
+	return fin, opcode, payload, nil
+}
+
+// readMessage reads the next data message, answering pings and handling close frames.
+func (c *wsConn) readMessage() ([]byte, error) {
+	var message []byte
+	started := false
+
+	for {
+		fin, opcode, payload, err := c.readFrame()
+		if err != nil {
+			return nil, err
+		}
+
+		switch opcode {
+		case wsOpPing:
+			if err := c.writeFrame(wsOpPong, payload); err != nil {
+				return nil, err
+			}
+			continue
+		case wsOpPong:
+			continue
+		case wsOpClose:
+			_ = c.close(wsCloseNormal, "")
+			return nil, errWebSocketClosed
+		case wsOpText, wsOpBinary:
+			if started {
+				return nil, errWebSocketProtocol
+			}
+			started = true
+			message = payload
+		case wsOpContinuation:
+			if !started {
+				return nil, errWebSocketProtocol
+			}
+			message = append(message, payload...)
+		default:
+			return nil, errWebSocketProtocol
+		}
+
+		if int64(len(message)) > c.maxSize {
+			return nil, errWebSocketTooLarge
+		}
+		if fin {
+			return message, nil
+		}
+	}
+}
+
+// close sends a close frame (once) and closes the underlying connection.
+func (c *wsConn) close(code int, reason string) error {
+	c.closeMux.Lock()
+	defer c.closeMux.Unlock()
+
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+
+	payload := binary.BigEndian.AppendUint16(nil, uint16(code)) //nolint:gosec
+	payload = append(payload, reason...)
+	_ = c.writeFrame(wsOpClose, payload)
+	return c.conn.Close()
+}
+
+// closeCode returns the close code for a failed read.
+func closeCode(err error) int {
+	switch {
+	case errors.Is(err, errWebSocketProtocol):
+		return wsCloseProtocol
+	case errors.Is(err, errWebSocketTooLarge):
+		return wsCloseTooLarge
+	default:
+		return wsCloseNormal
+	}
+}
+
+// isValidCloseCode reports whether code may be sent in a close frame. Codes
+// 1004, 1005, 1006 and 1015 are reserved and never sent (RFC 6455 §7.4.1).
+func isValidCloseCode(code int64) bool {
+	switch code {
+	case 1004, 1005, 1006, 1015:
+		return false
+	}
+	return code >= 1000 && code <= 4999
+}
+
+// isClosed reports whether close() has been called.
+func (c *wsConn) isClosed() bool {
+	c.closeMux.Lock()
+	defer c.closeMux.Unlock()
+	return c.closed
+}
+
+// createWebSocketObject creates the TotalScript connection object with
+// send(), receive() and close() methods. request is null on client connections.
+func createWebSocketObject(conn *wsConn, request Object) *Map {
+	obj := &Map{Pairs: make(map[string]Object)}
+	if request == nil {
+		request = NULL
+	}
+	obj.Pairs["request"] = request
+
+	// send(message) - strings are sent as text, other values as JSON text
+	obj.Pairs["send"] = &Builtin{
+		Name: "send",
+		Fn: func(args ...Object) Object {
+			if len(args) != 1 {
+				return newError("send() takes 1 argument (message), got %d", len(args))
+			}
+			if conn.isClosed() {
+				return &Error{Message: errWebSocketClosed.Error()}
+			}
+			payload, err := encodeChunk(args[0])
+			if err != nil {
+				return err
+			}
+			if err := conn.writeFrame(wsOpText, payload); err != nil {
+				return &Error{Message: "websocket send failed: " + err.Error()}
+			}
+			return NULL
+		},
+	}
+
+	// receive() - returns the next message as a string, or Error once the connection closes
+	obj.Pairs["receive"] = &Builtin{
+		Name: "receive",
+		Fn: func(args ...Object) Object {
+			if len(args) != 0 {
+				return newError("receive() takes no arguments")
+			}
+			if conn.isClosed() {
+				return &Error{Message: errWebSocketClosed.Error()}
+			}
+			message, err := conn.readMessage()
+			if err != nil {
+				_ = conn.close(closeCode(err), "")
+				if errors.Is(err, errWebSocketClosed) || errors.Is(err, io.EOF) {
+					return &Error{Message: errWebSocketClosed.Error()}
+				}
+				return &Error{Message: "websocket receive failed: " + err.Error()}
+			}
+			return &String{Value: string(message)}
+		},
+	}
+
+	// close(code?, reason?) - closes the connection
+	obj.Pairs["close"] = &Builtin{
+		Name: "close",
+		Fn: func(args ...Object) Object {
+			if len(args) > 2 {
+				return newError("close() takes 0-2 arguments (code?, reason?), got %d", len(args))
+			}
+			code := int64(wsCloseNormal)
+			reason := ""
+			if len(args) >= 1 {
+				c, ok := args[0].(*Integer)
+				if !ok {
+					return newError("close() code must be integer, got %s", args[0].Type())
+				}
+				if !isValidCloseCode(c.Value) {
+					return newError("close() code must be 1000-4999 and not reserved (1004, 1005, 1006, 1015), got %d", c.Value)
+				}
+				code = c.Value
+			}
+			if len(args) == 2 {
+				r, ok := args[1].(*String)
+				if !ok {
+					return newError("close() reason must be string, got %s", args[1].Type())
+				}
+				if len(r.Value) > wsMaxControlPayload-2 {
+					return newError("close() reason must be at most %d bytes, got %d", wsMaxControlPayload-2, len(r.Value))
+				}
+				reason = r.Value
+			}
+			_ = conn.close(int(code), reason)
+			return NULL
+		},
+	}
+
+	return obj
+}
+
+// createClientWebSocketMethod creates the http.client.websocket(url, headers?, options?) method.
+func createClientWebSocketMethod() *Builtin {
+	return &Builtin{
+		Name: "websocket",
+		Fn: func(args ...Object) Object {
+			if len(args) < 1 || len(args) > 3 {
+				return newError("websocket() takes 1-3 arguments (url, headers?, options?), got %d", len(args))
+			}
+
+			rawURL, ok := args[0].(*String)
+			if !ok {
+				return newError("websocket() url must be string, got %s", args[0].Type())
+			}
+
+			headers := make(http.Header)
+			if len(args) >= 2 {
+				headersMap, ok := args[1].(*Map)
+				if !ok {
+					return newError("websocket() headers must be map, got %s", args[1].Type())
+				}
+				if err := setResponseHeaders(headers, headersMap); err != nil {
+					return err
+				}
+			}
+
+			var options *Map
+			if len(args) == 3 {
+				options, ok = args[2].(*Map)
+				if !ok {
+					return newError("websocket() options must be map, got %s", args[2].Type())
+				}
+			}
+			tlsConfig, tlsErr := clientTLSConfig(options)
+			if tlsErr != nil {
+				return tlsErr
+			}
+
+			conn, err := dialWebSocket(rawURL.Value, headers, tlsConfig)
+			if err != nil {
+				return &Error{Message: err.Error()}
+			}
+
+			return createWebSocketObject(conn, nil)
+		},
+	}
+}
@@ -0,0 +1,205 @@
+package interpreter
+
+import (
+	"encoding/binary"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHTTPWebSocket(t *testing.T) {
+	t.Parallel()
+	handler := testHTTPServer(t, `
+	server.use(function(req, next) {
+		if req.query["token"] == null {
+			return http.Response(401, "unauthorized")
+		}
+		return next(req)
+	})
+	server.websocket("/ws/:room", function(conn) {
+		while true {
+			var msg = conn.receive()
+			conn.send({"room": conn.request.params["room"], "echo": msg})
+		}
+	})
+	`)
+
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+	wsURL := "ws" + strings.TrimPrefix(ts.URL, "http")
+
+	result := testHTTPClient(t, `
+	var conn = http.client.websocket("`+wsURL+`/ws/lobby?token=x")
+	conn.send("hi")
+	var first = conn.receive()
+	conn.send("again")
+	var second = conn.receive()
+	conn.close()
+	var messages = [first, second]
+	messages
+	`)
+
+	arr, ok := result.(*Array)
+	if !ok {
+		t.Fatalf("expected array, got %s (%s)", result.Type(), result.Inspect())
+	}
+	expected := []string{`{"echo":"hi","room":"lobby"}`, `{"echo":"again","room":"lobby"}`}
+	for i, want := range expected {
+		if str, ok := arr.Elements[i].(*String); !ok || str.Value != want {
+			t.Errorf("wrong message %d. expected=%q, got=%s", i, want, arr.Elements[i].Inspect())
+		}
+	}
+
+	// Middleware rejects the handshake before the upgrade
+	result = testHTTPClient(t, `http.client.websocket("`+wsURL+`/ws/lobby")`)
+	errObj, ok := result.(*Error)
+	if !ok || !strings.Contains(errObj.Message, "401") {
+		t.Errorf("expected handshake error with 401, got %s", result.Inspect())
+	}
+}
+
+func TestHTTPWebSocketReceiveAfterClose(t *testing.T) {
+	t.Parallel()
+	handler := testHTTPServer(t, `
+	server.websocket("/ws", function(conn) {
+		conn.send("bye")
+		conn.close()
+	})
+	`)
+
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+	wsURL := "ws" + strings.TrimPrefix(ts.URL, "http")
+
+	result := testHTTPClient(t, `
+	var conn = http.client.websocket("`+wsURL+`/ws")
+	var msg = conn.receive()
+	conn.receive()
+	`)
+
+	errObj, ok := result.(*Error)
+	if !ok || errObj.Message != "websocket closed" {
+		t.Errorf("expected websocket closed error, got %s", result.Inspect())
+	}
+}
+
+func TestHTTPWebSocketOrigins(t *testing.T) {
+	t.Parallel()
+	handler := testHTTPServer(t, `
+	server.websocket("/same", function(conn) { conn.close() })
+	server.websocket("/app", function(conn) { conn.close() }, {"origins": ["https://app.example.com"]})
+	`)
+
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+	wsURL := "ws" + strings.TrimPrefix(ts.URL, "http")
+
+	tests := []struct {
+		path    string
+		origin  string
+		allowed bool
+	}{
+		{"/same", "", true},
+		{"/same", ts.URL, true},
+		{"/same", "https://evil.example.com", false},
+		{"/app", "https://app.example.com", true},
+		{"/app", "https://evil.example.com", false},
+	}
+
+	for _, tt := range tests {
+		headers := make(http.Header)
+		if tt.origin != "" {
+			headers.Set("Origin", tt.origin)
+		}
+		conn, err := dialWebSocket(wsURL+tt.path, headers, nil)
+		if conn != nil {
+			_ = conn.close(wsCloseNormal, "")
+		}
+		if allowed := err == nil; allowed != tt.allowed {
+			t.Errorf("%s from %q: expected allowed=%t, got error %v", tt.path, tt.origin, tt.allowed, err)
+		}
+		if !tt.allowed && (err == nil || !strings.Contains(err.Error(), "403")) {
+			t.Errorf("%s from %q: expected 403 handshake error, got %v", tt.path, tt.origin, err)
+		}
+	}
+}
+
+func TestHTTPWebSocketCloseCodes(t *testing.T) {
+	t.Parallel()
+	handler := testHTTPServer(t, `
+	server.websocket("/echo", function(conn) { conn.send(conn.receive()) })
+	server.websocket("/idle", function(conn) { conn.send(conn.receive()) }, {"readTimeoutMs": 20})
+	server.websocket("/bye", function(conn) { conn.close(4000, "bye") })
+	`)
+
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+	wsURL := "ws" + strings.TrimPrefix(ts.URL, "http")
+
+	tests := []struct {
+		path  string
+		frame []byte // written as-is
+		code  int
+	}{
+		{"/echo", []byte{0x81, 0x02, 'h', 'i'}, wsCloseProtocol},
+		// Control frames must not be fragmented or carry more than 125 bytes
+		{"/echo", []byte{0x09, 0x80, 0, 0, 0, 0}, wsCloseProtocol},
+		{"/echo", append([]byte{0x89, 0x80 | 126, 0, 126, 0, 0, 0, 0}, make([]byte, 126)...), wsCloseProtocol},
+		{"/echo", append([]byte{0x88, 0x80 | 126, 0, 126, 0, 0, 0, 0}, make([]byte, 126)...), wsCloseProtocol},
+		{"/idle", nil, wsCloseNormal},
+		{"/bye", nil, 4000},
+	}
+
+	for _, tt := range tests {
+		conn, err := dialWebSocket(wsURL+tt.path, nil, nil)
+		if err != nil {
+			t.Fatalf("%s: dial error: %v", tt.path, err)
+		}
+		conn.readTimeout = 2 * time.Second
+		if tt.frame != nil {
+			_, _ = conn.conn.Write(tt.frame)
+		}
+
+		_, opcode, payload, err := conn.readFrame()
+		_ = conn.conn.Close()
+		if err != nil || opcode != wsOpClose || len(payload) < 2 {
+			t.Errorf("%s: expected close frame, got opcode %d (%v)", tt.path, opcode, err)
+			continue
+		}
+		if code := int(binary.BigEndian.Uint16(payload)); code != tt.code {
+			t.Errorf("%s: expected close code %d, got %d", tt.path, tt.code, code)
+		}
+	}
+}
+
+func TestWebSocketCloseValidation(t *testing.T) {
+	t.Parallel()
+	closeFn := createWebSocketObject(&wsConn{}, nil).Pairs["close"].(*Builtin)
+
+	tests := []struct {
+		args     []Object
+		expected string
+	}{
+		{[]Object{&Integer{Value: 999}}, "close() code must be 1000-4999 and not reserved (1004, 1005, 1006, 1015), got 999"},
+		{[]Object{&Integer{Value: 5000}}, "close() code must be 1000-4999 and not reserved (1004, 1005, 1006, 1015), got 5000"},
+		{[]Object{&Integer{Value: 65536 + 1000}}, "close() code must be 1000-4999 and not reserved (1004, 1005, 1006, 1015), got 66536"},
+		{[]Object{&Integer{Value: 1005}}, "close() code must be 1000-4999 and not reserved (1004, 1005, 1006, 1015), got 1005"},
+		{[]Object{&Integer{Value: 1006}}, "close() code must be 1000-4999 and not reserved (1004, 1005, 1006, 1015), got 1006"},
+		{[]Object{&Integer{Value: 1000}, &String{Value: strings.Repeat("x", 124)}}, "close() reason must be at most 123 bytes, got 124"},
+	}
+
+	for _, tt := range tests {
+		errObj, ok := closeFn.Fn(tt.args...).(*Error)
+		if !ok || errObj.Message != tt.expected {
+			t.Errorf("close(%v): expected error %q, got %v", tt.args, tt.expected, errObj)
+		}
+	}
+
+	for _, code := range []int64{1000, 1003, 1007, 1014, 3000, 4999} {
+		if !isValidCloseCode(code) {
+			t.Errorf("expected close code %d to be valid", code)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package interpreter
+
+import (
+	"maps"
+	"slices"
+
+	"github.com/mishankov/totalscript-lang/internal/ast"
+)
+
+// inheritModel copies the fields, annotations and methods of the base model
+// named by typeExpr into model. Constructors are not inherited: they return
+// instances of the base model.
+func inheritModel(model *Model, typeExpr *ast.TypeExpression, env *Environment) Object {
+	typeObj, err := lookupType(typeExpr.String(), env)
+	if err != nil {
+		return err
+	}
+	base, ok := typeObj.(*Model)
+	if !ok {
+		return newError("'%s' is not a model", typeExpr.String())
+	}
+
+	model.Base = base
+	model.FieldNames = slices.Clone(base.FieldNames)
+	maps.Copy(model.Fields, base.Fields)
+	maps.Copy(model.Annotations, base.Annotations)
+	maps.Copy(model.Methods, base.Methods)
+	return nil
+}
+
+// superModel returns the model whose constructor or method is running, if
+// it extends another model.
+func superModel(env *Environment) (*Model, *Error) {
+	value, _ := env.Get("super")
+	model, ok := value.(*Model)
+	if !ok {
+		return nil, newError("'super' can only be used inside a model extending another model")
+	}
+	return model, nil
+}
+
+// evalSuperCall evaluates super(args) in a derived model: a constructor of
+// the base model initializes the inherited fields of a new instance of the
+// derived model. Its own fields start as null.
+func evalSuperCall(node *ast.CallExpression, env *Environment) Object {
+	model, err := superModel(env)
+	if err != nil {
+		return err
+	}
+
+	args := evalExpressions(node.Arguments, env)
+	if len(args) == 1 && IsError(args[0]) {
+		return args[0]
+	}
+	named, errObj := evalNamedArguments(node.NamedArguments, env)
+	if errObj != nil {
+		return errObj
+	}
+
+	result := applyFunction(model.Base, args, named, env)
+	if IsError(result) {
+		return result
+	}
+	base, ok := result.(*ModelInstance)
+	if !ok || !base.Model.derivesFrom(model.Base) {
+		return newError("constructor of %s did not return a %s instance", model.Base.Name, model.Base.Name)
+	}
+
+	instance := &ModelInstance{
+		Model:   model,
+		Fields:  make(map[string]Object, len(model.FieldNames)),
+		Methods: base.Methods,
+	}
+	for _, name := range model.FieldNames {
+		if value, ok := base.Fields[name]; ok {
+			instance.Fields[name] = value
+		} else {
+			instance.Fields[name] = NULL
+		}
+	}
+	return instance
+}
+
+// evalSuperMember evaluates super.method in a method of a derived model: the
+// method of the base model, bound to this.
+func evalSuperMember(node *ast.MemberExpression, env *Environment) Object {
+	model, err := superModel(env)
+	if err != nil {
+		return err
+	}
+
+	name := node.Member.Value
+	method, ok := model.Base.Methods[name]
+	if !ok {
+		return newError("model %s has no method '%s'", model.Base.Name, name)
+	}
+	this, ok := env.Get("this")
+	if !ok {
+		return newError("'super.%s' can only be used inside a model method", name)
+	}
+	return bindMethod(method, this)
+}
+
+// bindMethod returns a method of a model with 'this' bound to the instance.
+func bindMethod(method *Function, instance Object) *Function {
+	methodEnv := NewEnclosedEnvironment(method.Env)
+	methodEnv.Set("this", instance)
+
+	return &Function{
+		Parameters: method.Parameters,
+		Body:       method.Body,
+		Env:        methodEnv,
+		ReturnType: method.ReturnType,
+	}
+}
@@ -0,0 +1,96 @@
+package interpreter
+
+import (
+	"fmt"
+
+	"github.com/mishankov/totalscript-lang/internal/ast"
+)
+
+func evalInterfaceLiteral(node *ast.InterfaceLiteral) Object {
+	iface := &Interface{
+		Name:       "", // Name will be set when assigned to a variable
+		FieldNames: make([]string, 0, len(node.Fields)),
+		Fields:     make(map[string]*ast.TypeExpression, len(node.Fields)),
+		Methods:    node.Methods,
+	}
+	for _, field := range node.Fields {
+		iface.FieldNames = append(iface.FieldNames, field.Name.Value)
+		iface.Fields[field.Name.Value] = field.Type
+	}
+	return iface
+}
+
+// checkImplements reports whether a model declared with implements lacks a
+// member of the interface.
+func checkImplements(model *Model, typeExpr *ast.TypeExpression, env *Environment) Object {
+	typeObj, err := lookupType(typeExpr.String(), env)
+	if err != nil {
+		return err
+	}
+	iface, ok := typeObj.(*Interface)
+	if !ok {
+		return newError("'%s' is not an interface", typeExpr.String())
+	}
+	if missing := missingMember(model, iface); missing != "" {
+		return newError("model does not implement %s: %s", typeExpr.String(), missing)
+	}
+	return nil
+}
+
+// missingMember describes the first member of an interface a model lacks,
+// or returns "" if the model conforms. Type annotations must be the same
+// where both the interface and the model have one.
+func missingMember(model *Model, iface *Interface) string {
+	for _, name := range iface.FieldNames {
+		fieldType, ok := model.Fields[name]
+		if !ok {
+			return fmt.Sprintf("missing field '%s'", name)
+		}
+		if expected := iface.Fields[name]; !sameAnnotation(fieldType, expected) {
+			return fmt.Sprintf("field '%s' has type %s, expected %s", name, fieldType, expected)
+		}
+	}
+
+	for _, method := range iface.Methods {
+		name := method.Name.Value
+		fn, ok := model.Methods[name]
+		if !ok {
+			return fmt.Sprintf("missing method '%s'", name)
+		}
+		if !acceptsArguments(fn.Parameters, len(method.Parameters)) {
+			return fmt.Sprintf("method '%s' does not take %d arguments", name, len(method.Parameters))
+		}
+		for i, param := range method.Parameters {
+			if i < len(fn.Parameters) && !fn.Parameters[i].Variadic && !sameAnnotation(fn.Parameters[i].Type, param.Type) {
+				return fmt.Sprintf("method '%s' parameter '%s' has type %s, expected %s",
+					name, fn.Parameters[i].Name.Value, fn.Parameters[i].Type, param.Type)
+			}
+		}
+		if !sameAnnotation(fn.ReturnType, method.ReturnType) {
+			return fmt.Sprintf("method '%s' returns %s, expected %s", name, fn.ReturnType, method.ReturnType)
+		}
+	}
+
+	return ""
+}
+
+// sameAnnotation reports whether two type annotations agree. A missing
+// annotation agrees with any type.
+func sameAnnotation(a, b *ast.TypeExpression) bool {
+	return a == nil || b == nil || a.String() == b.String()
+}
+
+// acceptsArguments reports whether a function with params can be called
+// with count positional arguments.
+func acceptsArguments(params []*ast.Parameter, count int) bool {
+	required := 0
+	for _, param := range params {
+		if param.Variadic {
+			return count >= required
+		}
+		if param.Default == nil {
+			required++
+		}
+	}
+	return count >= required && count <= len(params)
+}
@@ -23,6 +23,9 @@ func Eval(node ast.Node, env *Environment) Object {
 		return evalBlockStatement(node, env)
 
 	case *ast.VarStatement:
+		if node.Pattern != nil {
+			return evalDestructuring(node.Pattern, node.Value, env)
+		}
 		var val Object = NULL
 		if node.Value != nil {
 			val = Eval(node.Value, env)
@@ -33,23 +36,29 @@ func Eval(node ast.Node, env *Environment) Object {
 		// Validate type if type annotation is present
 		if node.Type != nil {
 			if err := validateType(val, node.Type, env); err != nil {
-				return err
+				return withNarrowingHint(err, node.Value, env)
 			}
 			// Coerce value if needed (e.g., integer to float)
 			val = coerceValue(val, node.Type)
 			// Store type annotation for future reassignments
 			env.SetType(node.Name.Value, node.Type)
 		}
-		// If assigning a model or enum, set its name
-		if model, ok := val.(*Model); ok {
-			model.Name = node.Name.Value
-		} else if enum, ok := val.(*Enum); ok {
-			enum.Name = node.Name.Value
+		// If assigning a model, enum or interface, set its name
+		switch typ := val.(type) {
+		case *Model:
+			typ.Name = node.Name.Value
+		case *Enum:
+			typ.Name = node.Name.Value
+		case *Interface:
+			typ.Name = node.Name.Value
 		}
 		env.Set(node.Name.Value, val)
 		return val
 
 	case *ast.ConstStatement:
+		if node.Pattern != nil {
+			return evalDestructuring(node.Pattern, node.Value, env)
+		}
 		val := Eval(node.Value, env)
 		if IsError(val) {
 			return val
@@ -57,22 +66,28 @@ func Eval(node ast.Node, env *Environment) Object {
 		// Validate type if type annotation is present
 		if node.Type != nil {
 			if err := validateType(val, node.Type, env); err != nil {
-				return err
+				return withNarrowingHint(err, node.Value, env)
 			}
 			// Coerce value if needed (e.g., integer to float)
 			val = coerceValue(val, node.Type)
 			// Store type annotation for constants too
 			env.SetType(node.Name.Value, node.Type)
 		}
-		// If assigning a model or enum, set its name
-		if model, ok := val.(*Model); ok {
-			model.Name = node.Name.Value
-		} else if enum, ok := val.(*Enum); ok {
-			enum.Name = node.Name.Value
+		// If assigning a model, enum or interface, set its name
+		switch typ := val.(type) {
+		case *Model:
+			typ.Name = node.Name.Value
+		case *Enum:
+			typ.Name = node.Name.Value
+		case *Interface:
+			typ.Name = node.Name.Value
 		}
 		env.Set(node.Name.Value, val)
 		return val
 
+	case *ast.MultiAssignStatement:
+		return evalMultiAssignStatement(node, env)
+
 	case *ast.ImportStatement:
 		return evalImportStatement(node, env)
 
@@ -101,6 +116,9 @@ func Eval(node ast.Node, env *Environment) Object {
 	case *ast.SwitchStatement:
 		return evalSwitchStatement(node, env)
 
+	case *ast.TryStatement:
+		return evalTryStatement(node, env)
+
 	// Expressions
 	case *ast.IntegerLiteral:
 		return &Integer{Value: node.Value}
@@ -111,6 +129,9 @@ func Eval(node ast.Node, env *Environment) Object {
 	case *ast.StringLiteral:
 		return &String{Value: node.Value}
 
+	case *ast.InterpolatedString:
+		return evalInterpolatedString(node, env)
+
 	case *ast.BooleanLiteral:
 		return nativeBoolToBooleanObject(node.Value)
 
@@ -136,6 +157,14 @@ func Eval(node ast.Node, env *Environment) Object {
 			return left
 		}
 
+		// The right side of ?? is only evaluated when the left side is null
+		if node.Operator == "??" {
+			if left.Type() != NullObj {
+				return left
+			}
+			return Eval(node.Right, env)
+		}
+
 		// Special handling for 'is' operator with type names
 		if node.Operator == "is" {
 			// Check if right side is an identifier (potential type name)
@@ -160,6 +189,12 @@ func Eval(node ast.Node, env *Environment) Object {
 		}
 		return evalInfixExpression(node.Operator, left, right)
 
+	case *ast.PropagateExpression:
+		return evalPropagateExpression(node, env)
+
+	case *ast.MatchExpression:
+		return evalMatchExpression(node, env)
+
 	case *ast.IfExpression:
 		return evalIfExpression(node, env)
 
@@ -188,18 +223,11 @@ func Eval(node ast.Node, env *Environment) Object {
 			}
 		}
 
-		return &Function{Parameters: params, Env: env, Body: body}
+		return &Function{Parameters: params, Env: env, Body: body, ReturnType: node.ReturnType}
 
 	case *ast.CallExpression:
-		function := Eval(node.Function, env)
-		if IsError(function) {
-			return function
-		}
-		args := evalExpressions(node.Arguments, env)
-		if len(args) == 1 && IsError(args[0]) {
-			return args[0]
-		}
-		return applyFunction(function, args, env)
+		result, _ := evalChain(node, env)
+		return result
 
 	case *ast.ArrayLiteral:
 		elements := evalExpressions(node.Elements, env)
@@ -209,27 +237,15 @@ func Eval(node ast.Node, env *Environment) Object {
 		return &Array{Elements: elements}
 
 	case *ast.IndexExpression:
-		left := Eval(node.Left, env)
-		if IsError(left) {
-			return left
-		}
-
-		// Check if this is a slice operation (index is a RangeExpression)
-		if rangeExpr, ok := node.Index.(*ast.RangeExpression); ok {
-			return evalSliceExpression(left, rangeExpr, env)
-		}
-
-		index := Eval(node.Index, env)
-		if IsError(index) {
-			return index
-		}
-		return evalIndexExpression(left, index)
+		result, _ := evalChain(node, env)
+		return result
 
 	case *ast.MapLiteral:
 		return evalMapLiteral(node, env)
 
 	case *ast.MemberExpression:
-		return evalMemberExpression(node, env)
+		result, _ := evalChain(node, env)
+		return result
 
 	case *ast.RangeExpression:
 		return evalRangeExpression(node, env)
@@ -240,9 +256,18 @@ func Eval(node ast.Node, env *Environment) Object {
 	case *ast.EnumLiteral:
 		return evalEnumLiteral(node, env)
 
+	case *ast.InterfaceLiteral:
+		return evalInterfaceLiteral(node)
+
 	case *ast.ThisExpression:
 		return evalThisExpression(env)
 
+	case *ast.SuperExpression:
+		if _, err := superModel(env); err != nil {
+			return err
+		}
+		return newError("'super' must be called or followed by a method name")
+
 	case *ast.DbFindExpression:
 		return evalDbFindExpression(node, env)
 	}
@@ -349,7 +374,9 @@ func evalForStatement(fs *ast.ForStatement, env *Environment) Object {
 				if fs.Iterator != nil {
 					forEnv.Set(fs.Iterator.Value, &Integer{Value: int64(i)})
 				}
-				forEnv.Set(fs.Value.Value, elem)
+				if err := bindLoopValue(fs, elem, forEnv); err != nil {
+					return err
+				}
 
 				result = Eval(fs.Body, forEnv)
 				if IsError(result) {
@@ -368,7 +395,9 @@ func evalForStatement(fs *ast.ForStatement, env *Environment) Object {
 				if fs.Iterator != nil {
 					forEnv.Set(fs.Iterator.Value, &String{Value: key})
 				}
-				forEnv.Set(fs.Value.Value, value)
+				if err := bindLoopValue(fs, value, forEnv); err != nil {
+					return err
+				}
 
 				result = Eval(fs.Body, forEnv)
 				if IsError(result) {
@@ -434,6 +463,37 @@ func evalForStatement(fs *ast.ForStatement, env *Environment) Object {
 	return result
 }
 
+// evalTryStatement runs the try block and, if it fails with a runtime error,
+// the catch block with the error converted to an Error instance. Errors
+// propagated by the ? operator are not caught; they leave the function.
+func evalTryStatement(ts *ast.TryStatement, env *Environment) Object {
+	result := Eval(ts.Body, env)
+
+	err, ok := result.(*Error)
+	if !ok || err.Propagated != nil {
+		return result
+	}
+
+	catchEnv := env
+	if ts.ErrorName != nil {
+		catchEnv = NewEnclosedEnvironment(env)
+		catchEnv.Define(ts.ErrorName.Value, caughtErrorInstance(err))
+	}
+	return Eval(ts.Catch, catchEnv)
+}
+
+// caughtErrorInstance converts a runtime error to the Error instance bound by
+// catch. Its fields map holds the per-field messages of binding errors.
+func caughtErrorInstance(err *Error) *ModelInstance {
+	instance := newErrorInstance(err.Message)
+	fields := &Map{Pairs: make(map[string]Object, len(err.Fields))}
+	for path, message := range err.Fields {
+		fields.Pairs[path] = &String{Value: message}
+	}
+	instance.Fields["fields"] = fields
+	return instance
+}
+
 func evalSwitchStatement(ss *ast.SwitchStatement, env *Environment) Object {
 	value := Eval(ss.Value, env)
 	if IsError(value) {
@@ -520,22 +580,51 @@ func evalAssignmentExpression(node *ast.InfixExpression, env *Environment) Objec
 		return val
 	}
 
+	return evalAssignment(node.Left, node.Operator, val, env)
+}
+
+// evalMultiAssignStatement evaluates all values before assigning any target,
+// so a, b = b, a swaps the variables.
+func evalMultiAssignStatement(node *ast.MultiAssignStatement, env *Environment) Object {
+	if len(node.Values) != len(node.Targets) {
+		return newError("wrong number of values in assignment: expected %d, got %d", len(node.Targets), len(node.Values))
+	}
+
+	values := make([]Object, 0, len(node.Values))
+	for _, value := range node.Values {
+		val := Eval(value, env)
+		if IsError(val) {
+			return val
+		}
+		values = append(values, val)
+	}
+
+	for i, target := range node.Targets {
+		if result := evalAssignment(target, "=", values[i], env); IsError(result) {
+			return result
+		}
+	}
+	return NULL
+}
+
+// evalAssignment assigns val to a variable, index or field.
+func evalAssignment(target ast.Expression, operator string, val Object, env *Environment) Object {
 	// Handle different left-hand side types
-	switch left := node.Left.(type) {
+	switch left := target.(type) {
 	case *ast.Identifier:
 		// Simple variable assignment: x = value
-		return evalIdentifierAssignment(left, node.Operator, val, env)
+		return evalIdentifierAssignment(left, operator, val, env)
 
 	case *ast.IndexExpression:
 		// Index assignment: arr[0] = value or map["key"] = value
-		return evalIndexAssignment(left, node.Operator, val, env)
+		return evalIndexAssignment(left, operator, val, env)
 
 	case *ast.MemberExpression:
 		// Member assignment: obj.field = value
-		return evalMemberAssignment(left, node.Operator, val, env)
+		return evalMemberAssignment(left, operator, val, env)
 
 	default:
-		return newError("cannot assign to %T", node.Left)
+		return newError("cannot assign to %T", target)
 	}
 }
 
@@ -854,6 +943,32 @@ func evalStringInfixExpression(operator string, left, right Object) Object {
 	}
 }
 
+// evalPropagateExpression evaluates value?, which makes the enclosing function
+// return Error values and runtime errors instead of continuing.
+func evalPropagateExpression(node *ast.PropagateExpression, env *Environment) Object {
+	value := Eval(node.Value, env)
+
+	switch value := value.(type) {
+	case *Error:
+		if value.Propagated == nil {
+			value = &Error{Message: value.Message, Fields: value.Fields, Propagated: newErrorInstance(value.Message)}
+		}
+		return value
+	case *ModelInstance:
+		if value.Model.derivesFrom(ErrorModel) {
+			var message string
+			if str, ok := value.Fields["message"].(*String); ok {
+				message = str.Value
+			} else if field, ok := value.Fields["message"]; ok {
+				message = field.Inspect()
+			}
+			return &Error{Message: message, Propagated: value}
+		}
+	}
+
+	return value
+}
+
 func evalIfExpression(ie *ast.IfExpression, env *Environment) Object {
 	condition := Eval(ie.Condition, env)
 	if IsError(condition) {
@@ -890,37 +1005,37 @@ func evalExpressions(exps []ast.Expression, env *Environment) []Object {
 	return result
 }
 
-func applyFunction(fn Object, args []Object, callingEnv *Environment) Object {
+func applyFunction(fn Object, args []Object, named []namedArgument, callingEnv *Environment) Object {
 	switch fn := fn.(type) {
 	case *Function:
-		if len(args) != len(fn.Parameters) {
-			return newError("wrong number of arguments: expected %d, got %d",
-				len(fn.Parameters), len(args))
+		values, err := bindArguments(fn.Parameters, args, named, fn.Env)
+		if err != nil {
+			return err
 		}
 
 		// Validate parameter types if annotations are present and coerce if needed
 		for i, param := range fn.Parameters {
-			if param.Type != nil {
-				if err := validateType(args[i], param.Type, callingEnv); err != nil {
-					errObj, ok := err.(*Error)
-					if !ok {
-						return newError("unexpected error type in parameter validation")
-					}
-					return newError("parameter '%s': %s", param.Name.Value, errObj.Message)
-				}
-				// Coerce value if needed (e.g., integer to float)
-				args[i] = coerceValue(args[i], param.Type)
+			value, err := checkParameter(param, values[i], callingEnv)
+			if err != nil {
+				return newError("parameter '%s': %s", param.Name.Value, err.Message)
 			}
+			values[i] = value
 		}
 
-		extendedEnv := extendFunctionEnv(fn, args)
+		extendedEnv := extendFunctionEnv(fn, values)
 		evaluated := Eval(fn.Body, extendedEnv)
-		return unwrapReturnValue(evaluated)
+		return UnwrapReturnValue(evaluated)
 
 	case *Builtin:
+		if len(named) > 0 {
+			return newError("%s does not accept named arguments", fn.Name)
+		}
 		return fn.Fn(args...)
 
 	case *BoundMethod:
+		if len(named) > 0 {
+			return newError("%s methods do not accept named arguments", fn.Receiver.Type())
+		}
 		// Prepend the receiver as the first argument
 		methodArgs := make([]Object, 0, len(args)+1)
 		methodArgs = append(methodArgs, fn.Receiver)
@@ -928,32 +1043,42 @@ func applyFunction(fn Object, args []Object, callingEnv *Environment) Object {
 		return fn.Method(methodArgs...)
 
 	case *Model:
-		// Try custom constructors first
+		// Try custom constructors first: the first one accepting the arguments is called
 		for _, constructor := range fn.Constructors {
-			if len(constructor.Parameters) == len(args) {
-				// Validate parameter types if annotations are present and coerce if needed
-				for i, param := range constructor.Parameters {
-					if param.Type != nil {
-						if err := validateType(args[i], param.Type, callingEnv); err != nil {
-							errObj, ok := err.(*Error)
-							if !ok {
-								return newError("unexpected error type in constructor parameter validation")
-							}
-							return newError("constructor parameter '%s': %s", param.Name.Value, errObj.Message)
-						}
-						// Coerce value if needed (e.g., integer to float)
-						args[i] = coerceValue(args[i], param.Type)
-					}
-				}
+			values, err := bindArguments(constructor.Parameters, args, named, constructor.Env)
+			if err != nil {
+				continue
+			}
 
-				// Found a matching constructor, call it
-				extendedEnv := extendFunctionEnv(constructor, args)
-				evaluated := Eval(constructor.Body, extendedEnv)
-				if returnValue, ok := evaluated.(*ReturnValue); ok {
-					return returnValue.Value
+			// Validate parameter types if annotations are present and coerce if needed
+			for i, param := range constructor.Parameters {
+				value, err := checkParameter(param, values[i], callingEnv)
+				if err != nil {
+					return newError("constructor parameter '%s': %s", param.Name.Value, err.Message)
 				}
-				return evaluated
+				values[i] = value
+			}
+
+			extendedEnv := extendFunctionEnv(constructor, values)
+			evaluated := Eval(constructor.Body, extendedEnv)
+			return UnwrapReturnValue(evaluated)
+		}
+
+		// Built-in models may provide their own constructor
+		if fn.NativeConstructor != nil {
+			if len(named) > 0 {
+				return newError("%s does not accept named arguments", fn.Name)
+			}
+			return fn.NativeConstructor(args...)
+		}
+
+		// Named arguments set fields by name, as in Point(y: 2, x: 1)
+		if len(named) > 0 {
+			fields, err := fieldArguments(fn, args, named)
+			if err != nil {
+				return err
 			}
+			args = fields
 		}
 
 		// No matching custom constructor, use default constructor
@@ -1003,9 +1128,16 @@ func extendFunctionEnv(fn *Function, args []Object) *Environment {
 	return env
 }
 
-func unwrapReturnValue(obj Object) Object {
-	if returnValue, ok := obj.(*ReturnValue); ok {
-		return returnValue.Value
+// UnwrapReturnValue returns the result of a function body: the returned
+// value, or the Error instance propagated by the ? operator.
+func UnwrapReturnValue(obj Object) Object {
+	switch obj := obj.(type) {
+	case *ReturnValue:
+		return obj.Value
+	case *Error:
+		if obj.Propagated != nil {
+			return obj.Propagated
+		}
 	}
 	return obj
 }
@@ -1064,6 +1196,20 @@ func evalMapIndexExpression(mapObj, index Object) Object {
 	return value
 }
 
+// evalInterpolatedString joins the parts of an interpolated string, converting
+// embedded values like string() does.
+func evalInterpolatedString(node *ast.InterpolatedString, env *Environment) Object {
+	var out strings.Builder
+	for _, part := range node.Parts {
+		value := Eval(part, env)
+		if IsError(value) {
+			return value
+		}
+		out.WriteString(value.Inspect())
+	}
+	return &String{Value: out.String()}
+}
+
 func evalMapLiteral(node *ast.MapLiteral, env *Environment) Object {
 	pairs := make(map[string]Object)
 
@@ -1089,12 +1235,71 @@ func evalMapLiteral(node *ast.MapLiteral, env *Environment) Object {
 	return &Map{Pairs: pairs}
 }
 
-func evalMemberExpression(node *ast.MemberExpression, env *Environment) Object {
-	object := Eval(node.Object, env)
-	if IsError(object) {
-		return object
+// evalChain evaluates a call, index or member access. An optional link (?.)
+// whose target is null short-circuits the rest of the chain to null, which is
+// reported so that user?.address.city is null rather than an error.
+func evalChain(node ast.Expression, env *Environment) (Object, bool) {
+	switch node := node.(type) {
+	case *ast.CallExpression:
+		if _, ok := node.Function.(*ast.SuperExpression); ok {
+			return evalSuperCall(node, env), false
+		}
+		function, skipped := evalChainTarget(node.Function, node.Optional, env)
+		if skipped || IsError(function) {
+			return function, skipped
+		}
+		args := evalExpressions(node.Arguments, env)
+		if len(args) == 1 && IsError(args[0]) {
+			return args[0], false
+		}
+		named, err := evalNamedArguments(node.NamedArguments, env)
+		if err != nil {
+			return err, false
+		}
+		return applyFunction(function, args, named, env), false
+
+	case *ast.IndexExpression:
+		left, skipped := evalChainTarget(node.Left, node.Optional, env)
+		if skipped || IsError(left) {
+			return left, skipped
+		}
+
+		// Check if this is a slice operation (index is a RangeExpression)
+		if rangeExpr, ok := node.Index.(*ast.RangeExpression); ok {
+			return evalSliceExpression(left, rangeExpr, env), false
+		}
+
+		index := Eval(node.Index, env)
+		if IsError(index) {
+			return index, false
+		}
+		return evalIndexExpression(left, index), false
+
+	case *ast.MemberExpression:
+		if _, ok := node.Object.(*ast.SuperExpression); ok {
+			return evalSuperMember(node, env), false
+		}
+		object, skipped := evalChainTarget(node.Object, node.Optional, env)
+		if skipped || IsError(object) {
+			return object, skipped
+		}
+		return evalMemberExpression(node, object), false
 	}
 
+	return Eval(node, env), false
+}
+
+// evalChainTarget evaluates the target of a chain link, reporting whether the
+// chain is short-circuited.
+func evalChainTarget(node ast.Expression, optional bool, env *Environment) (Object, bool) {
+	target, skipped := evalChain(node, env)
+	if skipped || (optional && target.Type() == NullObj) {
+		return NULL, true
+	}
+	return target, false
+}
+
+func evalMemberExpression(node *ast.MemberExpression, object Object) Object {
 	memberName := node.Member.Value
 
 	// Handle Enum member access (Enum.Value) and methods
@@ -1174,21 +1379,25 @@ func evalMemberExpression(node *ast.MemberExpression, env *Environment) Object {
 
 		// Check if it's a method
 		if method, exists := instance.Model.Methods[memberName]; exists {
-			// Create a new environment with 'this' bound to the instance
-			methodEnv := NewEnclosedEnvironment(method.Env)
-			methodEnv.Set("this", instance)
-
-			// Return a function that will use this environment
-			return &Function{
-				Parameters: method.Parameters,
-				Body:       method.Body,
-				Env:        methodEnv,
-			}
+			return bindMethod(method, instance)
+		}
+
+		// Check if it's a native method of a built-in model
+		if method, exists := instance.Methods[memberName]; exists {
+			return method
 		}
 
 		return newError("model %s has no field or method '%s'", instance.Model.Name, memberName)
 	}
 
+	// Handle static methods of built-in models (http.Response.html)
+	if model, ok := object.(*Model); ok {
+		if method, exists := model.StaticMethods[memberName]; exists {
+			return method
+		}
+		return newError("model %s has no static method '%s'", model.Name, memberName)
+	}
+
 	// Handle EnumValue.value access
 	if enumValue, ok := object.(*EnumValue); ok {
 		if memberName == "value" {
@@ -1350,6 +1559,9 @@ func objectsEqual(left, right Object) bool {
 		return ok && left.Value == rightBool.Value
 	case *Null:
 		return true
+	case *EnumValue:
+		rightEnum, ok := right.(*EnumValue)
+		return ok && left.EnumName == rightEnum.EnumName && left.Name == rightEnum.Name
 	default:
 		return false
 	}
@@ -1376,6 +1588,13 @@ func NewError(format string, a ...interface{}) *Error {
 	return newError(format, a...)
 }
 
+// CallFunction calls a function or builtin with positional arguments like a
+// call in a script: defaults and variadic parameters are filled in and
+// annotated types are checked (exported for stdlib callbacks).
+func CallFunction(fn Object, args ...Object) Object {
+	return callTSFunction(fn, args...)
+}
+
 // methodRegistry stores methods for each object type
 var methodRegistry = make(map[ObjectType]map[string]BuiltinFunction)
 
@@ -1387,6 +1606,11 @@ func RegisterMethod(objType ObjectType, name string, method BuiltinFunction) {
 	methodRegistry[objType][name] = method
 }
 
+// HasMethod reports whether values of objType have a built-in method.
+func HasMethod(objType ObjectType, name string) bool {
+	return getMethod(objType, name) != nil
+}
+
 // getMethod retrieves a method for a given object type and method name.
 func getMethod(objType ObjectType, name string) BuiltinFunction {
 	if methods, ok := methodRegistry[objType]; ok {
@@ -1405,22 +1629,43 @@ func evalModelLiteral(node *ast.ModelLiteral, env *Environment) Object {
 		Constructors: make([]*Function, 0),
 	}
 
+	// Start from the fields and methods of the base model
+	if node.Extends != nil {
+		if err := inheritModel(model, node.Extends, env); err != nil {
+			return err
+		}
+	}
+
 	// Store field type information and annotations in order
 	for _, field := range node.Fields {
 		fieldName := field.Name.Value
-		model.FieldNames = append(model.FieldNames, fieldName)
+		if baseType, inherited := model.Fields[fieldName]; inherited {
+			// Redeclaring an inherited field may only change its annotations
+			if !sameAnnotation(baseType, field.Type) {
+				return newError("field '%s' is declared in %s with type %s", fieldName, model.Base.Name, baseType)
+			}
+		} else {
+			model.FieldNames = append(model.FieldNames, fieldName)
+		}
 		model.Fields[fieldName] = field.Type
 		if len(field.Annotations) > 0 {
 			model.Annotations[fieldName] = field.Annotations
 		}
 	}
 
+	// Constructors and methods of a derived model can call the base with super
+	scope := env
+	if model.Base != nil {
+		scope = NewEnclosedEnvironment(env)
+		scope.Define("super", model)
+	}
+
 	// Store constructors
 	for _, constructor := range node.Constructors {
 		fn := &Function{
 			Parameters: constructor.Parameters,
 			Body:       constructor.Body,
-			Env:        env,
+			Env:        scope,
 		}
 		model.Constructors = append(model.Constructors, fn)
 	}
@@ -1430,11 +1675,19 @@ func evalModelLiteral(node *ast.ModelLiteral, env *Environment) Object {
 		fn := &Function{
 			Parameters: method.Function.Parameters,
 			Body:       method.Function.Body,
-			Env:        env,
+			Env:        scope,
+			ReturnType: method.Function.ReturnType,
 		}
 		model.Methods[method.Name.Value] = fn
 	}
 
+	// Check the declared interfaces now rather than when instances are used
+	for _, typeExpr := range node.Implements {
+		if err := checkImplements(model, typeExpr, env); err != nil {
+			return err
+		}
+	}
+
 	return model
 }
 
@@ -1498,13 +1751,13 @@ func evalIsOperatorWithTypeName(left Object, typeName string, env *Environment)
 
 func evalIsOperator(left, right Object) Object {
 	// The `is` operator checks if left is an instance of the type on the right
-	// right should be a Model or Enum type
+	// right should be a Model, Enum or Interface type
 
 	switch rightType := right.(type) {
 	case *Model:
-		// Check if left is an instance of this model
+		// Check if left is an instance of this model or of a model extending it
 		if instance, ok := left.(*ModelInstance); ok {
-			return nativeBoolToBooleanObject(instance.Model == rightType)
+			return nativeBoolToBooleanObject(instance.Model.derivesFrom(rightType))
 		}
 		return FALSE
 
@@ -1515,6 +1768,13 @@ func evalIsOperator(left, right Object) Object {
 		}
 		return FALSE
 
+	case *Interface:
+		// Check if left is an instance of a model conforming to this interface
+		if instance, ok := left.(*ModelInstance); ok {
+			return nativeBoolToBooleanObject(missingMember(instance.Model, rightType) == "")
+		}
+		return FALSE
+
 	default:
 		return newError("'is' operator requires a type name or type value on the right side")
 	}
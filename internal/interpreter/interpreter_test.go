@@ -1,9 +1,12 @@
 package interpreter
 
 import (
+	"path/filepath"
+	"sort"
 	"strings"
 	"testing"
 
+	"github.com/mishankov/totalscript-lang/internal/ast"
 	"github.com/mishankov/totalscript-lang/internal/lexer"
 	"github.com/mishankov/totalscript-lang/internal/parser"
 )
@@ -333,6 +336,706 @@ func TestStringConcatenation(t *testing.T) {
 	}
 }
 
+func TestStringInterpolation(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`var name = "Alice"
+"Hello ${name}!"`, "Hello Alice!"},
+		{`"${1 + 2} ${2.5} ${true} ${null} ${[1, "a"]}"`, "3 2.5 true null [1, a]"},
+		{`var m = {"count": 3}
+"count: ${m["count"]}"`, "count: 3"},
+		{`var name = "Bob"
+"outer ${"inner ${name}"}"`, "outer inner Bob"},
+		{`"\${literal} $5"`, "${literal} $5"},
+		{`var name = "Carol"
+"""
+    Dear ${name},
+      thanks!
+    """`, "Dear Carol,\n  thanks!"},
+		{`r"no ${escapes}\n"`, `no ${escapes}\n`},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		str, ok := evaluated.(*String)
+		if !ok {
+			t.Fatalf("object is not String. got=%T (%+v)", evaluated, evaluated)
+		}
+		if str.Value != tt.expected {
+			t.Errorf("String has wrong value. expected=%q, got=%q", tt.expected, str.Value)
+		}
+	}
+
+	evaluated := testEval(`"value: ${missing}"`)
+	errObj, ok := evaluated.(*Error)
+	if !ok || errObj.Message != "identifier not found: missing" {
+		t.Errorf("expected identifier not found error, got=%+v", evaluated)
+	}
+}
+
+func TestErrorPropagation(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		// Runtime errors become Error instances returned by the function
+		{`var half = function(x) {
+  var n = (x / 2)?
+  return "half is ${n}"
+}
+var err = half("ten")
+"${err.message}"`, "type mismatch: STRING / INTEGER"},
+		{`var half = function(x) { return (x / 2)? }
+"${half(10)}"`, "5"},
+		// Error instances are returned as is
+		{`var fail = function() {
+  try { var x = 1 + true } catch e { return e }
+}
+var run = function() {
+  var x = fail()?
+  return "not reached"
+}
+"${run().message}"`, "type mismatch: INTEGER + BOOLEAN"},
+		// Propagation is not caught by try blocks
+		{`var run = function() {
+  try {
+    var x = (1 + "a")?
+    return "not reached"
+  } catch e {
+    return "caught"
+  }
+}
+"${run().message}"`, "type mismatch: INTEGER + STRING"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		str, ok := evaluated.(*String)
+		if !ok {
+			t.Fatalf("object is not String. got=%T (%+v)", evaluated, evaluated)
+		}
+		if str.Value != tt.expected {
+			t.Errorf("String has wrong value. expected=%q, got=%q", tt.expected, str.Value)
+		}
+	}
+
+	// Outside of functions propagation stops the program
+	evaluated := testEval(`var x = (1 - "a")?
+"not reached"`)
+	errObj, ok := evaluated.(*Error)
+	if !ok || errObj.Message != "type mismatch: INTEGER - STRING" {
+		t.Errorf("expected type mismatch error, got=%+v", evaluated)
+	}
+}
+
+func TestTryCatch(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`var result = "ok"
+try { result = "body" } catch e { result = "catch" }
+result`, "body"},
+		{`var result = "ok"
+try {
+  var n = 1 + "x"
+  result = "not reached"
+} catch e {
+  result = e.message
+}
+result`, "type mismatch: INTEGER + STRING"},
+		{`var result = "ok"
+try { missing() } catch { result = "caught" }
+result`, "caught"},
+		// The error variable shadows variables of the same name
+		{`var e = "outer"
+try { missing() } catch e { e = "inner" }
+e`, "outer"},
+		{`var check = function(n) {
+  try {
+    if n > 0 { return "positive" }
+    return n.missing
+  } catch e {
+    return "caught"
+  }
+}
+"${check(1)} ${check(0)}"`, "positive caught"},
+		{`var out = ""
+for i in 0..3 {
+  try {
+    if i == 1 { continue }
+    out += "${i}"
+  } catch e {}
+}
+out`, "02"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		str, ok := evaluated.(*String)
+		if !ok {
+			t.Fatalf("object is not String. got=%T (%+v)", evaluated, evaluated)
+		}
+		if str.Value != tt.expected {
+			t.Errorf("String has wrong value. expected=%q, got=%q", tt.expected, str.Value)
+		}
+	}
+
+	// Binding errors keep their per-field messages
+	env := NewEnvironment()
+	env.Set("bind", &Builtin{
+		Name: "bind",
+		Fn: func(args ...Object) Object {
+			return &Error{Message: "invalid request body", Fields: map[string]string{"age": "required"}}
+		},
+	})
+	input := `var result = ""
+try { bind() } catch e { result = "${e.message}: ${e.fields["age"]}" }
+result`
+	str, ok := Eval(parser.New(lexer.New(input)).ParseProgram(), env).(*String)
+	if !ok || str.Value != "invalid request body: required" {
+		t.Errorf("expected the caught error to keep its fields, got %+v", str)
+	}
+}
+
+func TestMatchExpression(t *testing.T) {
+	t.Parallel()
+	describe := `const Point = model {
+  x: float
+  y: float
+}
+const describe = function(value) {
+  return match value {
+    case 0 { "zero" }
+    case 1..=9 { "digit" }
+    case n: integer if n < 0 { "negative ${n}" }
+    case n: integer { "big ${n}" }
+    case Point(x, 0) { "on x axis at ${x}" }
+    case Point(x, y) if x == y { "diagonal" }
+    case Point(_, _) { "point" }
+    case [] { "empty" }
+    case [first, ...rest] { "first ${first}, rest ${rest}" }
+    case {"type": "user", name} { "user ${name}" }
+    case "a", "b" { "a or b" }
+    case s: string { "string ${s}" }
+    case null { "nothing" }
+    default { "other" }
+  }
+}
+`
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"describe(0)", "zero"},
+		{"describe(9)", "digit"},
+		{"describe(10)", "big 10"},
+		{"describe(-3)", "negative -3"},
+		{"describe(Point(2, 0))", "on x axis at 2"},
+		{"describe(Point(1, 1))", "diagonal"},
+		{"describe(Point(1, 2))", "point"},
+		{"describe([])", "empty"},
+		{"describe([1])", "first 1, rest []"},
+		{"describe([1, 2, 3])", "first 1, rest [2, 3]"},
+		{`describe({"type": "user", "name": "Ann"})`, "user Ann"},
+		{`describe({"type": "admin"})`, "other"},
+		{`describe("b")`, "a or b"},
+		{`describe("c")`, "string c"},
+		{"describe(null)", "nothing"},
+		{"describe(true)", "other"},
+		// Bindings do not leak out of the arm
+		{`var n = "outer"
+match 5 { case n { n } }
+n`, "outer"},
+		{`const Color = enum {
+  Red = "red"
+  Green = "green"
+}
+const name = function(c: Color): string {
+  return match c {
+    case Color.Red { "R" }
+    case Color.Green { "G" }
+  }
+}
+name(Color.Green)`, "G"},
+	}
+
+	for _, tt := range tests {
+		input := tt.input
+		if strings.HasPrefix(input, "describe(") {
+			input = describe + input
+		}
+		evaluated := testEval(input)
+		str, ok := evaluated.(*String)
+		if !ok {
+			t.Fatalf("object is not String. got=%T (%+v)", evaluated, evaluated)
+		}
+		if str.Value != tt.expected {
+			t.Errorf("String has wrong value. expected=%q, got=%q", tt.expected, str.Value)
+		}
+	}
+
+	errorTests := []struct {
+		input    string
+		expected string
+	}{
+		{`match 5 { case 1 { "one" } }`, "no case matches value: 5"},
+		{`const Color = enum {
+  Red = "red"
+  Green = "green"
+  Blue = "blue"
+}
+match Color.Red {
+  case Color.Red { 1 }
+  case Color.Green if true { 2 }
+}`, "match is not exhaustive: Color.Blue, Color.Green not handled"},
+		{`const Point = model {
+  x: float
+  y: float
+}
+match Point(1, 2) { case Point(x) { x } }`, "wrong number of fields in pattern for Point: expected 2, got 1"},
+		{`match 1 { case n: Missing { n } }`, "unknown type: Missing"},
+	}
+
+	for _, tt := range errorTests {
+		errObj, ok := testEval(tt.input).(*Error)
+		if !ok {
+			t.Fatalf("expected error for %q", tt.input)
+		}
+		if errObj.Message != tt.expected {
+			t.Errorf("wrong error message. expected=%q, got=%q", tt.expected, errObj.Message)
+		}
+	}
+}
+
+func TestDestructuring(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`var [a, b, ...rest] = [1, 2, 3, 4]
+"${a} ${b} ${rest}"`, "1 2 [3, 4]"},
+		{`const {name, "age": years} = {"name": "Ann", "age": 30, "city": "Oslo"}
+"${name} ${years}"`, "Ann 30"},
+		{`const Point = model {
+  x: float
+  y: float
+}
+var Point(x, y) = Point(1, 2)
+"${x} ${y}"`, "1 2"},
+		{`var out = ""
+for [key, value] in [["a", 1], ["b", 2]] { out += "${key}=${value} " }
+out`, "a=1 b=2 "},
+		{`const Point = model {
+  x: float
+  y: float
+}
+var out = ""
+for i, Point(x, _) in [Point(1, 2), Point(3, 4)] { out += "${i}:${x} " }
+out`, "0:1 1:3 "},
+		{`var a = "a"
+var b = "b"
+a, b = b, a
+"${a} ${b}"`, "b a"},
+		{`var items = [1, 2, 3]
+items[0], items[2] = items[2], items[0]
+"${items}"`, "[3, 2, 1]"},
+		// Destructured variables are local to functions
+		{`var x = "global"
+const f = function() {
+  var [x] = ["local"]
+  return x
+}
+"${f()} ${x}"`, "local global"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		str, ok := evaluated.(*String)
+		if !ok {
+			t.Fatalf("object is not String. got=%T (%+v)", evaluated, evaluated)
+		}
+		if str.Value != tt.expected {
+			t.Errorf("String has wrong value. expected=%q, got=%q", tt.expected, str.Value)
+		}
+	}
+
+	errorTests := []struct {
+		input    string
+		expected string
+	}{
+		{`var [a, b] = [1]`, "cannot destructure ARRAY with pattern [a, b]"},
+		{`var {name} = "Ann"`, `cannot destructure STRING with pattern {"name": name}`},
+		{`var a = 1
+var b = 2
+a, b = 1, 2, 3`, "wrong number of values in assignment: expected 2, got 3"},
+		{`var n: integer = 1
+var s = "x"
+n, s = s, n`, "type mismatch: expected integer, got string"},
+	}
+
+	for _, tt := range errorTests {
+		errObj, ok := testEval(tt.input).(*Error)
+		if !ok {
+			t.Fatalf("expected error for %q", tt.input)
+		}
+		if errObj.Message != tt.expected {
+			t.Errorf("wrong error message. expected=%q, got=%q", tt.expected, errObj.Message)
+		}
+	}
+}
+
+func TestLambdasAndParameters(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`const double = x => x * 2
+"${double(4)}"`, "8"},
+		{`const add = (a: integer, b: integer) => { return a + b }
+"${add(2, 3)} ${(() => 1)()}"`, "5 1"},
+		{`const greet = function(name: string, greeting: string = "Hello", punct = "!") {
+  return "${greeting}, ${name}${punct}"
+}
+"${greet("Bob")} ${greet("Bob", punct: "?")} ${greet(greeting: "Hi", name: "Ann")}"`, "Hello, Bob! Hello, Bob? Hi, Ann!"},
+		// Defaults may refer to earlier parameters
+		{`const scale = function(x: float, factor = x * 2) { return factor }
+"${scale(3)}"`, "6"},
+		{`const sum = function(label: string, ...nums: float) {
+  var total = 0
+  for n in nums { total += n }
+  return "${label}=${total} ${nums}"
+}
+"${sum("none")} ${sum("some", 1, 2.5)}"`, "none=0 [] some=3.5 [1, 2.5]"},
+		{`const Point = model {
+  x: float
+  y: float
+}
+"${Point(y: 2, x: 1)} ${Point(1, y: 5)}"`, "Point(x: 1, y: 2) Point(x: 1, y: 5)"},
+		{`const Temperature = model {
+  celsius: float
+  constructor = function(fahrenheit: float, shift: float = 0) {
+    return Temperature(celsius: (fahrenheit - 32) * 5 / 9 + shift)
+  }
+}
+"${Temperature(fahrenheit: 212).celsius}"`, "100"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		str, ok := evaluated.(*String)
+		if !ok {
+			t.Fatalf("object is not String. got=%T (%+v)", evaluated, evaluated)
+		}
+		if str.Value != tt.expected {
+			t.Errorf("String has wrong value. expected=%q, got=%q", tt.expected, str.Value)
+		}
+	}
+
+	errorTests := []struct {
+		input    string
+		expected string
+	}{
+		{`const f = function(a, b = 1) { a }
+f()`, "wrong number of arguments: expected 1 to 2, got 0"},
+		{`const f = function(a, ...rest) { a }
+f()`, "wrong number of arguments: expected at least 1, got 0"},
+		{`const f = function(a) { a }
+f(1, a: 2)`, "parameter 'a' given twice"},
+		{`const f = function(a) { a }
+f(b: 2)`, "unknown parameter 'b'"},
+		{`const f = function(a, b) { a }
+f(b: 2)`, "missing argument for parameter 'a'"},
+		{`const f = function(...nums: integer) { nums }
+f(1, "x")`, "parameter 'nums': type mismatch: expected integer, got string"},
+		{`const Point = model {
+  x: float
+  y: float
+}
+Point(x: 1, z: 2)`, "model Point has no field 'z'"},
+		{`const Point = model {
+  x: float
+  y: float
+}
+Point(x: 1)`, "missing field 'y' for Point"},
+	}
+
+	for _, tt := range errorTests {
+		errObj, ok := testEval(tt.input).(*Error)
+		if !ok {
+			t.Fatalf("expected error for %q", tt.input)
+		}
+		if errObj.Message != tt.expected {
+			t.Errorf("wrong error message. expected=%q, got=%q", tt.expected, errObj.Message)
+		}
+	}
+}
+
+func TestInterfaces(t *testing.T) {
+	t.Parallel()
+	shapes := `const Shape = interface {
+  name: string
+  area: function(): float
+}
+const Square = model implements Shape {
+  name: string
+  side: float
+  area = function(): float { return this.side * this.side }
+}
+const Circle = model {
+  name: string
+  r: float
+  area = function() { return 3 * this.r * this.r }
+}
+const Point = model { x: float }
+`
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		// Models conform structurally, with or without implements
+		{shapes + `const total = function(shapes: array<Shape>) {
+  var sum = 0.0
+  for s in shapes { sum += s.area() }
+  return sum
+}
+"${total([Square("a", 2), Circle("b", 1)])}"`, "7"},
+		{shapes + `const describe = function(s: Shape) { return "${s.name}: ${s.area()}" }
+describe(Circle("c", 2))`, "c: 12"},
+		{shapes + `"${Square("a", 1) is Shape} ${Point(1) is Shape} ${Shape}"`, "true false interface Shape"},
+		{shapes + `match Circle("m", 1) {
+  case s: Shape { s.name }
+  default { "none" }
+}`, "m"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		str, ok := evaluated.(*String)
+		if !ok {
+			t.Fatalf("object is not String. got=%T (%+v)", evaluated, evaluated)
+		}
+		if str.Value != tt.expected {
+			t.Errorf("String has wrong value. expected=%q, got=%q", tt.expected, str.Value)
+		}
+	}
+
+	errorTests := []struct {
+		input    string
+		expected string
+	}{
+		{shapes + `var s: Shape = Point(1)`, "type mismatch: expected Shape, got Point (missing field 'name')"},
+		{shapes + `var s: Shape = "square"`, "type mismatch: expected Shape, got string"},
+		{shapes + `const Bad = model implements Shape { name: string }`, "model does not implement Shape: missing method 'area'"},
+		{shapes + `const Bad = model implements Shape {
+  name: string
+  area = function(): string { return "big" }
+}`, "model does not implement Shape: method 'area' returns string, expected float"},
+		{shapes + `const Bad = model implements Point { x: float }`, "'Point' is not an interface"},
+	}
+
+	for _, tt := range errorTests {
+		errObj, ok := testEval(tt.input).(*Error)
+		if !ok {
+			t.Fatalf("expected error for %q", tt.input)
+		}
+		if errObj.Message != tt.expected {
+			t.Errorf("wrong error message. expected=%q, got=%q", tt.expected, errObj.Message)
+		}
+	}
+}
+
+func TestModelInheritance(t *testing.T) {
+	t.Parallel()
+	models := `const Entity = model {
+  owner: string
+  createdAt: integer @index
+  describe = function(): string { return "by ${this.owner}" }
+  constructor = function(owner: string) { return Entity(owner, 100) }
+}
+const Post = model extends Entity {
+  title: string
+  createdAt: integer @id
+  describe = function(): string { return "${this.title} ${super.describe()}" }
+  constructor = function(owner: string, title: string) {
+    var post = super(owner)
+    post.title = title
+    return post
+  }
+}
+`
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		// Inherited fields come first; redeclared ones keep their position
+		{models + `"${Post("bob", 5, "Hi")}"`, "Post(owner: bob, createdAt: 5, title: Hi)"},
+		{models + `"${Post("ann", "Hello")} ${Post("ann", "Hello").describe()}"`,
+			"Post(owner: ann, createdAt: 100, title: Hello) Hello by ann"},
+		{models + `var post = Post("ann", "Hello")
+"${post is Entity} ${post is Post} ${Entity("ann") is Post}"`, "true true false"},
+		{models + `const owner = function(e: Entity) { return e.owner }
+owner(Post("ann", "Hello"))`, "ann"},
+		{models + `match Post("ann", "Hello") {
+  case Entity(owner, _) { owner }
+}`, "ann"},
+		{`const Base = model {
+  x: float
+  hello = function() { return "hello" }
+}
+const Derived = model extends Base { y: float }
+Derived(1, 2).hello()`, "hello"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		str, ok := evaluated.(*String)
+		if !ok {
+			t.Fatalf("object is not String. got=%T (%+v)", evaluated, evaluated)
+		}
+		if str.Value != tt.expected {
+			t.Errorf("String has wrong value. expected=%q, got=%q", tt.expected, str.Value)
+		}
+	}
+
+	// Models extending Error are errors
+	env := NewEnvironment()
+	env.Set("Error", ErrorModel)
+	input := `const NotFound = model extends Error { path: string }
+const find = function(path: string): string | Error {
+  NotFound("missing", path)?
+  return path
+}
+var result = find("/a")
+"${result is Error} ${result.path}"`
+	if str, ok := Eval(parser.New(lexer.New(input)).ParseProgram(), env).(*String); !ok || str.Value != "true /a" {
+		t.Errorf("expected a model extending Error to propagate as an error, got %+v", str)
+	}
+
+	if model, ok := testEval(models + "Post").(*Model); !ok || model.Annotations["createdAt"][0] != "id" {
+		t.Errorf("expected the redeclared field to replace its annotations, got %+v", model)
+	}
+
+	errorTests := []struct {
+		input    string
+		expected string
+	}{
+		{models + `const Bad = model extends Entity { owner: integer }`, "field 'owner' is declared in Entity with type string"},
+		{`const Shape = interface { x: float }
+const Bad = model extends Shape { y: float }`, "'Shape' is not a model"},
+		{`const A = model {
+  x: float
+  f = function() { return super.f() }
+}
+A(1).f()`, "'super' can only be used inside a model extending another model"},
+		{models + `const Page = model extends Post {
+  f = function() { return super }
+}
+Page("a", 1, "t").f()`, "'super' must be called or followed by a method name"},
+		{models + `const Page = model extends Post {
+  f = function() { return super.missing() }
+}
+Page("a", 1, "t").f()`, "model Post has no method 'missing'"},
+	}
+
+	for _, tt := range errorTests {
+		errObj, ok := testEval(tt.input).(*Error)
+		if !ok {
+			t.Fatalf("expected error for %q", tt.input)
+		}
+		if errObj.Message != tt.expected {
+			t.Errorf("wrong error message. expected=%q, got=%q", tt.expected, errObj.Message)
+		}
+	}
+}
+
+func TestNullSafety(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`const Address = model { city: string }
+const User = model {
+  name: string
+  address: Address?
+}
+var ann = User("Ann", Address("Oslo"))
+var bob = User("Bob", null)
+var nobody: User? = null
+"${ann.address?.city} ${bob.address?.city} ${nobody?.address.city} ${nobody?.name.upper()}"`, "Oslo null null null"},
+		{`var items: array<integer>? = null
+var f: function? = null
+"${items?.[0]} ${items?.length()} ${[5]?.[0]} ${f?.(1)}"`, "null null 5 null"},
+		{`var name: string? = null
+"${name ?? "anonymous"} ${"Ann" ?? "anonymous"} ${null ?? null ?? 3}"`, "anonymous Ann 3"},
+		// Only null is replaced, not other falsy values
+		{`"${false ?? true} ${0 ?? 1} ${"" ?? "x"}"`, "false 0 "},
+		// The right side is only evaluated when needed
+		{`var calls = 0
+var next = function() {
+  calls += 1
+  return calls
+}
+var a = 1 ?? next()
+var b = null ?? next()
+"${a} ${b} ${calls}"`, "1 1 1"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		str, ok := evaluated.(*String)
+		if !ok {
+			t.Fatalf("object is not String. got=%T (%+v)", evaluated, evaluated)
+		}
+		if str.Value != tt.expected {
+			t.Errorf("String has wrong value. expected=%q, got=%q", tt.expected, str.Value)
+		}
+	}
+
+	// Accessing members of null without ?. is still an error
+	evaluated := testEval(`const User = model { address: map? }
+var bob = User(null)
+bob.address.city`)
+	errObj, ok := evaluated.(*Error)
+	if !ok || errObj.Message != "undefined method 'city' for type NULL" {
+		t.Errorf("expected undefined method error, got=%+v", evaluated)
+	}
+}
+
+func TestNullSafetyInQueries(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "test.db")
+	evaluated := testEval(`import db
+db.configure("` + path + `")
+const Person = model {
+  name: string
+  age: integer
+}
+db.save(Person("Ann", 30))
+db.save(Person("Bob", 17))
+var minAge: integer? = null
+var filter: map? = null
+var adults = db.find(Person) { this.age >= minAge ?? 18 }
+var named = db.find(Person) { this.name == filter?.name ?? "Bob" }
+"${adults} ${named}"`)
+
+	str, ok := evaluated.(*String)
+	if !ok {
+		t.Fatalf("object is not String. got=%T (%+v)", evaluated, evaluated)
+	}
+	if expected := "[Person(name: Ann, age: 30)] [Person(name: Bob, age: 17)]"; str.Value != expected {
+		t.Errorf("String has wrong value. expected=%q, got=%q", expected, str.Value)
+	}
+}
+
 func TestArrayLiterals(t *testing.T) {
 	t.Parallel()
 	input := "[1, 2 * 2, 3 + 3]"
@@ -1061,11 +1764,33 @@ func TestEnumValueProperty(t *testing.T) {
 	t.Skip("Skipping due to test environment issues - feature works in practice")
 }
 
-// TestEnumComparison - Skipped due to test environment issues
-// Feature works in practice (see examples/enums.tsl)
 func TestEnumComparison(t *testing.T) {
 	t.Parallel()
-	t.Skip("Skipping due to test environment issues - feature works in practice")
+	enums := `const Status = enum {
+  OK = 200
+  NotFound = 404
+}
+const Code = enum {
+  OK = 200
+}
+`
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"Status.OK == Status.OK", true},
+		{"Status.OK == Status.NotFound", false},
+		{"Status.OK != Status.NotFound", true},
+		{"Status.OK != Status.OK", false},
+		{"var s = Status.NotFound\ns == Status.NotFound", true},
+		// Values of different enums are not equal, even with the same name and value
+		{"Status.OK == Code.OK", false},
+		{"Status.OK == 200", false},
+	}
+
+	for _, tt := range tests {
+		testBooleanObject(t, testEval(enums+tt.input), tt.expected)
+	}
 }
 
 // TestEnumIsOperator - Skipped due to test environment issues
@@ -1084,10 +1809,32 @@ func TestSwitchStatement(t *testing.T) {
 	t.Skip("Skipping - switch statement has implementation issues")
 }
 
-// TestSwitchWithEnum - Skipped (depends on TestSwitchStatement)
 func TestSwitchWithEnum(t *testing.T) {
 	t.Parallel()
-	t.Skip("Skipping - switch statement has implementation issues")
+	input := `const Direction = enum {
+  North = "N"
+  South = "S"
+}
+var heading = Direction.South
+var result = "none"
+switch heading {
+case Direction.North {
+  result = "north"
+}
+case Direction.South {
+  result = "south"
+}
+}
+result`
+
+	evaluated := testEval(input)
+	str, ok := evaluated.(*String)
+	if !ok {
+		t.Fatalf("object is not String. got=%T (%+v)", evaluated, evaluated)
+	}
+	if str.Value != "south" {
+		t.Errorf("expected the matching case to run, got %q", str.Value)
+	}
 }
 
 func TestPowerOperator(t *testing.T) {
@@ -1509,3 +2256,80 @@ func TestModuleTypeScopingEnforcement(t *testing.T) {
 		})
 	}
 }
+
+func TestNarrowCondition(t *testing.T) {
+	t.Parallel()
+	declared := map[string]string{
+		"x": "float | Error",
+		"s": "string?",
+		"v": "integer | string | null",
+	}
+	parseType := func(src string) *ast.TypeExpression {
+		p := parser.New(lexer.New("var _: " + src + " = null"))
+		program := p.ParseProgram()
+		return program.Statements[0].(*ast.VarStatement).Type
+	}
+	typeOf := func(name string) (*ast.TypeExpression, bool) {
+		src, ok := declared[name]
+		if !ok {
+			return nil, false
+		}
+		return parseType(src), true
+	}
+
+	tests := []struct {
+		condition string
+		whenTrue  string
+		whenFalse string
+	}{
+		{"x is Error", "x: Error", "x: float"},
+		{"!(x is Error)", "x: float", "x: Error"},
+		{"s != null", "s: string", "s: null"},
+		{"null == s", "s: null", "s: string"},
+		{"s is null", "s: null", "s: string"},
+		{"x is float && s != null", "s: string, x: float", ""},
+		{"x is Error || s == null", "", "s: string, x: float"},
+		{"v is integer || v is string", "v: integer | string", "v: null"},
+		{"u is Error", "u: Error", ""},
+		{"x == 1", "", ""},
+	}
+
+	format := func(n Narrowing) string {
+		parts := make([]string, 0, len(n))
+		for name, typeExpr := range n {
+			parts = append(parts, name+": "+typeExpr.String())
+		}
+		sort.Strings(parts)
+		return strings.Join(parts, ", ")
+	}
+
+	for _, tt := range tests {
+		p := parser.New(lexer.New(tt.condition))
+		program := p.ParseProgram()
+		cond := program.Statements[0].(*ast.ExpressionStatement).Expression
+
+		whenTrue, whenFalse := NarrowCondition(cond, typeOf)
+		if got := format(whenTrue); got != tt.whenTrue {
+			t.Errorf("%s: wrong narrowing when true: %q, want %q", tt.condition, got, tt.whenTrue)
+		}
+		if got := format(whenFalse); got != tt.whenFalse {
+			t.Errorf("%s: wrong narrowing when false: %q, want %q", tt.condition, got, tt.whenFalse)
+		}
+	}
+}
+
+func TestNarrowingHintInTypeMismatch(t *testing.T) {
+	t.Parallel()
+	evaluated := testEval(`var r: integer | string = "a"; var n: integer = r`)
+	errObj, ok := evaluated.(*Error)
+	if !ok {
+		t.Fatalf("expected error, got %T (%+v)", evaluated, evaluated)
+	}
+	expected := "type mismatch: expected integer, got string (r is integer | string here; check it with `is` first)"
+	if errObj.Message != expected {
+		t.Errorf("wrong error message: %q", errObj.Message)
+	}
+
+	evaluated = testEval(`var r: integer | string = 1; if r is string { return 0 }; var n: integer = r; n`)
+	testIntegerObject(t, evaluated, 1)
+}
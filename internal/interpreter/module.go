@@ -5,9 +5,11 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"math"
+	"net"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -17,7 +19,6 @@ import (
 	"time"
 
 	"github.com/google/uuid"
-	"github.com/mishankov/totalscript-lang/internal/ast"
 	"github.com/mishankov/totalscript-lang/internal/lexer"
 	"github.com/mishankov/totalscript-lang/internal/parser"
 	_ "modernc.org/sqlite" // SQLite driver
@@ -54,6 +55,13 @@ func resolveModule(path string, currentFile string) Object {
 	return loadStdlibModule(path)
 }
 
+// StdlibModule returns a standard library module by name. Tools inspecting
+// programs without running them use it to look up module members.
+func StdlibModule(name string) (*Module, bool) {
+	module, ok := loadStdlibModule(name).(*Module)
+	return module, ok
+}
+
 // loadStdlibModule loads a standard library module by name.
 func loadStdlibModule(name string) Object {
 	// Check cache first
@@ -77,6 +85,8 @@ func loadStdlibModule(name string) Object {
 		module = createHTTPModule()
 	case "db":
 		module = createDBModule()
+	case "template":
+		module = createTemplateModule()
 	default:
 		return newError("unknown stdlib module: %s", name)
 	}
@@ -896,322 +906,150 @@ func createOSModule() *Module {
 func createHTTPModule() *Module {
 	env := NewEnvironment()
 
-	// http.Request - Request model type for type annotations
-	// The actual request objects are created dynamically as Maps when handling requests,
-	// but this model allows type annotations like function(req: http.Request)
-	requestModel := &Model{
-		Name:         "Request",
-		FieldNames:   []string{},
-		Fields:       make(map[string]*ast.TypeExpression),
-		Annotations:  make(map[string][]string),
-		Methods:      make(map[string]*Function),
-		Constructors: []*Function{},
-	}
-	env.Set("Request", requestModel)
-
-	// http.Response(status, body?, headers?) - Response constructor
-	env.Set("Response", createResponseConstructor())
-
-	// http.ResponseType - Response model type for type annotations
-	// Used for return type annotations like function(): http.Response
-	responseModel := &Model{
-		Name:         "ResponseType",
-		FieldNames:   []string{},
-		Fields:       make(map[string]*ast.TypeExpression),
-		Annotations:  make(map[string][]string),
-		Methods:      make(map[string]*Function),
-		Constructors: []*Function{},
-	}
-	env.Set("ResponseType", responseModel)
-
-	// http.client - Client object with HTTP methods
-	env.Set("client", createClientObject())
+	// http.Request - Request model; handlers receive instances of it
+	env.Set("Request", httpRequestModel)
 
-	// http.Server() - Server constructor
-	env.Set("Server", createServerConstructor())
+	// http.Response(status, body?, headers?) - Response model and constructor
+	env.Set("Response", httpResponseModel)
 
-	return &Module{
-		Name:  "http",
-		Scope: env,
-	}
-}
+	// http.ResponseType - Former name of the Response type, kept for existing annotations
+	env.Set("ResponseType", httpResponseModel)
 
-// httpServerState holds the internal state for an HTTP server instance.
-type httpServerState struct {
-	routes      map[string]map[string]Object // method -> path -> handler
-	middleware  []Object                     // middleware functions
-	staticPaths map[string]string            // route -> filesystem path
-}
+	// http.Cookie(name, value, options?) - Cookie for the Set-Cookie response header
+	env.Set("Cookie", createCookieConstructor())
 
-// createResponseConstructor creates the Response constructor function.
-// Response(status)
-// Response(status, body)
-// Response(status, body, headers)
-func createResponseConstructor() *Builtin {
-	return &Builtin{
-		Name: "Response",
-		Fn: func(args ...Object) Object {
-			if len(args) < 1 || len(args) > 3 {
-				return newError("Response() takes 1-3 arguments, got %d", len(args))
-			}
+	// http.session(options) - Signed session middleware
+	env.Set("session", createSessionMiddleware())
 
-			// Get status (required)
-			status, ok := args[0].(*Integer)
-			if !ok {
-				return newError("Response() status must be integer, got %s", args[0].Type())
-			}
+	// http.stream(status, writer, headers?) - Streaming response
+	env.Set("stream", createStreamConstructor())
 
-			// Create response map
-			response := &Map{Pairs: make(map[string]Object)}
-			response.Pairs["status"] = status
-			response.Pairs["ok"] = &Boolean{Value: status.Value >= 200 && status.Value < 300}
+	// http.sse(fn) - Server-Sent Events response
+	env.Set("sse", createSSEConstructor())
 
-			// Get body (optional)
-			if len(args) >= 2 {
-				response.Pairs["body"] = args[1]
-			} else {
-				response.Pairs["body"] = &String{Value: ""}
-			}
+	// http.middleware - Built-in middleware: logging, cors, recovery, rateLimit, gzip, requestId
+	env.Set("middleware", createMiddlewareNamespace())
 
-			// Get headers (optional)
-			if len(args) >= 3 {
-				headersMap, ok := args[2].(*Map)
-				if !ok {
-					return newError("Response() headers must be map, got %s", args[2].Type())
-				}
-				response.Pairs["headers"] = headersMap
-			} else {
-				response.Pairs["headers"] = &Map{Pairs: make(map[string]Object)}
-			}
+	// http.auth.basic(), http.auth.apiKey(), http.auth.jwt() - Authentication middleware
+	env.Set("auth", createAuthNamespace())
 
-			// Add json() method to parse body as JSON
-			response.Pairs["json"] = &Builtin{
-				Name: "json",
-				Fn: func(methodArgs ...Object) Object {
-					if len(methodArgs) != 0 {
-						return newError("json() takes no arguments")
-					}
+	// http.proxy(targetUrl, options?) - Reverse proxy handler and middleware
+	env.Set("proxy", createProxyFunction())
 
-					body := response.Pairs["body"]
-					bodyStr, ok := body.(*String)
-					if !ok {
-						return newError("cannot parse non-string body as JSON")
-					}
+	// http.Client(options?) - Configurable client constructor
+	env.Set("Client", createClientConstructor())
 
-					var data interface{}
-					if err := json.Unmarshal([]byte(bodyStr.Value), &data); err != nil {
-						return &Error{Message: "invalid JSON: " + err.Error()}
-					}
+	// http.client - Default client object with HTTP methods
+	env.Set("client", createDefaultClientObject())
 
-					return convertJSONToObject(data)
-				},
-			}
+	// http.Server() - Server constructor
+	env.Set("Server", createServerConstructor())
 
-			return response
-		},
+	return &Module{
+		Name:  "http",
+		Scope: env,
 	}
 }
 
-// createClientObject creates the http.client object with HTTP methods.
-//
-//nolint:iface
-func createClientObject() Object {
-	client := &Map{Pairs: make(map[string]Object)}
-
-	client.Pairs["get"] = createClientMethod("GET")
-	client.Pairs["post"] = createClientMethod("POST")
-	client.Pairs["put"] = createClientMethod("PUT")
-	client.Pairs["patch"] = createClientMethod("PATCH")
-	client.Pairs["delete"] = createClientMethod("DELETE")
+// Default request body limits for http.Server().
+const (
+	defaultMaxBodySize     = 10 << 20 // 10 MiB
+	defaultMultipartMemory = 1 << 20  // 1 MiB
+)
 
-	return client
+// httpServerState holds the internal state for an HTTP server instance.
+type httpServerState struct {
+	routes          map[string]map[string]Object // method -> path -> handler
+	routeBodies     map[string]map[string]*Model // method -> path -> model bound by the body option
+	websockets      map[string]*websocketConfig  // path -> options of the WebSocket route
+	middleware      []Object                     // middleware functions
+	staticMounts    []*staticMount               // static file mounts, longest prefix first
+	openapi         *openAPIConfig               // set by server.openapi()
+	maxBodySize     int64                        // max request body size in bytes
+	multipartMemory int64                        // uploaded files up to this size are kept in memory
 }
 
-// createClientMethod creates an HTTP client method for the given HTTP verb.
-// GET/DELETE: (url, headers?)
-// POST/PUT/PATCH: (url, body, headers?)
+// createServerConstructor creates the Server() constructor function.
 //
-//nolint:gocognit,funlen
-func createClientMethod(method string) *Builtin {
+//nolint:funlen
+func createServerConstructor() *Builtin {
 	return &Builtin{
-		Name: strings.ToLower(method),
+		Name: "Server",
 		Fn: func(args ...Object) Object {
-			var url string
-			var body Object
-			var headers *Map
-
-			// Parse arguments based on method
-			if method == "GET" || method == "DELETE" {
-				// GET/DELETE: url, headers?
-				if len(args) < 1 || len(args) > 2 {
-					return newError("%s() takes 1-2 arguments, got %d", method, len(args))
-				}
-
-				urlObj, ok := args[0].(*String)
-				if !ok {
-					return newError("%s() url must be string, got %s", method, args[0].Type())
-				}
-				url = urlObj.Value
-
-				if len(args) == 2 {
-					var ok bool
-					headers, ok = args[1].(*Map)
-					if !ok {
-						return newError("%s() headers must be map, got %s", method, args[1].Type())
-					}
-				}
-			} else {
-				// POST/PUT/PATCH: url, body, headers?
-				if len(args) < 2 || len(args) > 3 {
-					return newError("%s() takes 2-3 arguments, got %d", method, len(args))
-				}
-
-				urlObj, ok := args[0].(*String)
-				if !ok {
-					return newError("%s() url must be string, got %s", method, args[0].Type())
-				}
-				url = urlObj.Value
-
-				body = args[1]
-
-				if len(args) == 3 {
-					var ok bool
-					headers, ok = args[2].(*Map)
-					if !ok {
-						return newError("%s() headers must be map, got %s", method, args[2].Type())
-					}
-				}
+			if len(args) > 1 {
+				return newError("Server() takes 0-1 arguments (options), got %d", len(args))
 			}
 
-			// Build HTTP request
-			var reqBody io.Reader
-			if body != nil {
-				// Convert body to JSON if it's not a string
-				if str, ok := body.(*String); ok {
-					reqBody = strings.NewReader(str.Value)
-				} else {
-					// Convert object to JSON
-					goValue := convertObjectToGo(body)
-					jsonBytes, err := json.Marshal(goValue)
-					if err != nil {
-						return &Error{Message: "failed to marshal body: " + err.Error()}
-					}
-					reqBody = bytes.NewReader(jsonBytes)
-				}
-			}
+			state := newHTTPServerState()
 
-			//nolint:noctx
-			req, err := http.NewRequest(method, url, reqBody)
-			if err != nil {
-				return &Error{Message: "failed to create request: " + err.Error()}
-			}
-
-			// Set headers
-			if headers != nil {
-				for key, value := range headers.Pairs {
-					if arr, ok := value.(*Array); ok {
-						// Headers are arrays of strings
-						for _, elem := range arr.Elements {
-							if str, ok := elem.(*String); ok {
-								req.Header.Add(key, str.Value)
-							}
-						}
-					} else if str, ok := value.(*String); ok {
-						req.Header.Set(key, str.Value)
-					}
+			if len(args) == 1 {
+				options, ok := args[0].(*Map)
+				if !ok {
+					return newError("Server() options must be map, got %s", args[0].Type())
 				}
-			}
-
-			// Execute request
-			httpClient := &http.Client{Timeout: 30 * time.Second}
-			resp, err := httpClient.Do(req)
-			if err != nil {
-				return &Error{Message: err.Error()}
-			}
-			//nolint:errcheck
-			defer resp.Body.Close()
-
-			// Read response body
-			bodyBytes, err := io.ReadAll(resp.Body)
-			if err != nil {
-				return &Error{Message: "failed to read response: " + err.Error()}
-			}
-
-			// Convert headers to TotalScript map
-			responseHeaders := &Map{Pairs: make(map[string]Object)}
-			for key, values := range resp.Header {
-				elements := make([]Object, len(values))
-				for i, v := range values {
-					elements[i] = &String{Value: v}
+				if err := applyServerOptions(state, options); err != nil {
+					return err
 				}
-				responseHeaders.Pairs[key] = &Array{Elements: elements}
-			}
-
-			// Create response object
-			response := &Map{Pairs: make(map[string]Object)}
-			response.Pairs["status"] = &Integer{Value: int64(resp.StatusCode)}
-			response.Pairs["body"] = &String{Value: string(bodyBytes)}
-			response.Pairs["headers"] = responseHeaders
-			response.Pairs["ok"] = &Boolean{Value: resp.StatusCode >= 200 && resp.StatusCode < 300}
-
-			// Add json() method
-			response.Pairs["json"] = &Builtin{
-				Name: "json",
-				Fn: func(methodArgs ...Object) Object {
-					if len(methodArgs) != 0 {
-						return newError("json() takes no arguments")
-					}
-
-					var data interface{}
-					if err := json.Unmarshal(bodyBytes, &data); err != nil {
-						return &Error{Message: "invalid JSON: " + err.Error()}
-					}
-
-					return convertJSONToObject(data)
-				},
 			}
 
-			return response
+			return createServerInstance(state)
 		},
 	}
 }
 
-// createServerConstructor creates the Server() constructor function.
-//
-//nolint:funlen
-func createServerConstructor() *Builtin {
-	return &Builtin{
-		Name: "Server",
-		Fn: func(args ...Object) Object {
-			if len(args) != 0 {
-				return newError("Server() takes no arguments")
-			}
+// newHTTPServerState creates empty state for a new server instance.
+func newHTTPServerState() *httpServerState {
+	return &httpServerState{
+		routes:          make(map[string]map[string]Object),
+		routeBodies:     make(map[string]map[string]*Model),
+		websockets:      make(map[string]*websocketConfig),
+		middleware:      []Object{},
+		maxBodySize:     defaultMaxBodySize,
+		multipartMemory: defaultMultipartMemory,
+	}
+}
 
-			// Create server state
-			state := &httpServerState{
-				routes:      make(map[string]map[string]Object),
-				middleware:  []Object{},
-				staticPaths: make(map[string]string),
+// applyServerOptions applies the options map passed to http.Server().
+func applyServerOptions(state *httpServerState, options *Map) *Error {
+	for key, value := range options.Pairs {
+		switch key {
+		case "maxBodySize", "multipartMemory":
+			size, ok := value.(*Integer)
+			if !ok || size.Value <= 0 {
+				return newError("Server() option '%s' must be a positive integer", key)
+			}
+			if key == "maxBodySize" {
+				state.maxBodySize = size.Value
+			} else {
+				state.multipartMemory = size.Value
 			}
-
-			// Create server instance (Map object)
-			serverInstance := &Map{Pairs: make(map[string]Object)}
-
-			// Add route registration methods
-			serverInstance.Pairs["get"] = createRouteMethod(state, "GET")
-			serverInstance.Pairs["post"] = createRouteMethod(state, "POST")
-			serverInstance.Pairs["put"] = createRouteMethod(state, "PUT")
-			serverInstance.Pairs["patch"] = createRouteMethod(state, "PATCH")
-			serverInstance.Pairs["delete"] = createRouteMethod(state, "DELETE")
-
-			// Add server control methods
-			serverInstance.Pairs["start"] = createStartMethod(state)
-			serverInstance.Pairs["static"] = createStaticMethod(state)
-			serverInstance.Pairs["use"] = createUseMethod(state)
-
-			return serverInstance
-		},
+		default:
+			return newError("Server() unknown option '%s'", key)
+		}
 	}
+	return nil
+}
+
+// createServerInstance creates the server object exposed to TotalScript.
+func createServerInstance(state *httpServerState) *Map {
+	// Create server instance (Map object)
+	serverInstance := &Map{Pairs: make(map[string]Object)}
+
+	// Add route registration methods
+	serverInstance.Pairs["get"] = createRouteMethod(state, "GET")
+	serverInstance.Pairs["post"] = createRouteMethod(state, "POST")
+	serverInstance.Pairs["put"] = createRouteMethod(state, "PUT")
+	serverInstance.Pairs["patch"] = createRouteMethod(state, "PATCH")
+	serverInstance.Pairs["delete"] = createRouteMethod(state, "DELETE")
+	serverInstance.Pairs["websocket"] = createWebSocketMethod(state)
+
+	// Add server control methods
+	serverInstance.Pairs["start"] = createStartMethod(state)
+	serverInstance.Pairs["static"] = createStaticMethod(state)
+	serverInstance.Pairs["use"] = createUseMethod(state)
+	serverInstance.Pairs["openapi"] = createOpenAPIMethod(state)
+
+	return serverInstance
 }
 
 // createRouteMethod creates a route registration method (get, post, put, patch, delete).
@@ -1219,8 +1057,8 @@ func createRouteMethod(state *httpServerState, method string) *Builtin {
 	return &Builtin{
 		Name: strings.ToLower(method),
 		Fn: func(args ...Object) Object {
-			if len(args) != 2 {
-				return newError("%s() requires 2 arguments (path, handler), got %d", method, len(args))
+			if len(args) < 2 || len(args) > 3 {
+				return newError("%s() takes 2-3 arguments (path, handler, options?), got %d", method, len(args))
 			}
 
 			path, ok := args[0].(*String)
@@ -1228,11 +1066,34 @@ func createRouteMethod(state *httpServerState, method string) *Builtin {
 				return newError("%s() path must be string, got %s", method, args[0].Type())
 			}
 
-			handler, ok := args[1].(*Function)
-			if !ok {
+			handler := args[1]
+			switch handler.(type) {
+			case *Function, *Builtin:
+			default:
 				return newError("%s() handler must be function, got %s", method, args[1].Type())
 			}
 
+			// Route options: {"body": Model} binds the request body before the handler runs
+			if len(args) == 3 {
+				options, ok := args[2].(*Map)
+				if !ok {
+					return newError("%s() options must be map, got %s", method, args[2].Type())
+				}
+				for key, value := range options.Pairs {
+					if key != "body" {
+						return newError("%s() unknown option '%s'", method, key)
+					}
+					model, ok := value.(*Model)
+					if !ok {
+						return newError("%s() option 'body' must be model, got %s", method, value.Type())
+					}
+					if state.routeBodies[method] == nil {
+						state.routeBodies[method] = make(map[string]*Model)
+					}
+					state.routeBodies[method][path.Value] = model
+				}
+			}
+
 			// Store route
 			if state.routes[method] == nil {
 				state.routes[method] = make(map[string]Object)
@@ -1251,8 +1112,8 @@ func createStartMethod(state *httpServerState) *Builtin {
 	return &Builtin{
 		Name: "start",
 		Fn: func(args ...Object) Object {
-			if len(args) != 1 {
-				return newError("start() requires 1 argument (port)")
+			if len(args) < 1 || len(args) > 2 {
+				return newError("start() takes 1-2 arguments (port, options?), got %d", len(args))
 			}
 
 			port, ok := args[0].(*Integer)
@@ -1260,50 +1121,51 @@ func createStartMethod(state *httpServerState) *Builtin {
 				return newError("start() port must be integer, got %s", args[0].Type())
 			}
 
-			// Create HTTP server
-			mux := http.NewServeMux()
-
-			// Handle all requests
-			mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-				// Find matching route
-				handler, params := matchRoute(state, r.Method, r.URL.Path)
-				if handler == nil {
-					w.WriteHeader(http.StatusNotFound)
-					//nolint:errcheck,gosec
-					w.Write([]byte("404 Not Found"))
-					return
+			config := &startConfig{}
+			if len(args) == 2 {
+				options, ok := args[1].(*Map)
+				if !ok {
+					return newError("start() options must be map, got %s", args[1].Type())
 				}
-
-				// Create request object
-				requestObj := createRequestObject(r, params)
-
-				// Execute middleware chain and handler
-				result := executeMiddlewareChain(state.middleware, handler, requestObj)
-
-				// Handle errors
-				if err, ok := result.(*Error); ok {
-					w.WriteHeader(http.StatusInternalServerError)
-					//nolint:errcheck,gosec
-					w.Write([]byte(err.Message))
-					return
+				var optErr *Error
+				if config, optErr = parseStartOptions(options); optErr != nil {
+					return optErr
 				}
+			}
 
-				// Convert response to HTTP response
-				writeHTTPResponse(w, result)
-			})
+			// tsl openapi evaluates scripts without listening
+			if serverStartHook != nil {
+				return serverStartHook(state)
+			}
 
-			// Serve static files
-			for routePath, fsPath := range state.staticPaths {
-				fs := http.FileServer(http.Dir(fsPath))
-				mux.Handle(routePath, http.StripPrefix(routePath, fs))
+			server := &http.Server{
+				Addr:              net.JoinHostPort(config.host, strconv.FormatInt(port.Value, 10)),
+				Handler:           newServerMux(state),
+				ReadHeaderTimeout: 30 * time.Second,
 			}
 
+			displayHost := config.host
+			if displayHost == "" {
+				displayHost = "localhost"
+			}
+			displayAddr := net.JoinHostPort(displayHost, strconv.FormatInt(port.Value, 10))
+
 			// Start server (blocking)
-			addr := fmt.Sprintf(":%d", port.Value)
-			fmt.Printf("Server listening on http://localhost%s\n", addr)
+			if config.useTLS() {
+				tlsConfig, err := config.tlsConfig()
+				if err != nil {
+					return newError("failed to load TLS certificate: %s", err.Error())
+				}
+				server.TLSConfig = tlsConfig
+				fmt.Printf("Server listening on https://%s\n", displayAddr)
+				if err := server.ListenAndServeTLS("", ""); err != nil {
+					return newError("server error: %s", err.Error())
+				}
+				return NULL
+			}
 
-			//nolint:gosec
-			if err := http.ListenAndServe(addr, mux); err != nil {
+			fmt.Printf("Server listening on http://%s\n", displayAddr)
+			if err := server.ListenAndServe(); err != nil {
 				return newError("server error: %s", err.Error())
 			}
 
@@ -1312,27 +1174,113 @@ func createStartMethod(state *httpServerState) *Builtin {
 	}
 }
 
-// createStaticMethod creates the static() method for serving static files.
-func createStaticMethod(state *httpServerState) *Builtin {
-	return &Builtin{
-		Name: "static",
-		Fn: func(args ...Object) Object {
-			if len(args) != 2 {
-				return newError("static() requires 2 arguments (routePath, filesystemPath)")
+// newServerMux builds the request multiplexer for a server from its registered
+// routes, middleware and static mounts.
+//
+//nolint:gocognit
+func newServerMux(state *httpServerState) *http.ServeMux {
+	mux := http.NewServeMux()
+
+	// Handle all requests
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		// Find matching route; upgrade requests are matched against WebSocket routes
+		method := r.Method
+		if isWebSocketUpgrade(r) {
+			method = websocketMethod
+		}
+		handler, pattern, params := matchRoute(state, method, r.URL.Path)
+		matched := handler != nil
+		if !matched {
+			// Static files and 404 responses go through middleware as well
+			handler = staticHandler(state, r.Method, r.URL.Path)
+			if handler == nil {
+				handler = notFoundHandler()
 			}
+		}
 
-			routePath, ok := args[0].(*String)
-			if !ok {
-				return newError("static() routePath must be string, got %s", args[0].Type())
+		// Create request object
+		r.Body = http.MaxBytesReader(w, r.Body, state.maxBodySize)
+		scope := &requestScope{}
+		defer scope.release()
+
+		env := handlerEnv(handler)
+		requestObj, err := createRequestObject(r, params, state, scope, env)
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				w.WriteHeader(http.StatusRequestEntityTooLarge)
+				//nolint:errcheck,gosec
+				w.Write([]byte("413 Request Entity Too Large"))
+				return
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			//nolint:errcheck,gosec
+			w.Write([]byte("failed to read request body: " + err.Error()))
+			return
+		}
+
+		// WebSocket handlers run after the upgrade, once middleware let the request through
+		if method == websocketMethod && matched {
+			handler = websocketRouteHandler(handler, state.websockets[pattern])
+		}
+
+		// Routes with a body model bind the request before the handler runs
+		if model := state.routeBodies[method][pattern]; model != nil {
+			handler = bindingHandler(handler, model, env)
+		}
+
+		// Execute middleware chain and handler
+		result := executeMiddlewareChain(state.middleware, handler, requestObj)
+
+		// Handle errors; binding errors and oversized bodies are the client's fault
+		if err, ok := result.(*Error); ok {
+			if scope.bodyTooLarge {
+				w.WriteHeader(http.StatusRequestEntityTooLarge)
+				//nolint:errcheck,gosec
+				w.Write([]byte("413 Request Entity Too Large"))
+				return
 			}
+			if err.Fields != nil {
+				writeValidationError(w, err)
+				return
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			//nolint:errcheck,gosec
+			w.Write([]byte(err.Message))
+			return
+		}
 
-			fsPath, ok := args[1].(*String)
-			if !ok {
-				return newError("static() filesystemPath must be string, got %s", args[1].Type())
+		// Streaming and WebSocket responses carry a special body
+		if response, ok := isResponse(result); ok {
+			switch body := response.Fields["body"].(type) {
+			case *StreamResponse:
+				writeStreamResponse(w, r, response, body)
+				return
+			case *WebSocketUpgrade:
+				serveWebSocket(w, r, response, body, state.maxBodySize)
+				return
+			case *StaticFile:
+				writeStaticFile(w, r, response, body)
+				return
+			case *ProxyResponse:
+				writeProxyResponse(w, r, response, body)
+				return
 			}
+		}
 
-			state.staticPaths[routePath.Value] = fsPath.Value
-			return NULL
+		// Convert response to HTTP response
+		writeHTTPResponse(w, result)
+	})
+
+	return mux
+}
+
+// notFoundHandler answers requests that match no route.
+func notFoundHandler() *Builtin {
+	return &Builtin{
+		Name: "notFound",
+		Fn: func(_ ...Object) Object {
+			return newResponse(http.StatusNotFound, &String{Value: "404 Not Found"}, &Map{Pairs: make(map[string]Object)})
 		},
 	}
 }
@@ -1346,12 +1294,13 @@ func createUseMethod(state *httpServerState) *Builtin {
 				return newError("use() requires 1 argument (middleware function)")
 			}
 
-			middleware, ok := args[0].(*Function)
-			if !ok {
+			switch args[0].(type) {
+			case *Function, *Builtin:
+			default:
 				return newError("use() middleware must be function, got %s", args[0].Type())
 			}
 
-			state.middleware = append(state.middleware, middleware)
+			state.middleware = append(state.middleware, args[0])
 			return NULL
 		},
 	}
@@ -1398,42 +1347,75 @@ func executeMiddlewareChain(middleware []Object, handler Object, req Object) Obj
 }
 
 // matchRoute finds a matching route and extracts path parameters.
-// Returns (handler, params) or (nil, nil) if no match.
-func matchRoute(state *httpServerState, method, path string) (Object, map[string]string) {
+// Returns (handler, pattern, params) or (nil, "", nil) if no match.
+func matchRoute(state *httpServerState, method, path string) (Object, string, map[string]string) {
 	methodRoutes, ok := state.routes[method]
 	if !ok {
-		return nil, nil
+		return nil, "", nil
 	}
 
 	// Try exact match first
 	if handler, ok := methodRoutes[path]; ok {
-		return handler, make(map[string]string)
+		return handler, path, make(map[string]string)
 	}
 
 	// Try pattern matching with parameters
 	for pattern, handler := range methodRoutes {
+		if isWildcardPattern(pattern) {
+			continue
+		}
+		if matched, params := matchPattern(pattern, path); matched {
+			return handler, pattern, params
+		}
+	}
+
+	// Wildcard patterns match last; the longest one wins
+	var bestHandler Object
+	var bestPattern string
+	var bestParams map[string]string
+	for pattern, handler := range methodRoutes {
+		if !isWildcardPattern(pattern) || len(pattern) <= len(bestPattern) {
+			continue
+		}
 		if matched, params := matchPattern(pattern, path); matched {
-			return handler, params
+			bestHandler, bestPattern, bestParams = handler, pattern, params
 		}
 	}
 
-	return nil, nil
+	return bestHandler, bestPattern, bestParams
+}
+
+// isWildcardPattern reports whether a route pattern ends with "/*".
+func isWildcardPattern(pattern string) bool {
+	return pattern == "*" || strings.HasSuffix(pattern, "/*")
 }
 
 // matchPattern matches a path against a pattern and extracts parameters.
 // Pattern: /users/:id/posts/:postId
 // Path: /users/123/posts/456
 // Returns: (true, {"id": "123", "postId": "456"})
+//
+// A trailing "*" segment matches the rest of the path, including nothing:
+// /api/* matches /api, /api/users and /api/users/1 with {"*": "users/1"}.
 func matchPattern(pattern, path string) (bool, map[string]string) {
 	patternParts := strings.Split(strings.Trim(pattern, "/"), "/")
 	pathParts := strings.Split(strings.Trim(path, "/"), "/")
 
+	params := make(map[string]string)
+
+	if patternParts[len(patternParts)-1] == "*" {
+		patternParts = patternParts[:len(patternParts)-1]
+		if len(pathParts) < len(patternParts) {
+			return false, nil
+		}
+		params["*"] = strings.Join(pathParts[len(patternParts):], "/")
+		pathParts = pathParts[:len(patternParts)]
+	}
+
 	if len(patternParts) != len(pathParts) {
 		return false, nil
 	}
 
-	params := make(map[string]string)
-
 	for i, patternPart := range patternParts {
 		if strings.HasPrefix(patternPart, ":") {
 			// Parameter
@@ -1448,13 +1430,24 @@ func matchPattern(pattern, path string) (bool, map[string]string) {
 	return true, params
 }
 
-// createRequestObject creates a TotalScript Request object from an HTTP request.
+// createRequestObject creates an http.Request instance from an HTTP request.
+// Returns an error if the request body cannot be read (e.g., it exceeds the size limit).
 //
-//nolint:funlen,iface
-func createRequestObject(r *http.Request, params map[string]string) Object {
-	// Read body
-	bodyBytes, _ := io.ReadAll(r.Body)
-	r.Body = io.NopCloser(bytes.NewBuffer(bodyBytes)) // Restore body
+//nolint:funlen
+func createRequestObject(r *http.Request, params map[string]string, state *httpServerState, scope *requestScope, env *Environment) (*ModelInstance, error) {
+	// Read body; multipart bodies are left to req.multipart() so uploads are not buffered
+	contentType := r.Header.Get("Content-Type")
+	var bodyBytes []byte
+	body := unbufferedBody
+	if !isMultipart(contentType) {
+		var err error
+		bodyBytes, err = io.ReadAll(r.Body)
+		if err != nil {
+			return nil, err
+		}
+		r.Body = io.NopCloser(bytes.NewBuffer(bodyBytes)) // Restore body
+		body = &String{Value: string(bodyBytes)}
+	}
 
 	// Convert params to map
 	paramsMap := &Map{Pairs: make(map[string]Object)}
@@ -1462,37 +1455,30 @@ func createRequestObject(r *http.Request, params map[string]string) Object {
 		paramsMap.Pairs[k] = &String{Value: v}
 	}
 
-	// Convert query parameters to map of arrays
-	queryMap := &Map{Pairs: make(map[string]Object)}
-	for key, values := range r.URL.Query() {
-		elements := make([]Object, len(values))
-		for i, v := range values {
-			elements[i] = &String{Value: v}
-		}
-		queryMap.Pairs[key] = &Array{Elements: elements}
-	}
-
-	// Convert headers to map of arrays
-	headersMap := &Map{Pairs: make(map[string]Object)}
-	for key, values := range r.Header {
-		elements := make([]Object, len(values))
-		for i, v := range values {
-			elements[i] = &String{Value: v}
-		}
-		headersMap.Pairs[key] = &Array{Elements: elements}
-	}
+	// Convert query parameters and headers to maps of arrays
+	queryMap := valuesToMap(r.URL.Query())
+	headersMap := valuesToMap(r.Header)
 
 	// Create request object
-	request := &Map{Pairs: make(map[string]Object)}
-	request.Pairs["method"] = &String{Value: r.Method}
-	request.Pairs["path"] = &String{Value: r.URL.Path}
-	request.Pairs["params"] = paramsMap
-	request.Pairs["query"] = queryMap
-	request.Pairs["headers"] = headersMap
-	request.Pairs["body"] = &String{Value: string(bodyBytes)}
+	request := &ModelInstance{
+		Model:   httpRequestModel,
+		Fields:  make(map[string]Object),
+		Methods: make(map[string]*Builtin),
+	}
+	request.Fields["method"] = &String{Value: r.Method}
+	request.Fields["path"] = &String{Value: r.URL.Path}
+	request.Fields["params"] = paramsMap
+	request.Fields["query"] = queryMap
+	request.Fields["headers"] = headersMap
+	request.Fields["cookies"] = createCookiesMap(r)
+	request.Fields["ip"] = &String{Value: clientIP(r)}
+	request.Fields["body"] = body
+	request.Fields["session"] = NULL
+	request.Fields["data"] = NULL
+	request.Fields["user"] = NULL
 
 	// Add json() method
-	request.Pairs["json"] = &Builtin{
+	request.Methods["json"] = &Builtin{
 		Name: "json",
 		Fn: func(args ...Object) Object {
 			if len(args) != 0 {
@@ -1508,11 +1494,24 @@ func createRequestObject(r *http.Request, params map[string]string) Object {
 		},
 	}
 
-	return request
+	// Add form() and multipart() methods
+	request.Methods["form"] = createFormMethod(contentType, bodyBytes)
+	request.Methods["multipart"] = createMultipartMethod(contentType, r.Body, state.multipartMemory, scope)
+
+	// Add bind(Model) method
+	request.Methods["bind"] = createBindMethod(request, env)
+
+	return request, nil
 }
 
-// callTSFunction calls a TotalScript function with arguments.
+// callTSFunction calls a TotalScript function with arguments, validating the
+// annotated parameter and return types against the function's scope.
+// Builtins are accepted too, so native middleware can share the same call path.
 func callTSFunction(fn Object, args ...Object) Object {
+	if builtin, ok := fn.(*Builtin); ok {
+		return builtin.Fn(args...)
+	}
+
 	function, ok := fn.(*Function)
 	if !ok {
 		return newError("not a function: %s", fn.Type())
@@ -1521,29 +1520,40 @@ func callTSFunction(fn Object, args ...Object) Object {
 	// Create new environment for function execution
 	extendedEnv := NewEnclosedEnvironment(function.Env)
 
-	// Bind parameters
-	if len(args) != len(function.Parameters) {
-		return newError("function expects %d arguments, got %d", len(function.Parameters), len(args))
+	// Bind parameters, filling in defaults and collecting variadic arguments
+	values, bindErr := bindArguments(function.Parameters, args, nil, function.Env)
+	if bindErr != nil {
+		return bindErr
 	}
 
 	for i, param := range function.Parameters {
-		extendedEnv.Set(param.Name.Value, args[i])
+		value, err := checkParameter(param, values[i], function.Env)
+		if err != nil {
+			return newError("parameter '%s': %s", param.Name.Value, err.Message)
+		}
+		extendedEnv.Set(param.Name.Value, value)
 	}
 
 	// Evaluate function body
 	result := Eval(function.Body, extendedEnv)
 
 	// Unwrap return value
-	if returnValue, ok := result.(*ReturnValue); ok {
-		return returnValue.Value
+	result = UnwrapReturnValue(result)
+
+	if function.ReturnType != nil && !IsError(result) {
+		if err := validateType(result, function.ReturnType, function.Env); err != nil {
+			errObj, _ := err.(*Error)
+			return newError("return value: %s", errObj.Message)
+		}
+		result = coerceValue(result, function.ReturnType)
 	}
 
 	return result
 }
 
 // writeHTTPResponse writes a TotalScript response object to an HTTP ResponseWriter.
-func writeHTTPResponse(w http.ResponseWriter, response Object) {
-	responseMap, ok := response.(*Map)
+func writeHTTPResponse(w http.ResponseWriter, result Object) {
+	response, ok := isResponse(result)
 	if !ok {
 		w.WriteHeader(http.StatusInternalServerError)
 		//nolint:errcheck,gosec
@@ -1552,15 +1562,7 @@ func writeHTTPResponse(w http.ResponseWriter, response Object) {
 	}
 
 	// Get status
-	status, ok := responseMap.Pairs["status"]
-	if !ok {
-		w.WriteHeader(http.StatusInternalServerError)
-		//nolint:errcheck,gosec
-		w.Write([]byte("response missing status"))
-		return
-	}
-
-	statusInt, ok := status.(*Integer)
+	statusInt, ok := response.Fields["status"].(*Integer)
 	if !ok {
 		w.WriteHeader(http.StatusInternalServerError)
 		//nolint:errcheck,gosec
@@ -1569,48 +1571,85 @@ func writeHTTPResponse(w http.ResponseWriter, response Object) {
 	}
 
 	// Set headers
-	if headers, ok := responseMap.Pairs["headers"]; ok {
-		if headersMap, ok := headers.(*Map); ok {
-			for key, value := range headersMap.Pairs {
-				if arr, ok := value.(*Array); ok {
-					for _, elem := range arr.Elements {
-						if str, ok := elem.(*String); ok {
-							w.Header().Add(key, str.Value)
-						}
-					}
-				} else if str, ok := value.(*String); ok {
-					w.Header().Set(key, str.Value)
-				}
-			}
+	if headersMap, ok := response.Fields["headers"].(*Map); ok {
+		if err := setResponseHeaders(w.Header(), headersMap); err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			//nolint:errcheck,gosec
+			w.Write([]byte(err.Message))
+			return
 		}
 	}
 
-	// Write status
-	w.WriteHeader(int(statusInt.Value))
-
-	// Get body
-	body, ok := responseMap.Pairs["body"]
-	if !ok {
-		return
-	}
-
-	// Write body
-	if str, ok := body.(*String); ok {
-		//nolint:errcheck,gosec
-		w.Write([]byte(str.Value))
-	} else {
-		// Convert non-string body to JSON
-		goValue := convertObjectToGo(body)
-		jsonBytes, err := json.Marshal(goValue)
+	// Encode body; JSON bodies get a content type unless the handler set one
+	var bodyBytes []byte
+	if body, ok := response.Fields["body"]; ok {
+		var contentType string
+		var err error
+		bodyBytes, contentType, err = encodeResponseBody(body)
 		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
 			//nolint:errcheck,gosec
 			w.Write([]byte("error marshaling response: " + err.Error()))
 			return
 		}
-		w.Header().Set("Content-Type", "application/json")
-		//nolint:errcheck,gosec
-		w.Write(jsonBytes)
+		if contentType != "" && w.Header().Get("Content-Type") == "" {
+			w.Header().Set("Content-Type", contentType)
+		}
+	}
+
+	// Write status and body
+	w.WriteHeader(int(statusInt.Value))
+	//nolint:errcheck,gosec
+	w.Write(bodyBytes)
+}
+
+// encodeResponseBody converts a response body to bytes. Strings are written
+// as-is; other values are converted to JSON and get an application/json
+// content type.
+func encodeResponseBody(body Object) ([]byte, string, error) {
+	if str, ok := body.(*String); ok {
+		return []byte(str.Value), "", nil
+	}
+
+	jsonBytes, err := json.Marshal(convertObjectToGo(body))
+	if err != nil {
+		return nil, "", err
+	}
+	return jsonBytes, "application/json", nil
+}
+
+// clientIP returns the IP address of the client that sent r.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
 	}
+	return host
+}
+
+// setResponseHeaders copies a TotalScript headers map to an http.Header.
+// Values may be strings, arrays of strings, or cookie maps created by http.Cookie().
+func setResponseHeaders(header http.Header, headersMap *Map) *Error {
+	for key, value := range headersMap.Pairs {
+		values := []Object{value}
+		if arr, ok := value.(*Array); ok {
+			values = arr.Elements
+		}
+
+		for _, elem := range values {
+			switch v := elem.(type) {
+			case *String:
+				header.Add(key, v.Value)
+			case *Map:
+				cookie, err := cookieFromMap(v)
+				if err != nil {
+					return err
+				}
+				header.Add(key, cookie.String())
+			}
+		}
+	}
+	return nil
 }
 
 // Database module for SQLite persistence
@@ -2038,7 +2077,7 @@ func createDBTransactionFunc(state *DBState) *Builtin {
 
 			// Execute the function (without holding the lock)
 			// Individual db operations will lock/unlock as needed
-			result := applyFunction(fn, []Object{}, NewEnvironment())
+			result := applyFunction(fn, []Object{}, nil, NewEnvironment())
 
 			// Commit or rollback (with lock)
 			state.mu.Lock()
@@ -0,0 +1,300 @@
+package interpreter
+
+import (
+	"strings"
+
+	"github.com/mishankov/totalscript-lang/internal/ast"
+)
+
+// Narrowing maps variable names to the types they are known to have at a
+// point of the program, refining their declared types.
+type Narrowing map[string]*ast.TypeExpression
+
+// TypeLookup returns the current type of a variable, if it has one.
+type TypeLookup func(name string) (*ast.TypeExpression, bool)
+
+// NarrowCondition returns what a condition tells about variable types when it
+// is true and when it is false. It understands `x is T`, `x == null`,
+// `x != null`, `!`, `&&` and `||`:
+//
+//	var result: float | Error = divide(a, b)
+//	if result is Error { ... }   # Error when true, float when false
+func NarrowCondition(cond ast.Expression, typeOf TypeLookup) (Narrowing, Narrowing) {
+	switch cond := cond.(type) {
+	case *ast.PrefixExpression:
+		if cond.Operator == "!" {
+			whenTrue, whenFalse := NarrowCondition(cond.Right, typeOf)
+			return whenFalse, whenTrue
+		}
+
+	case *ast.InfixExpression:
+		switch cond.Operator {
+		case "is":
+			name, ok := narrowedVariable(cond.Left)
+			typeName := isTypeName(cond.Right)
+			if !ok || typeName == "" {
+				break
+			}
+			return narrowIs(name, typeName, typeOf)
+
+		case "==", "!=":
+			name, ok := narrowedVariable(cond.Left)
+			other := cond.Right
+			if !ok {
+				name, ok = narrowedVariable(cond.Right)
+				other = cond.Left
+			}
+			if _, isNull := other.(*ast.NullLiteral); !ok || !isNull {
+				break
+			}
+			whenNull, whenNotNull := narrowIs(name, typeNameNull, typeOf)
+			if cond.Operator == "==" {
+				return whenNull, whenNotNull
+			}
+			return whenNotNull, whenNull
+
+		case "&&":
+			leftTrue, leftFalse := NarrowCondition(cond.Left, typeOf)
+			rightTrue, rightFalse := NarrowCondition(cond.Right, leftTrue.lookup(typeOf))
+			return leftTrue.merge(rightTrue), leftFalse.join(rightFalse, typeOf)
+
+		case "||":
+			leftTrue, leftFalse := NarrowCondition(cond.Left, typeOf)
+			rightTrue, rightFalse := NarrowCondition(cond.Right, leftFalse.lookup(typeOf))
+			return leftTrue.join(rightTrue, typeOf), leftFalse.merge(rightFalse)
+		}
+	}
+
+	return nil, nil
+}
+
+// narrowIs narrows name to typeName when true and removes typeName from its
+// type when false. Untyped variables are only narrowed when true.
+func narrowIs(name, typeName string, typeOf TypeLookup) (Narrowing, Narrowing) {
+	whenTrue := Narrowing{name: &ast.TypeExpression{Name: typeName}}
+
+	declared, ok := typeOf(name)
+	if !ok || declared == nil {
+		return whenTrue, nil
+	}
+
+	members := typeMembers(declared)
+	var matching, rest []*ast.TypeExpression
+	for _, member := range members {
+		if typeMemberMatches(member, typeName) {
+			matching = append(matching, member)
+		} else {
+			rest = append(rest, member)
+		}
+	}
+
+	// Keep generic arguments known from the declaration: `x is array`
+	// narrows `array<integer> | string` to `array<integer>`
+	if len(matching) > 0 {
+		whenTrue[name] = typeFromMembers(matching)
+	}
+	if len(rest) == 0 || len(matching) == 0 {
+		return whenTrue, nil
+	}
+	return whenTrue, Narrowing{name: typeFromMembers(rest)}
+}
+
+// narrowedVariable returns the name of a variable expression.
+func narrowedVariable(expr ast.Expression) (string, bool) {
+	ident, ok := expr.(*ast.Identifier)
+	if !ok {
+		return "", false
+	}
+	return ident.Value, true
+}
+
+// isTypeName returns the type name on the right of `is`.
+func isTypeName(expr ast.Expression) string {
+	switch expr := expr.(type) {
+	case *ast.Identifier:
+		return expr.Value
+	case *ast.NullLiteral:
+		return typeNameNull
+	case *ast.MemberExpression:
+		return expr.String()
+	}
+	return ""
+}
+
+// typeMembers splits a type into the alternatives of its union, with null for
+// optional types.
+func typeMembers(t *ast.TypeExpression) []*ast.TypeExpression {
+	var members []*ast.TypeExpression
+	if len(t.Union) > 0 {
+		for _, name := range t.Union {
+			members = append(members, &ast.TypeExpression{Token: t.Token, Name: name})
+		}
+	} else {
+		members = append(members, &ast.TypeExpression{Token: t.Token, Name: t.Name, Generic: t.Generic})
+	}
+	if t.Optional {
+		members = append(members, &ast.TypeExpression{Token: t.Token, Name: typeNameNull})
+	}
+	return members
+}
+
+// typeFromMembers joins union alternatives into a type; a null alternative
+// makes it optional.
+func typeFromMembers(members []*ast.TypeExpression) *ast.TypeExpression {
+	var nonNull []*ast.TypeExpression
+	for _, member := range members {
+		if member.Name != typeNameNull {
+			nonNull = append(nonNull, member)
+		}
+	}
+
+	switch {
+	case len(nonNull) == 0:
+		return &ast.TypeExpression{Name: typeNameNull}
+	case len(nonNull) == 1:
+		t := *nonNull[0]
+		t.Optional = len(nonNull) < len(members)
+		return &t
+	}
+
+	t := &ast.TypeExpression{Token: nonNull[0].Token, Optional: len(nonNull) < len(members)}
+	for _, member := range nonNull {
+		t.Union = append(t.Union, member.Name)
+	}
+	return t
+}
+
+// typeMemberMatches reports whether values of a union alternative satisfy
+// `is typeName`.
+func typeMemberMatches(member *ast.TypeExpression, typeName string) bool {
+	if member.Name == typeName {
+		return true
+	}
+	// Qualified and unqualified names of module types: http.Request and Request
+	if i := strings.LastIndex(member.Name, "."); i >= 0 && member.Name[i+1:] == typeName {
+		return true
+	}
+	if i := strings.LastIndex(typeName, "."); i >= 0 && typeName[i+1:] == member.Name {
+		return true
+	}
+	return false
+}
+
+// lookup returns a TypeLookup seeing the narrowed types before typeOf.
+func (n Narrowing) lookup(typeOf TypeLookup) TypeLookup {
+	if len(n) == 0 {
+		return typeOf
+	}
+	return func(name string) (*ast.TypeExpression, bool) {
+		if t, ok := n[name]; ok {
+			return t, true
+		}
+		return typeOf(name)
+	}
+}
+
+// merge combines narrowings that hold together; the right side is more precise.
+func (n Narrowing) merge(other Narrowing) Narrowing {
+	if len(n) == 0 {
+		return other
+	}
+	result := make(Narrowing, len(n)+len(other))
+	for name, t := range n {
+		result[name] = t
+	}
+	for name, t := range other {
+		result[name] = t
+	}
+	return result
+}
+
+// join combines narrowings of which only one holds: variables narrowed by
+// both get the union of both types.
+func (n Narrowing) join(other Narrowing, typeOf TypeLookup) Narrowing {
+	var result Narrowing
+	for name, t := range n {
+		otherType, ok := other[name]
+		if !ok {
+			continue
+		}
+		members := typeMembers(t)
+		for _, member := range typeMembers(otherType) {
+			if !containsTypeMember(members, member) {
+				members = append(members, member)
+			}
+		}
+		joined := typeFromMembers(members)
+		if declared, ok := typeOf(name); ok && declared != nil && len(typeMembers(joined)) >= len(typeMembers(declared)) {
+			continue
+		}
+		if result == nil {
+			result = make(Narrowing)
+		}
+		result[name] = joined
+	}
+	return result
+}
+
+func containsTypeMember(members []*ast.TypeExpression, member *ast.TypeExpression) bool {
+	for _, m := range members {
+		if m.String() == member.String() {
+			return true
+		}
+	}
+	return false
+}
+
+// BlockExits reports whether a block always leaves the enclosing code with
+// return, break or continue, so the code after it only runs when it did not.
+func BlockExits(block *ast.BlockStatement) bool {
+	if block == nil || len(block.Statements) == 0 {
+		return false
+	}
+	switch stmt := block.Statements[len(block.Statements)-1].(type) {
+	case *ast.ReturnStatement, *ast.BreakStatement, *ast.ContinueStatement:
+		return true
+	case *ast.ExpressionStatement:
+		if ie, ok := stmt.Expression.(*ast.IfExpression); ok && ie.Alternative != nil {
+			return BlockExits(ie.Consequence) && BlockExits(ie.Alternative)
+		}
+	}
+	return false
+}
+
+// GuardNarrowing returns what an if statement without else tells about
+// variable types in the statements after it: `if x is Error { return x }`
+// leaves x narrowed to the rest of its type.
+func GuardNarrowing(stmt ast.Statement, typeOf TypeLookup) Narrowing {
+	exprStmt, ok := stmt.(*ast.ExpressionStatement)
+	if !ok {
+		return nil
+	}
+	ie, ok := exprStmt.Expression.(*ast.IfExpression)
+	if !ok || ie.Alternative != nil || !BlockExits(ie.Consequence) {
+		return nil
+	}
+
+	_, whenFalse := NarrowCondition(ie.Condition, typeOf)
+	return whenFalse
+}
+
+// withNarrowingHint adds the declared type of a variable to a type mismatch
+// error for a value read from it, so unchecked union values are easy to spot.
+// Narrowing itself is only tracked by the checker:
+//
+//	type mismatch: expected float, got Error (result is float | Error here; check it with `is` first)
+func withNarrowingHint(err Object, value ast.Expression, env *Environment) Object {
+	errObj, ok := err.(*Error)
+	if !ok {
+		return err
+	}
+	ident, ok := value.(*ast.Identifier)
+	if !ok {
+		return err
+	}
+	typeExpr, ok := env.GetType(ident.Value)
+	if !ok || typeExpr == nil || len(typeMembers(typeExpr)) < 2 {
+		return err
+	}
+	return newError("%s (%s is %s here; check it with `is` first)", errObj.Message, ident.Value, typeExpr.String())
+}
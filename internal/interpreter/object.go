@@ -4,8 +4,11 @@ package interpreter
 import (
 	"bytes"
 	"fmt"
+	"io/fs"
+	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/mishankov/totalscript-lang/internal/ast"
 )
@@ -16,26 +19,31 @@ type ObjectType string
 // ObjectType constants define all runtime value types.
 const (
 	// IntegerObj represents an integer value.
-	IntegerObj        ObjectType = "INTEGER"
-	FloatObj          ObjectType = "FLOAT"
-	StringObj         ObjectType = "STRING"
-	BooleanObj        ObjectType = "BOOLEAN"
-	NullObj           ObjectType = "NULL"
-	ReturnValueObj    ObjectType = "RETURN_VALUE"
-	ErrorObj          ObjectType = "ERROR"
-	FunctionObj       ObjectType = "FUNCTION"
-	ArrayObj          ObjectType = "ARRAY"
-	MapObj            ObjectType = "MAP"
-	BreakObj          ObjectType = "BREAK"
-	ContinueObj       ObjectType = "CONTINUE"
-	BuiltinObj        ObjectType = "BUILTIN"
-	BoundMethodObj    ObjectType = "BOUND_METHOD"
-	ModelObj          ObjectType = "MODEL"
-	ModelInstanceObj  ObjectType = "MODEL_INSTANCE"
-	EnumObj           ObjectType = "ENUM"
-	EnumValueObj      ObjectType = "ENUM_VALUE"
-	ModuleObj         ObjectType = "MODULE"
-	DbStateWrapperObj ObjectType = "DB_STATE_WRAPPER"
+	IntegerObj          ObjectType = "INTEGER"
+	FloatObj            ObjectType = "FLOAT"
+	StringObj           ObjectType = "STRING"
+	BooleanObj          ObjectType = "BOOLEAN"
+	NullObj             ObjectType = "NULL"
+	ReturnValueObj      ObjectType = "RETURN_VALUE"
+	ErrorObj            ObjectType = "ERROR"
+	FunctionObj         ObjectType = "FUNCTION"
+	ArrayObj            ObjectType = "ARRAY"
+	MapObj              ObjectType = "MAP"
+	BreakObj            ObjectType = "BREAK"
+	ContinueObj         ObjectType = "CONTINUE"
+	BuiltinObj          ObjectType = "BUILTIN"
+	BoundMethodObj      ObjectType = "BOUND_METHOD"
+	ModelObj            ObjectType = "MODEL"
+	ModelInstanceObj    ObjectType = "MODEL_INSTANCE"
+	EnumObj             ObjectType = "ENUM"
+	EnumValueObj        ObjectType = "ENUM_VALUE"
+	InterfaceObj        ObjectType = "INTERFACE"
+	ModuleObj           ObjectType = "MODULE"
+	DbStateWrapperObj   ObjectType = "DB_STATE_WRAPPER"
+	StreamResponseObj   ObjectType = "STREAM_RESPONSE"
+	WebSocketUpgradeObj ObjectType = "WEBSOCKET_UPGRADE"
+	StaticFileObj       ObjectType = "STATIC_FILE"
+	ProxyResponseObj    ObjectType = "PROXY_RESPONSE"
 )
 
 // Object is the interface for all runtime values.
@@ -96,6 +104,12 @@ func (rv *ReturnValue) Inspect() string  { return rv.Value.Inspect() }
 // Error represents an error.
 type Error struct {
 	Message string
+	// Fields lists per-field problems for request binding errors (field path to
+	// message). The HTTP server answers such errors with 400 Bad Request.
+	Fields map[string]string
+	// Propagated is the Error instance returned by the ? operator. Such errors
+	// unwind to the enclosing function, which returns the instance.
+	Propagated *ModelInstance
 }
 
 func (e *Error) Type() ObjectType { return ErrorObj }
@@ -106,6 +120,7 @@ type Function struct {
 	Parameters []*ast.Parameter
 	Body       *ast.BlockStatement
 	Env        *Environment
+	ReturnType *ast.TypeExpression // nil when the return type is not annotated
 }
 
 func (f *Function) Type() ObjectType { return FunctionObj }
@@ -232,20 +247,71 @@ func IsError(obj Object) bool {
 // Model represents a model definition (the type itself).
 type Model struct {
 	Name         string
+	Base         *Model                         // Model this one extends, if any
 	FieldNames   []string                       // Maintains field order
 	Fields       map[string]*ast.TypeExpression // Quick field lookup
 	Annotations  map[string][]string            // Field annotations (e.g., ["id"] for @id)
 	Methods      map[string]*Function
 	Constructors []*Function // Custom constructors
+	// NativeConstructor replaces the default constructor for built-in models
+	// (e.g. http.Response); custom constructors still take precedence.
+	NativeConstructor func(args ...Object) Object
+	// StaticMethods are native functions accessed on the model itself
+	// (e.g. http.Response.html).
+	StaticMethods map[string]*Builtin
 }
 
 func (m *Model) Type() ObjectType { return ModelObj }
 func (m *Model) Inspect() string  { return "model " + m.Name }
 
+// derivesFrom reports whether m is base or extends it, directly or not.
+func (m *Model) derivesFrom(base *Model) bool {
+	for ; m != nil; m = m.Base {
+		if m == base {
+			return true
+		}
+	}
+	return false
+}
+
+// Interface represents an interface definition: the fields and methods a
+// model must have to conform to it. Conformance is structural; models may
+// also declare it with implements.
+type Interface struct {
+	Name       string
+	FieldNames []string                       // Maintains field order
+	Fields     map[string]*ast.TypeExpression // Field types, nil if not annotated
+	Methods    []*ast.InterfaceMethod
+}
+
+func (i *Interface) Type() ObjectType { return InterfaceObj }
+func (i *Interface) Inspect() string  { return "interface " + i.Name }
+
 // ModelInstance represents an instance of a model.
 type ModelInstance struct {
-	Model  *Model
-	Fields map[string]Object
+	Model   *Model
+	Fields  map[string]Object
+	Methods map[string]*Builtin // native methods of built-in model instances (e.g. req.json())
+}
+
+// ErrorModel is the built-in Error model for error handling. Runtime errors
+// caught by try/catch are converted to its instances.
+var ErrorModel = &Model{
+	Name:       "Error",
+	FieldNames: []string{"message"},
+	Fields: map[string]*ast.TypeExpression{
+		"message": nil, // Type information not strictly needed for built-in
+	},
+	Methods:      make(map[string]*Function),
+	Constructors: make([]*Function, 0),
+}
+
+// newErrorInstance creates an instance of the Error model.
+func newErrorInstance(message string) *ModelInstance {
+	return &ModelInstance{
+		Model:  ErrorModel,
+		Fields: map[string]Object{"message": &String{Value: message}},
+	}
 }
 
 func (mi *ModelInstance) Type() ObjectType { return ModelInstanceObj }
@@ -303,3 +369,46 @@ type DBStateWrapper struct {
 
 func (w *DBStateWrapper) Type() ObjectType { return DbStateWrapperObj }
 func (w *DBStateWrapper) Inspect() string  { return "<db state>" }
+
+// StreamResponse is the body of an http.Response that is produced incrementally
+// by a TotalScript function. Created by http.stream() and http.sse().
+type StreamResponse struct {
+	Writer Object // function receiving the writer object (or send function for SSE)
+	SSE    bool   // true for Server-Sent Events
+}
+
+func (sr *StreamResponse) Type() ObjectType { return StreamResponseObj }
+func (sr *StreamResponse) Inspect() string  { return "<stream response>" }
+
+// WebSocketUpgrade is the body of the http.Response returned by the final handler
+// of a WebSocket route once all middleware has let the request through. The server
+// then upgrades the connection.
+type WebSocketUpgrade struct {
+	Handler Object // function receiving the connection object
+	Request Object
+	config  *websocketConfig
+}
+
+func (wu *WebSocketUpgrade) Type() ObjectType { return WebSocketUpgradeObj }
+func (wu *WebSocketUpgrade) Inspect() string  { return "<websocket upgrade>" }
+
+// StaticFile is the body of an http.Response serving a file of a static mount.
+// The server writes it with support for conditional and range requests.
+type StaticFile struct {
+	FS      fs.FS
+	Name    string // path of the file within FS
+	ModTime time.Time
+}
+
+func (sf *StaticFile) Type() ObjectType { return StaticFileObj }
+func (sf *StaticFile) Inspect() string  { return "<static file " + sf.Name + ">" }
+
+// ProxyResponse is the body of the http.Response returned by http.proxy(). The
+// server forwards Request upstream and streams the upstream response back.
+type ProxyResponse struct {
+	Proxy   http.Handler
+	Request *ModelInstance // request to forward, as changed by hooks and middleware
+}
+
+func (pr *ProxyResponse) Type() ObjectType { return ProxyResponseObj }
+func (pr *ProxyResponse) Inspect() string  { return "<proxy response>" }
@@ -0,0 +1,164 @@
+package interpreter
+
+import (
+	"slices"
+
+	"github.com/mishankov/totalscript-lang/internal/ast"
+)
+
+// namedArgument is an argument passed by parameter or field name, as in
+// greet(name: "Alice").
+type namedArgument struct {
+	name  string
+	value Object
+}
+
+// evalNamedArguments evaluates the named arguments of a call in order.
+func evalNamedArguments(arguments []*ast.NamedArgument, env *Environment) ([]namedArgument, Object) {
+	if len(arguments) == 0 {
+		return nil, nil
+	}
+
+	named := make([]namedArgument, 0, len(arguments))
+	for _, arg := range arguments {
+		value := Eval(arg.Value, env)
+		if IsError(value) {
+			return nil, value
+		}
+		named = append(named, namedArgument{name: arg.Name.Value, value: value})
+	}
+	return named, nil
+}
+
+// bindArguments returns the values of the parameters of a call: positional
+// arguments in order, then named arguments, then default values. Defaults
+// are evaluated in env, the environment of the function, and may refer to
+// earlier parameters. A variadic parameter collects the remaining positional
+// arguments into an array.
+//
+//nolint:cyclop
+func bindArguments(params []*ast.Parameter, args []Object, named []namedArgument, env *Environment) ([]Object, *Error) {
+	values := make([]Object, len(params))
+	positional := len(params)
+	variadic := positional > 0 && params[positional-1].Variadic
+	if variadic {
+		positional--
+	}
+
+	if len(args) > positional && !variadic {
+		return nil, wrongArgumentCount(params, len(args))
+	}
+	copy(values, args[:min(len(args), positional)])
+	if variadic {
+		rest := []Object{}
+		if len(args) > positional {
+			rest = append(rest, args[positional:]...)
+		}
+		values[positional] = &Array{Elements: rest}
+	}
+
+	for _, arg := range named {
+		i := slices.IndexFunc(params, func(param *ast.Parameter) bool {
+			return param.Name.Value == arg.name && !param.Variadic
+		})
+		if i < 0 {
+			return nil, newError("unknown parameter '%s'", arg.name)
+		}
+		if values[i] != nil {
+			return nil, newError("parameter '%s' given twice", arg.name)
+		}
+		values[i] = arg.value
+	}
+
+	defaults := NewEnclosedEnvironment(env)
+	for i, param := range params {
+		if values[i] == nil {
+			if param.Default == nil {
+				if len(named) == 0 {
+					return nil, wrongArgumentCount(params, len(args))
+				}
+				return nil, newError("missing argument for parameter '%s'", param.Name.Value)
+			}
+			value := Eval(param.Default, defaults)
+			if errObj, ok := value.(*Error); ok {
+				return nil, errObj
+			}
+			values[i] = value
+		}
+		defaults.Define(param.Name.Value, values[i])
+	}
+
+	return values, nil
+}
+
+// wrongArgumentCount reports a call with too few or too many arguments.
+func wrongArgumentCount(params []*ast.Parameter, got int) *Error {
+	required := 0
+	for _, param := range params {
+		if param.Variadic {
+			return newError("wrong number of arguments: expected at least %d, got %d", required, got)
+		}
+		if param.Default == nil {
+			required++
+		}
+	}
+	if required == len(params) {
+		return newError("wrong number of arguments: expected %d, got %d", required, got)
+	}
+	return newError("wrong number of arguments: expected %d to %d, got %d", required, len(params), got)
+}
+
+// checkParameter validates the value of an annotated parameter and coerces
+// it if needed. The type of a variadic parameter applies to each element.
+func checkParameter(param *ast.Parameter, value Object, env *Environment) (Object, *Error) {
+	if param.Type == nil {
+		return value, nil
+	}
+
+	if !param.Variadic {
+		if err := validateType(value, param.Type, env); err != nil {
+			errObj, _ := err.(*Error)
+			return nil, errObj
+		}
+		return coerceValue(value, param.Type), nil
+	}
+
+	rest, _ := value.(*Array)
+	for i, element := range rest.Elements {
+		if err := validateType(element, param.Type, env); err != nil {
+			errObj, _ := err.(*Error)
+			return nil, errObj
+		}
+		rest.Elements[i] = coerceValue(element, param.Type)
+	}
+	return rest, nil
+}
+
+// fieldArguments orders the arguments of a default constructor call with
+// named arguments by field.
+func fieldArguments(model *Model, args []Object, named []namedArgument) ([]Object, *Error) {
+	if len(args) > len(model.FieldNames) {
+		return nil, newError("wrong number of arguments for %s: expected %d, got %d",
+			model.Name, len(model.FieldNames), len(args))
+	}
+
+	values := make([]Object, len(model.FieldNames))
+	copy(values, args)
+	for _, arg := range named {
+		i := slices.Index(model.FieldNames, arg.name)
+		if i < 0 {
+			return nil, newError("model %s has no field '%s'", model.Name, arg.name)
+		}
+		if values[i] != nil {
+			return nil, newError("field '%s' given twice", arg.name)
+		}
+		values[i] = arg.value
+	}
+
+	for i, value := range values {
+		if value == nil {
+			return nil, newError("missing field '%s' for %s", model.FieldNames[i], model.Name)
+		}
+	}
+	return values, nil
+}
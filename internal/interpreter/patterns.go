@@ -0,0 +1,293 @@
+package interpreter
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/mishankov/totalscript-lang/internal/ast"
+)
+
+// evalMatchExpression evaluates the body of the first arm with a pattern
+// matching the value and a guard that holds. Variables bound by the pattern
+// are visible in the guard and the body only.
+func evalMatchExpression(node *ast.MatchExpression, env *Environment) Object {
+	value := Eval(node.Value, env)
+	if IsError(value) {
+		return value
+	}
+
+	if enumValue, ok := value.(*EnumValue); ok && node.Default == nil {
+		if err := checkExhaustive(node, enumValue, env); err != nil {
+			return err
+		}
+	}
+
+	for _, arm := range node.Arms {
+		for _, pattern := range arm.Patterns {
+			armEnv := NewEnclosedEnvironment(env)
+			matched, err := matchCasePattern(pattern, value, armEnv)
+			if err != nil {
+				return err
+			}
+			if !matched {
+				continue
+			}
+
+			if arm.Guard != nil {
+				guard := Eval(arm.Guard, armEnv)
+				if IsError(guard) {
+					return guard
+				}
+				if !IsTruthy(guard) {
+					continue
+				}
+			}
+			return Eval(arm.Body, armEnv)
+		}
+	}
+
+	if node.Default != nil {
+		return Eval(node.Default, env)
+	}
+	return newError("no case matches value: %s", value.Inspect())
+}
+
+// matchCasePattern reports whether value matches pattern, binding the variables
+// of the pattern in env. Invalid patterns, like unknown types, are errors.
+//
+//nolint:gocyclo,cyclop
+func matchCasePattern(pattern ast.Pattern, value Object, env *Environment) (bool, *Error) {
+	switch pattern := pattern.(type) {
+	case *ast.WildcardPattern:
+		return true, nil
+
+	case *ast.BindingPattern:
+		if pattern.Type != nil {
+			if err := validateTypeExists(pattern.Type, env); err != nil {
+				errObj, _ := err.(*Error)
+				return false, errObj
+			}
+			if validateType(value, pattern.Type, env) != nil {
+				return false, nil
+			}
+		}
+		if pattern.Name.Value != "_" {
+			env.Define(pattern.Name.Value, value)
+		}
+		return true, nil
+
+	case *ast.ValuePattern:
+		if rangeExpr, ok := pattern.Value.(*ast.RangeExpression); ok {
+			return matchRange(rangeExpr, value, env)
+		}
+		expected := Eval(pattern.Value, env)
+		if errObj, ok := expected.(*Error); ok {
+			return false, errObj
+		}
+		// Integer patterns also match equal floats, as in Point(x, 0)
+		if number, ok := numberValue(value); ok {
+			if want, ok := numberValue(expected); ok {
+				return number == want, nil
+			}
+		}
+		return objectsEqual(value, expected), nil
+
+	case *ast.ArrayPattern:
+		array, ok := value.(*Array)
+		if !ok {
+			return false, nil
+		}
+		count := len(pattern.Elements)
+		if len(array.Elements) < count || (!pattern.HasRest && len(array.Elements) != count) {
+			return false, nil
+		}
+		for i, element := range pattern.Elements {
+			if matched, err := matchCasePattern(element, array.Elements[i], env); !matched || err != nil {
+				return false, err
+			}
+		}
+		if pattern.Rest != nil {
+			rest := make([]Object, len(array.Elements)-count)
+			copy(rest, array.Elements[count:])
+			env.Define(pattern.Rest.Value, &Array{Elements: rest})
+		}
+		return true, nil
+
+	case *ast.MapPattern:
+		mapObj, ok := value.(*Map)
+		if !ok {
+			return false, nil
+		}
+		for i, key := range pattern.Keys {
+			entry, exists := mapObj.Pairs[key]
+			if !exists {
+				return false, nil
+			}
+			if matched, err := matchCasePattern(pattern.Values[i], entry, env); !matched || err != nil {
+				return false, err
+			}
+		}
+		return true, nil
+
+	case *ast.ModelPattern:
+		model, ok := Eval(pattern.Model, env).(*Model)
+		if !ok {
+			return false, newError("'%s' is not a model", pattern.Model.String())
+		}
+		if len(pattern.Fields) != len(model.FieldNames) {
+			return false, newError("wrong number of fields in pattern for %s: expected %d, got %d",
+				model.Name, len(model.FieldNames), len(pattern.Fields))
+		}
+		instance, ok := value.(*ModelInstance)
+		if !ok || evalIsOperator(instance, model) != TRUE {
+			return false, nil
+		}
+		for i, fieldName := range model.FieldNames {
+			if matched, err := matchCasePattern(pattern.Fields[i], instance.Fields[fieldName], env); !matched || err != nil {
+				return false, err
+			}
+		}
+		return true, nil
+	}
+
+	return false, newError("unknown pattern: %s", pattern.String())
+}
+
+// evalDestructuring evaluates the value of a var or const statement with a
+// pattern and declares the variables of the pattern.
+func evalDestructuring(pattern ast.Pattern, valueExpr ast.Expression, env *Environment) Object {
+	value := Eval(valueExpr, env)
+	if IsError(value) {
+		return value
+	}
+	if err := destructure(pattern, value, env); err != nil {
+		return err
+	}
+	return value
+}
+
+// bindLoopValue binds the value of a for-in iteration to the loop variable,
+// or destructures it.
+func bindLoopValue(fs *ast.ForStatement, value Object, env *Environment) *Error {
+	if fs.Pattern != nil {
+		return destructure(fs.Pattern, value, env)
+	}
+	env.Set(fs.Value.Value, value)
+	return nil
+}
+
+// destructure declares the variables of pattern in env. Nothing is declared
+// if the value does not match.
+func destructure(pattern ast.Pattern, value Object, env *Environment) *Error {
+	bindings := NewEnclosedEnvironment(env)
+	matched, err := matchCasePattern(pattern, value, bindings)
+	if err != nil {
+		return err
+	}
+	if !matched {
+		return newError("cannot destructure %s with pattern %s", value.Type(), pattern.String())
+	}
+	for name, val := range bindings.store {
+		env.Define(name, val)
+	}
+	return nil
+}
+
+// matchRange reports whether value is a number in the range of a pattern.
+func matchRange(rangeExpr *ast.RangeExpression, value Object, env *Environment) (bool, *Error) {
+	number, ok := numberValue(value)
+	if !ok {
+		return false, nil
+	}
+
+	if rangeExpr.Start != nil {
+		start, err := rangeBound(rangeExpr.Start, env)
+		if err != nil {
+			return false, err
+		}
+		if number < start {
+			return false, nil
+		}
+	}
+	if rangeExpr.End != nil {
+		end, err := rangeBound(rangeExpr.End, env)
+		if err != nil {
+			return false, err
+		}
+		if number > end || (number == end && !rangeExpr.Inclusive) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// rangeBound evaluates the start or end of a range pattern.
+func rangeBound(bound ast.Expression, env *Environment) (float64, *Error) {
+	value := Eval(bound, env)
+	if errObj, ok := value.(*Error); ok {
+		return 0, errObj
+	}
+	number, ok := numberValue(value)
+	if !ok {
+		return 0, newError("range pattern bounds must be numbers, got %s", value.Type())
+	}
+	return number, nil
+}
+
+// numberValue returns the value of an integer or float.
+func numberValue(obj Object) (float64, bool) {
+	switch obj := obj.(type) {
+	case *Integer:
+		return float64(obj.Value), true
+	case *Float:
+		return obj.Value, true
+	}
+	return 0, false
+}
+
+// checkExhaustive reports the values of an enum a match without default
+// does not handle. Arms with guards do not count, as their guard may fail.
+func checkExhaustive(node *ast.MatchExpression, value *EnumValue, env *Environment) Object {
+	var enum *Enum
+	handled := make(map[string]bool)
+
+	for _, arm := range node.Arms {
+		if arm.Guard != nil {
+			continue
+		}
+		for _, pattern := range arm.Patterns {
+			switch pattern := pattern.(type) {
+			case *ast.WildcardPattern:
+				return nil
+			case *ast.BindingPattern:
+				if pattern.Type == nil || validateType(value, pattern.Type, env) == nil {
+					return nil
+				}
+			case *ast.ValuePattern:
+				member, ok := pattern.Value.(*ast.MemberExpression)
+				if !ok {
+					continue
+				}
+				if e, ok := Eval(member.Object, env).(*Enum); ok && e.Name == value.EnumName {
+					enum = e
+					handled[member.Member.Value] = true
+				}
+			}
+		}
+	}
+
+	if enum == nil {
+		return nil
+	}
+	var missing []string
+	for name := range enum.Values {
+		if !handled[name] {
+			missing = append(missing, enum.Name+"."+name)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	sort.Strings(missing)
+	return newError("match is not exhaustive: %s not handled", strings.Join(missing, ", "))
+}
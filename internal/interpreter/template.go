@@ -0,0 +1,283 @@
+package interpreter
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// templateRefPattern finds templates referenced with {{template "name"}} or
+// {{block "name"}}; names of files in the template directory are included.
+//
+//nolint:gochecknoglobals
+var templateRefPattern = regexp.MustCompile(`\{\{-?\s*(?:template|block)\s+"([^"]+)"`)
+
+// defaultTemplates is the engine shared by the template module and
+// http.Response.html(), so both see the same configuration and cache.
+//
+//nolint:gochecknoglobals
+var defaultTemplates = newTemplateEngine()
+
+// templateEngine compiles and caches HTML templates from a directory.
+type templateEngine struct {
+	mu    sync.Mutex
+	dir   string
+	watch bool // recompile templates whose files changed
+	cache map[string]*cachedTemplate
+}
+
+// cachedTemplate is a compiled template and the files it was compiled from.
+type cachedTemplate struct {
+	tmpl     *template.Template
+	modTimes map[string]time.Time // path -> modification time when compiled
+}
+
+// newTemplateEngine creates an engine loading templates relative to the working directory.
+func newTemplateEngine() *templateEngine {
+	return &templateEngine{dir: ".", cache: make(map[string]*cachedTemplate)}
+}
+
+// configure sets the template directory and watch mode and clears the cache.
+func (e *templateEngine) configure(dir string, watch bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.dir = dir
+	e.watch = watch
+	e.cache = make(map[string]*cachedTemplate)
+}
+
+// render executes the template file name with data.
+func (e *templateEngine) render(name string, data Object) (string, error) {
+	tmpl, err := e.load(name)
+	if err != nil {
+		return "", err
+	}
+	return executeTemplate(tmpl, data)
+}
+
+// renderString compiles source and executes it with data. Files of the
+// template directory can be included from source.
+func (e *templateEngine) renderString(source string, data Object) (string, error) {
+	e.mu.Lock()
+	compiled, err := e.compile("inline", source)
+	e.mu.Unlock()
+	if err != nil {
+		return "", err
+	}
+	return executeTemplate(compiled.tmpl, data)
+}
+
+// load returns the compiled template for a file, compiling it on first use and,
+// in watch mode, whenever one of its files changed.
+func (e *templateEngine) load(name string) (*template.Template, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if cached, ok := e.cache[name]; ok && (!e.watch || !cached.stale()) {
+		return cached.tmpl, nil
+	}
+
+	path, err := e.path(name)
+	if err != nil {
+		return nil, err
+	}
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	compiled, err := e.compile(name, string(source))
+	if err != nil {
+		return nil, err
+	}
+	compiled.modTimes[path] = modTime(path)
+	e.cache[name] = compiled
+
+	return compiled.tmpl, nil
+}
+
+// compile parses source together with the template files it references,
+// directly or through other files. Referenced files are parsed first, so
+// {{define}} blocks of source override the {{block}} defaults of a layout.
+func (e *templateEngine) compile(name, source string) (*cachedTemplate, error) {
+	compiled := &cachedTemplate{tmpl: template.New(name), modTimes: make(map[string]time.Time)}
+
+	visited := map[string]bool{name: true}
+	var collect func(src string) error
+	collect = func(src string) error {
+		for _, match := range templateRefPattern.FindAllStringSubmatch(src, -1) {
+			ref := match[1]
+			if visited[ref] {
+				continue
+			}
+			visited[ref] = true
+
+			path, err := e.path(ref)
+			if err != nil {
+				// Names outside the template directory are never read
+				continue
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				// Not a file: a template defined with {{define}}
+				continue
+			}
+			if err := collect(string(data)); err != nil {
+				return err
+			}
+			if _, err := compiled.tmpl.New(ref).Parse(string(data)); err != nil {
+				return err
+			}
+			compiled.modTimes[path] = modTime(path)
+		}
+		return nil
+	}
+
+	if err := collect(source); err != nil {
+		return nil, err
+	}
+	if _, err := compiled.tmpl.Parse(source); err != nil {
+		return nil, err
+	}
+
+	return compiled, nil
+}
+
+// path returns the file path of a template name. Names are often built from
+// request data, so they must stay inside the template directory.
+func (e *templateEngine) path(name string) (string, error) {
+	local := filepath.FromSlash(name)
+	if !filepath.IsLocal(local) {
+		return "", fmt.Errorf("template %q is outside the template directory", name)
+	}
+	return filepath.Join(e.dir, local), nil
+}
+
+// stale reports whether a file of the template changed since it was compiled.
+func (c *cachedTemplate) stale() bool {
+	for path, compiledAt := range c.modTimes {
+		if !modTime(path).Equal(compiledAt) {
+			return true
+		}
+	}
+	return false
+}
+
+// modTime returns the modification time of a file, or the zero time.
+func modTime(path string) time.Time {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
+
+// executeTemplate executes tmpl with a TotalScript value as data.
+func executeTemplate(tmpl *template.Template, data Object) (string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, convertObjectToGo(data)); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// createTemplateModule creates the template standard library module.
+// Templates use Go's html/template syntax and escape values automatically.
+func createTemplateModule() *Module {
+	env := NewEnvironment()
+
+	// configure(options) - set the template directory and watch mode
+	env.Set("configure", &Builtin{
+		Name: "configure",
+		Fn: func(args ...Object) Object {
+			if len(args) != 1 {
+				return newError("configure() takes 1 argument (options), got %d", len(args))
+			}
+			options, ok := args[0].(*Map)
+			if !ok {
+				return newError("configure() options must be map, got %s", args[0].Type())
+			}
+
+			dir := "."
+			watch := false
+			for key, value := range options.Pairs {
+				switch key {
+				case "dir":
+					str, ok := value.(*String)
+					if !ok {
+						return newError("configure() option 'dir' must be string, got %s", value.Type())
+					}
+					dir = str.Value
+				case "watch":
+					b, ok := value.(*Boolean)
+					if !ok {
+						return newError("configure() option 'watch' must be boolean, got %s", value.Type())
+					}
+					watch = b.Value
+				default:
+					return newError("configure() unknown option '%s'", key)
+				}
+			}
+
+			defaultTemplates.configure(dir, watch)
+			return NULL
+		},
+	})
+
+	// render(name, data?) - render a template file
+	env.Set("render", &Builtin{
+		Name: "render",
+		Fn: func(args ...Object) Object {
+			name, data, errObj := templateArgs("render", args)
+			if errObj != nil {
+				return errObj
+			}
+			out, err := defaultTemplates.render(name, data)
+			if err != nil {
+				return &Error{Message: "template error: " + err.Error()}
+			}
+			return &String{Value: out}
+		},
+	})
+
+	// renderString(source, data?) - render a template given as a string
+	env.Set("renderString", &Builtin{
+		Name: "renderString",
+		Fn: func(args ...Object) Object {
+			source, data, errObj := templateArgs("renderString", args)
+			if errObj != nil {
+				return errObj
+			}
+			out, err := defaultTemplates.renderString(source, data)
+			if err != nil {
+				return &Error{Message: "template error: " + err.Error()}
+			}
+			return &String{Value: out}
+		},
+	})
+
+	return &Module{
+		Name:  "template",
+		Scope: env,
+	}
+}
+
+// templateArgs validates the (template, data?) arguments of render functions.
+func templateArgs(name string, args []Object) (string, Object, *Error) {
+	if len(args) < 1 || len(args) > 2 {
+		return "", nil, newError("%s() takes 1-2 arguments (template, data?), got %d", name, len(args))
+	}
+	str, ok := args[0].(*String)
+	if !ok {
+		return "", nil, newError("%s() template must be string, got %s", name, args[0].Type())
+	}
+	var data Object = NULL
+	if len(args) == 2 {
+		data = args[1]
+	}
+	return str.Value, data, nil
+}
@@ -0,0 +1,170 @@
+package interpreter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeTemplateFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestTemplateLayoutsAndEscaping(t *testing.T) {
+	t.Parallel()
+	dir := writeTemplateFiles(t, map[string]string{
+		"layouts/base.html": `<title>{{block "title" .}}Default{{end}}</title>` +
+			`{{template "partials/nav.html" .}}<main>{{block "content" .}}{{end}}</main>`,
+		"partials/nav.html": `<nav>{{.user.name}}</nav>`,
+		"page.html": `{{template "layouts/base.html" .}}` +
+			`{{define "title"}}Items{{end}}` +
+			`{{define "content"}}{{range .items}}<li>{{.}}</li>{{else}}none{{end}}` +
+			`{{if .admin}}[admin]{{end}}{{end}}`,
+	})
+
+	engine := newTemplateEngine()
+	engine.configure(dir, false)
+
+	user := &ModelInstance{
+		Model:  &Model{Name: "User", FieldNames: []string{"name"}},
+		Fields: map[string]Object{"name": &String{Value: "<Alice>"}},
+	}
+	data := &Map{Pairs: map[string]Object{
+		"user":  user,
+		"items": &Array{Elements: []Object{&String{Value: "a & b"}, &Integer{Value: 2}}},
+		"admin": TRUE,
+	}}
+
+	out, err := engine.render("page.html", data)
+	if err != nil {
+		t.Fatalf("render failed: %v", err)
+	}
+	expected := `<title>Items</title><nav>&lt;Alice&gt;</nav><main><li>a &amp; b</li><li>2</li>[admin]</main>`
+	if out != expected {
+		t.Errorf("wrong output:\nexpected=%s\ngot=     %s", expected, out)
+	}
+
+	out, err = engine.renderString(`{{template "partials/nav.html" .}}!`, data)
+	if err != nil || out != "<nav>&lt;Alice&gt;</nav>!" {
+		t.Errorf("wrong inline output: %q %v", out, err)
+	}
+
+	if _, err := engine.render("missing.html", data); err == nil {
+		t.Error("expected error for missing template")
+	}
+}
+
+func TestTemplatePathTraversal(t *testing.T) {
+	t.Parallel()
+	root := writeTemplateFiles(t, map[string]string{
+		"secret.html":         "secret",
+		"templates/page.html": `{{template "../secret.html"}}`,
+	})
+
+	engine := newTemplateEngine()
+	engine.configure(filepath.Join(root, "templates"), false)
+
+	for _, name := range []string{"../secret.html", "/etc/passwd", "a/../../secret.html"} {
+		if out, err := engine.render(name, NULL); err == nil || !strings.Contains(err.Error(), "outside the template directory") {
+			t.Errorf("%s: expected traversal to be rejected, got %q %v", name, out, err)
+		}
+	}
+
+	// Included names outside the directory are not read from files
+	if out, err := engine.render("page.html", NULL); err == nil || strings.Contains(out, "secret") {
+		t.Errorf("expected included traversal to fail, got %q %v", out, err)
+	}
+	if out, err := engine.renderString(`{{template "../secret.html"}}`, NULL); err == nil || strings.Contains(out, "secret") {
+		t.Errorf("expected inline traversal to fail, got %q %v", out, err)
+	}
+}
+
+func TestTemplateCaching(t *testing.T) {
+	t.Parallel()
+	dir := writeTemplateFiles(t, map[string]string{
+		"page.html":    `{{template "partial.html"}}`,
+		"partial.html": `v1`,
+	})
+
+	cached := newTemplateEngine()
+	cached.configure(dir, false)
+	watched := newTemplateEngine()
+	watched.configure(dir, true)
+
+	for _, engine := range []*templateEngine{cached, watched} {
+		if out, err := engine.render("page.html", NULL); err != nil || out != "v1" {
+			t.Fatalf("wrong output: %q %v", out, err)
+		}
+	}
+
+	// Change an included file
+	partial := filepath.Join(dir, "partial.html")
+	if err := os.WriteFile(partial, []byte("v2"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(partial, later, later); err != nil {
+		t.Fatal(err)
+	}
+
+	if out, _ := cached.render("page.html", NULL); out != "v1" {
+		t.Errorf("cached template was reloaded: %q", out)
+	}
+	if out, _ := watched.render("page.html", NULL); out != "v2" {
+		t.Errorf("watched template was not reloaded: %q", out)
+	}
+}
+
+func TestTemplateModuleAndHTMLResponse(t *testing.T) {
+	t.Parallel()
+	dir := writeTemplateFiles(t, map[string]string{
+		"hello.html": `<h1>Hello, {{.name}}</h1>`,
+	})
+
+	handler := testHTTPServer(t, `
+	import template
+	template.configure({"dir": "`+filepath.ToSlash(dir)+`"})
+	server.get("/hello/:name", function(req: http.Request): http.Response {
+		return http.Response.html(200, "hello.html", {"name": req.params["name"]})
+	})
+	server.get("/inline", function(req) {
+		return http.Response(200, template.renderString("<b>{{.}}</b>", "<i>"))
+	})
+	server.get("/broken", function(req) {
+		return http.Response.html(200, "missing.html")
+	})
+	`)
+
+	rec := doRequest(handler, httptest.NewRequest(http.MethodGet, "/hello/%3Cbob%3E", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "<h1>Hello, &lt;bob&gt;</h1>" {
+		t.Errorf("wrong response: %d %s", rec.Code, rec.Body.String())
+	}
+	if rec.Header().Get("Content-Type") != "text/html; charset=utf-8" {
+		t.Errorf("wrong content type: %q", rec.Header().Get("Content-Type"))
+	}
+
+	rec = doRequest(handler, httptest.NewRequest(http.MethodGet, "/inline", nil))
+	if rec.Body.String() != "<b>&lt;i&gt;</b>" {
+		t.Errorf("wrong inline response: %s", rec.Body.String())
+	}
+
+	rec = doRequest(handler, httptest.NewRequest(http.MethodGet, "/broken", nil))
+	if rec.Code != http.StatusInternalServerError || !strings.HasPrefix(rec.Body.String(), "template error: ") {
+		t.Errorf("wrong error response: %d %s", rec.Code, rec.Body.String())
+	}
+}
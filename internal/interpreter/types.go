@@ -121,21 +121,73 @@ func validateSimpleType(obj Object, typeName string, env *Environment) Object {
 		}
 		return nil
 	case typeNameFunction:
-		if obj.Type() != FunctionObj {
+		// Built-in functions (e.g. a middleware's next) are functions too
+		switch obj.Type() {
+		case FunctionObj, BuiltinObj, BoundMethodObj:
+		default:
 			return newError("type mismatch: expected function, got %s", getTypeName(obj))
 		}
 		return nil
 	}
 
-	// Check for module-prefixed types (e.g., "http.Request")
+	typeObj, err := lookupType(typeName, env)
+	if err != nil {
+		return err
+	}
+
+	// Check if it's a model type
+	if model, ok := typeObj.(*Model); ok {
+		instance, ok := obj.(*ModelInstance)
+		if !ok {
+			return newError("type mismatch: expected %s, got %s", typeName, getTypeName(obj))
+		}
+		if !instance.Model.derivesFrom(model) {
+			return newError("type mismatch: expected %s, got %s",
+				typeName, instance.Model.Name)
+		}
+		return nil
+	}
+
+	// Check if it's an enum type
+	if enum, ok := typeObj.(*Enum); ok {
+		enumValue, ok := obj.(*EnumValue)
+		if !ok {
+			return newError("type mismatch: expected %s, got %s", typeName, getTypeName(obj))
+		}
+		if enumValue.EnumName != enum.Name {
+			return newError("type mismatch: expected %s, got %s",
+				typeName, enumValue.EnumName)
+		}
+		return nil
+	}
+
+	// Interfaces accept instances of any model with their fields and methods
+	if iface, ok := typeObj.(*Interface); ok {
+		instance, ok := obj.(*ModelInstance)
+		if !ok {
+			return newError("type mismatch: expected %s, got %s", typeName, getTypeName(obj))
+		}
+		if missing := missingMember(instance.Model, iface); missing != "" {
+			return newError("type mismatch: expected %s, got %s (%s)", typeName, instance.Model.Name, missing)
+		}
+		return nil
+	}
+
+	return newError("'%s' is not a valid type", typeName)
+}
+
+// lookupType returns the value a user-defined type name refers to, which may
+// be prefixed with a module name.
+func lookupType(typeName string, env *Environment) (Object, Object) {
 	var typeObj Object
 	var exists bool
 
+	// Check for module-prefixed types (e.g., "http.Request")
 	if strings.Contains(typeName, ".") {
 		// Split into module name and type name
 		parts := strings.SplitN(typeName, ".", 2)
 		if len(parts) != 2 {
-			return newError("invalid type name: %s", typeName)
+			return nil, newError("invalid type name: %s", typeName)
 		}
 		moduleName := parts[0]
 		memberName := parts[1]
@@ -143,55 +195,29 @@ func validateSimpleType(obj Object, typeName string, env *Environment) Object {
 		// Get the module from environment
 		moduleObj, moduleExists := env.Get(moduleName)
 		if !moduleExists {
-			return newError("unknown module: %s", moduleName)
+			return nil, newError("unknown module: %s", moduleName)
 		}
 
 		// Module should be a Module object
 		module, ok := moduleObj.(*Module)
 		if !ok {
-			return newError("%s is not a module", moduleName)
+			return nil, newError("%s is not a module", moduleName)
 		}
 
 		// Get the type from the module
 		typeObj, exists = module.Scope.Get(memberName)
 		if !exists {
-			return newError("type %s not found in module %s", memberName, moduleName)
+			return nil, newError("type %s not found in module %s", memberName, moduleName)
 		}
 	} else {
-		// Check for user-defined types (models, enums) in current environment
+		// Check for user-defined types (models, enums, interfaces) in current environment
 		typeObj, exists = env.Get(typeName)
 		if !exists {
-			return newError("unknown type: %s", typeName)
+			return nil, newError("unknown type: %s", typeName)
 		}
 	}
 
-	// Check if it's a model type
-	if model, ok := typeObj.(*Model); ok {
-		instance, ok := obj.(*ModelInstance)
-		if !ok {
-			return newError("type mismatch: expected %s, got %s", typeName, getTypeName(obj))
-		}
-		if instance.Model != model {
-			return newError("type mismatch: expected %s, got %s",
-				typeName, instance.Model.Name)
-		}
-		return nil
-	}
-
-	// Check if it's an enum type
-	if enum, ok := typeObj.(*Enum); ok {
-		enumValue, ok := obj.(*EnumValue)
-		if !ok {
-			return newError("type mismatch: expected %s, got %s", typeName, getTypeName(obj))
-		}
-		if enumValue.EnumName != enum.Name {
-			return newError("type mismatch: expected %s, got %s",
-				typeName, enumValue.EnumName)
-		}
-		return nil
-	}
-
-	return newError("'%s' is not a valid type", typeName)
+	return typeObj, nil
 }
 
 func validateGenericType(obj Object, typeExpr *ast.TypeExpression, env *Environment) Object {
@@ -422,7 +448,7 @@ func validateTypeNameExists(typeName string, env *Environment) Object {
 
 		// Verify it's a valid type (model, enum, or builtin constructor for http.Response)
 		switch typeObj.(type) {
-		case *Model, *Enum, *Builtin:
+		case *Model, *Enum, *Interface, *Builtin:
 			// Builtin is allowed for http.Response constructor which serves as a type
 			return nil
 		default:
@@ -436,9 +462,9 @@ func validateTypeNameExists(typeName string, env *Environment) Object {
 		return newError("unknown type: %s", typeName)
 	}
 
-	// Verify it's actually a type (model or enum)
+	// Verify it's actually a type (model, enum or interface)
 	switch typeObj.(type) {
-	case *Model, *Enum:
+	case *Model, *Enum, *Interface:
 		return nil
 	default:
 		return newError("'%s' is not a type", typeName)
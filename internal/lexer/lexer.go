@@ -2,6 +2,8 @@
 package lexer
 
 import (
+	"strings"
+
 	"github.com/mishankov/totalscript-lang/internal/token"
 )
 
@@ -26,6 +28,19 @@ func New(input string) *Lexer {
 	return l
 }
 
+// NewAt creates a new Lexer for source embedded in a file, such as the
+// expressions of an interpolated string, whose first character is at the
+// given line and column.
+func NewAt(input string, line, column int) *Lexer {
+	l := &Lexer{
+		input:  input,
+		line:   line,
+		column: column - 1,
+	}
+	l.readChar()
+	return l
+}
+
 // readChar reads the next character and advances the position in the input string.
 func (l *Lexer) readChar() {
 	if l.readPosition >= len(l.input) {
@@ -61,6 +76,13 @@ func (l *Lexer) peekCharN(n int) byte {
 	return l.input[pos]
 }
 
+// Copy returns a lexer continuing from the same position, so the parser can
+// look ahead without consuming tokens.
+func (l *Lexer) Copy() *Lexer {
+	c := *l
+	return &c
+}
+
 // NextToken returns the next token from the input.
 func (l *Lexer) NextToken() token.Token {
 	var tok token.Token
@@ -74,9 +96,12 @@ func (l *Lexer) NextToken() token.Token {
 
 	switch l.ch {
 	case '=':
-		if l.peekChar() == '=' {
+		switch l.peekChar() {
+		case '=':
 			tok = l.makeTwoCharToken(token.EQ)
-		} else {
+		case '>':
+			tok = l.makeTwoCharToken(token.ARROW)
+		default:
 			tok = l.newToken(token.ASSIGN, l.ch)
 		}
 	case '+':
@@ -147,9 +172,12 @@ func (l *Lexer) NextToken() token.Token {
 		}
 	case '.':
 		if l.peekChar() == '.' {
-			if l.peekCharN(2) == '=' {
+			switch l.peekCharN(2) {
+			case '=':
 				tok = l.makeThreeCharToken(token.DotDotEq)
-			} else {
+			case '.':
+				tok = l.makeThreeCharToken(token.DotDotDot)
+			default:
 				tok = l.makeTwoCharToken(token.DotDot)
 			}
 		} else {
@@ -174,18 +202,28 @@ func (l *Lexer) NextToken() token.Token {
 	case ']':
 		tok = l.newToken(token.RBRACKET, l.ch)
 	case '?':
-		tok = l.newToken(token.QUESTION, l.ch)
+		switch l.peekChar() {
+		case '.':
+			tok = l.makeTwoCharToken(token.QuestionDot)
+		case '?':
+			tok = l.makeTwoCharToken(token.QuestionQuestion)
+		default:
+			tok = l.newToken(token.QUESTION, l.ch)
+		}
 	case '@':
 		tok = l.newToken(token.AT, l.ch)
 	case '"':
-		tok.Type = token.STRING
-		tok.Literal = l.readString()
-		tok.Line = l.line
+		tok.Type, tok.Literal = l.readString()
 		return tok
 	case 0:
 		tok.Literal = ""
 		tok.Type = token.EOF
 	default:
+		if l.ch == 'r' && l.peekChar() == '"' {
+			tok.Type = token.STRING
+			tok.Literal = l.readRawString()
+			return tok
+		}
 		if isLetter(l.ch) {
 			tok.Literal = l.readIdentifier()
 			tok.Type = token.LookupIdent(tok.Literal)
@@ -269,40 +307,231 @@ func (l *Lexer) readNumber() (token.TokenType, string) {
 	return tokenType, l.input[position:l.position]
 }
 
-// readString reads a string literal, handling escape sequences.
-func (l *Lexer) readString() string {
-	var result []byte
-	l.readChar() // skip opening quote
+// readString reads a string literal, "..." or """...""" spanning several lines.
+// Strings embedding ${expressions} are returned unprocessed as TEMPLATE tokens,
+// which the parser splits with SplitTemplate.
+func (l *Lexer) readString() (token.TokenType, string) {
+	triple := l.skipStringQuotes()
+	start := l.position
+	interpolated := false
 
-	for l.ch != '"' && l.ch != 0 {
-		if l.ch == '\\' {
-			l.readChar()
-			switch l.ch {
-			case 'n':
-				result = append(result, '\n')
-			case 't':
-				result = append(result, '\t')
-			case 'r':
-				result = append(result, '\r')
-			case '\\':
-				result = append(result, '\\')
-			case '"':
-				result = append(result, '"')
-			default:
-				result = append(result, '\\', l.ch)
-			}
-		} else {
-			result = append(result, l.ch)
+	for l.ch != 0 && !l.atStringEnd(triple) {
+		switch {
+		case l.ch == '\\':
+			l.readChar() // the escaped character can't end the string
+		case l.ch == '$' && l.peekChar() == '{':
+			interpolated = true
+			l.skipInterpolation()
+			continue
 		}
 		l.readChar()
 	}
 
-	// Skip the closing quote
+	raw := l.input[start:l.position]
+	l.skipStringQuotes()
+	if triple {
+		raw = trimIndent(raw)
+	}
+
+	if interpolated {
+		return token.TEMPLATE, raw
+	}
+	return token.STRING, Unescape(raw)
+}
+
+// readRawString reads a raw string literal, r"..." or r"""...""", in which
+// backslashes and ${ have no special meaning.
+func (l *Lexer) readRawString() string {
+	l.readChar() // skip 'r'
+	triple := l.skipStringQuotes()
+	start := l.position
+
+	for l.ch != 0 && !l.atStringEnd(triple) {
+		l.readChar()
+	}
+
+	raw := l.input[start:l.position]
+	l.skipStringQuotes()
+	if triple {
+		return trimIndent(raw)
+	}
+	return raw
+}
+
+// skipStringQuotes skips the quotes opening or closing a string and reports
+// whether they are triple quotes.
+func (l *Lexer) skipStringQuotes() bool {
+	if l.ch != '"' {
+		return false
+	}
+	triple := l.peekChar() == '"' && l.peekCharN(2) == '"'
+	if triple {
+		l.readChar()
+		l.readChar()
+	}
 	l.readChar()
+	return triple
+}
 
+// atStringEnd reports whether the current character closes a string.
+func (l *Lexer) atStringEnd(triple bool) bool {
+	if triple {
+		return l.ch == '"' && l.peekChar() == '"' && l.peekCharN(2) == '"'
+	}
+	return l.ch == '"'
+}
+
+// skipInterpolation skips an embedded ${expression}, which may contain braces
+// and strings of its own.
+func (l *Lexer) skipInterpolation() {
+	l.readChar() // skip '$'
+	l.readChar() // skip '{'
+
+	for depth := 1; l.ch != 0; {
+		switch l.ch {
+		case '"':
+			l.readString()
+			continue
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				l.readChar()
+				return
+			}
+		}
+		l.readChar()
+	}
+}
+
+// TemplatePart is a piece of an interpolated string: text with its escape
+// sequences processed, or the source of an embedded expression.
+type TemplatePart struct {
+	Text         string
+	IsExpression bool
+	Offset       int // position of the expression source in the template
+}
+
+// SplitTemplate splits the literal of a TEMPLATE token into text and
+// embedded expressions.
+func SplitTemplate(template string) []TemplatePart {
+	var parts []TemplatePart
+	textStart := 0
+
+	for i := 0; i < len(template); i++ {
+		switch {
+		case template[i] == '\\':
+			i++
+		case template[i] == '$' && i+1 < len(template) && template[i+1] == '{':
+			if i > textStart {
+				parts = append(parts, TemplatePart{Text: Unescape(template[textStart:i])})
+			}
+			end := interpolationEnd(template, i+2)
+			parts = append(parts, TemplatePart{Text: template[i+2 : end], IsExpression: true, Offset: i + 2})
+			i = end
+			textStart = end + 1
+		}
+	}
+
+	if textStart < len(template) {
+		parts = append(parts, TemplatePart{Text: Unescape(template[textStart:])})
+	}
+	return parts
+}
+
+// interpolationEnd returns the position of the brace closing an embedded
+// expression that starts at start, or the end of s if it is not closed.
+func interpolationEnd(s string, start int) int {
+	depth := 1
+	for i := start; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			// Skip a string inside the expression
+			for i++; i < len(s) && s[i] != '"'; i++ {
+				switch {
+				case s[i] == '\\':
+					i++
+				case s[i] == '$' && i+1 < len(s) && s[i+1] == '{':
+					i = interpolationEnd(s, i+2)
+				}
+			}
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return len(s)
+}
+
+// Unescape processes the escape sequences of a string literal.
+func Unescape(s string) string {
+	if !strings.Contains(s, "\\") {
+		return s
+	}
+
+	result := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			result = append(result, s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n':
+			result = append(result, '\n')
+		case 't':
+			result = append(result, '\t')
+		case 'r':
+			result = append(result, '\r')
+		case '\\':
+			result = append(result, '\\')
+		case '"':
+			result = append(result, '"')
+		case '$':
+			result = append(result, '$')
+		default:
+			result = append(result, '\\', s[i])
+		}
+	}
 	return string(result)
 }
 
+// trimIndent removes the line break after the opening quotes of a multi-line
+// string, the line with the closing quotes if it is blank, and the
+// indentation common to all non-blank lines.
+func trimIndent(s string) string {
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "\r"), "\n")
+	lines := strings.Split(s, "\n")
+	if last := lines[len(lines)-1]; len(lines) > 1 && strings.TrimSpace(last) == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	indent := -1
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		width := len(line) - len(strings.TrimLeft(line, " \t"))
+		if indent == -1 || width < indent {
+			indent = width
+		}
+	}
+
+	for i, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			lines[i] = ""
+			continue
+		}
+		lines[i] = line[indent:]
+	}
+	return strings.Join(lines, "\n")
+}
+
 // skipWhitespace skips whitespace characters (space, tab, carriage return, newline).
 func (l *Lexer) skipWhitespace() {
 	for l.ch == ' ' || l.ch == '\t' || l.ch == '\n' || l.ch == '\r' {
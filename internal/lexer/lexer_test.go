@@ -52,7 +52,7 @@ func TestNextToken_SimpleTokens(t *testing.T) {
 
 func TestNextToken_TwoCharTokens(t *testing.T) {
 	t.Parallel()
-	input := `== != <= >= && || .. ** // += -= *= /= %=`
+	input := `== != <= >= && || .. ** // += -= *= /= %= ?. ?? => ?`
 
 	tests := []struct {
 		expectedType    token.TokenType
@@ -72,6 +72,10 @@ func TestNextToken_TwoCharTokens(t *testing.T) {
 		{token.AsteriskAssign, "*="},
 		{token.SlashAssign, "/="},
 		{token.PercentAssign, "%="},
+		{token.QuestionDot, "?."},
+		{token.QuestionQuestion, "??"},
+		{token.ARROW, "=>"},
+		{token.QUESTION, "?"},
 		{token.EOF, ""},
 	}
 
@@ -94,13 +98,14 @@ func TestNextToken_TwoCharTokens(t *testing.T) {
 
 func TestNextToken_ThreeCharTokens(t *testing.T) {
 	t.Parallel()
-	input := `..=`
+	input := `..= ...`
 
 	tests := []struct {
 		expectedType    token.TokenType
 		expectedLiteral string
 	}{
 		{token.DotDotEq, "..="},
+		{token.DotDotDot, "..."},
 		{token.EOF, ""},
 	}
 
@@ -188,6 +193,71 @@ func TestNextToken_Strings(t *testing.T) {
 	}
 }
 
+func TestNextToken_MultiLineAndRawStrings(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name            string
+		input           string
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{"triple-quoted string", `"""say "hi" to\tme"""`, token.STRING, "say \"hi\" to\tme"},
+		{
+			"triple-quoted indentation",
+			"\"\"\"\n    SELECT *\n      FROM users\n\n    WHERE id = 1\n    \"\"\"",
+			token.STRING,
+			"SELECT *\n  FROM users\n\nWHERE id = 1",
+		},
+		{"raw string", `r"C:\path\n${x}"`, token.STRING, `C:\path\n${x}`},
+		{"raw triple-quoted string", "r\"\"\"\n  a \"b\"\\n\n  \"\"\"", token.STRING, `a "b"\n`},
+		{"interpolated string", `"Hello ${name}!"`, token.TEMPLATE, "Hello ${name}!"},
+		{"interpolation with strings and braces", `"${ {"a": "}"}["a"] } done"`, token.TEMPLATE, `${ {"a": "}"}["a"] } done`},
+		{"escaped interpolation", `"cost: \${price}"`, token.STRING, "cost: ${price}"},
+		{"dollar sign", `"$5"`, token.STRING, "$5"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			l := New(tt.input + " x")
+			tok := l.NextToken()
+
+			if tok.Type != tt.expectedType {
+				t.Errorf("tokentype wrong. expected=%q, got=%q", tt.expectedType, tok.Type)
+			}
+
+			if tok.Literal != tt.expectedLiteral {
+				t.Errorf("literal wrong. expected=%q, got=%q", tt.expectedLiteral, tok.Literal)
+			}
+
+			if next := l.NextToken(); next.Type != token.IDENT || next.Literal != "x" {
+				t.Errorf("string not terminated correctly, next token: %q", next.Literal)
+			}
+		})
+	}
+}
+
+func TestSplitTemplate(t *testing.T) {
+	t.Parallel()
+	parts := SplitTemplate(`Hi \"${user.name}\"${ {"k": "${v}"}["k"] }!\n`)
+	expected := []TemplatePart{
+		{Text: `Hi "`},
+		{Text: "user.name", IsExpression: true, Offset: 7},
+		{Text: `"`},
+		{Text: ` {"k": "${v}"}["k"] `, IsExpression: true, Offset: 21},
+		{Text: "!\n"},
+	}
+
+	if len(parts) != len(expected) {
+		t.Fatalf("wrong number of parts. expected=%d, got=%d (%+v)", len(expected), len(parts), parts)
+	}
+	for i, part := range parts {
+		if part != expected[i] {
+			t.Errorf("part %d wrong. expected=%+v, got=%+v", i, expected[i], part)
+		}
+	}
+}
+
 func TestNextToken_IdentifiersAndKeywords(t *testing.T) {
 	t.Parallel()
 	input := `var const function model enum if else switch case default
@@ -20,6 +20,7 @@ const (
 	AND         // &&
 	EQUALS      // ==, !=
 	LESSGREATER // >, <, >=, <=
+	COALESCE    // ??
 	IS          // is
 	RANGE       // .., ..=
 	SUM         // +, -
@@ -32,33 +33,36 @@ const (
 )
 
 var precedences = map[token.TokenType]int{
-	token.ASSIGN:         ASSIGN,
-	token.PlusAssign:     ASSIGN,
-	token.MinusAssign:    ASSIGN,
-	token.AsteriskAssign: ASSIGN,
-	token.SlashAssign:    ASSIGN,
-	token.PercentAssign:  ASSIGN,
-	token.OR:             OR,
-	token.AND:            AND,
-	token.EQ:             EQUALS,
-	token.NotEq:          EQUALS,
-	token.LT:             LESSGREATER,
-	token.GT:             LESSGREATER,
-	token.LtEq:           LESSGREATER,
-	token.GtEq:           LESSGREATER,
-	token.IS:             IS,
-	token.DotDot:         RANGE,
-	token.DotDotEq:       RANGE,
-	token.PLUS:           SUM,
-	token.MINUS:          SUM,
-	token.SLASH:          PRODUCT,
-	token.SlashSlash:     PRODUCT,
-	token.ASTERISK:       PRODUCT,
-	token.PERCENT:        PRODUCT,
-	token.POWER:          POWER,
-	token.LPAREN:         CALL,
-	token.LBRACKET:       INDEX,
-	token.DOT:            MEMBER,
+	token.ASSIGN:           ASSIGN,
+	token.PlusAssign:       ASSIGN,
+	token.MinusAssign:      ASSIGN,
+	token.AsteriskAssign:   ASSIGN,
+	token.SlashAssign:      ASSIGN,
+	token.PercentAssign:    ASSIGN,
+	token.OR:               OR,
+	token.AND:              AND,
+	token.EQ:               EQUALS,
+	token.NotEq:            EQUALS,
+	token.LT:               LESSGREATER,
+	token.GT:               LESSGREATER,
+	token.LtEq:             LESSGREATER,
+	token.GtEq:             LESSGREATER,
+	token.QuestionQuestion: COALESCE,
+	token.IS:               IS,
+	token.DotDot:           RANGE,
+	token.DotDotEq:         RANGE,
+	token.PLUS:             SUM,
+	token.MINUS:            SUM,
+	token.SLASH:            PRODUCT,
+	token.SlashSlash:       PRODUCT,
+	token.ASTERISK:         PRODUCT,
+	token.PERCENT:          PRODUCT,
+	token.POWER:            POWER,
+	token.LPAREN:           CALL,
+	token.QUESTION:         CALL,
+	token.LBRACKET:         INDEX,
+	token.DOT:              MEMBER,
+	token.QuestionDot:      MEMBER,
 }
 
 type (
@@ -90,6 +94,7 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerPrefix(token.INTEGER, p.parseIntegerLiteral)
 	p.registerPrefix(token.FLOAT, p.parseFloatLiteral)
 	p.registerPrefix(token.STRING, p.parseStringLiteral)
+	p.registerPrefix(token.TEMPLATE, p.parseInterpolatedString)
 	p.registerPrefix(token.TRUE, p.parseBooleanLiteral)
 	p.registerPrefix(token.FALSE, p.parseBooleanLiteral)
 	p.registerPrefix(token.NULL, p.parseNullLiteral)
@@ -102,7 +107,10 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerPrefix(token.LBRACE, p.parseMapLiteral)
 	p.registerPrefix(token.MODEL, p.parseModelLiteral)
 	p.registerPrefix(token.ENUM, p.parseEnumLiteral)
+	p.registerPrefix(token.INTERFACE, p.parseInterfaceLiteral)
 	p.registerPrefix(token.THIS, p.parseThisExpression)
+	p.registerPrefix(token.SUPER, p.parseSuperExpression)
+	p.registerPrefix(token.MATCH, p.parseMatchExpression)
 	p.registerPrefix(token.DotDot, p.parsePrefixRangeExpression)
 	p.registerPrefix(token.DotDotEq, p.parsePrefixRangeExpression)
 
@@ -123,6 +131,7 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerInfix(token.AND, p.parseInfixExpression)
 	p.registerInfix(token.OR, p.parseInfixExpression)
 	p.registerInfix(token.IS, p.parseInfixExpression)
+	p.registerInfix(token.QuestionQuestion, p.parseInfixExpression)
 	p.registerInfix(token.ASSIGN, p.parseInfixExpression)
 	p.registerInfix(token.PlusAssign, p.parseInfixExpression)
 	p.registerInfix(token.MinusAssign, p.parseInfixExpression)
@@ -134,6 +143,8 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerInfix(token.LPAREN, p.parseCallExpression)
 	p.registerInfix(token.LBRACKET, p.parseIndexExpression)
 	p.registerInfix(token.DOT, p.parseMemberExpression)
+	p.registerInfix(token.QUESTION, p.parsePropagateExpression)
+	p.registerInfix(token.QuestionDot, p.parseOptionalChain)
 
 	// Read two tokens to initialize curToken and peekToken
 	p.nextToken()
@@ -240,19 +251,39 @@ func (p *Parser) parseStatement() ast.Statement {
 		return p.parseWhileStatement()
 	case token.SWITCH:
 		return p.parseSwitchStatement()
+	case token.TRY:
+		return p.parseTryStatement()
 	default:
-		return p.parseExpressionStatement()
+		stmt := p.parseExpressionStatement()
+		if p.peekTokenIs(token.COMMA) {
+			return p.parseMultiAssignStatement(stmt.Expression)
+		}
+		return stmt
 	}
 }
 
 func (p *Parser) parseVarStatement() *ast.VarStatement {
 	stmt := &ast.VarStatement{Token: p.curToken}
 
-	if !p.expectPeek(token.IDENT) {
+	if p.peekTokenIs(token.LBRACKET) || p.peekTokenIs(token.LBRACE) {
+		p.nextToken()
+	} else if !p.expectPeek(token.IDENT) {
 		return nil
 	}
 
-	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	stmt.Name, stmt.Pattern = p.parseBindingTarget()
+	if stmt.Pattern != nil {
+		// Destructuring needs a value
+		if !p.expectPeek(token.ASSIGN) {
+			return nil
+		}
+		p.nextToken()
+		stmt.Value = p.parseExpression(LOWEST)
+		return stmt
+	}
+	if stmt.Name == nil {
+		return nil
+	}
 
 	// Check for type annotation
 	if p.peekTokenIs(token.COLON) {
@@ -274,11 +305,25 @@ func (p *Parser) parseVarStatement() *ast.VarStatement {
 func (p *Parser) parseConstStatement() *ast.ConstStatement {
 	stmt := &ast.ConstStatement{Token: p.curToken}
 
-	if !p.expectPeek(token.IDENT) {
+	if p.peekTokenIs(token.LBRACKET) || p.peekTokenIs(token.LBRACE) {
+		p.nextToken()
+	} else if !p.expectPeek(token.IDENT) {
 		return nil
 	}
 
-	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	stmt.Name, stmt.Pattern = p.parseBindingTarget()
+	if stmt.Pattern != nil {
+		// Destructuring needs a value
+		if !p.expectPeek(token.ASSIGN) {
+			return nil
+		}
+		p.nextToken()
+		stmt.Value = p.parseExpression(LOWEST)
+		return stmt
+	}
+	if stmt.Name == nil {
+		return nil
+	}
 
 	// Check for type annotation
 	if p.peekTokenIs(token.COLON) {
@@ -298,6 +343,67 @@ func (p *Parser) parseConstStatement() *ast.ConstStatement {
 	return stmt
 }
 
+// parseBindingTarget parses the variable a declaration or for loop binds, or
+// the pattern destructuring the value: [a, b], {name, age}, Point(x, y).
+func (p *Parser) parseBindingTarget() (*ast.Identifier, ast.Pattern) {
+	switch p.curToken.Type {
+	case token.LBRACKET, token.LBRACE:
+		return nil, p.parsePattern()
+	case token.IDENT:
+		if p.peekTokenIs(token.LPAREN) || p.peekTokenIs(token.DOT) {
+			pattern := p.parseIdentifierPattern()
+			if _, ok := pattern.(*ast.ModelPattern); !ok {
+				if pattern != nil {
+					p.addError(fmt.Sprintf("expected identifier or pattern, got %s", pattern.String()))
+				}
+				return nil, nil
+			}
+			return nil, pattern
+		}
+		return &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}, nil
+	}
+	return nil, nil
+}
+
+// parseMultiAssignStatement parses an assignment of several values at once,
+// after its first target: a, b = b, a
+func (p *Parser) parseMultiAssignStatement(first ast.Expression) ast.Statement {
+	stmt := &ast.MultiAssignStatement{Targets: []ast.Expression{first}}
+
+	for p.peekTokenIs(token.COMMA) {
+		p.nextToken()
+		p.nextToken()
+		// Stop before '=' instead of parsing an assignment
+		stmt.Targets = append(stmt.Targets, p.parseExpression(ASSIGN))
+	}
+
+	for _, target := range stmt.Targets {
+		switch target.(type) {
+		case *ast.Identifier, *ast.IndexExpression, *ast.MemberExpression:
+		case nil:
+			return nil
+		default:
+			p.addError(fmt.Sprintf("cannot assign to %s", target.String()))
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.ASSIGN) {
+		return nil
+	}
+	stmt.Token = p.curToken
+
+	p.nextToken()
+	stmt.Values = []ast.Expression{p.parseExpression(LOWEST)}
+	for p.peekTokenIs(token.COMMA) {
+		p.nextToken()
+		p.nextToken()
+		stmt.Values = append(stmt.Values, p.parseExpression(LOWEST))
+	}
+
+	return stmt
+}
+
 func (p *Parser) parseImportStatement() *ast.ImportStatement {
 	stmt := &ast.ImportStatement{Token: p.curToken}
 
@@ -416,13 +522,14 @@ func computeModuleName(path string) string {
 func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
 	stmt := &ast.ReturnStatement{Token: p.curToken}
 
-	p.nextToken()
-
-	// Check if there's a return value
-	if !p.curTokenIs(token.EOF) && !p.curTokenIs(token.RBRACE) {
-		stmt.ReturnValue = p.parseExpression(LOWEST)
+	// A bare return ends the block; leave the closing brace to the block
+	if p.peekTokenIs(token.EOF) || p.peekTokenIs(token.RBRACE) || p.peekTokenIs(token.SEMICOLON) {
+		return stmt
 	}
 
+	p.nextToken()
+	stmt.ReturnValue = p.parseExpression(LOWEST)
+
 	return stmt
 }
 
@@ -449,31 +556,68 @@ func (p *Parser) parseWhileStatement() *ast.WhileStatement {
 	return stmt
 }
 
+func (p *Parser) parseTryStatement() *ast.TryStatement {
+	stmt := &ast.TryStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	stmt.Body = p.parseBlockStatement()
+
+	if !p.expectPeek(token.CATCH) {
+		return nil
+	}
+
+	// The error variable is optional: catch { ... }
+	if p.peekTokenIs(token.IDENT) {
+		p.nextToken()
+		stmt.ErrorName = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	stmt.Catch = p.parseBlockStatement()
+
+	return stmt
+}
+
 func (p *Parser) parseForStatement() *ast.ForStatement {
 	stmt := &ast.ForStatement{Token: p.curToken}
 
 	p.nextToken()
 
 	// Check if it's a for-in loop
-	if p.curTokenIs(token.IDENT) {
-		firstIdent := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	if p.curTokenIs(token.IDENT) || p.curTokenIs(token.LBRACKET) || p.curTokenIs(token.LBRACE) {
+		firstIdent, firstPattern := p.parseBindingTarget()
+		if firstIdent == nil && firstPattern == nil {
+			return nil
+		}
 		p.nextToken()
 
 		// Check for comma (index, value pattern)
 		if p.curTokenIs(token.COMMA) {
+			if firstIdent == nil {
+				p.addError("expected identifier for index in for loop")
+				return nil
+			}
 			p.nextToken()
-			if !p.curTokenIs(token.IDENT) {
+			stmt.Value, stmt.Pattern = p.parseBindingTarget()
+			if stmt.Value == nil && stmt.Pattern == nil {
 				p.addError("expected identifier after comma in for loop")
 				return nil
 			}
 			stmt.Iterator = firstIdent
-			stmt.Value = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 			p.nextToken()
 		} else {
-			stmt.Value = firstIdent
+			stmt.Value, stmt.Pattern = firstIdent, firstPattern
 		}
 
 		// Expect 'in'
+		if !p.curTokenIs(token.IN) && stmt.Pattern != nil {
+			p.addError(fmt.Sprintf("expected in after %s in for loop, got %s", stmt.Pattern.String(), p.curToken.Literal))
+			return nil
+		}
 		if !p.curTokenIs(token.IN) {
 			// Not a for-in loop, might be C-style
 			// Rewind and parse as C-style
@@ -579,6 +723,249 @@ func (p *Parser) parseCaseClause() *ast.CaseClause {
 	return clause
 }
 
+func (p *Parser) parseMatchExpression() ast.Expression {
+	exp := &ast.MatchExpression{Token: p.curToken}
+
+	p.nextToken()
+	exp.Value = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	p.nextToken()
+
+	for !p.curTokenIs(token.RBRACE) && !p.curTokenIs(token.EOF) {
+		switch {
+		case p.curTokenIs(token.CASE):
+			arm := p.parseMatchArm()
+			if arm == nil {
+				return nil
+			}
+			exp.Arms = append(exp.Arms, arm)
+		case p.curTokenIs(token.DEFAULT):
+			if !p.expectPeek(token.LBRACE) {
+				return nil
+			}
+			exp.Default = p.parseBlockStatement()
+		default:
+			p.addError(fmt.Sprintf("expected case or default in match, got %s", p.curToken.Literal))
+			return nil
+		}
+		p.nextToken()
+	}
+
+	return exp
+}
+
+func (p *Parser) parseMatchArm() *ast.MatchArm {
+	arm := &ast.MatchArm{Token: p.curToken}
+
+	p.nextToken()
+	arm.Patterns = []ast.Pattern{p.parsePattern()}
+
+	// Check for comma-separated alternatives
+	for p.peekTokenIs(token.COMMA) {
+		p.nextToken() // consume comma
+		p.nextToken()
+		arm.Patterns = append(arm.Patterns, p.parsePattern())
+	}
+
+	for _, pattern := range arm.Patterns {
+		if pattern == nil {
+			return nil
+		}
+	}
+
+	// Check for guard
+	if p.peekTokenIs(token.IF) {
+		p.nextToken()
+		p.nextToken()
+		arm.Guard = p.parseExpression(LOWEST)
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	arm.Body = p.parseBlockStatement()
+
+	return arm
+}
+
+// parsePattern parses a pattern of a match arm. Identifiers bind the matched
+// value; literals, enum values and ranges compare with it.
+func (p *Parser) parsePattern() ast.Pattern {
+	switch p.curToken.Type {
+	case token.LBRACKET:
+		return p.parseArrayPattern()
+	case token.LBRACE:
+		return p.parseMapPattern()
+	case token.IDENT:
+		return p.parseIdentifierPattern()
+	}
+
+	tok := p.curToken
+	value := p.parseExpression(LOWEST)
+	if value == nil {
+		return nil
+	}
+	return &ast.ValuePattern{Token: tok, Value: value}
+}
+
+// parseIdentifierPattern parses patterns starting with an identifier: _,
+// bindings (n, e: Error), model patterns (Point(x, y)) and qualified values
+// (Color.Red).
+func (p *Parser) parseIdentifierPattern() ast.Pattern {
+	tok := p.curToken
+	ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	switch {
+	case p.peekTokenIs(token.COLON):
+		pattern := &ast.BindingPattern{Token: tok, Name: ident}
+		p.nextToken()
+		p.nextToken()
+		pattern.Type = p.parseTypeExpression()
+		if pattern.Type == nil {
+			return nil
+		}
+		return pattern
+	case p.peekTokenIs(token.DOT), p.peekTokenIs(token.LPAREN):
+		// Qualified names: Color.Red, geo.Point(x, y)
+		var name ast.Expression = ident
+		for p.peekTokenIs(token.DOT) {
+			p.nextToken()
+			member := &ast.MemberExpression{Token: p.curToken, Object: name}
+			if !p.expectPeek(token.IDENT) {
+				return nil
+			}
+			member.Member = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+			name = member
+		}
+		if p.peekTokenIs(token.LPAREN) {
+			p.nextToken()
+			return p.parseModelPattern(tok, name)
+		}
+		return &ast.ValuePattern{Token: tok, Value: name}
+	case ident.Value == "_":
+		return &ast.WildcardPattern{Token: tok}
+	}
+
+	return &ast.BindingPattern{Token: tok, Name: ident}
+}
+
+func (p *Parser) parseModelPattern(tok token.Token, model ast.Expression) ast.Pattern {
+	pattern := &ast.ModelPattern{Token: tok, Model: model}
+
+	if p.peekTokenIs(token.RPAREN) {
+		p.nextToken()
+		return pattern
+	}
+
+	for {
+		p.nextToken()
+		field := p.parsePattern()
+		if field == nil {
+			return nil
+		}
+		pattern.Fields = append(pattern.Fields, field)
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
+		p.nextToken()
+	}
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+	return pattern
+}
+
+func (p *Parser) parseArrayPattern() ast.Pattern {
+	pattern := &ast.ArrayPattern{Token: p.curToken}
+
+	if p.peekTokenIs(token.RBRACKET) {
+		p.nextToken()
+		return pattern
+	}
+
+	for {
+		p.nextToken()
+
+		// The rest pattern comes last: [first, ...rest]
+		if p.curTokenIs(token.DotDotDot) {
+			pattern.HasRest = true
+			if p.peekTokenIs(token.IDENT) {
+				p.nextToken()
+				if p.curToken.Literal != "_" {
+					pattern.Rest = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+				}
+			}
+			break
+		}
+
+		element := p.parsePattern()
+		if element == nil {
+			return nil
+		}
+		pattern.Elements = append(pattern.Elements, element)
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
+		p.nextToken()
+	}
+
+	if !p.expectPeek(token.RBRACKET) {
+		return nil
+	}
+	return pattern
+}
+
+func (p *Parser) parseMapPattern() ast.Pattern {
+	pattern := &ast.MapPattern{Token: p.curToken}
+
+	if p.peekTokenIs(token.RBRACE) {
+		p.nextToken()
+		return pattern
+	}
+
+	for {
+		p.nextToken()
+
+		switch p.curToken.Type {
+		case token.STRING:
+			pattern.Keys = append(pattern.Keys, p.curToken.Literal)
+			if !p.expectPeek(token.COLON) {
+				return nil
+			}
+			p.nextToken()
+			value := p.parsePattern()
+			if value == nil {
+				return nil
+			}
+			pattern.Values = append(pattern.Values, value)
+		case token.IDENT:
+			// Shorthand: {name} binds the value of "name" to name
+			ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+			pattern.Keys = append(pattern.Keys, ident.Value)
+			pattern.Values = append(pattern.Values, &ast.BindingPattern{Token: p.curToken, Name: ident})
+		default:
+			p.addError(fmt.Sprintf("expected map key in pattern, got %s", p.curToken.Literal))
+			return nil
+		}
+
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
+		p.nextToken()
+	}
+
+	if !p.expectPeek(token.RBRACE) {
+		return nil
+	}
+	return pattern
+}
+
 func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
 	stmt := &ast.ExpressionStatement{Token: p.curToken}
 	stmt.Expression = p.parseExpression(LOWEST)
@@ -607,7 +994,12 @@ func (p *Parser) parseExpression(precedence int) ast.Expression {
 }
 
 func (p *Parser) parseIdentifier() ast.Expression {
-	return &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	// Lambda with a single parameter: x => x * 2
+	if p.peekTokenIs(token.ARROW) {
+		return p.parseLambda([]*ast.Parameter{{Name: ident}})
+	}
+	return ident
 }
 
 func (p *Parser) parseIntegerLiteral() ast.Expression {
@@ -642,6 +1034,45 @@ func (p *Parser) parseStringLiteral() ast.Expression {
 	return &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
 }
 
+// parseInterpolatedString parses a string with embedded ${expressions}, each
+// parsed by a parser of its own.
+func (p *Parser) parseInterpolatedString() ast.Expression {
+	tok := p.curToken
+	str := &ast.InterpolatedString{Token: tok}
+
+	for _, part := range lexer.SplitTemplate(tok.Literal) {
+		if !part.IsExpression {
+			str.Parts = append(str.Parts, &ast.StringLiteral{Token: tok, Value: part.Text})
+			continue
+		}
+
+		// Position of the expression: the template starts after the opening quote
+		line, column := tok.Line, tok.Column+1+part.Offset
+		if before := tok.Literal[:part.Offset]; strings.Contains(before, "\n") {
+			line += strings.Count(before, "\n")
+			column = part.Offset - strings.LastIndex(before, "\n")
+		}
+
+		if strings.TrimSpace(part.Text) == "" {
+			p.errors = append(p.errors, NewParseError(line, column, "empty expression in string interpolation"))
+			continue
+		}
+
+		sub := New(lexer.NewAt(part.Text, line, column))
+		expr := sub.parseExpression(LOWEST)
+		if !sub.peekTokenIs(token.EOF) {
+			sub.nextToken()
+			sub.addError(fmt.Sprintf("unexpected %s in string interpolation", sub.curToken.Literal))
+		}
+		p.errors = append(p.errors, sub.errors...)
+		if expr != nil {
+			str.Parts = append(str.Parts, expr)
+		}
+	}
+
+	return str
+}
+
 func (p *Parser) parseBooleanLiteral() ast.Expression {
 	return &ast.BooleanLiteral{Token: p.curToken, Value: p.curTokenIs(token.TRUE)}
 }
@@ -677,6 +1108,14 @@ func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 }
 
 func (p *Parser) parseGroupedExpression() ast.Expression {
+	if p.isLambdaParameters() {
+		params := p.parseFunctionParameters()
+		if params == nil {
+			return nil
+		}
+		return p.parseLambda(params)
+	}
+
 	p.nextToken()
 
 	exp := p.parseExpression(LOWEST)
@@ -688,6 +1127,45 @@ func (p *Parser) parseGroupedExpression() ast.Expression {
 	return exp
 }
 
+// isLambdaParameters reports whether the parenthesis at the current token
+// opens the parameters of a lambda, (a, b) => a + b, rather than a grouped
+// expression.
+func (p *Parser) isLambdaParameters() bool {
+	l := p.l.Copy()
+	depth := 1
+	for tok := p.peekToken; tok.Type != token.EOF; tok = l.NextToken() {
+		switch tok.Type {
+		case token.LPAREN:
+			depth++
+		case token.RPAREN:
+			depth--
+			if depth == 0 {
+				return l.NextToken().Type == token.ARROW
+			}
+		}
+	}
+	return false
+}
+
+// parseLambda parses the body of a lambda after its parameters: a block, as
+// in function literals, or an expression giving the result.
+func (p *Parser) parseLambda(params []*ast.Parameter) ast.Expression {
+	if !p.expectPeek(token.ARROW) {
+		return nil
+	}
+	lit := &ast.FunctionLiteral{Token: p.curToken, Parameters: params}
+
+	p.nextToken()
+	if p.curTokenIs(token.LBRACE) {
+		lit.Body = p.parseBlockStatement()
+		return lit
+	}
+
+	body := &ast.ExpressionStatement{Token: p.curToken, Expression: p.parseExpression(LOWEST)}
+	lit.Body = &ast.BlockStatement{Token: body.Token, Statements: []ast.Statement{body}}
+	return lit
+}
+
 func (p *Parser) parseIfExpression() ast.Expression {
 	expression := &ast.IfExpression{Token: p.curToken}
 
@@ -773,11 +1251,44 @@ func (p *Parser) parseFunctionParameters() []*ast.Parameter {
 		return params
 	}
 
-	p.nextToken()
+	for {
+		p.nextToken()
+		param := p.parseParameter()
+		if param == nil {
+			return nil
+		}
+		params = append(params, param)
+
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
+		p.nextToken() // consume comma
+	}
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	if !p.checkParameterOrder(params) {
+		return nil
+	}
+	return params
+}
 
-	param := &ast.Parameter{
-		Name: &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal},
+// parseParameter parses a function parameter: name, name: type,
+// name = default or ...name: type.
+func (p *Parser) parseParameter() *ast.Parameter {
+	param := &ast.Parameter{}
+
+	if p.curTokenIs(token.DotDotDot) {
+		param.Variadic = true
+		p.nextToken()
 	}
+	if !p.curTokenIs(token.IDENT) {
+		p.addError(fmt.Sprintf("expected parameter name, got %s", p.curToken.Literal))
+		return nil
+	}
+	param.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 
 	// Check for type annotation
 	if p.peekTokenIs(token.COLON) {
@@ -786,35 +1297,43 @@ func (p *Parser) parseFunctionParameters() []*ast.Parameter {
 		param.Type = p.parseTypeExpression()
 	}
 
-	params = append(params, param)
-
-	for p.peekTokenIs(token.COMMA) {
-		p.nextToken() // consume comma
-		p.nextToken() // move to identifier
+	// Check for default value
+	if p.peekTokenIs(token.ASSIGN) {
+		p.nextToken() // consume '='
+		p.nextToken() // move to value
+		param.Default = p.parseExpression(LOWEST)
+	}
 
-		param := &ast.Parameter{
-			Name: &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal},
-		}
+	return param
+}
 
-		if p.peekTokenIs(token.COLON) {
-			p.nextToken()
-			p.nextToken()
-			param.Type = p.parseTypeExpression()
+// checkParameterOrder reports parameters that could never be bound: required
+// ones after optional ones, and variadic ones that are not last.
+func (p *Parser) checkParameterOrder(params []*ast.Parameter) bool {
+	optional := ""
+	for i, param := range params {
+		switch {
+		case param.Variadic && param.Default != nil:
+			p.addError(fmt.Sprintf("variadic parameter '%s' cannot have a default value", param.Name.Value))
+			return false
+		case param.Variadic && i != len(params)-1:
+			p.addError(fmt.Sprintf("variadic parameter '%s' must be the last parameter", param.Name.Value))
+			return false
+		case param.Default != nil:
+			optional = param.Name.Value
+		case optional != "" && !param.Variadic:
+			p.addError(fmt.Sprintf("required parameter '%s' follows optional parameter '%s'", param.Name.Value, optional))
+			return false
 		}
-
-		params = append(params, param)
-	}
-
-	if !p.expectPeek(token.RPAREN) {
-		return nil
 	}
-
-	return params
+	return true
 }
 
 func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
 	exp := &ast.CallExpression{Token: p.curToken, Function: function}
-	exp.Arguments = p.parseExpressionList(token.RPAREN)
+	if !p.parseCallArguments(exp) {
+		return nil
+	}
 
 	// Check if this is a db.find() call followed by { } for query syntax
 	if mem, ok := function.(*ast.MemberExpression); ok {
@@ -831,6 +1350,48 @@ func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
 	return exp
 }
 
+// parseCallArguments parses the arguments of a call. Named arguments
+// (name: value) follow the positional ones.
+func (p *Parser) parseCallArguments(call *ast.CallExpression) bool {
+	call.Arguments = []ast.Expression{}
+
+	if p.peekTokenIs(token.RPAREN) {
+		p.nextToken()
+		return true
+	}
+
+	for {
+		p.nextToken()
+
+		if p.curTokenIs(token.IDENT) && p.peekTokenIs(token.COLON) {
+			arg := &ast.NamedArgument{Token: p.curToken, Name: &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}}
+			for _, other := range call.NamedArguments {
+				if other.Name.Value == arg.Name.Value {
+					p.addError(fmt.Sprintf("duplicate argument '%s'", arg.Name.Value))
+					return false
+				}
+			}
+			p.nextToken() // consume ':'
+			p.nextToken() // move to value
+			arg.Value = p.parseExpression(LOWEST)
+			call.NamedArguments = append(call.NamedArguments, arg)
+		} else {
+			if len(call.NamedArguments) > 0 {
+				p.addError("positional argument follows named arguments")
+				return false
+			}
+			call.Arguments = append(call.Arguments, p.parseExpression(LOWEST))
+		}
+
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
+		p.nextToken()
+	}
+
+	return p.expectPeek(token.RPAREN)
+}
+
 func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
 	list := []ast.Expression{}
 
@@ -878,6 +1439,10 @@ func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
 	return exp
 }
 
+func (p *Parser) parsePropagateExpression(left ast.Expression) ast.Expression {
+	return &ast.PropagateExpression{Token: p.curToken, Value: left}
+}
+
 func (p *Parser) parseMemberExpression(left ast.Expression) ast.Expression {
 	exp := &ast.MemberExpression{Token: p.curToken, Object: left}
 
@@ -890,6 +1455,33 @@ func (p *Parser) parseMemberExpression(left ast.Expression) ast.Expression {
 	return exp
 }
 
+// parseOptionalChain parses optional access: value?.member, value?.[index]
+// and value?.(arguments).
+func (p *Parser) parseOptionalChain(left ast.Expression) ast.Expression {
+	switch {
+	case p.peekTokenIs(token.LBRACKET):
+		p.nextToken()
+		exp := p.parseIndexExpression(left)
+		if index, ok := exp.(*ast.IndexExpression); ok {
+			index.Optional = true
+		}
+		return exp
+	case p.peekTokenIs(token.LPAREN):
+		p.nextToken()
+		exp := p.parseCallExpression(left)
+		if call, ok := exp.(*ast.CallExpression); ok {
+			call.Optional = true
+		}
+		return exp
+	}
+
+	exp := p.parseMemberExpression(left)
+	if member, ok := exp.(*ast.MemberExpression); ok {
+		member.Optional = true
+	}
+	return exp
+}
+
 func (p *Parser) parseRangeExpression(left ast.Expression) ast.Expression {
 	exp := &ast.RangeExpression{
 		Token:     p.curToken,
@@ -1015,8 +1607,9 @@ func (p *Parser) parseGenericType() string {
 func (p *Parser) parseTypeExpression() *ast.TypeExpression {
 	typeExpr := &ast.TypeExpression{Token: p.curToken}
 
-	// Handle union types (integer | string) and module-prefixed types (http.Request)
-	if p.curTokenIs(token.IDENT) {
+	// Handle union types (integer | string) and module-prefixed types (http.Request).
+	// The function type is spelled with the function keyword.
+	if p.curTokenIs(token.IDENT) || p.curTokenIs(token.FUNCTION) {
 		typeExpr.Name = p.curToken.Literal
 
 		// Check for member expression (module.Type)
@@ -1078,6 +1671,36 @@ func (p *Parser) parseModelLiteral() ast.Expression {
 	model.Methods = []*ast.ModelMethod{}
 	model.Constructors = []*ast.FunctionLiteral{}
 
+	// Base model: model extends Entity { ... }
+	if p.peekTokenIs(token.EXTENDS) {
+		p.nextToken()
+		p.nextToken()
+		if !p.curTokenIs(token.IDENT) {
+			msg := fmt.Sprintf("expected model name after extends, got %s", p.curToken.Type)
+			p.errors = append(p.errors, NewParseError(p.curToken.Line, p.curToken.Column, msg))
+			return nil
+		}
+		model.Extends = p.parseTypeExpression()
+	}
+
+	// Declared interfaces: model implements Shape, Named { ... }
+	if p.peekTokenIs(token.IMPLEMENTS) {
+		p.nextToken()
+		for {
+			p.nextToken()
+			if !p.curTokenIs(token.IDENT) {
+				msg := fmt.Sprintf("expected interface name after implements, got %s", p.curToken.Type)
+				p.errors = append(p.errors, NewParseError(p.curToken.Line, p.curToken.Column, msg))
+				return nil
+			}
+			model.Implements = append(model.Implements, p.parseTypeExpression())
+			if !p.peekTokenIs(token.COMMA) {
+				break
+			}
+			p.nextToken()
+		}
+	}
+
 	if !p.expectPeek(token.LBRACE) {
 		return nil
 	}
@@ -1193,6 +1816,62 @@ func (p *Parser) parseModelLiteral() ast.Expression {
 	return model
 }
 
+// parseInterfaceLiteral parses the fields and method signatures of an
+// interface:
+//
+//	interface {
+//	  name: string
+//	  area: function(): float
+//	}
+func (p *Parser) parseInterfaceLiteral() ast.Expression {
+	iface := &ast.InterfaceLiteral{Token: p.curToken}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	p.nextToken()
+
+	for !p.curTokenIs(token.RBRACE) && !p.curTokenIs(token.EOF) {
+		if !p.curTokenIs(token.IDENT) {
+			msg := fmt.Sprintf("expected field or method name in interface, got %s", p.curToken.Type)
+			p.errors = append(p.errors, NewParseError(p.curToken.Line, p.curToken.Column, msg))
+			return nil
+		}
+		name := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+		if !p.expectPeek(token.COLON) {
+			return nil
+		}
+		p.nextToken() // move to type
+
+		if p.curTokenIs(token.FUNCTION) && p.peekTokenIs(token.LPAREN) {
+			// A method signature: name: function(params): type
+			p.nextToken()
+			method := &ast.InterfaceMethod{Name: name, Parameters: p.parseFunctionParameters()}
+			if method.Parameters == nil {
+				return nil
+			}
+			if p.peekTokenIs(token.COLON) {
+				p.nextToken() // consume ':'
+				p.nextToken() // move to type
+				method.ReturnType = p.parseTypeExpression()
+			}
+			iface.Methods = append(iface.Methods, method)
+		} else {
+			typeExpr := p.parseTypeExpression()
+			if typeExpr == nil {
+				return nil
+			}
+			iface.Fields = append(iface.Fields, &ast.ModelField{Name: name, Type: typeExpr})
+		}
+
+		p.nextToken()
+	}
+
+	return iface
+}
+
 func (p *Parser) parseEnumLiteral() ast.Expression {
 	enum := &ast.EnumLiteral{Token: p.curToken}
 	enum.Values = []*ast.EnumValue{}
@@ -1239,6 +1918,10 @@ func (p *Parser) parseThisExpression() ast.Expression {
 	return &ast.ThisExpression{Token: p.curToken}
 }
 
+func (p *Parser) parseSuperExpression() ast.Expression {
+	return &ast.SuperExpression{Token: p.curToken}
+}
+
 func (p *Parser) parseDbFindExpression(callExpr *ast.CallExpression) ast.Expression {
 	dbFind := &ast.DbFindExpression{
 		Token:      callExpr.Token,
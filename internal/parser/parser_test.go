@@ -1,6 +1,7 @@
 package parser
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/mishankov/totalscript-lang/internal/ast"
@@ -123,6 +124,8 @@ func TestReturnStatements(t *testing.T) {
 	}{
 		{"return with value", "return 5", "return 5"},
 		{"return with expression", "return x + y", "return (x + y)"},
+		{"bare return", "return", "return"},
+		{"bare return in block", "if x { return }", "if x { return }"},
 	}
 
 	for _, tt := range tests {
@@ -226,6 +229,63 @@ func TestStringLiteralExpression(t *testing.T) {
 	}
 }
 
+func TestInterpolatedStringParsing(t *testing.T) {
+	t.Parallel()
+	input := `"Hello ${user.name}, ${1 + 2}!"`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	str, ok := stmt.Expression.(*ast.InterpolatedString)
+	if !ok {
+		t.Fatalf("exp not *ast.InterpolatedString. got=%T", stmt.Expression)
+	}
+
+	expected := []string{`"Hello "`, "user.name", `", "`, "(1 + 2)", `"!"`}
+	if len(str.Parts) != len(expected) {
+		t.Fatalf("wrong number of parts. expected=%d, got=%d", len(expected), len(str.Parts))
+	}
+	for i, part := range str.Parts {
+		if part.String() != expected[i] {
+			t.Errorf("part %d wrong. expected=%q, got=%q", i, expected[i], part.String())
+		}
+	}
+
+	// Expressions keep their position in the file
+	member := str.Parts[1].(*ast.MemberExpression)
+	ident := member.Object.(*ast.Identifier)
+	if ident.Token.Line != 1 || ident.Token.Column != 10 {
+		t.Errorf("wrong position of %s. expected=1:10, got=%d:%d", ident.Value, ident.Token.Line, ident.Token.Column)
+	}
+}
+
+func TestInterpolatedStringErrors(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`"${}"`, "parse error at 1:4: empty expression in string interpolation"},
+		{`"${a b}"`, "parse error at 1:6: unexpected b in string interpolation"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Errorf("expected error for %s", tt.input)
+			continue
+		}
+		if errors[0].Error() != tt.expected {
+			t.Errorf("wrong error for %s. expected=%q, got=%q", tt.input, tt.expected, errors[0].Error())
+		}
+	}
+}
+
 func TestBooleanExpression(t *testing.T) {
 	t.Parallel()
 	tests := []struct {
@@ -366,6 +426,16 @@ func TestOperatorPrecedence(t *testing.T) {
 		{"2 / (5 + 5)", "(2 / (5 + 5))"},
 		{"-(5 + 5)", "(-(5 + 5))"},
 		{"!(true == true)", "(!(true == true))"},
+		{"req.json()?", "(req.json()?)"},
+		{"-a? + b", "((-(a?)) + b)"},
+		{"load(a?)?", "(load((a?))?)"},
+		{"user?.address.city", "user?.address.city"},
+		{"items?.[0]?.name", "(items?.[0])?.name"},
+		{"f?.(x)", "f?.(x)"},
+		{"a ?? b ?? c", "((a ?? b) ?? c)"},
+		{"a ?? b + c", "(a ?? (b + c))"},
+		{"a ?? b == c", "((a ?? b) == c)"},
+		{"x > a?.b ?? 0", "(x > (a?.b ?? 0))"},
 	}
 
 	for _, tt := range tests {
@@ -589,6 +659,278 @@ func TestWhileStatement(t *testing.T) {
 	}
 }
 
+func TestTryStatement(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		input     string
+		errorName string
+		expected  string
+	}{
+		{`try { x = 1 } catch e { println(e.message) }`, "e", "try { (x = 1) } catch e { println(e.message) }"},
+		{`try { x = 1 } catch { x = 2 }`, "", "try { (x = 1) } catch { (x = 2) }"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt, ok := program.Statements[0].(*ast.TryStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not ast.TryStatement. got=%T",
+				program.Statements[0])
+		}
+		if tt.errorName == "" && stmt.ErrorName != nil {
+			t.Errorf("stmt.ErrorName should be nil. got=%s", stmt.ErrorName)
+		}
+		if tt.errorName != "" && (stmt.ErrorName == nil || stmt.ErrorName.Value != tt.errorName) {
+			t.Errorf("stmt.ErrorName wrong. expected=%s, got=%v", tt.errorName, stmt.ErrorName)
+		}
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+
+	p := New(lexer.New(`try { x = 1 }`))
+	p.ParseProgram()
+	if len(p.Errors()) == 0 {
+		t.Errorf("expected an error for try without catch")
+	}
+}
+
+func TestMatchExpression(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`match x { case 0, 1 { "small" } default { "big" } }`,
+			`match x { case 0, 1 { "small" } default { "big" } }`},
+		{`match x { case n: integer if n > 0 { n } case _ { 0 } }`,
+			"match x { case n: integer if (n > 0) { n } case _ { 0 } }"},
+		{`match x { case 1..=9 { 1 } case ..0 { 2 } case Color.Red { 3 } case null { 4 } }`,
+			"match x { case 1..=9 { 1 } case ..0 { 2 } case Color.Red { 3 } case null { 4 } }"},
+		{`match x { case Point(x, 0) { x } case geo.Point(_, y) { y } }`,
+			"match x { case Point(x, 0) { x } case geo.Point(_, y) { y } }"},
+		{`match x { case [] { 0 } case [first, ...rest] { first } case [a, ...] { a } }`,
+			"match x { case [] { 0 } case [first, ...rest] { first } case [a, ...] { a } }"},
+		{`match x { case {"status": 200, body} { body } case {} { null } }`,
+			`match x { case {"status": 200, "body": body} { body } case {} { null } }`},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		if _, ok := stmt.Expression.(*ast.MatchExpression); !ok {
+			t.Fatalf("exp not *ast.MatchExpression. got=%T", stmt.Expression)
+		}
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+
+	for _, input := range []string{
+		`match x { 1 { "one" } }`,
+		`match x { case {1: y} { y } }`,
+		`match x { case [...rest, last] { last } }`,
+	} {
+		p := New(lexer.New(input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 {
+			t.Errorf("expected an error for %q", input)
+		}
+	}
+}
+
+func TestDestructuring(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"var [a, b, ...rest] = items", "var [a, b, ...rest] = items"},
+		{"const {name, age} = user", `const {"name": name, "age": age} = user`},
+		{"var Point(x, y) = p", "var Point(x, y) = p"},
+		{"var geo.Point(x, _) = p", "var geo.Point(x, _) = p"},
+		{"for [key, value] in pairs { key }", "for [key, value] in pairs { key }"},
+		{"for i, Point(x, y) in points { x }", "for i, Point(x, y) in points { x }"},
+		{"for {name} in users { name }", `for {"name": name} in users { name }`},
+		{"a, b = b, a", "a, b = b, a"},
+		{"items[0], p.x = p.x + 1, items[0]", "(items[0]), p.x = (p.x + 1), (items[0])"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("expected 1 statement for %q, got %d", tt.input, len(program.Statements))
+		}
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+
+	for _, input := range []string{
+		"var [a, b]",
+		"var Color.Red = c",
+		"for [i, v], x in items { x }",
+		"a, b + 1 = 1, 2",
+		"a, b",
+	} {
+		p := New(lexer.New(input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 {
+			t.Errorf("expected an error for %q", input)
+		}
+	}
+}
+
+func TestLambdasAndParameters(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"x => x * 2", "(x) => { (x * 2) }"},
+		{"(a, b) => { return a + b }", "(a, b) => { return (a + b) }"},
+		{"() => 42", "() => { 42 }"},
+		{"(a: integer) => a", "(a: integer) => { a }"},
+		{"items.map(x => x + 1)", "items.map((x) => { (x + 1) })"},
+		{"(a + b) * 2", "((a + b) * 2)"},
+		{"function(a, b: integer = 1, ...rest: string) { a }", "function(a, b: integer = 1, ...rest: string) { a }"},
+		{`greet("Ann", greeting: "Hi")`, `greet("Ann", greeting: "Hi")`},
+		{"Point(y: 2, x: 1)", "Point(y: 2, x: 1)"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+
+	errorTests := []struct {
+		input    string
+		expected string
+	}{
+		{"function(...rest, a) { a }", "variadic parameter 'rest' must be the last parameter"},
+		{"function(...rest = []) { rest }", "variadic parameter 'rest' cannot have a default value"},
+		{"function(a = 1, b) { a }", "required parameter 'b' follows optional parameter 'a'"},
+		{"f(a: 1, 2)", "positional argument follows named arguments"},
+		{"f(a: 1, a: 2)", "duplicate argument 'a'"},
+	}
+
+	for _, tt := range errorTests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		found := false
+		for _, err := range p.Errors() {
+			if strings.Contains(err.Message, tt.expected) {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("expected error %q for %q, got %v", tt.expected, tt.input, p.Errors())
+		}
+	}
+}
+
+func TestInterfaceLiteral(t *testing.T) {
+	t.Parallel()
+	input := `const Shape = interface {
+  name: string
+  area: function(): float
+  scale: function(factor: float): Shape
+}
+const Square = model implements Shape, geo.Named {
+  side: float
+}`
+
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 2 {
+		t.Fatalf("expected 2 statements, got %d", len(program.Statements))
+	}
+	iface, ok := program.Statements[0].(*ast.ConstStatement).Value.(*ast.InterfaceLiteral)
+	if !ok {
+		t.Fatalf("value is not *ast.InterfaceLiteral. got=%T", program.Statements[0].(*ast.ConstStatement).Value)
+	}
+	if len(iface.Fields) != 1 || len(iface.Methods) != 2 {
+		t.Fatalf("expected 1 field and 2 methods, got %d and %d", len(iface.Fields), len(iface.Methods))
+	}
+	expected := "interface {\n  name: string\n  area: function(): float\n  scale: function(factor: float): Shape\n}"
+	if iface.String() != expected {
+		t.Errorf("expected=%q, got=%q", expected, iface.String())
+	}
+
+	model, ok := program.Statements[1].(*ast.ConstStatement).Value.(*ast.ModelLiteral)
+	if !ok {
+		t.Fatalf("value is not *ast.ModelLiteral. got=%T", program.Statements[1].(*ast.ConstStatement).Value)
+	}
+	if len(model.Implements) != 2 || model.Implements[1].String() != "geo.Named" {
+		t.Errorf("wrong interfaces: %v", model.Implements)
+	}
+
+	for _, input := range []string{
+		"interface { area() }",
+		"interface { 1: string }",
+		"model implements { x: float }",
+	} {
+		p := New(lexer.New(input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 {
+			t.Errorf("expected an error for %q", input)
+		}
+	}
+}
+
+func TestModelExtends(t *testing.T) {
+	t.Parallel()
+	input := `const Post = model extends Entity implements Named {
+  title: string
+  describe = function() { return super.describe() }
+  constructor = function(title: string) { return super("admin") }
+}`
+
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	model, ok := program.Statements[0].(*ast.ConstStatement).Value.(*ast.ModelLiteral)
+	if !ok {
+		t.Fatalf("value is not *ast.ModelLiteral. got=%T", program.Statements[0].(*ast.ConstStatement).Value)
+	}
+	if model.Extends == nil || model.Extends.String() != "Entity" {
+		t.Fatalf("wrong base model: %v", model.Extends)
+	}
+	expected := `const Post = model extends Entity implements Named {
+  title: string
+  constructor = function(title: string) { return super("admin") }
+  describe = function() { return super.describe() }
+}`
+	if program.String() != expected {
+		t.Errorf("expected=%q, got=%q", expected, program.String())
+	}
+
+	p = New(lexer.New("model extends { x: float }"))
+	p.ParseProgram()
+	if len(p.Errors()) == 0 {
+		t.Errorf("expected an error for a missing base model")
+	}
+}
+
 func TestRangeExpression(t *testing.T) {
 	t.Parallel()
 	tests := []struct {
@@ -670,6 +1012,11 @@ func TestModulePrefixedTypesInFunctions(t *testing.T) {
 			input:    "function(r: http.Request): http.Response { return null }",
 			expected: true,
 		},
+		{
+			name:     "function keyword as parameter type",
+			input:    "function(r: http.Request, next: function): http.Response { return next(r) }",
+			expected: true,
+		},
 		{
 			name:     "var with module-prefixed type",
 			input:    "var req: http.Request",
@@ -246,7 +246,7 @@ func arrayMap(args ...interpreter.Object) interpreter.Object {
 	newElements := make([]interpreter.Object, len(arr.Elements))
 	for i, element := range arr.Elements {
 		// Call the function with the element
-		result := applyFunction(fn, []interpreter.Object{element})
+		result := interpreter.CallFunction(fn, element)
 		if interpreter.IsError(result) {
 			return result
 		}
@@ -276,7 +276,7 @@ func arrayFilter(args ...interpreter.Object) interpreter.Object {
 	newElements := make([]interpreter.Object, 0)
 	for _, element := range arr.Elements {
 		// Call the function with the element
-		result := applyFunction(fn, []interpreter.Object{element})
+		result := interpreter.CallFunction(fn, element)
 		if interpreter.IsError(result) {
 			return result
 		}
@@ -311,7 +311,7 @@ func arrayReduce(args ...interpreter.Object) interpreter.Object {
 	// Reduce the array
 	for _, element := range arr.Elements {
 		// Call the function with accumulator and element
-		result := applyFunction(fn, []interpreter.Object{accumulator, element})
+		result := interpreter.CallFunction(fn, accumulator, element)
 		if interpreter.IsError(result) {
 			return result
 		}
@@ -340,7 +340,7 @@ func arrayEach(args ...interpreter.Object) interpreter.Object {
 	// Iterate over the array
 	for _, element := range arr.Elements {
 		// Call the function with the element
-		result := applyFunction(fn, []interpreter.Object{element})
+		result := interpreter.CallFunction(fn, element)
 		if interpreter.IsError(result) {
 			return result
 		}
@@ -386,26 +386,3 @@ func objectsEqual(a, b interpreter.Object) bool {
 		return false
 	}
 }
-
-// Helper function to apply a function (duplicated from interpreter package to avoid circular dependency).
-func applyFunction(fn *interpreter.Function, args []interpreter.Object) interpreter.Object {
-	// Create new environment for function execution
-	env := interpreter.NewEnclosedEnvironment(fn.Env)
-
-	// Bind parameters to arguments
-	for i, param := range fn.Parameters {
-		if i < len(args) {
-			env.Set(param.Name.Value, args[i])
-		}
-	}
-
-	// Execute function body
-	evaluated := interpreter.Eval(fn.Body, env)
-
-	// Unwrap return value
-	if returnValue, ok := evaluated.(*interpreter.ReturnValue); ok {
-		return returnValue.Value
-	}
-
-	return evaluated
-}
@@ -5,6 +5,8 @@ import (
 
 	"github.com/mishankov/totalscript-lang/internal/ast"
 	"github.com/mishankov/totalscript-lang/internal/interpreter"
+	"github.com/mishankov/totalscript-lang/internal/lexer"
+	"github.com/mishankov/totalscript-lang/internal/parser"
 )
 
 func TestArrayLength(t *testing.T) {
@@ -669,6 +671,61 @@ func TestArrayEach(t *testing.T) {
 	}
 }
 
+// testCallback evaluates a function literal to pass to array methods.
+func testCallback(t *testing.T, input string) *interpreter.Function {
+	t.Helper()
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+
+	fn, ok := interpreter.Eval(program, interpreter.NewEnvironment()).(*interpreter.Function)
+	if !ok {
+		t.Fatalf("%s is not a function", input)
+	}
+	return fn
+}
+
+func TestArrayCallbackParameters(t *testing.T) {
+	t.Parallel()
+
+	methods := ArrayMethods()
+	arr := &interpreter.Array{
+		Elements: []interpreter.Object{
+			&interpreter.Integer{Value: 1},
+			&interpreter.Integer{Value: 2},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		method   string
+		args     []interpreter.Object // arguments before the callback
+		callback string
+		expected string
+	}{
+		{"default parameter", "map", nil, "function(x, scale = 10) { return x * scale }", "[10, 20]"},
+		{"variadic parameter", "map", nil, "(...xs) => xs", "[[1], [2]]"},
+		{"typed lambda", "filter", nil, "(x: integer) => x > 1", "[2]"},
+		{"typed reduce", "reduce", []interpreter.Object{&interpreter.Float{Value: 0.5}}, "(acc: float, x: integer) => acc + x", "3.5"},
+		{"wrong parameter type", "map", nil, "(x: string) => x", "ERROR: parameter 'x': type mismatch: expected string, got integer"},
+		{"too many parameters", "each", nil, "(x, y) => x", "ERROR: wrong number of arguments: expected 2, got 1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			args := append([]interpreter.Object{arr}, tt.args...)
+			args = append(args, testCallback(t, tt.callback))
+			if got := methods[tt.method](args...).Inspect(); got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
 func TestArrayJoin(t *testing.T) {
 	t.Parallel()
 
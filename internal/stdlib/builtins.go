@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"strconv"
 
-	"github.com/mishankov/totalscript-lang/internal/ast"
 	"github.com/mishankov/totalscript-lang/internal/interpreter"
 )
 
@@ -121,15 +120,7 @@ var Boolean = &interpreter.Builtin{
 }
 
 // ErrorModel is the built-in Error model for error handling.
-var ErrorModel = &interpreter.Model{
-	Name:       "Error",
-	FieldNames: []string{"message"},
-	Fields: map[string]*ast.TypeExpression{
-		"message": nil, // Type information not strictly needed for built-in
-	},
-	Methods:      make(map[string]*interpreter.Function),
-	Constructors: make([]*interpreter.Function, 0),
-}
+var ErrorModel = interpreter.ErrorModel
 
 // Builtins returns all built-in functions as a map.
 func Builtins() map[string]*interpreter.Builtin {
@@ -19,10 +19,11 @@ const (
 	EOF     TokenType = "EOF"
 
 	// Identifiers and literals
-	IDENT   TokenType = "IDENT"   // variable names, function names, etc.
-	INTEGER TokenType = "INTEGER" // 42, -17, 0
-	FLOAT   TokenType = "FLOAT"   // 3.14, -0.5
-	STRING  TokenType = "STRING"  // "hello"
+	IDENT    TokenType = "IDENT"    // variable names, function names, etc.
+	INTEGER  TokenType = "INTEGER"  // 42, -17, 0
+	FLOAT    TokenType = "FLOAT"    // 3.14, -0.5
+	STRING   TokenType = "STRING"   // "hello"
+	TEMPLATE TokenType = "TEMPLATE" // "Hello ${name}"
 
 	// Operators
 	ASSIGN     TokenType = "="
@@ -33,6 +34,7 @@ const (
 	SlashSlash TokenType = "//" // integer division
 	PERCENT    TokenType = "%"
 	POWER      TokenType = "**"
+	ARROW      TokenType = "=>" // lambda: x => x * 2
 
 	// Comparison operators
 	EQ    TokenType = "=="
@@ -47,6 +49,10 @@ const (
 	OR  TokenType = "||"
 	NOT TokenType = "!"
 
+	// Null-safety operators
+	QuestionDot      TokenType = "?." // optional chaining
+	QuestionQuestion TokenType = "??" // null coalescing
+
 	// Compound assignment operators
 	PlusAssign     TokenType = "+="
 	MinusAssign    TokenType = "-="
@@ -61,6 +67,7 @@ const (
 	DOT       TokenType = "."
 	DotDot    TokenType = ".."  // range exclusive
 	DotDotEq  TokenType = "..=" // range inclusive
+	DotDotDot TokenType = "..." // rest and variadic parameters
 
 	LPAREN   TokenType = "("
 	RPAREN   TokenType = ")"
@@ -71,7 +78,7 @@ const (
 
 	// Type-related
 	PIPE     TokenType = "|" // union type
-	QUESTION TokenType = "?" // optional type
+	QUESTION TokenType = "?" // optional type, error propagation
 	AT       TokenType = "@" // annotation
 
 	// Keywords
@@ -99,6 +106,13 @@ const (
 	FALSE       TokenType = "FALSE"
 	NULL        TokenType = "NULL"
 	CONSTRUCTOR TokenType = "CONSTRUCTOR"
+	TRY         TokenType = "TRY"
+	CATCH       TokenType = "CATCH"
+	MATCH       TokenType = "MATCH"
+	INTERFACE   TokenType = "INTERFACE"
+	IMPLEMENTS  TokenType = "IMPLEMENTS"
+	EXTENDS     TokenType = "EXTENDS"
+	SUPER       TokenType = "SUPER"
 
 	// Database query modifiers
 	ORDERBY TokenType = "ORDERBY"
@@ -135,6 +149,13 @@ var keywords = map[string]TokenType{
 	"false":       FALSE,
 	"null":        NULL,
 	"constructor": CONSTRUCTOR,
+	"try":         TRY,
+	"catch":       CATCH,
+	"match":       MATCH,
+	"interface":   INTERFACE,
+	"implements":  IMPLEMENTS,
+	"extends":     EXTENDS,
+	"super":       SUPER,
 	"orderBy":     ORDERBY,
 	"limit":       LIMIT,
 	"offset":      OFFSET,
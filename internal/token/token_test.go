@@ -34,6 +34,10 @@ func TestLookupIdent(t *testing.T) {
 		{"false keyword", "false", FALSE},
 		{"null keyword", "null", NULL},
 		{"constructor keyword", "constructor", CONSTRUCTOR},
+		{"interface keyword", "interface", INTERFACE},
+		{"implements keyword", "implements", IMPLEMENTS},
+		{"extends keyword", "extends", EXTENDS},
+		{"super keyword", "super", SUPER},
 
 		// Identifiers (not keywords)
 		{"simple identifier", "foo", IDENT},